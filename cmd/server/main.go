@@ -9,6 +9,7 @@ import (
 	"crypto/tls"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
@@ -20,16 +21,23 @@ import (
 	"github.com/moov-io/base/admin"
 	"github.com/moov-io/paygate"
 	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/config/reload"
 	"github.com/moov-io/paygate/pkg/customers"
 	"github.com/moov-io/paygate/pkg/customers/accounts"
 	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/memberships"
+	membershipadmin "github.com/moov-io/paygate/pkg/memberships/admin"
 	"github.com/moov-io/paygate/pkg/organizations"
 	"github.com/moov-io/paygate/pkg/tenants"
 	tenantadmin "github.com/moov-io/paygate/pkg/tenants/admin"
 	"github.com/moov-io/paygate/pkg/transfers"
 	transferadmin "github.com/moov-io/paygate/pkg/transfers/admin"
+	"github.com/moov-io/paygate/pkg/transfers/deliveries"
 	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/inbound"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/returnrates"
+	"github.com/moov-io/paygate/pkg/transfers/review"
 	"github.com/moov-io/paygate/pkg/upload"
 	"github.com/moov-io/paygate/pkg/util"
 	"github.com/moov-io/paygate/x/route"
@@ -47,19 +55,18 @@ func main() {
 	flag.Parse()
 
 	// Read our config file
-	cfg := readConfig()
+	configFilepath := util.Or(os.Getenv("CONFIG_FILE"), *flagConfigFile, exampleConfigFilepath)
+	cfg := readConfig(configFilepath)
 
-	_, traceCloser, err := trace.NewConstantTracer(cfg.Logger, "paygate")
-	if err != nil {
-		panic(fmt.Sprintf("ERROR starting tracer: %v", err))
+	if traceCloser := setupTracing(cfg); traceCloser != nil {
+		defer traceCloser.Close()
 	}
-	defer traceCloser.Close()
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	defer cancelFunc()
 
 	// migrate database
-	db, err := database.New(ctx, cfg.Logger, database.Type())
+	db, err := database.New(ctx, cfg.Logger, cfg.Database)
 	if err != nil {
 		panic(fmt.Sprintf("error creating database: %v", err))
 	}
@@ -90,9 +97,6 @@ func main() {
 	}()
 	defer adminServer.Shutdown()
 
-	// Find our fundflow strategy
-	fundflowStrategy := fundflow.NewFirstPerson(cfg.Logger, cfg.ODFI)
-
 	// Setup our transfer publisher
 	transferPublisher, err := pipeline.NewPublisher(cfg.Pipeline)
 	if err != nil {
@@ -106,59 +110,151 @@ func main() {
 	}
 	defer transferSubscription.Shutdown(ctx)
 
-	agent, err := upload.New(cfg.Logger, cfg.ODFI)
-	if err != nil {
-		// We don't want to crash the system on this failure. It's an important
-		// connection, but not strictly required as the issue may be resolved
-		// without a restart of PayGate.
-		cfg.Logger.Log("main", fmt.Sprintf("problem with upload.Agent connection: %v", err))
-	}
-	defer agent.Close()
-	adminServer.AddLivenessCheck(upload.Type(cfg.ODFI), agent.Ping)
+	// Customers
+	customersClient := customers.NewClient(cfg.Logger, cfg.Customers.Endpoint, customers.HttpClient)
+	adminServer.AddLivenessCheck("customers", customersClient.Ping)
 
-	merger, err := pipeline.NewMerging(cfg.Logger, cfg.Pipeline)
+	// Accounts
+	accountDecryptor, err := accounts.NewDecryptor(cfg.Customers.Accounts.Decryptor, customersClient)
 	if err != nil {
-		panic(fmt.Sprintf("ERROR setting up xfer merging: %v", err))
+		panic(fmt.Sprintf("ERROR creating account decryptor: %v", err))
 	}
 
-	cutoffs, err := schedule.ForCutoffTimes(cfg.ODFI.Cutoffs.Timezone, cfg.ODFI.Cutoffs.Windows)
+	// Find our fundflow strategy
+	fundflowStrategy, err := fundflow.New(cfg.Logger, cfg, customersClient, accountDecryptor)
 	if err != nil {
-		panic(fmt.Sprintf("ERROR setting up cutoff times: %v", err))
-	} else {
-		cfg.Logger.Log("main", fmt.Sprintf("registered %s cutoffs=%v", cfg.ODFI.Cutoffs.Timezone, strings.Join(cfg.ODFI.Cutoffs.Windows, ",")))
+		panic(fmt.Sprintf("ERROR creating fundflow strategy: %v", err))
 	}
 
-	xferAgg := pipeline.NewAggregator(cfg.Logger, cfg.ODFI, agent, merger, transferSubscription)
-	go xferAgg.Start(ctx, cutoffs)
+	// Record each upload of an outbound file to the ODFI
+	deliveryRepo := deliveries.NewRepo(db)
+	deliveries.RegisterAdminRoutes(cfg.Logger, adminServer, deliveryRepo)
 
-	// Customers
-	customersClient := customers.NewClient(cfg.Logger, cfg.Customers.Endpoint, customers.HttpClient)
-	adminServer.AddLivenessCheck("customers", customersClient.Ping)
+	transfersRepo := transfers.NewRepo(db)
+	defer transfersRepo.Close()
 
-	// Accounts
-	accountDecryptor, err := accounts.NewDecryptor(cfg.Customers.Accounts.Decryptor, customersClient)
+	// Archive and record each file downloaded from an ODFI so it's only processed once.
+	// Returns and corrections are recorded against the Transfer they were originated for
+	// and our fundflow strategy originates any refunds or reversals.
+	inboundRepo := inbound.NewRepo(db)
+	returnHandler := transfers.NewReturnHandler(cfg.Logger, transfersRepo, fundflowStrategy, transferPublisher)
+	inboundProcessors := make(map[string]*inbound.Processor)
+	xferAggregators := make(map[string]*pipeline.XferAggregator)
+	uploadAgents := make(map[string]*upload.SwapAgent)
+
+	// Each ODFI has its own connection, cutoff times and merged files. Xfers are
+	// consumed once and handed to the ODFI they're routed to.
+	xferConsumer := pipeline.NewConsumer(cfg.Logger, transferSubscription)
+	for _, odfi := range cfg.ListODFIs() {
+		name := upload.Type(odfi)
+		if odfi.Name != "" {
+			name = fmt.Sprintf("%s-%s", name, odfi.Name)
+		}
+
+		conn, err := upload.New(cfg.Logger, odfi)
+		if err != nil {
+			// We don't want to crash the system on this failure. It's an important
+			// connection, but not strictly required as the issue may be resolved
+			// without a restart of PayGate.
+			cfg.Logger.Log("main", fmt.Sprintf("problem with %s upload.Agent connection: %v", name, err))
+		}
+		// The connection is replaced when its config is reloaded
+		agent := upload.NewSwapAgent(conn)
+		defer agent.Close()
+		uploadAgents[odfi.Name] = agent
+		adminServer.AddLivenessCheck(name, agent.Ping)
+
+		gpg, err := upload.NewGPG(odfi.GPG)
+		if err != nil {
+			panic(fmt.Sprintf("ERROR reading %s GPG keys: %v", name, err))
+		}
+
+		merger, err := pipeline.NewMerging(cfg.Logger, cfg.Pipeline, odfi.Name)
+		if err != nil {
+			panic(fmt.Sprintf("ERROR setting up %s xfer merging: %v", name, err))
+		}
+
+		cutoffs, err := schedule.ForCutoffTimes(odfi.Cutoffs.Timezone, odfi.Cutoffs.Windows)
+		if err != nil {
+			panic(fmt.Sprintf("ERROR setting up %s cutoff times: %v", name, err))
+		} else {
+			cfg.Logger.Log("main", fmt.Sprintf("registered %s %s cutoffs=%v", name, odfi.Cutoffs.Timezone, strings.Join(odfi.Cutoffs.Windows, ",")))
+		}
+
+		xferConsumer.AddODFI(odfi.Name, merger)
+
+		if odfi.Storage.ArchiveDirectory() != "" {
+			processor, err := inbound.NewProcessor(cfg.Logger, odfi, agent, gpg, inboundRepo)
+			if err != nil {
+				panic(fmt.Sprintf("ERROR setting up %s inbound processing: %v", name, err))
+			}
+			processor.AddHandler(returnHandler)
+			inboundProcessors[odfi.Name] = processor
+			go processor.Start(ctx)
+		} else {
+			cfg.Logger.Log("main", fmt.Sprintf("%s has no storage directory, skipping inbound file downloads", name))
+		}
+
+		if odfi.DryRun.Active() {
+			cfg.Logger.Log("main", fmt.Sprintf("%s is in dry run mode, merged files are written to %s", name, odfi.DryRun.ReviewDirectory(odfi.Name)))
+		}
+		xferAgg := pipeline.NewAggregator(cfg.Logger, odfi, agent, gpg, deliveryRepo, merger)
+		xferAggregators[odfi.Name] = xferAgg
+		go xferAgg.Start(ctx, cutoffs)
+	}
+	go xferConsumer.Start(ctx)
+	inbound.RegisterAdminRoutes(cfg.Logger, adminServer, inboundRepo, inboundProcessors)
+	pipeline.RegisterAdminRoutes(cfg.Logger, adminServer, xferAggregators)
+
+	// Apply changes to the config file without a restart
+	reloader := reload.New(cfg.Logger, configFilepath, cfg)
+	reloader.Register(pipeline.ReloadODFIs(cfg.Logger, xferAggregators, uploadAgents))
+	reload.RegisterAdminRoutes(cfg.Logger, adminServer, reloader)
+	go reloader.Start(ctx, configReloadInterval)
+
+	fundsChecker, err := fundflow.NewFundsChecker(cfg.Logger, cfg, db)
 	if err != nil {
-		panic(fmt.Sprintf("ERROR creating account decryptor: %v", err))
+		panic(fmt.Sprintf("ERROR creating funds checker: %v", err))
+	}
+	if fc := cfg.Fundflow.FundsCheck; fc != nil && fc.SQL != nil {
+		fundflow.RegisterAdminRoutes(cfg.Logger, adminServer, db)
+	}
+
+	// Monitor return rates of each originator
+	var returnRateChecker returnrates.Checker
+	if cfg.ReturnRates != nil {
+		monitor := returnrates.NewMonitor(cfg.Logger, cfg.ReturnRates, returnrates.NewRepo(db))
+		go monitor.Start(ctx)
+		returnrates.RegisterAdminRoutes(cfg.Logger, adminServer, monitor)
+		returnRateChecker = monitor
 	}
 
 	// Create HTTP handler
 	handler := mux.NewRouter()
 	route.PingRoute(cfg.Logger, handler)
 
-	// Organizations
-	organizationRepo := organizations.NewRepo(db)
-	organizations.NewRouter(cfg.Logger, organizationRepo).RegisterRoutes(handler)
+	// Memberships
+	membershipsRepo := memberships.NewRepo(db)
 
 	// Tenants
 	tenantsRepo := tenants.NewRepo(db)
-	tenants.NewRouter(cfg.Logger, tenantsRepo).RegisterRoutes(handler)
-	tenantadmin.RegisterRoutes(cfg.Logger, adminServer, tenantsRepo)
+	tenants.NewRouter(cfg.Logger, tenantsRepo, membershipsRepo).RegisterRoutes(handler)
+	tenantadmin.RegisterRoutes(cfg.Logger, adminServer, tenantsRepo, cfg)
+
+	// Organizations
+	organizationRepo := organizations.NewRepo(db)
+	organizations.NewRouter(cfg.Logger, organizationRepo, membershipsRepo).RegisterRoutes(handler)
+	membershipadmin.RegisterRoutes(cfg.Logger, adminServer, membershipsRepo, tenantsRepo, organizationRepo)
 
 	// Transfers
-	transfersRepo := transfers.NewRepo(db)
-	defer transfersRepo.Close()
-	transfers.NewRouter(cfg.Logger, transfersRepo, tenantsRepo, customersClient, accountDecryptor, fundflowStrategy, transferPublisher).RegisterRoutes(handler)
-	transferadmin.RegisterRoutes(cfg.Logger, adminServer, transfersRepo)
+	reviewRules, err := review.NewChecker(cfg.Logger, cfg.Review, review.NewRepo(db), customersClient)
+	if err != nil {
+		panic(fmt.Sprintf("ERROR creating review rules: %v", err))
+	}
+	transferRouter := transfers.NewRouter(cfg.Logger, transfersRepo, tenantsRepo, membershipsRepo, customersClient, accountDecryptor, fundflowStrategy, fundsChecker, returnRateChecker, reviewRules, cfg.ListODFIs(), cfg.Limits, transferPublisher)
+	transferRouter.RegisterRoutes(handler)
+	go transfers.NewHoldReleaser(cfg.Logger, transfersRepo, transferPublisher).Start(ctx)
+	transferadmin.RegisterRoutes(cfg.Logger, adminServer, transfersRepo, tenantsRepo, transferRouter.Reviews, transferRouter.Files, fundsChecker, cfg.Limits, transferPublisher)
 
 	// Create main HTTP server
 	serve := &http.Server{
@@ -182,9 +278,9 @@ func main() {
 
 	// Start main HTTP server
 	go func() {
-		if certFile, keyFile := os.Getenv("HTTPS_CERT_FILE"), os.Getenv("HTTPS_KEY_FILE"); certFile != "" && keyFile != "" {
+		if tls := cfg.Http.TLS; tls.Enabled() {
 			cfg.Logger.Log("startup", fmt.Sprintf("binding to %s for secure HTTP server", cfg.Http.BindAddress))
-			if err := serve.ListenAndServeTLS(certFile, keyFile); err != nil {
+			if err := serve.ListenAndServeTLS(tls.CertFile, tls.KeyFile); err != nil {
 				cfg.Logger.Log("exit", err)
 			}
 		} else {
@@ -202,14 +298,39 @@ func main() {
 
 var (
 	exampleConfigFilepath = filepath.Join("examples", "config.yaml")
+
+	// configReloadInterval is how often the config file is checked for changes
+	configReloadInterval = 30 * time.Second
 )
 
-func readConfig() *config.Config {
-	path := util.Or(os.Getenv("CONFIG_FILE"), *flagConfigFile, exampleConfigFilepath)
+func readConfig(path string) *config.Config {
 	cfg, err := config.FromFile(path)
 	if err != nil {
 		panic(fmt.Sprintf("failed to load config: %v", err))
 	}
+	if err := cfg.Validate(); err != nil {
+		panic(fmt.Sprintf("invalid config: %v", err))
+	}
 	cfg.Logger.Log("startup", fmt.Sprintf("Starting paygate server version %s", paygate.Version))
 	return cfg
 }
+
+func setupTracing(cfg *config.Config) io.Closer {
+	var (
+		closer io.Closer
+		err    error
+	)
+	switch cfg.Tracing.SamplerType() {
+	case config.TracingOff:
+		cfg.Logger.Log("startup", "tracing is disabled")
+		return nil
+	case config.TracingProbabilistic:
+		_, closer, err = trace.NewProbabilisticTracer(cfg.Logger, "paygate", cfg.Tracing.Rate)
+	default:
+		_, closer, err = trace.NewConstantTracer(cfg.Logger, "paygate")
+	}
+	if err != nil {
+		panic(fmt.Sprintf("ERROR starting tracer: %v", err))
+	}
+	return closer
+}
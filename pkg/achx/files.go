@@ -28,18 +28,10 @@ type Destination struct {
 }
 
 func ConstrctFile(id string, odfi config.ODFI, companyID string, xfer *client.Transfer, source Source, destination Destination) (*ach.File, error) {
-	file, now := ach.NewFile(), time.Now()
+	file := ach.NewFile()
 	file.ID = id
 	file.Control = ach.NewFileControl()
-
-	// File Header
-	file.Header.ID = id
-	file.Header.ImmediateOrigin = odfi.Gateway.Origin
-	file.Header.ImmediateOriginName = odfi.Gateway.OriginName
-	file.Header.ImmediateDestination = odfi.Gateway.Destination
-	file.Header.ImmediateDestinationName = odfi.Gateway.DestinationName
-	file.Header.FileCreationDate = now.Format("060102") // YYMMDD
-	file.Header.FileCreationTime = now.Format("1504")   // HHMM
+	file.Header = FileHeader(id, odfi)
 
 	// Right now we only support creating PPD files
 	batch, err := createPPDBatch(id, odfi, companyID, xfer, source, destination)
@@ -50,3 +42,17 @@ func ConstrctFile(id string, odfi config.ODFI, companyID string, xfer *client.Tr
 
 	return file, file.Validate()
 }
+
+// FileHeader returns the header of a file uploaded to the ODFI, addressed with its Gateway.
+func FileHeader(id string, odfi config.ODFI) ach.FileHeader {
+	now := time.Now()
+	header := ach.NewFileHeader()
+	header.ID = id
+	header.ImmediateOrigin = odfi.Gateway.Origin
+	header.ImmediateOriginName = odfi.Gateway.OriginName
+	header.ImmediateDestination = odfi.Gateway.Destination
+	header.ImmediateDestinationName = odfi.Gateway.DestinationName
+	header.FileCreationDate = now.Format("060102") // YYMMDD
+	header.FileCreationTime = now.Format("1504")   // HHMM
+	return header
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package achx
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	customers "github.com/moov-io/customers/client"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/model"
+)
+
+// Remote is an account held outside of the ODFI which is debited or credited
+// against the ODFI's settlement account.
+type Remote struct {
+	Customer customers.Customer
+	Account  customers.Account
+
+	// AccountNumber contains the decrypted account number from the customers service
+	AccountNumber string
+}
+
+// ConstrctSettlementFile creates an ACH file with a balanced PPD batch. The Remote account is
+// debited (or credited) and the ODFI's settlement account is offset by the same amount.
+//
+// companyName is shown on the Remote account's statement and effective is the date
+// the entries are posted.
+func ConstrctSettlementFile(id string, odfi config.ODFI, settlement config.SettlementAccount, companyID string, companyName string, xfer *client.Transfer, remote Remote, debit bool, effective time.Time) (*ach.File, error) {
+	file, now := ach.NewFile(), time.Now()
+	file.ID = id
+	file.Control = ach.NewFileControl()
+
+	// File Header
+	file.Header.ID = id
+	file.Header.ImmediateOrigin = odfi.Gateway.Origin
+	file.Header.ImmediateOriginName = odfi.Gateway.OriginName
+	file.Header.ImmediateDestination = odfi.Gateway.Destination
+	file.Header.ImmediateDestinationName = odfi.Gateway.DestinationName
+	file.Header.FileCreationDate = now.Format("060102") // YYMMDD
+	file.Header.FileCreationTime = now.Format("1504")   // HHMM
+
+	var amt model.Amount
+	if err := amt.FromString(xfer.Amount); err != nil {
+		return nil, fmt.Errorf("unable to parse '%s': %v", xfer.Amount, err)
+	}
+
+	bh := ach.NewBatchHeader()
+	bh.ID = id
+	bh.ServiceClassCode = ach.MixedDebitsAndCredits
+	bh.StandardEntryClassCode = ach.PPD
+	bh.CompanyName = companyName
+	bh.CompanyIdentification = companyID
+	bh.CompanyEntryDescription = xfer.Description
+	bh.CompanyDescriptiveDate = now.Format("060102")
+	bh.EffectiveEntryDate = effective.Format("060102") // Date to be posted, YYMMDD
+	bh.ODFIIdentification = ABA8(odfi.RoutingNumber)
+
+	batch, err := ach.NewBatch(bh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create settlement batch: %v", err)
+	}
+
+	// Entry against the remote account
+	ed := ach.NewEntryDetail()
+	ed.ID = id
+	ed.TransactionCode = remoteTransactionCode(remote.Account.Type, debit)
+	ed.RDFIIdentification = ABA8(remote.Account.RoutingNumber)
+	ed.CheckDigit = ABACheckDigit(remote.Account.RoutingNumber)
+	ed.DFIAccountNumber = remote.AccountNumber
+	ed.Amount = amt.Int()
+	ed.IdentificationNumber = createIdentificationNumber()
+	ed.IndividualName = fmt.Sprintf("%s %s", remote.Customer.FirstName, remote.Customer.LastName)
+	ed.DiscretionaryData = xfer.Description
+	batch.AddEntry(ed)
+
+	// Offsetting entry against the ODFI's settlement account
+	offset := ach.NewEntryDetail()
+	offset.ID = id
+	offset.TransactionCode = settlementTransactionCode(settlement.AccountType, !debit)
+	offset.RDFIIdentification = ABA8(settlement.RoutingNumber)
+	offset.CheckDigit = ABACheckDigit(settlement.RoutingNumber)
+	offset.DFIAccountNumber = settlement.AccountNumber
+	offset.Amount = amt.Int()
+	offset.IdentificationNumber = createIdentificationNumber()
+	offset.IndividualName = settlement.Name
+	batch.AddEntry(offset)
+
+	// TraceNumbers must be ascending within a batch
+	first, second := TraceNumber(odfi.RoutingNumber), TraceNumber(odfi.RoutingNumber)
+	if second <= first {
+		first, second = second, first
+	}
+	ed.TraceNumber, offset.TraceNumber = first, second
+
+	batch.SetControl(ach.NewBatchControl())
+	if err := batch.Create(); err != nil {
+		return nil, fmt.Errorf("constructSettlementFile: %v", err)
+	}
+	file.AddBatch(batch)
+
+	if err := file.Create(); err != nil {
+		return nil, err
+	}
+	return file, file.Validate()
+}
+
+func remoteTransactionCode(accountType customers.AccountType, debit bool) int {
+	switch accountType {
+	case customers.CHECKING:
+		if debit {
+			return ach.CheckingDebit
+		}
+		return ach.CheckingCredit
+	case customers.SAVINGS:
+		if debit {
+			return ach.SavingsDebit
+		}
+		return ach.SavingsCredit
+	}
+	return 0 // invalid, represents a logic bug
+}
+
+func settlementTransactionCode(accountType string, debit bool) int {
+	if strings.EqualFold(accountType, string(customers.SAVINGS)) {
+		return remoteTransactionCode(customers.SAVINGS, debit)
+	}
+	return remoteTransactionCode(customers.CHECKING, debit)
+}
@@ -9,6 +9,8 @@ import (
 	"math/rand"
 	"time"
 	"unicode/utf8"
+
+	"github.com/moov-io/ach"
 )
 
 var (
@@ -49,3 +51,24 @@ func ABACheckDigit(rtn string) string {
 	}
 	return rtn[8:9]
 }
+
+// TraceNumbers returns the trace number of each entry in a file.
+func TraceNumbers(file *ach.File) []string {
+	var out []string
+	for _, entry := range Entries(file) {
+		out = append(out, entry.TraceNumber)
+	}
+	return out
+}
+
+// Entries returns each entry of every batch in a file.
+func Entries(file *ach.File) []*ach.EntryDetail {
+	if file == nil {
+		return nil
+	}
+	var out []*ach.EntryDetail
+	for i := range file.Batches {
+		out = append(out, file.Batches[i].GetEntries()...)
+	}
+	return out
+}
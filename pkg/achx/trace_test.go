@@ -5,7 +5,10 @@
 package achx
 
 import (
+	"path/filepath"
 	"testing"
+
+	"github.com/moov-io/ach"
 )
 
 func TestTrace__ABA(t *testing.T) {
@@ -31,3 +34,31 @@ func TestTraceNumber(t *testing.T) {
 		t.Error("empty trace number")
 	}
 }
+
+func TestTraceNumbers(t *testing.T) {
+	if v := TraceNumbers(nil); len(v) != 0 {
+		t.Errorf("unexpected trace numbers: %v", v)
+	}
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := TraceNumbers(file); len(v) != 1 || v[0] != "076401255655291" {
+		t.Errorf("unexpected trace numbers: %v", v)
+	}
+}
+
+func TestEntries(t *testing.T) {
+	if v := Entries(nil); len(v) != 0 {
+		t.Errorf("unexpected entries: %v", v)
+	}
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := Entries(file); len(v) != 1 || v[0].CreditOrDebit() != "D" {
+		t.Errorf("unexpected entries: %#v", v)
+	}
+}
@@ -49,6 +49,20 @@ type APIClient struct {
 
 	AdminApi *AdminApiService
 
+	ConfigApi *ConfigApiService
+
+	DeliveriesApi *DeliveriesApiService
+
+	InboundApi *InboundApiService
+
+	LedgerApi *LedgerApiService
+
+	MembershipsApi *MembershipsApiService
+
+	MergingApi *MergingApiService
+
+	ReturnRatesApi *ReturnRatesApiService
+
 	TenantsApi *TenantsApiService
 
 	TransfersApi *TransfersApiService
@@ -71,6 +85,13 @@ func NewAPIClient(cfg *Configuration) *APIClient {
 
 	// API Services
 	c.AdminApi = (*AdminApiService)(&c.common)
+	c.ConfigApi = (*ConfigApiService)(&c.common)
+	c.DeliveriesApi = (*DeliveriesApiService)(&c.common)
+	c.InboundApi = (*InboundApiService)(&c.common)
+	c.LedgerApi = (*LedgerApiService)(&c.common)
+	c.MembershipsApi = (*MembershipsApiService)(&c.common)
+	c.MergingApi = (*MergingApiService)(&c.common)
+	c.ReturnRatesApi = (*ReturnRatesApiService)(&c.common)
 	c.TenantsApi = (*TenantsApiService)(&c.common)
 	c.TransfersApi = (*TransfersApiService)(&c.common)
 
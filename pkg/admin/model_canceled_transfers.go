@@ -0,0 +1,20 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// CanceledTransfers struct for CanceledTransfers
+type CanceledTransfers struct {
+	// Transfers which were canceled
+	Canceled []Transfer `json:"canceled,omitempty"`
+	// Pending Transfers which were already uploaded to the ODFI and can't be canceled
+	Uploaded []Transfer `json:"uploaded,omitempty"`
+	// Pending Transfers which failed to cancel
+	Failed []FailedCancel `json:"failed,omitempty"`
+}
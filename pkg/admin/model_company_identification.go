@@ -0,0 +1,24 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// CompanyIdentification struct for CompanyIdentification
+type CompanyIdentification struct {
+	// Value written into the CompanyIdentification field of each batch
+	CompanyIdentification string `json:"companyIdentification,omitempty"`
+	// When the value was assigned to the Tenant
+	Created time.Time `json:"created,omitempty"`
+	// When the value was replaced by another, empty for the current value
+	Retired time.Time `json:"retired,omitempty"`
+}
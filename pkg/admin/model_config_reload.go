@@ -0,0 +1,31 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// ConfigReload struct for ConfigReload
+type ConfigReload struct {
+	// Filepath of the config file
+	Path string `json:"path,omitempty"`
+	// Hex encoded SHA-256 checksum of the config file as read
+	Checksum string `json:"checksum,omitempty"`
+	// What started the reload
+	Trigger string `json:"trigger,omitempty"`
+	// Changes which are now in effect
+	Applied []string `json:"applied,omitempty"`
+	// Changes which PayGate needs to be restarted to apply
+	Restart []string `json:"restart,omitempty"`
+	// Why the config file couldn't be applied, it's unchanged when present
+	Error    string    `json:"error,omitempty"`
+	Reloaded time.Time `json:"reloaded,omitempty"`
+}
@@ -15,4 +15,10 @@ type CreateTenant struct {
 	Name string `json:"name,omitempty"`
 	// A customerID from the Customers service to use in Transfers with this Tenant. When transferring to or from the Tenant this Customer and Account(s) are used. The Customer assigned here should represent the legal entity that manages the Tenant.
 	PrimaryCustomer string `json:"primaryCustomer,omitempty"`
+	// Name of the ODFI that Transfers for this Tenant are originated through. When empty the first configured ODFI is used.
+	ODFI string `json:"odfi,omitempty"`
+	// CompanyIdentification to assign instead of one from the configured strategy. Up to 10 uppercase letters or digits.
+	CompanyIdentification string `json:"companyIdentification,omitempty"`
+	// Employer Identification Number of the Tenant, required when CompanyIdentifications are assigned from EINs
+	EIN string `json:"ein,omitempty"`
 }
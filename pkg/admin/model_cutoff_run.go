@@ -0,0 +1,30 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// CutoffRun struct for CutoffRun
+type CutoffRun struct {
+	RunID string `json:"runID,omitempty"`
+	// Name of the ODFI files were uploaded to
+	Odfi string `json:"odfi,omitempty"`
+	// Cutoff time as HH:MM in the ODFI's timezone
+	Window string `json:"window,omitempty"`
+	// Manual runs were triggered by an operator instead of a cutoff time
+	Manual bool `json:"manual,omitempty"`
+	// Why some files failed to merge or upload, empty when every file was uploaded
+	Error      string     `json:"error,omitempty"`
+	Deliveries []Delivery `json:"deliveries,omitempty"`
+	Started    time.Time  `json:"started,omitempty"`
+	Finished   time.Time  `json:"finished,omitempty"`
+}
@@ -0,0 +1,31 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// Delivery struct for Delivery
+type Delivery struct {
+	DeliveryID string `json:"deliveryID,omitempty"`
+	// Filename uploaded to the ODFI
+	Filename string `json:"filename,omitempty"`
+	// Number of bytes uploaded
+	Size int64 `json:"size,omitempty"`
+	// Hex encoded SHA-256 checksum of the uploaded file
+	Sha256   string            `json:"sha256,omitempty"`
+	Status   string            `json:"status,omitempty"`
+	Attempts []DeliveryAttempt `json:"attempts,omitempty"`
+	// Cutoff run which uploaded the file
+	RunID       string    `json:"runID,omitempty"`
+	Created     time.Time `json:"created,omitempty"`
+	LastUpdated time.Time `json:"lastUpdated,omitempty"`
+}
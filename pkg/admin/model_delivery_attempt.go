@@ -0,0 +1,23 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// DeliveryAttempt struct for DeliveryAttempt
+type DeliveryAttempt struct {
+	// Sequence number of this attempt starting at 1
+	Attempt int32 `json:"attempt,omitempty"`
+	// Why the upload failed, empty when the file was delivered
+	Error   string    `json:"error,omitempty"`
+	Created time.Time `json:"created,omitempty"`
+}
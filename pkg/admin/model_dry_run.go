@@ -0,0 +1,24 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// DryRun struct for DryRun
+type DryRun struct {
+	// Name of the ODFI files were merged for
+	Odfi string `json:"odfi,omitempty"`
+	// Local directory the merged files were written to
+	Directory string       `json:"directory,omitempty"`
+	Files     []MergedFile `json:"files,omitempty"`
+	Created   time.Time    `json:"created,omitempty"`
+}
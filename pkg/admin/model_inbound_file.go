@@ -0,0 +1,31 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+import (
+	"time"
+)
+
+// InboundFile struct for InboundFile
+type InboundFile struct {
+	// Hex encoded SHA-256 checksum of the file as downloaded
+	Sha256 string `json:"sha256,omitempty"`
+	// Name of the ODFI the file was received from
+	Odfi     string `json:"odfi,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	// Where the file was read from on the ODFI's server
+	RemotePath string `json:"remotePath,omitempty"`
+	// Number of bytes downloaded
+	Size     int64     `json:"size,omitempty"`
+	Received time.Time `json:"received,omitempty"`
+	// When the file was last processed, empty if it hasn't been processed successfully
+	Processed time.Time `json:"processed,omitempty"`
+}
@@ -0,0 +1,23 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+package admin
+
+import (
+	"time"
+)
+
+// Membership struct for Membership
+type Membership struct {
+	// userID of the member
+	UserID string `json:"userID,omitempty"`
+	// Role of the member which is one of owner, admin, operator or viewer
+	Role string `json:"role,omitempty"`
+	// When the user became a member
+	Created time.Time `json:"created,omitempty"`
+}
@@ -0,0 +1,24 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// MergedFile struct for MergedFile
+type MergedFile struct {
+	// Name the file was written as by a dry run
+	Filename             string `json:"filename,omitempty"`
+	ImmediateDestination string `json:"immediateDestination,omitempty"`
+	ImmediateOrigin      string `json:"immediateOrigin,omitempty"`
+	// Number of batches in the file
+	Batches int32 `json:"batches,omitempty"`
+	// Number of entries in the file
+	Entries     int32  `json:"entries,omitempty"`
+	TotalDebit  string `json:"totalDebit,omitempty"`
+	TotalCredit string `json:"totalCredit,omitempty"`
+}
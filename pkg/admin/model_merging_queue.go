@@ -0,0 +1,20 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// MergingQueue struct for MergingQueue
+type MergingQueue struct {
+	// Name of the ODFI files are uploaded to
+	Odfi string `json:"odfi,omitempty"`
+	// Transfers waiting for the next cutoff
+	Transfers []Transfer `json:"transfers,omitempty"`
+	// Files the next cutoff would upload
+	Files []MergedFile `json:"files,omitempty"`
+}
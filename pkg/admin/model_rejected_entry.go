@@ -0,0 +1,20 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// RejectedEntry struct for RejectedEntry
+type RejectedEntry struct {
+	// BatchNumber of the batch containing this entry
+	BatchNumber int32 `json:"batchNumber"`
+	// TraceNumber of the rejected entry
+	TraceNumber string `json:"traceNumber"`
+	// Reason the entry was rejected
+	Error string `json:"error"`
+}
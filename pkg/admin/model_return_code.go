@@ -0,0 +1,20 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// ReturnCode struct for ReturnCode
+type ReturnCode struct {
+	// Optional NACHA return code for this Transfer
+	Code string `json:"code"`
+	// Short NACHA description of return code
+	Reason string `json:"reason"`
+	// Long form explanation of return code
+	Description string `json:"description"`
+}
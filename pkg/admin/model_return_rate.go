@@ -0,0 +1,35 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// ReturnRate struct for ReturnRate
+type ReturnRate struct {
+	OriginatorType string `json:"originatorType,omitempty"`
+	// ID of the Tenant, Organization or the Company Identification
+	OriginatorID string `json:"originatorID,omitempty"`
+	// Count of Transfers created in the window
+	Transfers int32 `json:"transfers,omitempty"`
+	// Count of Transfers created in the window which originated a debit
+	Debits int32 `json:"debits,omitempty"`
+	// Count of Transfers returned as unauthorized (R05, R07, R10, R29, R51)
+	UnauthorizedReturns int32 `json:"unauthorizedReturns,omitempty"`
+	// Count of Transfers returned for administrative reasons (R02, R03, R04)
+	AdministrativeReturns int32 `json:"administrativeReturns,omitempty"`
+	// Count of all returned Transfers
+	Returns int32 `json:"returns,omitempty"`
+	// Percentage of debits returned as unauthorized
+	UnauthorizedRate float32 `json:"unauthorizedRate,omitempty"`
+	// Percentage of Transfers returned for administrative reasons
+	AdministrativeRate float32 `json:"administrativeRate,omitempty"`
+	// Percentage of Transfers returned
+	OverallRate float32 `json:"overallRate,omitempty"`
+	// If the Originator is paused for exceeding a return rate threshold
+	Paused bool `json:"paused,omitempty"`
+}
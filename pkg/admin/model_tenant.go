@@ -17,4 +17,8 @@ type Tenant struct {
 	Name string `json:"name"`
 	// A customerID from the Customers service to use in Transfers with this Tenant. When transferring to or from the Tenant this Customer and Account(s) are used. The Customer assigned here should represent the legal entity that manages the Tenant.
 	PrimaryCustomer string `json:"primaryCustomer"`
+	// Name of the ODFI that Transfers for this Tenant are originated through. When empty the first configured ODFI is used.
+	ODFI string `json:"odfi,omitempty"`
+	// Suspended Tenants are unable to create Transfers. One of active or suspended.
+	Status string `json:"status,omitempty"`
 }
@@ -0,0 +1,26 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// TenantSettings struct for TenantSettings
+type TenantSettings struct {
+	// Name written into each batch instead of the source Customer's name. Limited to 16 characters.
+	CompanyName string `json:"companyName,omitempty"`
+	// Standard Entry Class code batches are created with. One of PPD or CCD, PPD when empty.
+	DefaultSECCode string `json:"defaultSECCode,omitempty"`
+	// Name of the ODFI that Transfers for this Tenant are originated through. When empty the first configured ODFI is used.
+	ODFI string `json:"odfi,omitempty"`
+	// Largest amount allowed for a single entry, e.g. USD 25000.00
+	MaxEntryAmount string `json:"maxEntryAmount,omitempty"`
+	// Largest total of all entries in an uploaded file
+	MaxFileAmount string `json:"maxFileAmount,omitempty"`
+	// Most entries accepted in one uploaded file
+	MaxFileEntries int32 `json:"maxFileEntries,omitempty"`
+}
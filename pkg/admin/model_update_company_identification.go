@@ -0,0 +1,18 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package admin
+
+// UpdateCompanyIdentification struct for UpdateCompanyIdentification
+type UpdateCompanyIdentification struct {
+	// CompanyIdentification to assign instead of one from the configured strategy. Up to 10 uppercase letters or digits.
+	CompanyIdentification string `json:"companyIdentification,omitempty"`
+	// Employer Identification Number of the Tenant, required when CompanyIdentifications are assigned from EINs
+	EIN string `json:"ein,omitempty"`
+}
@@ -0,0 +1,15 @@
+/*
+ * Paygate Admin API
+ *
+ * PayGate is a RESTful API enabling first-party Automated Clearing House ([ACH](https://en.wikipedia.org/wiki/Automated_Clearing_House)) transfers to be created without a deep understanding of a full NACHA file specification. First-party transfers initiate at an Originating Depository Financial Institution (ODFI) and are sent off to other Financial Institutions.
+ *
+ * API version: v1
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+package admin
+
+// UpdateMembership struct for UpdateMembership
+type UpdateMembership struct {
+	// Role of the member which is one of owner, admin, operator or viewer
+	Role string `json:"role"`
+}
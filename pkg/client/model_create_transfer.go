@@ -19,4 +19,6 @@ type CreateTransfer struct {
 	Description string `json:"description"`
 	// When set to true this indicates the transfer should be processed the same day if possible.
 	SameDay bool `json:"sameDay,omitempty"`
+	// organizationID of the caller's Organization to create this Transfer for. Transfers can be listed by their Organization.
+	OrganizationID string `json:"organizationID,omitempty"`
 }
@@ -28,4 +28,6 @@ type Transfer struct {
 	SameDay    bool       `json:"sameDay"`
 	ReturnCode ReturnCode `json:"returnCode,omitempty"`
 	Created    time.Time  `json:"created"`
+	// organizationID of the Organization this Transfer was created for
+	OrganizationID string `json:"organizationID,omitempty"`
 }
@@ -4,6 +4,17 @@
 
 package config
 
+import (
+	"errors"
+)
+
 type Admin struct {
-	BindAddress string `yaml:"bind_address"`
+	BindAddress string `yaml:"bind_address" env:"HTTP_ADMIN_BIND_ADDRESS"`
+}
+
+func (cfg Admin) Validate() error {
+	if cfg.BindAddress == "" {
+		return errors.New("missing bind_address")
+	}
+	return nil
 }
@@ -24,9 +24,26 @@ type Config struct {
 	Http  HTTP  `yaml:"http"`
 	Admin Admin `yaml:"admin"`
 
-	ODFI     ODFI     `yaml:"odfi"`
+	Database Database `yaml:"database"`
+	Tracing  Tracing  `yaml:"tracing"`
+
+	ODFI ODFI `yaml:"odfi"`
+
+	// ODFIs holds each named ODFI when Transfers are originated at more than one financial
+	// institution. Tenants are assigned to an ODFI by its name.
+	ODFIs []ODFI `yaml:"odfis"`
+
+	Fundflow Fundflow `yaml:"fundflow"`
 	Pipeline Pipeline `yaml:"pipeline"`
 
+	ReturnRates *ReturnRates `yaml:"return_rates"`
+
+	Review *Review `yaml:"review"`
+
+	Limits Limits `yaml:"limits"`
+
+	Tenants Tenants `yaml:"tenants"`
+
 	Customers Customers `yaml:"customers"`
 }
 
@@ -42,8 +59,9 @@ func Empty() *Config {
 	}
 }
 
+// FromFile reads the YAML config at path, when it's non-empty, with each field
+// overridden by its environment variable.
 func FromFile(path string) (*Config, error) {
-	cfg := Empty()
 	if path != "" {
 		bs, err := ioutil.ReadFile(path)
 		if err != nil {
@@ -51,7 +69,7 @@ func FromFile(path string) (*Config, error) {
 		}
 		return Read(bs)
 	}
-	return setupLogger(cfg), nil
+	return Read(nil)
 }
 
 func Read(data []byte) (*Config, error) {
@@ -59,6 +77,9 @@ func Read(data []byte) (*Config, error) {
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("unmarshal: %v", err)
 	}
+	if err := cfg.OverrideFromEnv(); err != nil {
+		return nil, fmt.Errorf("environment: %v", err)
+	}
 	return setupLogger(cfg), nil
 }
 
@@ -81,8 +102,103 @@ func (cfg *Config) Validate() error {
 	if cfg == nil {
 		return errors.New("missing Config")
 	}
-	if err := cfg.ODFI.Validate(); err != nil {
-		return fmt.Errorf("odfi: %v", err)
+	switch strings.ToLower(cfg.LogFormat) {
+	case "", "json", "plain":
+	default:
+		return fmt.Errorf("unknown log_format %q", cfg.LogFormat)
+	}
+	if err := cfg.Http.Validate(); err != nil {
+		return fmt.Errorf("http: %v", err)
+	}
+	if err := cfg.Admin.Validate(); err != nil {
+		return fmt.Errorf("admin: %v", err)
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		return fmt.Errorf("database: %v", err)
+	}
+	if err := cfg.Tracing.Validate(); err != nil {
+		return fmt.Errorf("tracing: %v", err)
+	}
+	if err := cfg.validateODFIs(); err != nil {
+		return err
+	}
+	if err := cfg.Fundflow.Validate(); err != nil {
+		return fmt.Errorf("fundflow: %v", err)
+	}
+	if err := cfg.Pipeline.Validate(); err != nil {
+		return fmt.Errorf("pipeline: %v", err)
+	}
+	if err := cfg.ReturnRates.Validate(); err != nil {
+		return fmt.Errorf("return_rates: %v", err)
+	}
+	if err := cfg.Review.Validate(); err != nil {
+		return fmt.Errorf("review: %v", err)
+	}
+	if err := cfg.Limits.Validate(); err != nil {
+		return fmt.Errorf("limits: %v", err)
+	}
+	if err := cfg.Tenants.Validate(); err != nil {
+		return fmt.Errorf("tenants: %v", err)
+	}
+	if err := cfg.Customers.Validate(); err != nil {
+		return fmt.Errorf("customers: %v", err)
+	}
+	return nil
+}
+
+// ListODFIs returns each configured ODFI. The single ODFI is returned when no named ODFIs
+// are configured. The first ODFI is used for Tenants which aren't assigned to one.
+func (cfg *Config) ListODFIs() []ODFI {
+	if cfg == nil {
+		return nil
+	}
+	if len(cfg.ODFIs) > 0 {
+		return cfg.ODFIs
+	}
+	return []ODFI{cfg.ODFI}
+}
+
+// FindODFI returns the ODFI with the given name, or the first ODFI when name is empty.
+func (cfg *Config) FindODFI(name string) (*ODFI, error) {
+	odfis := cfg.ListODFIs()
+	if len(odfis) == 0 {
+		return nil, errors.New("no ODFIs configured")
+	}
+	if name == "" {
+		return &odfis[0], nil
+	}
+	for i := range odfis {
+		if odfis[i].Name == name {
+			return &odfis[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown ODFI %q", name)
+}
+
+func (cfg *Config) validateODFIs() error {
+	if len(cfg.ODFIs) == 0 {
+		if err := cfg.ODFI.Validate(); err != nil {
+			return fmt.Errorf("odfi: %v", err)
+		}
+		return nil
+	}
+	if cfg.ODFI.RoutingNumber != "" {
+		return errors.New("only one of odfi or odfis can be set")
+	}
+	names := make(map[string]bool)
+	for i := range cfg.ODFIs {
+		name := cfg.ODFIs[i].Name
+		if name == "" {
+			return fmt.Errorf("odfis[%d]: missing name", i)
+		}
+		if names[name] {
+			return fmt.Errorf("odfis[%d]: duplicate name %q", i, name)
+		}
+		names[name] = true
+
+		if err := cfg.ODFIs[i].Validate(); err != nil {
+			return fmt.Errorf("odfis[%s]: %v", name, err)
+		}
 	}
 	return nil
 }
@@ -5,7 +5,10 @@
 package config
 
 import (
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -129,4 +132,691 @@ func TestConfig__SFTP(t *testing.T) {
 	if v := cfg.PacketSize(); v != 20480 {
 		t.Errorf("max_packet_size=%d", v)
 	}
+	if v := cfg.Sessions(); v != 1 {
+		t.Errorf("max_sessions=%d", v)
+	}
+	if v := cfg.Keepalive(); v != 30*time.Second {
+		t.Errorf("keepalive_interval=%v", v)
+	}
+
+	cfg = &SFTP{MaxSessions: 4, KeepaliveInterval: time.Minute}
+	if v := cfg.Sessions(); v != 4 {
+		t.Errorf("max_sessions=%d", v)
+	}
+	if v := cfg.Keepalive(); v != time.Minute {
+		t.Errorf("keepalive_interval=%v", v)
+	}
+}
+
+func TestConfig__SFTPHostKeys(t *testing.T) {
+	var cfg *SFTP
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &SFTP{StrictHostKeyChecking: true}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.HostPublicKey = "ssh-ed25519 AAAA"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.KnownHostsFile = filepath.Join("testdata", "missing_known_hosts")
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__Fundflow(t *testing.T) {
+	cfg := Fundflow{}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.ThirdParty = &ThirdPartyFundflow{
+		Settlement: SettlementAccount{
+			RoutingNumber: "987654320",
+			AccountNumber: "12345",
+			AccountType:   "checking",
+		},
+		HoldDays: 2,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.ThirdParty.Settlement.AccountType = "other"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.FirstParty = &FirstPartyFundflow{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__FundsCheck(t *testing.T) {
+	var cfg *FundsCheck
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Review() {
+		t.Error("expected reject")
+	}
+
+	cfg = &FundsCheck{
+		Insufficient: "review",
+		SQL:          &SQLFundsCheck{},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if !cfg.Review() {
+		t.Error("expected review")
+	}
+
+	cfg.HTTP = &HTTPFundsCheck{Endpoint: "http://localhost:8080/balance"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.SQL = nil
+	cfg.Insufficient = "other"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__ReturnRates(t *testing.T) {
+	var cfg *ReturnRates
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Window() != 60*24*time.Hour {
+		t.Errorf("unexpected window: %v", cfg.Window())
+	}
+
+	cfg = &ReturnRates{
+		WindowDays: 30,
+		Thresholds: ReturnRateThresholds{
+			Overall: 10.0,
+		},
+		Paused: "review",
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if !cfg.Review() || cfg.Window() != 30*24*time.Hour {
+		t.Errorf("unexpected config: %#v", cfg)
+	}
+	if cfg.Thresholds.UnauthorizedLimit() != 0.5 || cfg.Thresholds.OverallLimit() != 10.0 {
+		t.Errorf("unexpected thresholds: %#v", cfg.Thresholds)
+	}
+
+	cfg.Thresholds.Administrative = 120.0
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__Review(t *testing.T) {
+	var cfg *Review
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if amt, err := cfg.Threshold(); amt != nil || err != nil {
+		t.Errorf("amount=%v error=%v", amt, err)
+	}
+
+	cfg = &Review{
+		AmountThreshold: "USD 10000.00",
+		NewDestination:  true,
+		OFACMatch:       0.95,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if amt, err := cfg.Threshold(); err != nil || amt.Int() != 1000000 {
+		t.Errorf("amount=%v error=%v", amt, err)
+	}
+
+	cfg.OFACMatch = 1.5
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.OFACMatch = 0.0
+	cfg.AmountThreshold = "10000"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__Limits(t *testing.T) {
+	var cfg Limits
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if amt, err := cfg.EntryAmount(); amt != nil || err != nil {
+		t.Errorf("amount=%v error=%v", amt, err)
+	}
+
+	cfg.MaxEntryAmount = "USD 25000.00"
+	cfg.MaxFileAmount = "USD 1000000.00"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if amt, err := cfg.EntryAmount(); err != nil || amt.Int() != 2500000 {
+		t.Errorf("amount=%v error=%v", amt, err)
+	}
+
+	cfg.MaxFileAmount = "1000"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.MaxFileAmount = ""
+	cfg.MaxFileEntries = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__CompanyIdentification(t *testing.T) {
+	var cfg Tenants
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	ci := cfg.CompanyIdentification
+	if ci.AssignmentStrategy() != CompanyIdentificationRandom || ci.RandomPrefix() != "MOOV" {
+		t.Errorf("unexpected defaults: %#v", ci)
+	}
+
+	cfg.CompanyIdentification = CompanyIdentification{Strategy: "EIN", Prefix: "ACME"}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if s := cfg.CompanyIdentification.AssignmentStrategy(); s != CompanyIdentificationEIN {
+		t.Errorf("unexpected strategy %q", s)
+	}
+
+	cfg.CompanyIdentification.Strategy = "other"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.CompanyIdentification = CompanyIdentification{Prefix: "TOOLONGPREFIX"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.CompanyIdentification.Prefix = "moov"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__GPG(t *testing.T) {
+	var cfg *GPG
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &GPG{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.ODFIPublicKey = "/opt/moov/odfi.pub"
+	cfg.SigningKey = &GPGPrivateKey{Password: "secret"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.SigningKey.Path = "/opt/moov/paygate.priv"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if s := cfg.SigningKey.String(); strings.Contains(s, "secret") {
+		t.Errorf("password not masked: %s", s)
+	}
+}
+
+func TestConfig__LocalAgent(t *testing.T) {
+	var cfg *LocalAgent
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &LocalAgent{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Directory = "/mnt/odfi/"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfig__BucketAgent(t *testing.T) {
+	var cfg *BucketAgent
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg = &BucketAgent{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.URL = "gs://my-bucket"
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfig__UploadRetry(t *testing.T) {
+	var cfg *UploadRetry
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.MaxAttempts(); n != 3 {
+		t.Errorf("unexpected attempts: %d", n)
+	}
+	if d := cfg.Delay(1); d != 10*time.Second {
+		t.Errorf("unexpected delay: %v", d)
+	}
+	if d := cfg.Delay(20); d != 5*time.Minute {
+		t.Errorf("unexpected delay: %v", d)
+	}
+
+	cfg = &UploadRetry{Attempts: 5, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+		if d := cfg.Delay(attempt + 1); d != expected {
+			t.Errorf("attempt %d: got %v, expected %v", attempt+1, d, expected)
+		}
+	}
+
+	cfg.Backoff = time.Minute
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Attempts = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__Inbound(t *testing.T) {
+	var cfg *Inbound
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if d := cfg.DownloadInterval(); d != 10*time.Minute {
+		t.Errorf("unexpected interval: %v", d)
+	}
+
+	cfg = &Inbound{Interval: time.Minute}
+	if d := cfg.DownloadInterval(); d != time.Minute {
+		t.Errorf("unexpected interval: %v", d)
+	}
+	cfg.Interval = -1 * time.Second
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	var storage *Storage
+	if dir := storage.ArchiveDirectory(); dir != "" {
+		t.Errorf("unexpected directory: %q", dir)
+	}
+	storage = &Storage{Local: &Local{Directory: "/opt/moov/storage/"}}
+	if dir := storage.ArchiveDirectory(); dir != filepath.Join("/opt/moov/storage", "archive") {
+		t.Errorf("unexpected directory: %q", dir)
+	}
+}
+
+func TestConfig__DryRun(t *testing.T) {
+	var cfg *DryRun
+	if cfg.Active() {
+		t.Error("expected inactive")
+	}
+	if dir := cfg.ReviewDirectory("first"); dir != filepath.Join("storage", "review", "first") {
+		t.Errorf("unexpected directory: %q", dir)
+	}
+
+	cfg = &DryRun{Enabled: true, Directory: "/opt/moov/review/"}
+	if !cfg.Active() {
+		t.Error("expected active")
+	}
+	if dir := cfg.ReviewDirectory("first"); dir != "/opt/moov/review/" {
+		t.Errorf("unexpected directory: %q", dir)
+	}
+}
+
+func TestConfig__ODFIs(t *testing.T) {
+	conf := []byte(`odfis:
+  - name: "bank-a"
+    routing_number: "987654320"
+    cutoffs:
+      timezone: "America/New_York"
+      windows: ["17:00"]
+    ftp:
+      hostname: "ftp.bank-a.com:21"
+  - name: "bank-b"
+    routing_number: "121042882"
+    cutoffs:
+      timezone: "America/Los_Angeles"
+      windows: ["16:30"]
+    sftp:
+      hostname: "sftp.bank-b.com:22"
+pipeline:
+  stream:
+    inmem:
+      url: "mem://paygate"
+`)
+	cfg, err := Read(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if odfis := cfg.ListODFIs(); len(odfis) != 2 {
+		t.Fatalf("unexpected ODFIs: %#v", odfis)
+	}
+	odfi, err := cfg.FindODFI("bank-b")
+	if err != nil || odfi.RoutingNumber != "121042882" {
+		t.Errorf("odfi=%#v error=%v", odfi, err)
+	}
+	odfi, err = cfg.FindODFI("")
+	if err != nil || odfi.Name != "bank-a" {
+		t.Errorf("odfi=%#v error=%v", odfi, err)
+	}
+	if _, err := cfg.FindODFI("bank-c"); err == nil {
+		t.Error("expected error")
+	}
+
+	// names must be unique
+	cfg.ODFIs[1].Name = "bank-a"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.ODFIs[1].Name = ""
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	// can't mix odfi and odfis
+	cfg.ODFIs[1].Name = "bank-b"
+	cfg.ODFI.RoutingNumber = "987654320"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__SingleODFI(t *testing.T) {
+	cfg := Empty()
+	cfg.ODFI.RoutingNumber = "987654320"
+
+	odfis := cfg.ListODFIs()
+	if len(odfis) != 1 || odfis[0].RoutingNumber != "987654320" {
+		t.Errorf("unexpected ODFIs: %#v", odfis)
+	}
+	if odfi, err := cfg.FindODFI(""); err != nil || odfi.RoutingNumber != "987654320" {
+		t.Errorf("odfi=%#v error=%v", odfi, err)
+	}
+}
+
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Unsetenv(key) })
+}
+
+func TestConfig__Env(t *testing.T) {
+	setenv(t, "LOG_FORMAT", "plain")
+	setenv(t, "HTTP_BIND_ADDRESS", ":9082")
+	setenv(t, "DATABASE_TYPE", "mysql")
+	setenv(t, "MYSQL_USER", "paygate") // older variable
+	setenv(t, "DATABASE_MYSQL_TIMEOUT", "5s")
+	setenv(t, "ODFI_CUTOFFS_WINDOWS", "16:20, 17:00")
+	setenv(t, "ODFIS_0_FTP_PASSWORD", "secret")
+	setenv(t, "TRACING_RATE", "0.5")
+
+	cfg, err := Read([]byte(`log_format: json
+odfis:
+  - name: "bank-a"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.LogFormat != "plain" || cfg.Http.BindAddress != ":9082" {
+		t.Errorf("log_format=%q http=%#v", cfg.LogFormat, cfg.Http)
+	}
+	if cfg.Database.Provider() != "mysql" || cfg.Database.MySQL.User != "paygate" || cfg.Database.MySQL.Timeout != 5*time.Second {
+		t.Errorf("unexpected database: %#v", cfg.Database.MySQL)
+	}
+	if cfg.Database.SQLite != nil {
+		t.Errorf("unexpected sqlite: %#v", cfg.Database.SQLite)
+	}
+	if w := cfg.ODFI.Cutoffs.Windows; len(w) != 2 || w[1] != "17:00" {
+		t.Errorf("unexpected windows: %q", w)
+	}
+	if ftp := cfg.ODFIs[0].FTP; ftp == nil || ftp.Password != "secret" {
+		t.Errorf("unexpected ftp: %#v", ftp)
+	}
+	if cfg.Tracing.Rate != 0.5 {
+		t.Errorf("unexpected tracing: %#v", cfg.Tracing)
+	}
+
+	setenv(t, "MYSQL_MAX_CONNECTIONS", "many")
+	if _, err := Read(nil); err == nil || !strings.Contains(err.Error(), "MYSQL_MAX_CONNECTIONS") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestConfig__EnvFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "paygate-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "password")
+	if err := ioutil.WriteFile(path, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	setenv(t, "DATABASE_MYSQL_PASSWORD_FILE", path)
+
+	cfg, err := Read(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Database.MySQL.Password != "secret" {
+		t.Errorf("unexpected password: %q", cfg.Database.MySQL.Password)
+	}
+
+	setenv(t, "DATABASE_MYSQL_PASSWORD_FILE", filepath.Join(dir, "missing"))
+	if _, err := Read(nil); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__Database(t *testing.T) {
+	var cfg Database
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if path := cfg.SQLite.Filepath(); path != "paygate.db" {
+		t.Errorf("unexpected path: %q", path)
+	}
+
+	cfg.SQLite = &SQLite{Path: "../paygate.db"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Type = "MySQL"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.MySQL = &MySQL{
+		Address:  "tcp(localhost:3306)",
+		User:     "paygate",
+		Database: "paygate",
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+	if n := cfg.MySQL.MaxOpenConnections(); n != 16 {
+		t.Errorf("max connections: %d", n)
+	}
+	if d := cfg.MySQL.ConnectTimeout(); d != 30*time.Second {
+		t.Errorf("timeout: %v", d)
+	}
+
+	cfg.MySQL.MaxConnections = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Type = "postgres"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__HTTP(t *testing.T) {
+	cfg := Empty()
+	if err := cfg.Http.Validate(); err != nil {
+		t.Error(err)
+	}
+	if cfg.Http.TLS.Enabled() {
+		t.Error("expected TLS to be disabled")
+	}
+
+	cfg.Http.TLS = &TLS{CertFile: filepath.Join("testdata", "valid.yaml")}
+	if err := cfg.Http.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Http.TLS.KeyFile = filepath.Join("testdata", "missing.key")
+	if err := cfg.Http.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Http.TLS.KeyFile = filepath.Join("testdata", "invalid.yaml")
+	if err := cfg.Http.Validate(); err != nil {
+		t.Error(err)
+	}
+	if !cfg.Http.TLS.Enabled() {
+		t.Error("expected TLS to be enabled")
+	}
+
+	cfg.Admin.BindAddress = ""
+	if err := cfg.Admin.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__Tracing(t *testing.T) {
+	var cfg Tracing
+	if err := cfg.Validate(); err != nil || cfg.SamplerType() != TracingConstant {
+		t.Errorf("sampler=%q error=%v", cfg.SamplerType(), err)
+	}
+
+	cfg.Sampler = "Probabilistic"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Rate = 0.1
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.Sampler = "remote"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__Pipeline(t *testing.T) {
+	var cfg Pipeline
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Stream = &StreamPipeline{
+		InMem: &InMemPipeline{URL: "mem://paygate"},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.Stream.Kafka = &KafkaPipeline{Brokers: []string{"localhost:9092"}, Topic: "paygate"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	cfg.Stream.InMem = nil
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.Merging = &Merging{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__Customers(t *testing.T) {
+	cfg := Customers{Endpoint: "http://localhost:8087"}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.Endpoint = "http://[::1"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg.Endpoint = ""
+	cfg.Accounts.Decryptor.Symmetric = &Symmetric{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__Cutoffs(t *testing.T) {
+	cfg := Cutoffs{Windows: []string{"16:20"}}
+	if err := cfg.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	cfg.Windows = append(cfg.Windows, "25:99")
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestConfig__LogFormat(t *testing.T) {
+	cfg, err := FromFile(filepath.Join("testdata", "valid.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.LogFormat = "xml"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected error")
+	}
 }
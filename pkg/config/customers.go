@@ -4,11 +4,29 @@
 
 package config
 
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
 type Customers struct {
 	Endpoint string   `yaml:"endpoint"`
 	Accounts Accounts `yaml:"accounts"`
 }
 
+func (cfg Customers) Validate() error {
+	if cfg.Endpoint != "" {
+		if _, err := url.Parse(cfg.Endpoint); err != nil {
+			return fmt.Errorf("endpoint: %v", err)
+		}
+	}
+	if sym := cfg.Accounts.Decryptor.Symmetric; sym != nil && sym.KeyURI == "" {
+		return errors.New("accounts: decryptor: symmetric: missing keyURI")
+	}
+	return nil
+}
+
 type Accounts struct {
 	Decryptor Decryptor `yaml:"decryptor"`
 }
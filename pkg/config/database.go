@@ -0,0 +1,102 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Database configures where PayGate stores its records.
+type Database struct {
+	// Type is either "sqlite" (default) or "mysql"
+	Type string `yaml:"type"`
+
+	SQLite *SQLite `yaml:"sqlite"`
+	MySQL  *MySQL  `yaml:"mysql"`
+}
+
+// Provider returns the lowercased database type, defaulting to sqlite.
+func (cfg Database) Provider() string {
+	if cfg.Type == "" {
+		return "sqlite"
+	}
+	return strings.ToLower(cfg.Type)
+}
+
+func (cfg Database) Validate() error {
+	switch cfg.Provider() {
+	case "sqlite":
+		return cfg.SQLite.Validate()
+	case "mysql":
+		if err := cfg.MySQL.Validate(); err != nil {
+			return fmt.Errorf("mysql: %v", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown type %q", cfg.Type)
+}
+
+type SQLite struct {
+	// Path is the filepath of the database, which defaults to paygate.db
+	Path string `yaml:"path" env:"SQLITE_DB_PATH"`
+}
+
+func (cfg *SQLite) Filepath() string {
+	if cfg == nil || cfg.Path == "" {
+		return "paygate.db"
+	}
+	return cfg.Path
+}
+
+func (cfg *SQLite) Validate() error {
+	if path := cfg.Filepath(); strings.Contains(path, "..") {
+		return fmt.Errorf("sqlite: path %q can't contain '..'", path)
+	}
+	return nil
+}
+
+type MySQL struct {
+	// Address is the network and host of the server, e.g. tcp(localhost:3306)
+	Address  string `yaml:"address" env:"MYSQL_ADDRESS"`
+	User     string `yaml:"user" env:"MYSQL_USER"`
+	Password string `yaml:"password" env:"MYSQL_PASSWORD"`
+	Database string `yaml:"database" env:"MYSQL_DATABASE"`
+
+	// MaxConnections limits how many connections are open at once, defaults to 16
+	MaxConnections int `yaml:"max_connections" env:"MYSQL_MAX_CONNECTIONS"`
+
+	// Timeout is used when connecting, reading and writing, defaults to 30s
+	Timeout time.Duration `yaml:"timeout" env:"MYSQL_TIMEOUT"`
+}
+
+func (cfg *MySQL) Validate() error {
+	if cfg == nil {
+		return errors.New("missing MySQL config")
+	}
+	if cfg.Address == "" || cfg.User == "" || cfg.Database == "" {
+		return errors.New("address, user and database are required")
+	}
+	if cfg.MaxConnections < 0 || cfg.Timeout < 0 {
+		return errors.New("negative max_connections or timeout")
+	}
+	return nil
+}
+
+func (cfg *MySQL) MaxOpenConnections() int {
+	if cfg == nil || cfg.MaxConnections == 0 {
+		return 16
+	}
+	return cfg.MaxConnections
+}
+
+func (cfg *MySQL) ConnectTimeout() time.Duration {
+	if cfg == nil || cfg.Timeout == 0 {
+		return 30 * time.Second
+	}
+	return cfg.Timeout
+}
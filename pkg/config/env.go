@@ -0,0 +1,180 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OverrideFromEnv replaces fields of the Config with environment variables. Each field is read
+// from its YAML path uppercased and joined with underscores, so http.bind_address is read from
+// HTTP_BIND_ADDRESS and the second ODFI's sftp.password from ODFIS_1_SFTP_PASSWORD. Some fields
+// are also read from older variables listed in their env tag, like MYSQL_USER.
+//
+// Secrets can be read from a file by setting the variable with a _FILE suffix instead, such as
+// DATABASE_MYSQL_PASSWORD_FILE=/run/secrets/mysql-password.
+//
+// Lists are comma separated and durations are parsed with time.ParseDuration. Empty variables
+// are ignored.
+func (cfg *Config) OverrideFromEnv() error {
+	_, err := overrideStruct(reflect.ValueOf(cfg).Elem(), "")
+	return err
+}
+
+func overrideStruct(v reflect.Value, prefix string) (bool, error) {
+	set := false
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name) // yaml.v2's default
+		}
+
+		envName := prefix + strings.ToUpper(name)
+		names := []string{envName}
+		if alias := field.Tag.Get("env"); alias != "" {
+			names = append(names, alias)
+		}
+		ok, err := overrideValue(v.Field(i), envName, names)
+		if err != nil {
+			return set, err
+		}
+		set = set || ok
+	}
+	return set, nil
+}
+
+func overrideValue(v reflect.Value, envName string, names []string) (bool, error) {
+	switch v.Kind() {
+	case reflect.Struct:
+		return overrideStruct(v, envName+"_")
+
+	case reflect.Ptr:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return false, nil
+		}
+		// nil sections are created when any of their fields are set
+		elem := v
+		if v.IsNil() {
+			elem = reflect.New(v.Type().Elem())
+		}
+		ok, err := overrideStruct(elem.Elem(), envName+"_")
+		if ok && v.IsNil() {
+			v.Set(elem)
+		}
+		return ok, err
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			set := false
+			for i := 0; i < v.Len(); i++ {
+				ok, err := overrideStruct(v.Index(i), fmt.Sprintf("%s_%d_", envName, i))
+				if err != nil {
+					return set, err
+				}
+				set = set || ok
+			}
+			return set, nil
+		}
+
+	case reflect.Interface, reflect.Map, reflect.Func, reflect.Chan:
+		return false, nil
+	}
+
+	for _, name := range names {
+		value, err := lookupEnv(name)
+		if err != nil {
+			return false, err
+		}
+		if value != "" {
+			if err := setValue(v, value); err != nil {
+				return false, fmt.Errorf("%s: %v", name, err)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// lookupEnv returns the value of an environment variable, or the contents of the file named
+// by name_FILE when it's set instead.
+func lookupEnv(name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		bs, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("%s_FILE: %v", name, err)
+		}
+		return strings.TrimRight(string(bs), "\r\n"), nil
+	}
+	return "", nil
+}
+
+func setValue(v reflect.Value, value string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		dur, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(dur))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %v", v.Type())
+		}
+		var values []string
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+		v.Set(reflect.ValueOf(values))
+
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+)
+
+// Fundflow determines which fundflow.Strategy is used to originate transfers.
+// When neither strategy is configured FirstParty is used.
+type Fundflow struct {
+	FirstParty *FirstPartyFundflow `yaml:"first_party"`
+	ThirdParty *ThirdPartyFundflow `yaml:"third_party"`
+
+	FundsCheck *FundsCheck `yaml:"funds_check"`
+}
+
+func (cfg Fundflow) Validate() error {
+	if cfg.FirstParty != nil && cfg.ThirdParty != nil {
+		return errors.New("only one of first_party or third_party can be configured")
+	}
+	if cfg.ThirdParty != nil {
+		if err := cfg.ThirdParty.Validate(); err != nil {
+			return fmt.Errorf("third_party: %v", err)
+		}
+	}
+	if err := cfg.FundsCheck.Validate(); err != nil {
+		return fmt.Errorf("funds_check: %v", err)
+	}
+	return nil
+}
+
+type FirstPartyFundflow struct{}
+
+// ThirdPartyFundflow moves money between two accounts outside of the ODFI. The source
+// is debited into a settlement account at the ODFI and after HoldDays banking days
+// the destination is credited from the settlement account.
+type ThirdPartyFundflow struct {
+	Settlement SettlementAccount `yaml:"settlement"`
+
+	// HoldDays is how many banking days funds are held in the settlement account
+	// after the source is debited and before the destination is credited.
+	HoldDays int `yaml:"hold_days"`
+}
+
+func (cfg *ThirdPartyFundflow) Validate() error {
+	if cfg == nil {
+		return errors.New("missing ThirdPartyFundflow config")
+	}
+	if err := cfg.Settlement.Validate(); err != nil {
+		return fmt.Errorf("settlement: %v", err)
+	}
+	if cfg.HoldDays < 0 {
+		return fmt.Errorf("negative hold_days: %d", cfg.HoldDays)
+	}
+	return nil
+}
+
+// SettlementAccount is an account held at the ODFI which is used to offset
+// each leg of a pass-through transfer.
+type SettlementAccount struct {
+	Name          string `yaml:"name"`
+	RoutingNumber string `yaml:"routing_number"`
+	AccountNumber string `yaml:"account_number"`
+
+	// AccountType is either "checking" or "savings"
+	AccountType string `yaml:"account_type"`
+}
+
+func (cfg SettlementAccount) Validate() error {
+	if err := ach.CheckRoutingNumber(cfg.RoutingNumber); err != nil {
+		return err
+	}
+	if cfg.AccountNumber == "" {
+		return errors.New("missing account_number")
+	}
+	switch strings.ToLower(cfg.AccountType) {
+	case "checking", "savings":
+		return nil
+	}
+	return fmt.Errorf("unknown account_type %q", cfg.AccountType)
+}
+
+// FundsCheck configures how the available balance of an ODFI account is verified
+// prior to originating a credit from it. When neither SQL or HTTP are set every
+// Transfer is allowed.
+type FundsCheck struct {
+	// Insufficient determines what happens to a Transfer whose source account can't
+	// cover the amount. Options: "reject" (default) or "review"
+	Insufficient string `yaml:"insufficient"`
+
+	SQL  *SQLFundsCheck  `yaml:"sql"`
+	HTTP *HTTPFundsCheck `yaml:"http"`
+}
+
+// Review returns true if Transfers with insufficient funds should be held in
+// the REVIEWABLE status instead of being rejected.
+func (cfg *FundsCheck) Review() bool {
+	if cfg == nil {
+		return false
+	}
+	return strings.EqualFold(cfg.Insufficient, "review")
+}
+
+func (cfg *FundsCheck) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	switch strings.ToLower(cfg.Insufficient) {
+	case "", "reject", "review":
+	default:
+		return fmt.Errorf("unknown insufficient option %q", cfg.Insufficient)
+	}
+	if cfg.SQL != nil && cfg.HTTP != nil {
+		return errors.New("only one of sql or http can be configured")
+	}
+	if cfg.HTTP != nil && cfg.HTTP.Endpoint == "" {
+		return errors.New("http: missing endpoint")
+	}
+	return nil
+}
+
+// SQLFundsCheck reads balances from a ledger stored in PayGate's database.
+type SQLFundsCheck struct{}
+
+// HTTPFundsCheck calls out to a core banking system for the available balance.
+type HTTPFundsCheck struct {
+	Endpoint string        `yaml:"endpoint"`
+	Timeout  time.Duration `yaml:"timeout"`
+}
+
+// RequestTimeout is how long each balance request can take, including reading the response.
+func (cfg *HTTPFundsCheck) RequestTimeout() time.Duration {
+	if cfg == nil || cfg.Timeout == 0*time.Second {
+		return 10 * time.Second
+	}
+	return cfg.Timeout
+}
@@ -4,6 +4,51 @@
 
 package config
 
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
 type HTTP struct {
 	BindAddress string `yaml:"bind_address"`
+
+	// TLS serves the HTTP server over secure HTTP when set
+	TLS *TLS `yaml:"tls"`
+}
+
+func (cfg HTTP) Validate() error {
+	if cfg.BindAddress == "" {
+		return errors.New("missing bind_address")
+	}
+	if err := cfg.TLS.Validate(); err != nil {
+		return fmt.Errorf("tls: %v", err)
+	}
+	return nil
+}
+
+// TLS holds a certificate (or intermediate chain) and the private key matching its leaf.
+type TLS struct {
+	CertFile string `yaml:"cert_file" env:"HTTPS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"HTTPS_KEY_FILE"`
+}
+
+// Enabled returns true when both files are configured.
+func (cfg *TLS) Enabled() bool {
+	return cfg != nil && cfg.CertFile != "" && cfg.KeyFile != ""
+}
+
+func (cfg *TLS) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return errors.New("both cert_file and key_file are required")
+	}
+	for _, path := range []string{cfg.CertFile, cfg.KeyFile} {
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,57 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/moov-io/paygate/pkg/model"
+)
+
+// Limits restrict the size of Transfers and uploaded files. Empty values are unlimited.
+type Limits struct {
+	// MaxEntryAmount is the largest amount allowed for a single entry, e.g. "USD 25000.00"
+	MaxEntryAmount string `yaml:"max_entry_amount"`
+
+	// MaxFileAmount is the largest total of all entries in an uploaded file
+	MaxFileAmount string `yaml:"max_file_amount"`
+
+	// MaxFileEntries is the most entries accepted in one uploaded file
+	MaxFileEntries int `yaml:"max_file_entries"`
+}
+
+func (cfg Limits) Validate() error {
+	if _, err := cfg.EntryAmount(); err != nil {
+		return fmt.Errorf("max_entry_amount: %v", err)
+	}
+	if _, err := cfg.FileAmount(); err != nil {
+		return fmt.Errorf("max_file_amount: %v", err)
+	}
+	if cfg.MaxFileEntries < 0 {
+		return fmt.Errorf("negative max_file_entries: %d", cfg.MaxFileEntries)
+	}
+	return nil
+}
+
+// EntryAmount returns the parsed MaxEntryAmount or nil when unlimited.
+func (cfg Limits) EntryAmount() (*model.Amount, error) {
+	return parseLimit(cfg.MaxEntryAmount)
+}
+
+// FileAmount returns the parsed MaxFileAmount or nil when unlimited.
+func (cfg Limits) FileAmount() (*model.Amount, error) {
+	return parseLimit(cfg.MaxFileAmount)
+}
+
+func parseLimit(value string) (*model.Amount, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var amt model.Amount
+	if err := amt.FromString(value); err != nil {
+		return nil, err
+	}
+	return &amt, nil
+}
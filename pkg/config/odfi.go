@@ -8,6 +8,8 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -29,6 +31,9 @@ var (
 // ODFI holds all the configuration for sending and retrieving ACH files with
 // a financial institution to originate files.
 type ODFI struct {
+	// Name identifies this ODFI when more than one is configured
+	Name string `yaml:"name"`
+
 	// RoutingNumber is a valid ABA routing number
 	RoutingNumber string `yaml:"routing_number"`
 
@@ -42,15 +47,32 @@ type ODFI struct {
 	OutboundPath string `yaml:"outbound_path"`
 	ReturnPath   string `yaml:"return_path"`
 
-	// AllowedIPs is a comma separated list of IP addresses and CIDR ranges
+	// AllowedIPs is a comma separated list of IP addresses and CIDR ranges (IPv4 or IPv6)
 	// where connections are allowed. If this value is non-empty remote servers
-	// not within these ranges will not be connected to.
+	// not within these ranges will not be connected to. Every address a hostname
+	// resolves to is checked when it's dialed.
 	AllowedIPs string `yaml:"allowed_ips"`
 
 	OutboundFilenameTemplate string `yaml:"outbound_filename_template"`
 
-	FTP  *FTP  `yaml:"ftp"`
-	SFTP *SFTP `yaml:"sftp"`
+	FTP    *FTP         `yaml:"ftp"`
+	SFTP   *SFTP        `yaml:"sftp"`
+	Local  *LocalAgent  `yaml:"local"`
+	Bucket *BucketAgent `yaml:"bucket"`
+
+	// GPG enables encryption and signing of files uploaded to the ODFI along with
+	// decrypting and verifying the files they send us.
+	GPG *GPG `yaml:"gpg"`
+
+	// UploadRetry controls how failed uploads of outbound files are attempted again.
+	UploadRetry *UploadRetry `yaml:"upload_retry"`
+
+	// Inbound controls how often inbound and return files are downloaded from the ODFI.
+	Inbound *Inbound `yaml:"inbound"`
+
+	// DryRun writes the files merged at each cutoff to a review directory instead of
+	// uploading them, for ODFIs which want sample files before going live.
+	DryRun *DryRun `yaml:"dry_run"`
 
 	Storage *Storage `yaml:"storage"`
 }
@@ -79,6 +101,24 @@ func (cfg *ODFI) Validate() error {
 	if err := cfg.Cutoffs.Validate(); err != nil {
 		return err
 	}
+	if err := cfg.Local.Validate(); err != nil {
+		return fmt.Errorf("local: %v", err)
+	}
+	if err := cfg.Bucket.Validate(); err != nil {
+		return fmt.Errorf("bucket: %v", err)
+	}
+	if err := cfg.GPG.Validate(); err != nil {
+		return fmt.Errorf("gpg: %v", err)
+	}
+	if err := cfg.UploadRetry.Validate(); err != nil {
+		return fmt.Errorf("upload_retry: %v", err)
+	}
+	if err := cfg.Inbound.Validate(); err != nil {
+		return fmt.Errorf("inbound: %v", err)
+	}
+	if err := cfg.SFTP.Validate(); err != nil {
+		return fmt.Errorf("sftp: %v", err)
+	}
 	return nil
 }
 
@@ -101,6 +141,11 @@ func (cfg Cutoffs) Validate() error {
 	if len(cfg.Windows) == 0 {
 		return errors.New("no cutoff windows")
 	}
+	for i := range cfg.Windows {
+		if _, err := time.Parse("15:04", cfg.Windows[i]); err != nil {
+			return fmt.Errorf("cutoffs: invalid window %q", cfg.Windows[i])
+		}
+	}
 	return nil
 }
 
@@ -152,9 +197,23 @@ type SFTP struct {
 
 	HostPublicKey string `yaml:"hostPublicKey"`
 
+	// KnownHostsFile is an OpenSSH known_hosts file whose keys for Hostname are accepted.
+	// Several keys can be listed so the ODFI can rotate keys without a config change.
+	KnownHostsFile string `yaml:"known_hosts_file"`
+
+	// StrictHostKeyChecking refuses to connect unless HostPublicKey or KnownHostsFile is set.
+	StrictHostKeyChecking bool `yaml:"strict_host_key_checking"`
+
 	DialTimeout           time.Duration `yaml:"dial_timeout"`
 	MaxConnectionsPerFile int           `yaml:"max_connections_per_file"`
 	MaxPacketSize         int           `yaml:"max_packet_size"`
+
+	// MaxSessions is how many SFTP sessions are opened at once, each over their own
+	// ssh connection. Files are downloaded concurrently up to this limit.
+	MaxSessions int `yaml:"max_sessions"`
+
+	// KeepaliveInterval is how often idle sessions are checked and kept open.
+	KeepaliveInterval time.Duration `yaml:"keepalive_interval"`
 }
 
 func (cfg *SFTP) Timeout() time.Duration {
@@ -178,16 +237,214 @@ func (cfg *SFTP) PacketSize() int {
 	return cfg.MaxPacketSize
 }
 
+func (cfg *SFTP) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.StrictHostKeyChecking && cfg.HostPublicKey == "" && cfg.KnownHostsFile == "" {
+		return errors.New("strict_host_key_checking requires hostPublicKey or known_hosts_file")
+	}
+	if cfg.KnownHostsFile != "" {
+		if _, err := os.Stat(cfg.KnownHostsFile); err != nil {
+			return fmt.Errorf("known_hosts_file: %v", err)
+		}
+	}
+	return nil
+}
+
+func (cfg *SFTP) Sessions() int {
+	if cfg == nil || cfg.MaxSessions <= 0 {
+		return 1 // ODFIs often limit how many logins we can have
+	}
+	return cfg.MaxSessions
+}
+
+func (cfg *SFTP) Keepalive() time.Duration {
+	if cfg == nil || cfg.KeepaliveInterval <= 0 {
+		return 30 * time.Second
+	}
+	return cfg.KeepaliveInterval
+}
+
 func (cfg *SFTP) String() string {
 	var buf bytes.Buffer
 	buf.WriteString(fmt.Sprintf("SFTP{Hostname=%s, ", cfg.Hostname))
 	buf.WriteString(fmt.Sprintf("Username=%s, ", cfg.Username))
 	buf.WriteString(fmt.Sprintf("Password=%s, ", mask.Password(cfg.Password)))
 	buf.WriteString(fmt.Sprintf("ClientPrivateKey:%v, ", cfg.ClientPrivateKey != ""))
-	buf.WriteString(fmt.Sprintf("HostPublicKey:%v}, ", cfg.HostPublicKey != ""))
+	buf.WriteString(fmt.Sprintf("HostPublicKey:%v, ", cfg.HostPublicKey != ""))
+	buf.WriteString(fmt.Sprintf("KnownHostsFile=%s, ", cfg.KnownHostsFile))
+	buf.WriteString(fmt.Sprintf("StrictHostKeyChecking:%v}, ", cfg.StrictHostKeyChecking))
 	return buf.String()
 }
 
+// LocalAgent reads and writes ACH files within a directory on the local filesystem. This is
+// often a network share or directory watched by the ODFI's connector.
+type LocalAgent struct {
+	// Directory is the root which InboundPath, OutboundPath and ReturnPath are relative to
+	Directory string `yaml:"directory"`
+}
+
+func (cfg *LocalAgent) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Directory == "" {
+		return errors.New("missing directory")
+	}
+	return nil
+}
+
+// BucketAgent reads and writes ACH files in an object storage bucket (S3, GCS, etc) where
+// InboundPath, OutboundPath and ReturnPath are key prefixes.
+type BucketAgent struct {
+	// URL is a gocloud.dev/blob URL for the bucket, e.g. s3://my-bucket?region=us-east-1 or gs://my-bucket
+	URL string `yaml:"url"`
+
+	// DisableDeletes skips removing files from the bucket, for partners who only grant
+	// us read and write access.
+	DisableDeletes bool `yaml:"disable_deletes"`
+}
+
+func (cfg *BucketAgent) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.URL == "" {
+		return errors.New("missing url")
+	}
+	return nil
+}
+
+type GPG struct {
+	// ODFIPublicKey is a filepath to the ODFI's ASCII armored public key. Outbound files are
+	// encrypted with it and inbound files must be signed by it.
+	ODFIPublicKey string `yaml:"odfi_public_key"`
+
+	// SigningKey is our private key used to sign outbound files and decrypt inbound files.
+	SigningKey *GPGPrivateKey `yaml:"signing_key"`
+}
+
+func (cfg *GPG) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.ODFIPublicKey == "" {
+		return errors.New("missing odfi_public_key")
+	}
+	if cfg.SigningKey != nil && cfg.SigningKey.Path == "" {
+		return errors.New("missing signing_key path")
+	}
+	return nil
+}
+
+type GPGPrivateKey struct {
+	// Path is the filepath of an ASCII armored private key
+	Path string `yaml:"path"`
+
+	// Password decrypts the private key if it's protected
+	Password string `yaml:"password"`
+}
+
+func (cfg *GPGPrivateKey) String() string {
+	return fmt.Sprintf("GPGPrivateKey{Path=%s, Password=%s}", cfg.Path, mask.Password(cfg.Password))
+}
+
+// UploadRetry is the policy for attempting a failed upload again. The delay between
+// attempts doubles each time up to MaxBackoff.
+type UploadRetry struct {
+	// Attempts is how many times a file is uploaded before giving up, including the first.
+	Attempts int `yaml:"attempts"`
+
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+func (cfg *UploadRetry) MaxAttempts() int {
+	if cfg == nil || cfg.Attempts == 0 {
+		return 3
+	}
+	return cfg.Attempts
+}
+
+// Delay returns how long to wait after the given (1-indexed) failed attempt.
+func (cfg *UploadRetry) Delay(attempt int) time.Duration {
+	backoff, max := 10*time.Second, 5*time.Minute
+	if cfg != nil && cfg.Backoff > 0 {
+		backoff = cfg.Backoff
+	}
+	if cfg != nil && cfg.MaxBackoff > 0 {
+		max = cfg.MaxBackoff
+	}
+	for i := 1; i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		return max
+	}
+	return backoff
+}
+
+func (cfg *UploadRetry) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Attempts < 0 {
+		return fmt.Errorf("negative attempts: %d", cfg.Attempts)
+	}
+	if cfg.Backoff < 0 || cfg.MaxBackoff < 0 {
+		return errors.New("negative backoff")
+	}
+	if cfg.MaxBackoff > 0 && cfg.Backoff > cfg.MaxBackoff {
+		return fmt.Errorf("backoff %v is larger than max_backoff %v", cfg.Backoff, cfg.MaxBackoff)
+	}
+	return nil
+}
+
+type Inbound struct {
+	// Interval is how often the ODFI's inbound and return directories are checked for new files
+	Interval time.Duration `yaml:"interval"`
+}
+
+func (cfg *Inbound) DownloadInterval() time.Duration {
+	if cfg == nil || cfg.Interval == 0 {
+		return 10 * time.Minute
+	}
+	return cfg.Interval
+}
+
+func (cfg *Inbound) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Interval < 0 {
+		return fmt.Errorf("negative interval: %v", cfg.Interval)
+	}
+	return nil
+}
+
+type DryRun struct {
+	// Enabled skips uploading files at each cutoff. Dry runs can be triggered from the
+	// admin server whether or not this is enabled.
+	Enabled bool `yaml:"enabled"`
+
+	// Directory is where the files of each dry run are written, defaults to storage/review/
+	Directory string `yaml:"directory"`
+}
+
+// Active returns true when cutoffs should write files for review instead of uploading them.
+func (cfg *DryRun) Active() bool {
+	return cfg != nil && cfg.Enabled
+}
+
+// ReviewDirectory returns where dry runs write files for the ODFI named odfi.
+func (cfg *DryRun) ReviewDirectory(odfi string) string {
+	if cfg == nil || cfg.Directory == "" {
+		return filepath.Join("storage", "review", odfi)
+	}
+	return cfg.Directory
+}
+
 type Storage struct {
 	// CleanupLocalDirectory determines if we delete the local directory after
 	// processing is finished. Leaving these files around helps debugging, but
@@ -198,9 +455,20 @@ type Storage struct {
 	// after downloading and processing of each file.
 	KeepRemoteFiles bool `yaml:"keep_remote_files"`
 
+	// Local holds the directory where every downloaded inbound and return file is
+	// archived by its SHA-256 checksum, which keeps a file from being processed twice.
 	Local *Local `json:"local"`
 }
 
+// ArchiveDirectory returns where downloaded files are archived, or an empty string
+// when no local directory is configured.
+func (cfg *Storage) ArchiveDirectory() string {
+	if cfg == nil || cfg.Local == nil || cfg.Local.Directory == "" {
+		return ""
+	}
+	return filepath.Join(cfg.Local.Directory, "archive")
+}
+
 type Local struct {
 	Directory string `yaml:"directory"`
 }
@@ -4,11 +4,26 @@
 
 package config
 
+import (
+	"errors"
+	"fmt"
+)
+
 type Pipeline struct {
 	Merging *Merging        `yaml:"merging"`
 	Stream  *StreamPipeline `yaml:"stream"`
 }
 
+func (cfg Pipeline) Validate() error {
+	if cfg.Merging != nil && cfg.Merging.Directory == "" {
+		return errors.New("merging: missing directory")
+	}
+	if err := cfg.Stream.Validate(); err != nil {
+		return fmt.Errorf("stream: %v", err)
+	}
+	return nil
+}
+
 type Merging struct {
 	Directory string `yaml:"directory"`
 }
@@ -18,6 +33,27 @@ type StreamPipeline struct {
 	Kafka *KafkaPipeline `yaml:"kafka"`
 }
 
+func (cfg *StreamPipeline) Validate() error {
+	if cfg == nil {
+		return errors.New("missing StreamPipeline config")
+	}
+	switch {
+	case cfg.InMem != nil && cfg.Kafka != nil:
+		return errors.New("only one of inmem or kafka can be configured")
+	case cfg.InMem != nil:
+		if cfg.InMem.URL == "" {
+			return errors.New("inmem: missing url")
+		}
+	case cfg.Kafka != nil:
+		if len(cfg.Kafka.Brokers) == 0 || cfg.Kafka.Topic == "" {
+			return errors.New("kafka: brokers and topic are required")
+		}
+	default:
+		return errors.New("one of inmem or kafka is required")
+	}
+	return nil
+}
+
 type InMemPipeline struct {
 	URL string `yaml:"url"`
 }
@@ -0,0 +1,68 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reload
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/base/admin"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+// RegisterAdminRoutes will add HTTP handlers for paygate's admin HTTP server
+func RegisterAdminRoutes(logger log.Logger, svc *admin.Server, reloader *Reloader) {
+	svc.AddHandler("/config/reloads", reloadsHandler(logger, reloader))
+}
+
+func reloadsHandler(logger log.Logger, reloader *Reloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			getReloads(logger, reloader)(w, r)
+		case http.MethodPost:
+			reloadConfig(logger, reloader)(w, r)
+		default:
+			route.NewResponder(logger, w, r).Problem(fmt.Errorf("invalid method %s", r.Method))
+		}
+	}
+}
+
+func getReloads(logger log.Logger, reloader *Reloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		results := reloader.Results()
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(results)
+		})
+	}
+}
+
+// reloadConfig reads the config file now rather than waiting for it to be noticed as changed.
+// The Result of a config which fails to validate or apply is returned with a 400 status.
+func reloadConfig(logger log.Logger, reloader *Reloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		result := reloader.Reload(Admin)
+		logger.Log(
+			"reload", fmt.Sprintf("config reload requested, checksum=%s", result.Checksum),
+			"userID", responder.XUserID, "requestID", responder.XRequestID)
+
+		responder.Respond(func(w http.ResponseWriter) {
+			if result.Error != "" {
+				w.WriteHeader(http.StatusBadRequest)
+			} else {
+				w.WriteHeader(http.StatusOK)
+			}
+			json.NewEncoder(w).Encode(result)
+		})
+	}
+}
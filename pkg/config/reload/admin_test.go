@@ -0,0 +1,54 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reload
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestAdmin__reloadConfig(t *testing.T) {
+	reloader, path := setupReloader(t)
+
+	svc, c := testclient.Admin(t)
+	RegisterAdminRoutes(log.NewNopLogger(), svc, reloader)
+
+	writeConfig(t, path, `"16:20"`, `"17:00"`)
+	result, resp, err := c.ConfigApi.ReloadConfig(context.TODO(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if result.Trigger != "admin" || result.Error != "" {
+		t.Errorf("unexpected result: %#v", result)
+	}
+
+	// an invalid config is returned with its error
+	writeConfig(t, path, `"987654320"`, `"123"`)
+	_, resp, err = c.ConfigApi.ReloadConfig(context.TODO(), "userID", nil)
+	if err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected error: %v", err)
+	}
+	resp.Body.Close()
+
+	results, resp, err := c.ConfigApi.GetConfigReloads(context.TODO(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if len(results) != 2 {
+		t.Fatalf("unexpected reloads: %#v", results)
+	}
+	if results[0].Error == "" || results[1].Error != "" {
+		t.Errorf("unexpected reloads: %#v", results)
+	}
+}
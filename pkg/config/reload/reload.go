@@ -0,0 +1,253 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Trigger describes what started a reload of the config file.
+type Trigger string
+
+const (
+	FileChanged Trigger = "file"
+	Signal      Trigger = "signal"
+	Admin       Trigger = "admin"
+)
+
+// Changes describes what differed between the running and reloaded config.
+type Changes struct {
+	// Applied are the changes now in effect
+	Applied []string `json:"applied"`
+
+	// Restart are the changes which PayGate needs to be restarted for
+	Restart []string `json:"restart"`
+}
+
+// Func applies the parts of next which can change while PayGate runs. It's given the
+// previously applied config to compare against.
+type Func func(prev, next *config.Config) (Changes, error)
+
+// Result records one attempt at reloading the config file.
+type Result struct {
+	Path     string    `json:"path"`
+	Checksum string    `json:"checksum"`
+	Trigger  Trigger   `json:"trigger"`
+	Applied  []string  `json:"applied"`
+	Restart  []string  `json:"restart"`
+	Error    string    `json:"error,omitempty"`
+	Reloaded time.Time `json:"reloaded"`
+}
+
+// maxResults is how many reloads are kept for the admin API
+const maxResults = 20
+
+// Reloader reads PayGate's config file again when it changes, on SIGHUP or when an
+// operator asks, and hands each valid config to the registered Funcs.
+type Reloader struct {
+	logger log.Logger
+	path   string
+
+	// reloading serializes reloads, which can wait on uploads in progress
+	reloading sync.Mutex
+
+	mu       sync.RWMutex // protects the fields below
+	current  *config.Config
+	checksum string
+	funcs    []Func
+	results  []*Result
+}
+
+// New returns a Reloader for the config file at path which cfg was read from.
+func New(logger log.Logger, path string, cfg *config.Config) *Reloader {
+	r := &Reloader{
+		logger:  logger,
+		path:    path,
+		current: cfg,
+	}
+	if bs, err := ioutil.ReadFile(path); err == nil {
+		r.checksum = checksum(bs)
+	}
+	return r
+}
+
+func checksum(bs []byte) string {
+	ss := sha256.Sum256(bs)
+	return hex.EncodeToString(ss[:])
+}
+
+// Register adds fn to be called on each reload, in the order they're registered.
+func (r *Reloader) Register(fn Func) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcs = append(r.funcs, fn)
+}
+
+// Start reloads the config file on SIGHUP and each time its contents change, which is
+// checked every interval.
+func (r *Reloader) Start(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-hup:
+			r.Reload(Signal)
+
+		case <-ticker.C:
+			if r.changed() {
+				r.Reload(FileChanged)
+			}
+		}
+	}
+}
+
+func (r *Reloader) changed() bool {
+	bs, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return false // the file could be mid-write, Reload reports errors reading it
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return checksum(bs) != r.checksum
+}
+
+// Reload reads and validates the config file, then applies it with each registered Func.
+// The running config is only replaced if every Func succeeds so a failed reload is
+// attempted again on the next one.
+func (r *Reloader) Reload(trigger Trigger) *Result {
+	r.reloading.Lock()
+	defer r.reloading.Unlock()
+
+	result := &Result{
+		Path:     r.path,
+		Trigger:  trigger,
+		Applied:  []string{},
+		Restart:  []string{},
+		Reloaded: time.Now(),
+	}
+	if err := r.reload(result); err != nil {
+		result.Error = err.Error()
+		r.logger.Log("reload", fmt.Sprintf("ERROR reloading %s (trigger=%s): %v", r.path, trigger, err))
+	} else {
+		r.logger.Log("reload", fmt.Sprintf("reloaded %s (trigger=%s) applied=%q restart=%q", r.path, trigger, result.Applied, result.Restart))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append([]*Result{result}, r.results...)
+	if len(r.results) > maxResults {
+		r.results = r.results[:maxResults]
+	}
+	return result
+}
+
+func (r *Reloader) reload(result *Result) error {
+	bs, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("reading config: %v", err)
+	}
+	result.Checksum = checksum(bs)
+
+	r.mu.Lock()
+	r.checksum = result.Checksum // don't retry an invalid file until it's changed again
+	prev, funcs := r.current, r.funcs
+	r.mu.Unlock()
+
+	next, err := config.Read(bs)
+	if err != nil {
+		return fmt.Errorf("reading config: %v", err)
+	}
+	if err := next.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+	next.Logger = prev.Logger
+
+	result.Restart = append(result.Restart, restartRequired(prev, next)...)
+
+	var el base.ErrorList
+	for i := range funcs {
+		changes, err := funcs[i](prev, next)
+		result.Applied = append(result.Applied, changes.Applied...)
+		result.Restart = append(result.Restart, changes.Restart...)
+		if err != nil {
+			el.Add(err)
+		}
+	}
+	if !el.Empty() {
+		return el
+	}
+
+	r.mu.Lock()
+	r.current = next
+	r.mu.Unlock()
+	return nil
+}
+
+// restartRequired lists the top-level sections of the config which differ. ODFIs are
+// compared by the registered Funcs.
+func restartRequired(prev, next *config.Config) []string {
+	sections := []struct {
+		name       string
+		prev, next interface{}
+	}{
+		{"log_format", prev.LogFormat, next.LogFormat},
+		{"http", prev.Http, next.Http},
+		{"admin", prev.Admin, next.Admin},
+		{"fundflow", prev.Fundflow, next.Fundflow},
+		{"pipeline", prev.Pipeline, next.Pipeline},
+		{"return_rates", prev.ReturnRates, next.ReturnRates},
+		{"review", prev.Review, next.Review},
+		{"limits", prev.Limits, next.Limits},
+		{"tenants", prev.Tenants, next.Tenants},
+		{"customers", prev.Customers, next.Customers},
+	}
+	var out []string
+	for i := range sections {
+		if !reflect.DeepEqual(sections[i].prev, sections[i].next) {
+			out = append(out, sections[i].name)
+		}
+	}
+	return out
+}
+
+// Current returns the config most recently applied.
+func (r *Reloader) Current() *config.Config {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current
+}
+
+// Results returns the most recent reloads, newest first.
+func (r *Reloader) Results() []*Result {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]*Result, len(r.results))
+	copy(out, r.results)
+	return out
+}
@@ -0,0 +1,156 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package reload
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/log"
+)
+
+var testConfig = `
+odfi:
+  routing_number: "987654320"
+  gateway:
+    origin: "CUSTID"
+  outbound_path: "outbound/"
+  outbound_filename_template: "{{ date \"20060102\" }}-{{ .RoutingNumber }}.ach"
+  cutoffs:
+    timezone: "America/New_York"
+    windows:
+      - "16:20"
+  local:
+    directory: "./storage/"
+http:
+  bind_address: ":8082"
+pipeline:
+  stream:
+    inmem:
+      url: "mem://paygate"
+`
+
+func writeConfig(t *testing.T, path string, replacements ...string) {
+	t.Helper()
+
+	contents := strings.NewReplacer(replacements...).Replace(testConfig)
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setupReloader(t *testing.T) (*Reloader, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "paygate-reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "config.yaml")
+	writeConfig(t, path)
+
+	cfg, err := config.FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(log.NewNopLogger(), path, cfg), path
+}
+
+func TestReloader(t *testing.T) {
+	reloader, path := setupReloader(t)
+	if reloader.changed() {
+		t.Fatal("config file hasn't changed")
+	}
+
+	var windows []string
+	reloader.Register(func(prev, next *config.Config) (Changes, error) {
+		windows = next.ODFI.Cutoffs.Windows
+		return Changes{Applied: []string{"odfi.cutoffs"}}, nil
+	})
+
+	writeConfig(t, path, `"16:20"`, `"17:00"`, `":8082"`, `":9082"`)
+	if !reloader.changed() {
+		t.Fatal("expected config file to be changed")
+	}
+
+	result := reloader.Reload(FileChanged)
+	if result.Error != "" {
+		t.Fatal(result.Error)
+	}
+	if result.Trigger != FileChanged || result.Checksum == "" {
+		t.Errorf("unexpected result: %#v", result)
+	}
+	if len(windows) != 1 || windows[0] != "17:00" {
+		t.Errorf("unexpected windows: %v", windows)
+	}
+	if len(result.Applied) != 1 || result.Applied[0] != "odfi.cutoffs" {
+		t.Errorf("unexpected applied: %v", result.Applied)
+	}
+	if len(result.Restart) != 1 || result.Restart[0] != "http" {
+		t.Errorf("unexpected restart: %v", result.Restart)
+	}
+	if reloader.changed() {
+		t.Error("config file was reloaded")
+	}
+	if cfg := reloader.Current(); cfg.Http.BindAddress != ":9082" {
+		t.Errorf("unexpected config: %#v", cfg.Http)
+	}
+
+	results := reloader.Results()
+	if len(results) != 1 || results[0] != result {
+		t.Errorf("unexpected results: %#v", results)
+	}
+}
+
+func TestReloader__invalid(t *testing.T) {
+	reloader, path := setupReloader(t)
+
+	called := false
+	reloader.Register(func(prev, next *config.Config) (Changes, error) {
+		called = true
+		return Changes{}, nil
+	})
+
+	writeConfig(t, path, `"987654320"`, `"123"`)
+	result := reloader.Reload(Signal)
+	if !strings.Contains(result.Error, "invalid config") {
+		t.Errorf("unexpected error: %q", result.Error)
+	}
+	if called {
+		t.Error("invalid config was applied")
+	}
+	if reloader.changed() {
+		t.Error("invalid config shouldn't be retried until it's changed")
+	}
+	if cfg := reloader.Current(); cfg.ODFI.RoutingNumber != "987654320" {
+		t.Errorf("unexpected routing number: %v", cfg.ODFI.RoutingNumber)
+	}
+}
+
+func TestReloader__applyErr(t *testing.T) {
+	reloader, path := setupReloader(t)
+	reloader.Register(func(prev, next *config.Config) (Changes, error) {
+		return Changes{}, errors.New("bad connection")
+	})
+
+	writeConfig(t, path, `"16:20"`, `"17:00"`)
+	result := reloader.Reload(Admin)
+	if !strings.Contains(result.Error, "bad connection") {
+		t.Errorf("unexpected error: %q", result.Error)
+	}
+
+	// the running config is kept so the changes are applied again on the next reload
+	if cfg := reloader.Current(); cfg.ODFI.Cutoffs.Windows[0] != "16:20" {
+		t.Errorf("unexpected cutoffs: %v", cfg.ODFI.Cutoffs.Windows)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReturnRates configures monitoring of NACHA return rate thresholds for each Originator.
+// Originators whose rates exceed a threshold are paused until their rates recover.
+type ReturnRates struct {
+	// Interval is how often return rates are calculated
+	Interval time.Duration `yaml:"interval"`
+
+	// WindowDays is how many days of Transfers are included in each calculation
+	WindowDays int `yaml:"window_days"`
+
+	Thresholds ReturnRateThresholds `yaml:"thresholds"`
+
+	// Paused determines what happens to new Transfers from a paused Originator.
+	// Options: "reject" (default) or "review"
+	Paused string `yaml:"paused"`
+}
+
+// ReturnRateThresholds are percentages of returned Transfers. NACHA's published
+// thresholds are used for any value left empty.
+type ReturnRateThresholds struct {
+	Unauthorized   float64 `yaml:"unauthorized"`
+	Administrative float64 `yaml:"administrative"`
+	Overall        float64 `yaml:"overall"`
+}
+
+func (cfg *ReturnRates) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Interval < 0 || cfg.WindowDays < 0 {
+		return errors.New("negative interval or window_days")
+	}
+	th := cfg.Thresholds
+	for _, v := range []float64{th.Unauthorized, th.Administrative, th.Overall} {
+		if v < 0 || v > 100 {
+			return fmt.Errorf("invalid threshold %.2f", v)
+		}
+	}
+	switch strings.ToLower(cfg.Paused) {
+	case "", "reject", "review":
+		return nil
+	}
+	return fmt.Errorf("unknown paused option %q", cfg.Paused)
+}
+
+func (cfg *ReturnRates) CalculationInterval() time.Duration {
+	if cfg == nil || cfg.Interval == 0*time.Second {
+		return 1 * time.Hour
+	}
+	return cfg.Interval
+}
+
+func (cfg *ReturnRates) Window() time.Duration {
+	if cfg == nil || cfg.WindowDays == 0 {
+		return 60 * 24 * time.Hour
+	}
+	return time.Duration(cfg.WindowDays) * 24 * time.Hour
+}
+
+// Review returns true if Transfers from paused Originators should be held in
+// the REVIEWABLE status instead of being rejected.
+func (cfg *ReturnRates) Review() bool {
+	if cfg == nil {
+		return false
+	}
+	return strings.EqualFold(cfg.Paused, "review")
+}
+
+func (th ReturnRateThresholds) UnauthorizedLimit() float64 {
+	if th.Unauthorized == 0.0 {
+		return 0.5
+	}
+	return th.Unauthorized
+}
+
+func (th ReturnRateThresholds) AdministrativeLimit() float64 {
+	if th.Administrative == 0.0 {
+		return 3.0
+	}
+	return th.Administrative
+}
+
+func (th ReturnRateThresholds) OverallLimit() float64 {
+	if th.Overall == 0.0 {
+		return 15.0
+	}
+	return th.Overall
+}
@@ -0,0 +1,46 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/moov-io/paygate/pkg/model"
+)
+
+// Review configures rules which hold Transfers in the REVIEWABLE status until someone
+// other than their creator approves them. Rules left empty are not checked.
+type Review struct {
+	// AmountThreshold holds Transfers of this amount or larger, e.g. "USD 10000.00"
+	AmountThreshold string `yaml:"amount_threshold"`
+
+	// NewDestination holds Transfers to a Customer account the user hasn't sent to before
+	NewDestination bool `yaml:"new_destination"`
+
+	// OFACMatch holds Transfers whose destination Customer's latest OFAC search matched
+	// at or above this score, between 0.0 and 1.0
+	OFACMatch float32 `yaml:"ofac_match"`
+}
+
+func (cfg *Review) Validate() error {
+	if cfg == nil {
+		return nil
+	}
+	if _, err := cfg.Threshold(); err != nil {
+		return fmt.Errorf("amount_threshold: %v", err)
+	}
+	if cfg.OFACMatch < 0.0 || cfg.OFACMatch > 1.0 {
+		return fmt.Errorf("invalid ofac_match %.2f", cfg.OFACMatch)
+	}
+	return nil
+}
+
+// Threshold returns the parsed AmountThreshold or nil when amounts aren't reviewed.
+func (cfg *Review) Threshold() (*model.Amount, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	return parseLimit(cfg.AmountThreshold)
+}
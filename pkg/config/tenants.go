@@ -0,0 +1,69 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Tenants struct {
+	CompanyIdentification CompanyIdentification `yaml:"company_identification"`
+}
+
+func (cfg Tenants) Validate() error {
+	if err := cfg.CompanyIdentification.Validate(); err != nil {
+		return fmt.Errorf("company_identification: %v", err)
+	}
+	return nil
+}
+
+// CompanyIdentification configures how the CompanyIdentification written into each Tenant's
+// batches is assigned. Values are only assigned to one Tenant at a time, but retired values can be assigned again.
+type CompanyIdentification struct {
+	// Strategy is how values are assigned to new Tenants.
+	// Options: "random" (default), "ein" ("1" followed by the Tenant's EIN) or "supplied" (set by an admin)
+	Strategy string `yaml:"strategy"`
+
+	// Prefix is prepended to random values, defaults to "MOOV"
+	Prefix string `yaml:"prefix"`
+}
+
+const (
+	CompanyIdentificationRandom   = "random"
+	CompanyIdentificationEIN      = "ein"
+	CompanyIdentificationSupplied = "supplied"
+)
+
+var (
+	companyIdentificationPrefixRegex = regexp.MustCompile(`^[A-Z0-9]{0,9}$`)
+)
+
+func (cfg CompanyIdentification) Validate() error {
+	switch cfg.AssignmentStrategy() {
+	case CompanyIdentificationRandom, CompanyIdentificationEIN, CompanyIdentificationSupplied:
+	default:
+		return fmt.Errorf("unknown strategy %q", cfg.Strategy)
+	}
+	if !companyIdentificationPrefixRegex.MatchString(cfg.Prefix) {
+		return fmt.Errorf("prefix %q must be at most 9 uppercase letters or digits", cfg.Prefix)
+	}
+	return nil
+}
+
+func (cfg CompanyIdentification) AssignmentStrategy() string {
+	if cfg.Strategy == "" {
+		return CompanyIdentificationRandom
+	}
+	return strings.ToLower(cfg.Strategy)
+}
+
+func (cfg CompanyIdentification) RandomPrefix() string {
+	if cfg.Prefix == "" {
+		return "MOOV"
+	}
+	return cfg.Prefix
+}
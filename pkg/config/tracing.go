@@ -0,0 +1,46 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tracing configures how spans of each request are sampled and reported to Jaeger.
+type Tracing struct {
+	// Sampler is "const" (default) to record every span, "probabilistic" to record
+	// Rate of them or "off" to disable tracing.
+	Sampler string `yaml:"sampler"`
+
+	// Rate is the fraction of spans recorded by the probabilistic sampler
+	Rate float64 `yaml:"rate"`
+}
+
+const (
+	TracingConstant      = "const"
+	TracingProbabilistic = "probabilistic"
+	TracingOff           = "off"
+)
+
+func (cfg Tracing) SamplerType() string {
+	if cfg.Sampler == "" {
+		return TracingConstant
+	}
+	return strings.ToLower(cfg.Sampler)
+}
+
+func (cfg Tracing) Validate() error {
+	switch cfg.SamplerType() {
+	case TracingConstant, TracingOff:
+		return nil
+	case TracingProbabilistic:
+		if cfg.Rate <= 0.0 || cfg.Rate > 1.0 {
+			return fmt.Errorf("rate %.2f must be within (0.0, 1.0]", cfg.Rate)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown sampler %q", cfg.Sampler)
+}
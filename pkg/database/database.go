@@ -8,31 +8,25 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
-	"strings"
 
-	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/pkg/config"
 
 	"github.com/go-kit/kit/log"
 	"github.com/lopezator/migrator"
 )
 
-// Type returns a string for which database to be used.
-func Type() string {
-	return util.Or(os.Getenv("DATABASE_TYPE"), "sqlite")
-}
-
-// New establishes a database connection according to the type and environmental
-// variables for that specific database.
-func New(ctx context.Context, logger log.Logger, _type string) (*sql.DB, error) {
-	logger.Log("database", fmt.Sprintf("looking for %s database provider", _type))
-	switch strings.ToLower(_type) {
+// New establishes a database connection according to the configured type and the
+// settings for that specific database.
+func New(ctx context.Context, logger log.Logger, cfg config.Database) (*sql.DB, error) {
+	provider := cfg.Provider()
+	logger.Log("database", fmt.Sprintf("looking for %s database provider", provider))
+	switch provider {
 	case "sqlite":
-		return sqliteConnection(logger, getSqlitePath()).Connect(ctx)
+		return sqliteConnection(logger, cfg.SQLite.Filepath()).Connect(ctx)
 	case "mysql":
-		return mysqlConnection(logger, os.Getenv("MYSQL_USER"), os.Getenv("MYSQL_PASSWORD"), os.Getenv("MYSQL_ADDRESS"), os.Getenv("MYSQL_DATABASE")).Connect(ctx)
+		return mysqlConnection(logger, cfg.MySQL).Connect(ctx)
 	}
-	return nil, fmt.Errorf("unknown database type %q", _type)
+	return nil, fmt.Errorf("unknown database type %q", cfg.Type)
 }
 
 func execsql(name, raw string) *migrator.MigrationNoTx {
@@ -8,13 +8,12 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
-	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/moov-io/base/docker"
+	"github.com/moov-io/paygate/pkg/config"
 
 	"github.com/go-kit/kit/log"
 	kitprom "github.com/go-kit/kit/metrics/prometheus"
@@ -34,15 +33,6 @@ var (
 	// https://dev.mysql.com/doc/refman/8.0/en/server-error-reference.html#error_er_dup_entry
 	mySQLErrDuplicateKey uint16 = 1062
 
-	maxActiveMySQLConnections = func() int {
-		if v := os.Getenv("MYSQL_MAX_CONNECTIONS"); v != "" {
-			if n, _ := strconv.ParseInt(v, 10, 32); n > 0 {
-				return int(n)
-			}
-		}
-		return 16
-	}()
-
 	mysqlMigrations = migrator.Migrations(
 		execsql(
 			"create_tenants",
@@ -69,6 +59,130 @@ var (
 			// Max length for IPv6 addresses -- https://stackoverflow.com/a/7477384
 			"alter table transfers add column remote_address varchar(45) default '';",
 		),
+		execsql(
+			"create_ledger_entries",
+			`create table if not exists ledger_entries(entry_id varchar(40) primary key, account_id varchar(40), transfer_id varchar(40), amount bigint, created_at datetime);`,
+		),
+		execsql(
+			"create_transfer_batches",
+			`create table if not exists transfer_batches(batch_id varchar(40) primary key, user_id varchar(40), atomic boolean, status varchar(10), created_at datetime, last_updated_at datetime);`,
+		),
+		execsql(
+			"create_transfer_batch_items",
+			`create table if not exists transfer_batch_items(batch_id varchar(40), item_index integer, transfer_id varchar(40), error text);`,
+		),
+		execsql(
+			"add_file_id_to_transfers",
+			"alter table transfers add column file_id varchar(40) default '';",
+		),
+		execsql(
+			"create_file_deliveries",
+			`create table if not exists file_deliveries(delivery_id varchar(40) primary key, filename varchar(255), size bigint, sha256 varchar(64), status varchar(10), created_at datetime, last_updated_at datetime);`,
+		),
+		execsql(
+			"create_file_delivery_attempts",
+			`create table if not exists file_delivery_attempts(delivery_id varchar(40), attempt integer, error text, created_at datetime);`,
+		),
+		execsql(
+			"add_odfi_to_tenants",
+			"alter table tenants add column odfi varchar(40) default '';",
+		),
+		execsql(
+			"create_inbound_files",
+			`create table if not exists inbound_files(sha256 varchar(64) primary key, odfi varchar(40), kind varchar(10), filename varchar(255), remote_path varchar(512), size bigint, received_at datetime, processed_at datetime);`,
+		),
+		execsql(
+			"add_status_to_tenants",
+			"alter table tenants add column status varchar(10) default 'active';",
+		),
+		execsql(
+			"create_tenant_settings",
+			`create table if not exists tenant_settings(tenant_id varchar(40) primary key, company_name varchar(16), default_sec_code varchar(3), max_entry_amount varchar(20), max_file_amount varchar(20), max_file_entries integer, updated_at datetime);`,
+		),
+		execsql(
+			"create_tenant_company_identifications",
+			`create table if not exists tenant_company_identifications(tenant_id varchar(40), company_identification varchar(10), created_at datetime, retired_at datetime);`,
+		),
+		execsql(
+			"backfill_tenant_company_identifications",
+			`insert into tenant_company_identifications (tenant_id, company_identification, created_at) select tenant_id, company_identification, created_at from tenants where company_identification is not null and company_identification <> '';`,
+		),
+		execsql(
+			"create_tenant_company_identifications_idx",
+			`create unique index tenant_company_identifications_idx on tenant_company_identifications (company_identification);`,
+		),
+		execsql(
+			"add_organization_id_to_transfers",
+			"alter table transfers add column organization_id varchar(40) default '';",
+		),
+		execsql(
+			"create_memberships",
+			`create table memberships(kind varchar(20), resource_id varchar(40), user_id varchar(40), role varchar(20), created_at datetime, deleted_at datetime);`,
+		),
+		execsql(
+			"create_memberships_idx",
+			`create unique index memberships_idx on memberships (kind, resource_id, user_id);`,
+		),
+		execsql(
+			"create_transfer_reviews",
+			`create table transfer_reviews(transfer_id varchar(40) primary key, reasons text, reviewed_by varchar(40), decision varchar(10), created_at datetime, reviewed_at datetime);`,
+		),
+		execsql(
+			"create_transfer_trace_numbers",
+			`create table transfer_trace_numbers(transfer_id varchar(40), trace_number varchar(15));`,
+		),
+		execsql(
+			"create_transfer_trace_numbers_idx",
+			`create index transfer_trace_numbers_idx on transfer_trace_numbers (trace_number);`,
+		),
+		execsql(
+			"create_file_delivery_trace_numbers",
+			`create table file_delivery_trace_numbers(delivery_id varchar(40), trace_number varchar(15));`,
+		),
+		execsql(
+			"create_file_delivery_trace_numbers_idx",
+			`create index file_delivery_trace_numbers_idx on file_delivery_trace_numbers (trace_number);`,
+		),
+		execsql(
+			"create_cutoff_runs",
+			`create table cutoff_runs(run_id varchar(40) primary key, odfi varchar(40), cutoff_window varchar(5), manual boolean, error text, started_at datetime, finished_at datetime);`,
+		),
+		execsql(
+			"add_run_id_to_file_deliveries",
+			"alter table file_deliveries add column run_id varchar(40) default '';",
+		),
+		execsql(
+			"create_ledger_entries_account_id_idx",
+			`create index ledger_entries_account_id_idx on ledger_entries (account_id);`,
+		),
+		execsql(
+			"create_ledger_entries_transfer_id_idx",
+			`create index ledger_entries_transfer_id_idx on ledger_entries (transfer_id);`,
+		),
+		execsql(
+			"add_tenant_id_to_transfers",
+			"alter table transfers add column tenant_id varchar(40) default '';",
+		),
+		execsql(
+			"add_debit_to_transfer_trace_numbers",
+			"alter table transfer_trace_numbers add column debit boolean default false;",
+		),
+		execsql(
+			"create_transfer_holds",
+			`create table transfer_holds(transfer_id varchar(40) primary key, odfi varchar(40), file mediumtext, release_at datetime, released_at datetime, canceled_at datetime, created_at datetime);`,
+		),
+		execsql(
+			"drop_tenant_company_identifications_idx",
+			`drop index tenant_company_identifications_idx on tenant_company_identifications;`,
+		),
+		execsql(
+			"add_current_company_identification_to_tenant_company_identifications",
+			`alter table tenant_company_identifications add column current_company_identification varchar(10) as (case when retired_at is null then company_identification end) stored;`,
+		),
+		execsql(
+			"create_current_tenant_company_identifications_idx",
+			`create unique index current_tenant_company_identifications_idx on tenant_company_identifications (current_company_identification);`,
+		),
 	)
 )
 
@@ -81,8 +195,9 @@ func init() {
 }
 
 type mysql struct {
-	dsn    string
-	logger log.Logger
+	dsn            string
+	maxConnections int
+	logger         log.Logger
 
 	connections *kitprom.Gauge
 }
@@ -92,7 +207,7 @@ func (my *mysql) Connect(ctx context.Context) (*sql.DB, error) {
 	if err != nil {
 		return nil, err
 	}
-	db.SetMaxOpenConns(maxActiveMySQLConnections)
+	db.SetMaxOpenConns(my.maxConnections)
 
 	// Check out DB is up and working
 	if err := db.Ping(); err != nil {
@@ -127,17 +242,17 @@ func (my *mysql) Connect(ctx context.Context) (*sql.DB, error) {
 	return db, nil
 }
 
-func mysqlConnection(logger log.Logger, user, pass string, address string, database string) *mysql {
-	timeout := "30s"
-	if v := os.Getenv("MYSQL_TIMEOUT"); v != "" {
-		timeout = v
+func mysqlConnection(logger log.Logger, cfg *config.MySQL) *mysql {
+	if cfg == nil {
+		cfg = &config.MySQL{}
 	}
-	params := fmt.Sprintf("timeout=%s&charset=utf8mb4&parseTime=true&sql_mode=ALLOW_INVALID_DATES", timeout)
-	dsn := fmt.Sprintf("%s:%s@%s/%s?%s", user, pass, address, database, params)
+	params := fmt.Sprintf("timeout=%v&charset=utf8mb4&parseTime=true&sql_mode=ALLOW_INVALID_DATES", cfg.ConnectTimeout())
+	dsn := fmt.Sprintf("%s:%s@%s/%s?%s", cfg.User, cfg.Password, cfg.Address, cfg.Database, params)
 	return &mysql{
-		dsn:         dsn,
-		logger:      logger,
-		connections: mysqlConnections,
+		dsn:            dsn,
+		maxConnections: cfg.MaxOpenConnections(),
+		logger:         logger,
+		connections:    mysqlConnections,
 	}
 }
 
@@ -211,7 +326,12 @@ func CreateTestMySQLDB(t *testing.T) *TestMySQLDB {
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
-	db, err := mysqlConnection(logger, "moov", "secret", address, "paygate").Connect(ctx)
+	db, err := mysqlConnection(logger, &config.MySQL{
+		Address:  address,
+		User:     "moov",
+		Password: "secret",
+		Database: "paygate",
+	}).Connect(ctx)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -9,6 +9,8 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/moov-io/paygate/pkg/config"
+
 	"github.com/go-kit/kit/log"
 )
 
@@ -21,7 +23,12 @@ func TestMySQL__basic(t *testing.T) {
 	}
 
 	// create a phony MySQL
-	m := mysqlConnection(log.NewNopLogger(), "user", "pass", "127.0.0.1:3006", "db")
+	m := mysqlConnection(log.NewNopLogger(), &config.MySQL{
+		Address:  "tcp(127.0.0.1:3006)",
+		User:     "user",
+		Password: "pass",
+		Database: "db",
+	})
 
 	ctx, cancelFunc := context.WithCancel(context.Background())
 
@@ -56,6 +56,126 @@ var (
 			"add_remote_addr_to_transfers",
 			"alter table transfers add column remote_address default '';",
 		),
+		execsql(
+			"create_ledger_entries",
+			`create table if not exists ledger_entries(entry_id primary key, account_id, transfer_id, amount integer, created_at datetime);`,
+		),
+		execsql(
+			"create_transfer_batches",
+			`create table if not exists transfer_batches(batch_id primary key, user_id, atomic boolean, status, created_at datetime, last_updated_at datetime);`,
+		),
+		execsql(
+			"create_transfer_batch_items",
+			`create table if not exists transfer_batch_items(batch_id, item_index integer, transfer_id, error);`,
+		),
+		execsql(
+			"add_file_id_to_transfers",
+			"alter table transfers add column file_id default '';",
+		),
+		execsql(
+			"create_file_deliveries",
+			`create table if not exists file_deliveries(delivery_id primary key, filename, size integer, sha256, status, created_at datetime, last_updated_at datetime);`,
+		),
+		execsql(
+			"create_file_delivery_attempts",
+			`create table if not exists file_delivery_attempts(delivery_id, attempt integer, error, created_at datetime);`,
+		),
+		execsql(
+			"add_odfi_to_tenants",
+			"alter table tenants add column odfi default '';",
+		),
+		execsql(
+			"create_inbound_files",
+			`create table if not exists inbound_files(sha256 primary key, odfi, kind, filename, remote_path, size integer, received_at datetime, processed_at datetime);`,
+		),
+		execsql(
+			"add_status_to_tenants",
+			"alter table tenants add column status default 'active';",
+		),
+		execsql(
+			"create_tenant_settings",
+			`create table if not exists tenant_settings(tenant_id primary key, company_name, default_sec_code, max_entry_amount, max_file_amount, max_file_entries integer, updated_at datetime);`,
+		),
+		execsql(
+			"create_tenant_company_identifications",
+			`create table if not exists tenant_company_identifications(tenant_id, company_identification, created_at datetime, retired_at datetime);`,
+		),
+		execsql(
+			"backfill_tenant_company_identifications",
+			`insert into tenant_company_identifications (tenant_id, company_identification, created_at) select tenant_id, company_identification, created_at from tenants where company_identification is not null and company_identification <> '';`,
+		),
+		execsql(
+			"create_tenant_company_identifications_idx",
+			`create unique index tenant_company_identifications_idx on tenant_company_identifications (company_identification);`,
+		),
+		execsql(
+			"add_organization_id_to_transfers",
+			"alter table transfers add column organization_id default '';",
+		),
+		execsql(
+			"create_memberships",
+			`create table memberships(kind, resource_id, user_id, role, created_at datetime, deleted_at datetime);`,
+		),
+		execsql(
+			"create_memberships_idx",
+			`create unique index memberships_idx on memberships (kind, resource_id, user_id);`,
+		),
+		execsql(
+			"create_transfer_reviews",
+			`create table transfer_reviews(transfer_id primary key, reasons, reviewed_by, decision, created_at datetime, reviewed_at datetime);`,
+		),
+		execsql(
+			"create_transfer_trace_numbers",
+			`create table transfer_trace_numbers(transfer_id, trace_number);`,
+		),
+		execsql(
+			"create_transfer_trace_numbers_idx",
+			`create index transfer_trace_numbers_idx on transfer_trace_numbers (trace_number);`,
+		),
+		execsql(
+			"create_file_delivery_trace_numbers",
+			`create table file_delivery_trace_numbers(delivery_id, trace_number);`,
+		),
+		execsql(
+			"create_file_delivery_trace_numbers_idx",
+			`create index file_delivery_trace_numbers_idx on file_delivery_trace_numbers (trace_number);`,
+		),
+		execsql(
+			"create_cutoff_runs",
+			`create table cutoff_runs(run_id primary key, odfi, cutoff_window, manual boolean, error, started_at datetime, finished_at datetime);`,
+		),
+		execsql(
+			"add_run_id_to_file_deliveries",
+			"alter table file_deliveries add column run_id default '';",
+		),
+		execsql(
+			"create_ledger_entries_account_id_idx",
+			`create index ledger_entries_account_id_idx on ledger_entries (account_id);`,
+		),
+		execsql(
+			"create_ledger_entries_transfer_id_idx",
+			`create index ledger_entries_transfer_id_idx on ledger_entries (transfer_id);`,
+		),
+		execsql(
+			"add_tenant_id_to_transfers",
+			"alter table transfers add column tenant_id default '';",
+		),
+		execsql(
+			"add_debit_to_transfer_trace_numbers",
+			"alter table transfer_trace_numbers add column debit boolean default false;",
+		),
+		execsql(
+			"create_transfer_holds",
+			`create table transfer_holds(transfer_id primary key, odfi, file, release_at datetime, released_at datetime, canceled_at datetime, created_at datetime);`,
+		),
+		execsql(
+			"drop_tenant_company_identifications_idx",
+			`drop index tenant_company_identifications_idx;`,
+		),
+		execsql(
+			"create_current_tenant_company_identifications_idx",
+			`create unique index current_tenant_company_identifications_idx on tenant_company_identifications (company_identification) where retired_at is null;`,
+		),
 	)
 )
 
@@ -124,16 +244,6 @@ func sqliteConnection(logger log.Logger, path string) *sqlite {
 	}
 }
 
-func getSqlitePath() string {
-	path := os.Getenv("SQLITE_DB_PATH")
-	if path == "" || strings.Contains(path, "..") {
-		// set default if empty or trying to escape
-		// don't filepath.ABS to avoid full-fs reads
-		path = "paygate.db"
-	}
-	return path
-}
-
 // TestSQLiteDB is a wrapper around sql.DB for SQLite connections designed for tests to provide
 // a clean database for each testcase.  Callers should cleanup with Close() when finished.
 type TestSQLiteDB struct {
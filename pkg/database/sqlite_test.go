@@ -10,6 +10,8 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/moov-io/paygate/pkg/config"
+
 	"github.com/go-kit/kit/log"
 )
 
@@ -40,8 +42,9 @@ func TestSQLite__basic(t *testing.T) {
 	conn.Close()
 }
 
-func TestSQLite__getSqlitePath(t *testing.T) {
-	if v := getSqlitePath(); v != "paygate.db" {
+func TestSQLite__path(t *testing.T) {
+	var cfg *config.SQLite
+	if v := cfg.Filepath(); v != "paygate.db" {
 		t.Errorf("got %s", v)
 	}
 }
@@ -0,0 +1,55 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package memberships
+
+import (
+	"net/http"
+
+	"github.com/moov-io/paygate/x/route"
+)
+
+// Kind is the type of resource a Membership grants access to.
+type Kind string
+
+const (
+	Tenant       Kind = "tenant"
+	Organization Kind = "organization"
+)
+
+// Checker returns the Role a user has for a Tenant or Organization. Users who created a resource
+// are its Owner and members of a Tenant inherit their Role on each of the Tenant's Organizations.
+// An empty Role is returned for users without access and for resources which don't exist.
+type Checker interface {
+	Role(userID string, kind Kind, resourceID string) (Role, error)
+}
+
+// Require returns nil when the user's Role for a resource includes the Permission, ErrNotMember
+// when the user has no Role and ErrForbidden when their Role lacks the Permission.
+func Require(checker Checker, userID string, kind Kind, resourceID string, perm Permission) error {
+	role, err := checker.Role(userID, kind, resourceID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrNotMember
+	}
+	if !role.Can(perm) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Problem writes an error from Require as 404 Not Found or 403 Forbidden. Other errors are
+// written with the responder.
+func Problem(responder *route.Responder, w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case ErrNotMember:
+		http.NotFound(w, r)
+	case ErrForbidden:
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		responder.Problem(err)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/memberships"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+// resource is a Tenant or Organization whose memberships are managed
+type resource struct {
+	kind    memberships.Kind
+	pathVar string
+	exists  func(id string) (bool, error)
+}
+
+// readResourceID writes a 404 and returns an empty string when the resource doesn't exist
+func (res resource) readResourceID(responder *route.Responder, w http.ResponseWriter, r *http.Request) string {
+	id := route.ReadPathID(res.pathVar, r)
+	if id == "" {
+		responder.Problem(fmt.Errorf("missing %s", res.pathVar))
+		return ""
+	}
+	exists, err := res.exists(id)
+	if err != nil {
+		responder.Problem(err)
+		return ""
+	}
+	if !exists {
+		http.NotFound(w, r)
+		return ""
+	}
+	return id
+}
+
+func toAdminMembership(m memberships.Membership) admin.Membership {
+	return admin.Membership{
+		UserID:  m.UserID,
+		Role:    string(m.Role),
+		Created: m.Created,
+	}
+}
+
+func listMemberships(logger log.Logger, repo memberships.Repository, res resource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		resourceID := res.readResourceID(responder, w, r)
+		if resourceID == "" {
+			return
+		}
+		members, err := repo.List(res.kind, resourceID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		out := make([]admin.Membership, len(members))
+		for i := range members {
+			out[i] = toAdminMembership(members[i])
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(out)
+		})
+	}
+}
+
+func membershipHandler(logger log.Logger, repo memberships.Repository, res resource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			updateMembership(logger, repo, res)(w, r)
+		case http.MethodDelete:
+			deleteMembership(logger, repo, res)(w, r)
+		default:
+			route.NewResponder(logger, w, r).Problem(fmt.Errorf("invalid method %s", r.Method))
+		}
+	}
+}
+
+func updateMembership(logger log.Logger, repo memberships.Repository, res resource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		var req admin.UpdateMembership
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(err)
+			return
+		}
+		role, err := memberships.ParseRole(req.Role)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		userID := route.PathUserID(r)
+		if userID == "" {
+			responder.Problem(errors.New("missing userId"))
+			return
+		}
+
+		resourceID := res.readResourceID(responder, w, r)
+		if resourceID == "" {
+			return
+		}
+		membership := memberships.Membership{
+			Kind:       res.kind,
+			ResourceID: resourceID,
+			UserID:     userID,
+			Role:       role,
+			Created:    time.Now(),
+		}
+		if err := repo.Save(membership); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Log("memberships", fmt.Sprintf("user=%s is now %s of %s=%s", userID, role, res.kind, resourceID))
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(toAdminMembership(membership))
+		})
+	}
+}
+
+func deleteMembership(logger log.Logger, repo memberships.Repository, res resource) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		resourceID := route.ReadPathID(res.pathVar, r)
+		userID := route.PathUserID(r)
+		if resourceID == "" || userID == "" {
+			responder.Problem(fmt.Errorf("missing %s or userId", res.pathVar))
+			return
+		}
+		if err := repo.Remove(res.kind, resourceID, userID); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Log("memberships", fmt.Sprintf("removed user=%s from %s=%s", userID, res.kind, resourceID))
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/memberships"
+	"github.com/moov-io/paygate/pkg/organizations"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestRoutes__TenantMemberships(t *testing.T) {
+	tenantID := base.ID()
+	tenantRepo := &tenants.MockRepository{
+		Tenants: []client.Tenant{{TenantID: tenantID}},
+	}
+	repo := &memberships.MockRepository{}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, tenantRepo, &organizations.MockRepository{})
+
+	m, resp, err := c.MembershipsApi.UpdateTenantMembership(context.Background(), tenantID, "finance", "userID", admin.UpdateMembership{
+		Role: "Viewer",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if m.UserID != "finance" || m.Role != "viewer" {
+		t.Errorf("unexpected membership: %#v", m)
+	}
+
+	members, resp, err := c.MembershipsApi.GetTenantMemberships(context.Background(), tenantID, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(members) != 1 || members[0].Role != "viewer" {
+		t.Errorf("unexpected memberships: %#v", members)
+	}
+
+	resp, err = c.MembershipsApi.DeleteTenantMembership(context.Background(), tenantID, "finance", "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(repo.Memberships) != 0 {
+		t.Errorf("unexpected memberships: %#v", repo.Memberships)
+	}
+
+	// unknown role
+	_, resp, err = c.MembershipsApi.UpdateTenantMembership(context.Background(), tenantID, "finance", "userID", admin.UpdateMembership{
+		Role: "superuser",
+	}, nil)
+	if err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected error: %v", err)
+	}
+
+	// unknown Tenant
+	_, resp, err = c.MembershipsApi.GetTenantMemberships(context.Background(), base.ID(), "userID", nil)
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404: %v", err)
+	}
+}
+
+func TestRoutes__OrganizationMemberships(t *testing.T) {
+	orgID := base.ID()
+	orgRepo := &organizations.MockRepository{
+		Organizations: []client.Organization{{OrganizationID: orgID}},
+	}
+	repo := &memberships.MockRepository{}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, &tenants.MockRepository{}, orgRepo)
+
+	_, resp, err := c.MembershipsApi.UpdateOrganizationMembership(context.Background(), orgID, "ops", "userID", admin.UpdateMembership{
+		Role: "operator",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if role, _ := repo.Role("ops", memberships.Organization, orgID); role != memberships.Operator {
+		t.Errorf("unexpected role %q", role)
+	}
+
+	// unknown Organization
+	_, resp, err = c.MembershipsApi.UpdateOrganizationMembership(context.Background(), base.ID(), "ops", "userID", admin.UpdateMembership{
+		Role: "operator",
+	}, nil)
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"github.com/moov-io/base/admin"
+	"github.com/moov-io/paygate/pkg/memberships"
+	"github.com/moov-io/paygate/pkg/organizations"
+	"github.com/moov-io/paygate/pkg/tenants"
+
+	"github.com/go-kit/kit/log"
+)
+
+// RegisterRoutes will add HTTP handlers for paygate's admin HTTP server
+func RegisterRoutes(logger log.Logger, svc *admin.Server, repo memberships.Repository, tenantRepo tenants.Repository, orgRepo organizations.Repository) {
+	tenant := resource{
+		kind:    memberships.Tenant,
+		pathVar: "tenantId",
+		exists: func(id string) (bool, error) {
+			t, err := tenantRepo.GetTenant(id)
+			return t != nil, err
+		},
+	}
+	svc.AddHandler("/tenants/{tenantId}/memberships", listMemberships(logger, repo, tenant))
+	svc.AddHandler("/tenants/{tenantId}/memberships/{userId}", membershipHandler(logger, repo, tenant))
+
+	org := resource{
+		kind:    memberships.Organization,
+		pathVar: "organizationId",
+		exists: func(id string) (bool, error) {
+			o, err := orgRepo.GetOrganization(id)
+			return o != nil, err
+		},
+	}
+	svc.AddHandler("/organizations/{organizationId}/memberships", listMemberships(logger, repo, org))
+	svc.AddHandler("/organizations/{organizationId}/memberships/{userId}", membershipHandler(logger, repo, org))
+}
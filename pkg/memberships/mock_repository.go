@@ -0,0 +1,60 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package memberships
+
+type MockRepository struct {
+	Memberships []Membership
+
+	Err error
+}
+
+func (r *MockRepository) Role(userID string, kind Kind, resourceID string) (Role, error) {
+	if r.Err != nil {
+		return "", r.Err
+	}
+	for i := range r.Memberships {
+		m := r.Memberships[i]
+		if m.Kind == kind && m.ResourceID == resourceID && m.UserID == userID {
+			return m.Role, nil
+		}
+	}
+	return "", nil
+}
+
+func (r *MockRepository) List(kind Kind, resourceID string) ([]Membership, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	var out []Membership
+	for i := range r.Memberships {
+		if r.Memberships[i].Kind == kind && r.Memberships[i].ResourceID == resourceID {
+			out = append(out, r.Memberships[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *MockRepository) Save(membership Membership) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Remove(membership.Kind, membership.ResourceID, membership.UserID)
+	r.Memberships = append(r.Memberships, membership)
+	return nil
+}
+
+func (r *MockRepository) Remove(kind Kind, resourceID string, userID string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Memberships {
+		m := r.Memberships[i]
+		if m.Kind == kind && m.ResourceID == resourceID && m.UserID == userID {
+			r.Memberships = append(r.Memberships[:i], r.Memberships[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package memberships
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Membership grants a user a Role on a Tenant or Organization they didn't create.
+type Membership struct {
+	Kind       Kind
+	ResourceID string
+	UserID     string
+	Role       Role
+	Created    time.Time
+}
+
+type Repository interface {
+	Checker
+
+	List(kind Kind, resourceID string) ([]Membership, error)
+
+	// Save adds a Membership or replaces the Role of an existing one
+	Save(membership Membership) error
+	Remove(kind Kind, resourceID string, userID string) error
+}
+
+func NewRepo(db *sql.DB) Repository {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+func (r *sqlRepo) Role(userID string, kind Kind, resourceID string) (Role, error) {
+	switch kind {
+	case Tenant:
+		return r.tenantRole(userID, resourceID)
+	case Organization:
+		return r.organizationRole(userID, resourceID)
+	}
+	return "", fmt.Errorf("unknown kind %q", kind)
+}
+
+func (r *sqlRepo) tenantRole(userID, tenantID string) (Role, error) {
+	query := `select user_id from tenants where tenant_id = ? and deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	var creator string
+	if err := stmt.QueryRow(tenantID).Scan(&creator); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	if creator == userID {
+		return Owner, nil
+	}
+	return r.memberRole(userID, Tenant, tenantID)
+}
+
+func (r *sqlRepo) organizationRole(userID, orgID string) (Role, error) {
+	query := `select o.user_id, ts.tenant_id from organizations as o
+inner join tenants_organizations as ts on o.organization_id = ts.organization_id
+where o.organization_id = ? and o.deleted_at is null and ts.deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	var creator, tenantID string
+	if err := stmt.QueryRow(orgID).Scan(&creator, &tenantID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	if creator == userID {
+		return Owner, nil
+	}
+
+	orgRole, err := r.memberRole(userID, Organization, orgID)
+	if err != nil {
+		return "", err
+	}
+	tenantRole, err := r.tenantRole(userID, tenantID)
+	if err != nil {
+		return "", err
+	}
+	return highest(orgRole, tenantRole), nil
+}
+
+func (r *sqlRepo) memberRole(userID string, kind Kind, resourceID string) (Role, error) {
+	query := `select role from memberships where kind = ? and resource_id = ? and user_id = ? and deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	var role Role
+	if err := stmt.QueryRow(kind, resourceID, userID).Scan(&role); err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	return role, nil
+}
+
+func (r *sqlRepo) List(kind Kind, resourceID string) ([]Membership, error) {
+	query := `select user_id, role, created_at from memberships where kind = ? and resource_id = ? and deleted_at is null order by created_at;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(kind, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Membership
+	for rows.Next() {
+		m := Membership{Kind: kind, ResourceID: resourceID}
+		if err := rows.Scan(&m.UserID, &m.Role, &m.Created); err != nil {
+			return nil, fmt.Errorf("list memberships: %s=%s error=%v", kind, resourceID, err)
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) Save(m Membership) error {
+	query := `replace into memberships (kind, resource_id, user_id, role, created_at, deleted_at) values (?, ?, ?, ?, ?, null);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(m.Kind, m.ResourceID, m.UserID, m.Role, m.Created)
+	return err
+}
+
+func (r *sqlRepo) Remove(kind Kind, resourceID string, userID string) error {
+	query := `update memberships set deleted_at = ? where kind = ? and resource_id = ? and user_id = ? and deleted_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(time.Now(), kind, resourceID, userID)
+	return err
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package memberships
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return &sqlRepo{db: db.DB}
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return &sqlRepo{db: db.DB}
+}
+
+// writeOrganization creates a Tenant and an Organization under it, each created by userID
+func writeOrganization(t *testing.T, db *sql.DB, userID string) (string, string) {
+	t.Helper()
+
+	tenantID, orgID := base.ID(), base.ID()
+	queries := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`insert into tenants (tenant_id, user_id, name, created_at) values (?, ?, 'My Company', ?);`, []interface{}{tenantID, userID, time.Now()}},
+		{`insert into organizations (organization_id, user_id, name, created_at) values (?, ?, 'My Organization', ?);`, []interface{}{orgID, userID, time.Now()}},
+		{`insert into tenants_organizations (tenant_id, organization_id, created_at) values (?, ?, ?);`, []interface{}{tenantID, orgID, time.Now()}},
+	}
+	for _, q := range queries {
+		if _, err := db.Exec(q.query, q.args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tenantID, orgID
+}
+
+func TestRepository__Role(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		creator := base.ID()
+		tenantID, orgID := writeOrganization(t, repo.db, creator)
+
+		// creators own their Tenants and Organizations
+		for _, kind := range []Kind{Tenant, Organization} {
+			id := tenantID
+			if kind == Organization {
+				id = orgID
+			}
+			if role, err := repo.Role(creator, kind, id); err != nil || role != Owner {
+				t.Errorf("%s: role=%q error=%v", kind, role, err)
+			}
+		}
+
+		// members of the Tenant inherit their role on its Organizations
+		member := base.ID()
+		if err := repo.Save(Membership{Kind: Tenant, ResourceID: tenantID, UserID: member, Role: Viewer, Created: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		if role, err := repo.Role(member, Organization, orgID); err != nil || role != Viewer {
+			t.Errorf("role=%q error=%v", role, err)
+		}
+
+		// the highest role applies
+		if err := repo.Save(Membership{Kind: Organization, ResourceID: orgID, UserID: member, Role: Operator, Created: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		if role, err := repo.Role(member, Organization, orgID); err != nil || role != Operator {
+			t.Errorf("role=%q error=%v", role, err)
+		}
+		if role, err := repo.Role(member, Tenant, tenantID); err != nil || role != Viewer {
+			t.Errorf("role=%q error=%v", role, err)
+		}
+
+		// other users and missing resources have no role
+		if role, err := repo.Role(base.ID(), Organization, orgID); err != nil || role != "" {
+			t.Errorf("role=%q error=%v", role, err)
+		}
+		if role, err := repo.Role(member, Tenant, base.ID()); err != nil || role != "" {
+			t.Errorf("role=%q error=%v", role, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__SaveAndRemove(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		tenantID, _ := writeOrganization(t, repo.db, base.ID())
+		userID := base.ID()
+
+		if err := repo.Save(Membership{Kind: Tenant, ResourceID: tenantID, UserID: userID, Role: Viewer, Created: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+		// change their role
+		if err := repo.Save(Membership{Kind: Tenant, ResourceID: tenantID, UserID: userID, Role: Admin, Created: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+
+		members, err := repo.List(Tenant, tenantID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(members) != 1 || members[0].UserID != userID || members[0].Role != Admin {
+			t.Errorf("unexpected memberships: %#v", members)
+		}
+
+		if err := repo.Remove(Tenant, tenantID, userID); err != nil {
+			t.Fatal(err)
+		}
+		if members, err := repo.List(Tenant, tenantID); err != nil || len(members) != 0 {
+			t.Errorf("unexpected memberships=%#v error=%v", members, err)
+		}
+		if role, err := repo.Role(userID, Tenant, tenantID); err != nil || role != "" {
+			t.Errorf("role=%q error=%v", role, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package memberships
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Role is the level of access a user has to a Tenant or Organization.
+type Role string
+
+const (
+	Owner    Role = "owner"
+	Admin    Role = "admin"
+	Operator Role = "operator"
+	Viewer   Role = "viewer"
+)
+
+// Permission is an action a user takes on a Tenant, Organization or their Transfers.
+type Permission string
+
+const (
+	// Read allows viewing the resource and its Transfers
+	Read Permission = "read"
+
+	// CreateTransfers allows creating and deleting Transfers
+	CreateTransfers Permission = "create-transfers"
+
+	// ApproveTransfers allows approving or rejecting REVIEWABLE Transfers
+	ApproveTransfers Permission = "approve-transfers"
+
+	// Manage allows updating the resource, creating Organizations and moving them between Tenants
+	Manage Permission = "manage"
+
+	// Delete allows removing the resource
+	Delete Permission = "delete"
+)
+
+var (
+	// ErrNotMember is returned when a user has no Role for a resource, which includes resources
+	// that don't exist. Handlers respond with 404 Not Found to avoid leaking their existence.
+	ErrNotMember = errors.New("not found")
+
+	// ErrForbidden is returned when a user's Role doesn't include the Permission requested.
+	ErrForbidden = errors.New("forbidden")
+)
+
+var permissions = map[Role][]Permission{
+	Owner:    {Read, CreateTransfers, ApproveTransfers, Manage, Delete},
+	Admin:    {Read, CreateTransfers, ApproveTransfers, Manage},
+	Operator: {Read, ApproveTransfers},
+	Viewer:   {Read},
+}
+
+// ParseRole returns the Role for a case-insensitive name.
+func ParseRole(name string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(name)))
+	if _, exists := permissions[role]; !exists {
+		return "", fmt.Errorf("unknown role %q", name)
+	}
+	return role, nil
+}
+
+// Can returns true when the Role includes the Permission.
+func (r Role) Can(p Permission) bool {
+	for _, perm := range permissions[r] {
+		if perm == p {
+			return true
+		}
+	}
+	return false
+}
+
+// rank orders Roles from the least to the most access.
+func (r Role) rank() int {
+	switch r {
+	case Owner:
+		return 4
+	case Admin:
+		return 3
+	case Operator:
+		return 2
+	case Viewer:
+		return 1
+	}
+	return 0
+}
+
+// highest returns the Role with the most access.
+func highest(roles ...Role) Role {
+	var out Role
+	for _, r := range roles {
+		if r.rank() > out.rank() {
+			out = r
+		}
+	}
+	return out
+}
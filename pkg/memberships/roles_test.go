@@ -0,0 +1,70 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package memberships
+
+import (
+	"testing"
+)
+
+func TestRoles__Can(t *testing.T) {
+	cases := []struct {
+		role    Role
+		allowed []Permission
+		denied  []Permission
+	}{
+		{Owner, []Permission{Read, CreateTransfers, ApproveTransfers, Manage, Delete}, nil},
+		{Admin, []Permission{Read, CreateTransfers, ApproveTransfers, Manage}, []Permission{Delete}},
+		{Operator, []Permission{Read, ApproveTransfers}, []Permission{CreateTransfers, Manage, Delete}},
+		{Viewer, []Permission{Read}, []Permission{CreateTransfers, ApproveTransfers, Manage, Delete}},
+		{Role(""), nil, []Permission{Read}},
+	}
+	for _, tc := range cases {
+		for _, p := range tc.allowed {
+			if !tc.role.Can(p) {
+				t.Errorf("%q should be allowed to %s", tc.role, p)
+			}
+		}
+		for _, p := range tc.denied {
+			if tc.role.Can(p) {
+				t.Errorf("%q should not be allowed to %s", tc.role, p)
+			}
+		}
+	}
+}
+
+func TestRoles__ParseRole(t *testing.T) {
+	if role, err := ParseRole(" Operator"); err != nil || role != Operator {
+		t.Errorf("role=%q error=%v", role, err)
+	}
+	if _, err := ParseRole("superuser"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestRoles__highest(t *testing.T) {
+	if r := highest(Viewer, "", Admin, Operator); r != Admin {
+		t.Errorf("unexpected role %q", r)
+	}
+	if r := highest(); r != "" {
+		t.Errorf("unexpected role %q", r)
+	}
+}
+
+func TestAccess__Require(t *testing.T) {
+	repo := &MockRepository{
+		Memberships: []Membership{
+			{Kind: Tenant, ResourceID: "tenantID", UserID: "finance", Role: Viewer},
+		},
+	}
+	if err := Require(repo, "finance", Tenant, "tenantID", Read); err != nil {
+		t.Error(err)
+	}
+	if err := Require(repo, "finance", Tenant, "tenantID", CreateTransfers); err != ErrForbidden {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := Require(repo, "other", Tenant, "tenantID", Read); err != ErrNotMember {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -8,22 +8,59 @@ import (
 	"github.com/moov-io/paygate/pkg/client"
 )
 
-type mockRepository struct {
+type MockRepository struct {
 	Organizations []client.Organization
 	Err           error
 }
 
-func (r *mockRepository) getOrganizations(userID string) ([]client.Organization, error) {
+func (r *MockRepository) getOrganizations(userID string) ([]client.Organization, error) {
 	if r.Err != nil {
 		return nil, r.Err
 	}
 	return r.Organizations, nil
 }
 
-func (r *mockRepository) createOrganization(userID string, org client.Organization) error {
+func (r *MockRepository) createOrganization(userID string, org client.Organization) error {
 	return r.Err
 }
 
-func (r *mockRepository) updateOrganizationName(orgID, name string) error {
+func (r *MockRepository) updateOrganizationName(orgID, name string) error {
 	return r.Err
 }
+
+func (r *MockRepository) deleteOrganization(orgID string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Organizations {
+		if r.Organizations[i].OrganizationID == orgID {
+			r.Organizations = append(r.Organizations[:i], r.Organizations[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) moveOrganization(orgID, tenantID string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Organizations {
+		if r.Organizations[i].OrganizationID == orgID {
+			r.Organizations[i].TenantID = tenantID
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) GetOrganization(orgID string) (*client.Organization, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Organizations {
+		if r.Organizations[i].OrganizationID == orgID {
+			return &r.Organizations[i], nil
+		}
+	}
+	return nil, nil
+}
@@ -15,6 +15,11 @@ type Repository interface {
 	getOrganizations(userID string) ([]client.Organization, error)
 	createOrganization(userID string, org client.Organization) error
 	updateOrganizationName(orgID, name string) error
+	deleteOrganization(orgID string) error
+	moveOrganization(orgID, tenantID string) error
+
+	// GetOrganization returns nil when the Organization doesn't exist or has been deleted.
+	GetOrganization(orgID string) (*client.Organization, error)
 }
 
 func NewRepo(db *sql.DB) Repository {
@@ -28,14 +33,18 @@ type sqlRepo struct {
 func (r *sqlRepo) getOrganizations(userID string) ([]client.Organization, error) {
 	query := `select o.organization_id, o.name, ts.tenant_id, o.primary_customer from organizations as o
 inner join tenants_organizations as ts on o.organization_id = ts.organization_id
-where o.user_id = ? and o.deleted_at is null and ts.deleted_at is null;`
+where (o.user_id = ?
+  or o.organization_id in (select resource_id from memberships where kind = 'organization' and user_id = ? and deleted_at is null)
+  or ts.tenant_id in (select resource_id from memberships where kind = 'tenant' and user_id = ? and deleted_at is null)
+  or ts.tenant_id in (select tenant_id from tenants where user_id = ? and deleted_at is null))
+and o.deleted_at is null and ts.deleted_at is null;`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(userID)
+	rows, err := stmt.Query(userID, userID, userID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -99,3 +108,96 @@ func (r *sqlRepo) updateOrganizationName(orgID, name string) error {
 	_, err = stmt.Exec(name, orgID)
 	return err
 }
+
+func (r *sqlRepo) GetOrganization(orgID string) (*client.Organization, error) {
+	query := `select o.organization_id, o.name, ts.tenant_id, o.primary_customer from organizations as o
+inner join tenants_organizations as ts on o.organization_id = ts.organization_id
+where o.organization_id = ? and o.deleted_at is null and ts.deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var org client.Organization
+	if err := stmt.QueryRow(orgID).Scan(&org.OrganizationID, &org.Name, &org.TenantID, &org.PrimaryCustomer); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (r *sqlRepo) deleteOrganization(orgID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `update organizations set deleted_at = ? where organization_id = ? and deleted_at is null;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(now, orgID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query = `update tenants_organizations set deleted_at = ? where organization_id = ? and deleted_at is null;`
+	stmt, err = tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(now, orgID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// moveOrganization links an Organization to another Tenant. The previous link is kept, but marked deleted.
+func (r *sqlRepo) moveOrganization(orgID, tenantID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `update tenants_organizations set deleted_at = ? where organization_id = ? and deleted_at is null;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(now, orgID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query = `replace into tenants_organizations(tenant_id, organization_id, created_at, deleted_at) values (?, ?, ?, null);`
+	stmt, err = tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(tenantID, orgID, now); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
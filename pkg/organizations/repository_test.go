@@ -0,0 +1,164 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package organizations
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/memberships"
+)
+
+func setupSQLiteDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestSqliteDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return &sqlRepo{db: db.DB}
+}
+
+func setupMySQLeDB(t *testing.T) *sqlRepo {
+	db := database.CreateTestMySQLDB(t)
+	t.Cleanup(func() { db.Close() })
+
+	return &sqlRepo{db: db.DB}
+}
+
+func writeOrganization(t *testing.T, userID string, repo Repository) client.Organization {
+	t.Helper()
+
+	org := client.Organization{
+		OrganizationID:  base.ID(),
+		Name:            "my organization",
+		TenantID:        base.ID(),
+		PrimaryCustomer: base.ID(),
+	}
+	if err := repo.createOrganization(userID, org); err != nil {
+		t.Fatal(err)
+	}
+	return org
+}
+
+func TestRepository__GetOrganization(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		org := writeOrganization(t, userID, repo)
+
+		found, err := repo.GetOrganization(org.OrganizationID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.TenantID != org.TenantID {
+			t.Errorf("unexpected organization: %#v", found)
+		}
+
+		if found, err := repo.GetOrganization(base.ID()); err != nil || found != nil {
+			t.Errorf("unexpected organization=%#v error=%v", found, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__getOrganizationsMembers(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		org := writeOrganization(t, base.ID(), repo)
+		members := memberships.NewRepo(repo.db)
+
+		// Members of the Organization and its Tenant can list it
+		orgMember, tenantMember := base.ID(), base.ID()
+		for _, m := range []memberships.Membership{
+			{Kind: memberships.Organization, ResourceID: org.OrganizationID, UserID: orgMember, Role: memberships.Viewer},
+			{Kind: memberships.Tenant, ResourceID: org.TenantID, UserID: tenantMember, Role: memberships.Operator},
+		} {
+			m.Created = time.Now()
+			if err := members.Save(m); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, userID := range []string{orgMember, tenantMember} {
+			orgs, err := repo.getOrganizations(userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(orgs) != 1 || orgs[0].OrganizationID != org.OrganizationID {
+				t.Errorf("userID=%s unexpected organizations: %#v", userID, orgs)
+			}
+		}
+
+		if orgs, err := repo.getOrganizations(base.ID()); err != nil || len(orgs) != 0 {
+			t.Errorf("unexpected organizations=%#v error=%v", orgs, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__moveOrganization(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		org := writeOrganization(t, userID, repo)
+
+		tenantID := base.ID()
+		if err := repo.moveOrganization(org.OrganizationID, tenantID); err != nil {
+			t.Fatal(err)
+		}
+
+		orgs, err := repo.getOrganizations(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(orgs) != 1 || orgs[0].TenantID != tenantID {
+			t.Errorf("unexpected organizations: %#v", orgs)
+		}
+
+		// move it back to the original Tenant
+		if err := repo.moveOrganization(org.OrganizationID, org.TenantID); err != nil {
+			t.Fatal(err)
+		}
+		found, err := repo.GetOrganization(org.OrganizationID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.TenantID != org.TenantID {
+			t.Errorf("unexpected organization: %#v", found)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__deleteOrganization(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		org := writeOrganization(t, userID, repo)
+
+		if err := repo.deleteOrganization(org.OrganizationID); err != nil {
+			t.Fatal(err)
+		}
+		if found, err := repo.GetOrganization(org.OrganizationID); err != nil || found != nil {
+			t.Errorf("unexpected organization=%#v error=%v", found, err)
+		}
+		if orgs, err := repo.getOrganizations(userID); err != nil || len(orgs) != 0 {
+			t.Errorf("unexpected organizations=%#v error=%v", orgs, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
@@ -7,10 +7,12 @@ package organizations
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/memberships"
 	"github.com/moov-io/paygate/x/route"
 
 	"github.com/go-kit/kit/log"
@@ -21,25 +23,34 @@ type Router struct {
 	Logger log.Logger
 	Repo   Repository
 
-	GetOrganizations   http.HandlerFunc
-	CreateOrganization http.HandlerFunc
-	UpdateOrganization http.HandlerFunc
+	GetOrganizations         http.HandlerFunc
+	GetOrganization          http.HandlerFunc
+	CreateOrganization       http.HandlerFunc
+	UpdateOrganization       http.HandlerFunc
+	UpdateOrganizationTenant http.HandlerFunc
+	DeleteOrganization       http.HandlerFunc
 }
 
-func NewRouter(logger log.Logger, repo Repository) *Router {
+func NewRouter(logger log.Logger, repo Repository, access memberships.Checker) *Router {
 	return &Router{
-		Logger:             logger,
-		Repo:               repo,
-		GetOrganizations:   GetOrganizations(logger, repo),
-		CreateOrganization: CreateOrganization(logger, repo),
-		UpdateOrganization: UpdateOrganization(logger, repo),
+		Logger:                   logger,
+		Repo:                     repo,
+		GetOrganizations:         GetOrganizations(logger, repo),
+		GetOrganization:          GetOrganization(logger, repo, access),
+		CreateOrganization:       CreateOrganization(logger, repo, access),
+		UpdateOrganization:       UpdateOrganization(logger, repo, access),
+		UpdateOrganizationTenant: UpdateOrganizationTenant(logger, repo, access),
+		DeleteOrganization:       DeleteOrganization(logger, repo, access),
 	}
 }
 
 func (c *Router) RegisterRoutes(r *mux.Router) {
 	r.Methods("GET").Path("/organizations").HandlerFunc(c.GetOrganizations)
 	r.Methods("POST").Path("/organizations").HandlerFunc(c.CreateOrganization)
+	r.Methods("GET").Path("/organizations/{organizationID}").HandlerFunc(c.GetOrganization)
 	r.Methods("PUT").Path("/organizations/{organizationID}").HandlerFunc(c.UpdateOrganization)
+	r.Methods("DELETE").Path("/organizations/{organizationID}").HandlerFunc(c.DeleteOrganization)
+	r.Methods("PUT").Path("/organizations/{organizationID}/tenant").HandlerFunc(c.UpdateOrganizationTenant)
 }
 
 func getOrganizationID(r *http.Request) string {
@@ -75,7 +86,7 @@ func GetOrganizations(logger log.Logger, repo Repository) http.HandlerFunc {
 	}
 }
 
-func CreateOrganization(logger log.Logger, repo Repository) http.HandlerFunc {
+func CreateOrganization(logger log.Logger, repo Repository, access memberships.Checker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(logger, w, r)
 
@@ -88,10 +99,13 @@ func CreateOrganization(logger log.Logger, repo Repository) http.HandlerFunc {
 			responder.Problem(err)
 			return
 		}
+		if err := memberships.Require(access, responder.XUserID, memberships.Tenant, org.TenantID, memberships.Manage); err != nil {
+			memberships.Problem(responder, w, r, err)
+			return
+		}
 		org.OrganizationID = base.ID()
 
-		userID := route.HeaderUserID(r)
-		if err := repo.createOrganization(userID, org); err != nil {
+		if err := repo.createOrganization(responder.XUserID, org); err != nil {
 			responder.Problem(err)
 			return
 		}
@@ -103,10 +117,16 @@ func CreateOrganization(logger log.Logger, repo Repository) http.HandlerFunc {
 	}
 }
 
-func UpdateOrganization(logger log.Logger, repo Repository) http.HandlerFunc {
+func UpdateOrganization(logger log.Logger, repo Repository, access memberships.Checker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(logger, w, r)
 
+		orgID := getOrganizationID(r)
+		if err := memberships.Require(access, responder.XUserID, memberships.Organization, orgID, memberships.Manage); err != nil {
+			memberships.Problem(responder, w, r, err)
+			return
+		}
+
 		var org client.Organization
 		if err := json.NewDecoder(r.Body).Decode(&org); err != nil {
 			responder.Problem(err)
@@ -117,13 +137,123 @@ func UpdateOrganization(logger log.Logger, repo Repository) http.HandlerFunc {
 			return
 		}
 
-		if err := repo.updateOrganizationName(getOrganizationID(r), org.Name); err != nil {
+		if err := repo.updateOrganizationName(orgID, org.Name); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
+
+func GetOrganization(logger log.Logger, repo Repository, access memberships.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		orgID := getOrganizationID(r)
+		if err := memberships.Require(access, responder.XUserID, memberships.Organization, orgID, memberships.Read); err != nil {
+			memberships.Problem(responder, w, r, err)
+			return
+		}
+
+		org, err := repo.GetOrganization(orgID)
+		if err != nil {
 			responder.Problem(err)
 			return
 		}
+		if org == nil {
+			http.NotFound(w, r)
+			return
+		}
 
 		responder.Respond(func(w http.ResponseWriter) {
 			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(org)
+		})
+	}
+}
+
+func DeleteOrganization(logger log.Logger, repo Repository, access memberships.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		orgID := getOrganizationID(r)
+		if err := memberships.Require(access, responder.XUserID, memberships.Organization, orgID, memberships.Delete); err != nil {
+			memberships.Problem(responder, w, r, err)
+			return
+		}
+
+		org, err := repo.GetOrganization(orgID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if org == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if err := repo.deleteOrganization(org.OrganizationID); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
+
+// UpdateOrganizationTenant moves an Organization to another Tenant. Callers need to manage both
+// the Organization and the Tenant it's moved to.
+func UpdateOrganizationTenant(logger log.Logger, repo Repository, access memberships.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		var req client.UpdateOrganizationTenant
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(err)
+			return
+		}
+		if req.TenantID == "" {
+			responder.Problem(errors.New("missing tenantID"))
+			return
+		}
+
+		orgID := getOrganizationID(r)
+		if err := memberships.Require(access, responder.XUserID, memberships.Organization, orgID, memberships.Manage); err != nil {
+			memberships.Problem(responder, w, r, err)
+			return
+		}
+		org, err := repo.GetOrganization(orgID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if org == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err := memberships.Require(access, responder.XUserID, memberships.Tenant, req.TenantID, memberships.Manage); err != nil {
+			if err == memberships.ErrNotMember {
+				err = fmt.Errorf("tenant %s not found", req.TenantID)
+			}
+			memberships.Problem(responder, w, r, err)
+			return
+		}
+
+		if err := repo.moveOrganization(org.OrganizationID, req.TenantID); err != nil {
+			responder.Problem(err)
+			return
+		}
+		org.TenantID = req.TenantID
+
+		responder.Log("organizations", fmt.Sprintf("moved organization=%s to tenant=%s", org.OrganizationID, req.TenantID))
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(org)
 		})
 	}
 }
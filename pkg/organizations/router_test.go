@@ -6,10 +6,12 @@ package organizations
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/memberships"
 	"github.com/moov-io/paygate/pkg/testclient"
 
 	"github.com/go-kit/kit/log"
@@ -17,7 +19,7 @@ import (
 )
 
 func TestRouter__getUserTenants(t *testing.T) {
-	repo := &mockRepository{
+	repo := &MockRepository{
 		Organizations: []client.Organization{
 			{
 				OrganizationID:  base.ID(),
@@ -29,7 +31,7 @@ func TestRouter__getUserTenants(t *testing.T) {
 	}
 
 	r := mux.NewRouter()
-	router := NewRouter(log.NewNopLogger(), repo)
+	router := NewRouter(log.NewNopLogger(), repo, &memberships.MockRepository{})
 	router.RegisterRoutes(r)
 
 	client := testclient.New(t, r)
@@ -44,3 +46,180 @@ func TestRouter__getUserTenants(t *testing.T) {
 		t.Errorf("got %d organizations: %#v", n, orgs)
 	}
 }
+
+func owner(kind memberships.Kind, resourceID string) memberships.Membership {
+	return memberships.Membership{Kind: kind, ResourceID: resourceID, UserID: "userID", Role: memberships.Owner}
+}
+
+func TestRouter__CreateOrganization(t *testing.T) {
+	tenantID := base.ID()
+	access := &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			owner(memberships.Tenant, tenantID),
+			{Kind: memberships.Tenant, ResourceID: tenantID, UserID: "finance", Role: memberships.Viewer},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewRouter(log.NewNopLogger(), &MockRepository{}, access).RegisterRoutes(r)
+	c := testclient.New(t, r)
+
+	req := client.CreateOrganization{
+		Name:            "reseller client",
+		TenantID:        tenantID,
+		PrimaryCustomer: base.ID(),
+	}
+	org, resp, err := c.OrganizationsApi.CreateOrganization(context.TODO(), "userID", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if org.OrganizationID == "" || org.TenantID != tenantID {
+		t.Errorf("unexpected organization: %#v", org)
+	}
+
+	// Viewers can't create Organizations
+	_, resp, err = c.OrganizationsApi.CreateOrganization(context.TODO(), "finance", req, nil)
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403: %v", err)
+	}
+
+	// Tenants the user isn't a member of aren't found
+	req.TenantID = base.ID()
+	_, resp, err = c.OrganizationsApi.CreateOrganization(context.TODO(), "userID", req, nil)
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404: %v", err)
+	}
+}
+
+func TestRouter__GetOrganization(t *testing.T) {
+	orgID := base.ID()
+	repo := &MockRepository{
+		Organizations: []client.Organization{
+			{
+				OrganizationID:  orgID,
+				Name:            "my organization",
+				TenantID:        base.ID(),
+				PrimaryCustomer: base.ID(),
+			},
+		},
+	}
+	access := &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			{Kind: memberships.Organization, ResourceID: orgID, UserID: "userID", Role: memberships.Viewer},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewRouter(log.NewNopLogger(), repo, access).RegisterRoutes(r)
+	client := testclient.New(t, r)
+
+	org, resp, err := client.OrganizationsApi.GetOrganization(context.TODO(), orgID, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if org.OrganizationID != orgID || org.Name != "my organization" {
+		t.Errorf("unexpected organization: %#v", org)
+	}
+
+	// another user
+	_, resp, err = client.OrganizationsApi.GetOrganization(context.TODO(), orgID, base.ID(), nil)
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404: %v", err)
+	}
+}
+
+func TestRouter__DeleteOrganization(t *testing.T) {
+	orgID := base.ID()
+	repo := &MockRepository{
+		Organizations: []client.Organization{
+			{OrganizationID: orgID, Name: "my organization", TenantID: base.ID()},
+		},
+	}
+	access := &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			owner(memberships.Organization, orgID),
+			{Kind: memberships.Organization, ResourceID: orgID, UserID: "ops", Role: memberships.Admin},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewRouter(log.NewNopLogger(), repo, access).RegisterRoutes(r)
+	client := testclient.New(t, r)
+
+	// only owners can delete
+	resp, err := client.OrganizationsApi.DeleteOrganization(context.TODO(), orgID, "ops", nil)
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403: %v", err)
+	}
+
+	resp, err = client.OrganizationsApi.DeleteOrganization(context.TODO(), orgID, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if n := len(repo.Organizations); n != 0 {
+		t.Errorf("got %d organizations", n)
+	}
+
+	// already deleted
+	resp, _ = client.OrganizationsApi.DeleteOrganization(context.TODO(), orgID, "userID", nil)
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404: %#v", resp)
+	}
+}
+
+func TestRouter__UpdateOrganizationTenant(t *testing.T) {
+	orgID, tenantID, otherTenantID := base.ID(), base.ID(), base.ID()
+	repo := &MockRepository{
+		Organizations: []client.Organization{
+			{OrganizationID: orgID, Name: "my organization", TenantID: base.ID()},
+		},
+	}
+	access := &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			owner(memberships.Organization, orgID),
+			owner(memberships.Tenant, tenantID),
+			{Kind: memberships.Tenant, ResourceID: otherTenantID, UserID: "userID", Role: memberships.Operator},
+		},
+	}
+
+	r := mux.NewRouter()
+	NewRouter(log.NewNopLogger(), repo, access).RegisterRoutes(r)
+	c := testclient.New(t, r)
+
+	// moving to a Tenant the user isn't a member of fails
+	_, resp, err := c.OrganizationsApi.UpdateOrganizationTenant(context.TODO(), orgID, "userID", client.UpdateOrganizationTenant{
+		TenantID: base.ID(),
+	}, nil)
+	if err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected error: %v", err)
+	}
+
+	// operators can't manage the destination Tenant
+	_, resp, err = c.OrganizationsApi.UpdateOrganizationTenant(context.TODO(), orgID, "userID", client.UpdateOrganizationTenant{
+		TenantID: otherTenantID,
+	}, nil)
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403: %v", err)
+	}
+
+	org, resp, err := c.OrganizationsApi.UpdateOrganizationTenant(context.TODO(), orgID, "userID", client.UpdateOrganizationTenant{
+		TenantID: tenantID,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if org.TenantID != tenantID {
+		t.Errorf("unexpected organization: %#v", org)
+	}
+	if repo.Organizations[0].TenantID != tenantID {
+		t.Errorf("organization wasn't moved: %#v", repo.Organizations[0])
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+func companyIdentificationsHandler(logger log.Logger, repo tenants.Repository, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			getCompanyIdentifications(logger, repo)(w, r)
+		case http.MethodPost:
+			updateCompanyIdentification(logger, repo, cfg)(w, r)
+		default:
+			route.NewResponder(logger, w, r).Problem(fmt.Errorf("invalid method %s", r.Method))
+		}
+	}
+}
+
+func getCompanyIdentifications(logger log.Logger, repo tenants.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		tenant := readTenant(responder, w, r, repo)
+		if tenant == nil {
+			return
+		}
+		records, err := repo.ListCompanyIdentifications(tenant.TenantID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		out := make([]admin.CompanyIdentification, len(records))
+		for i := range records {
+			out[i] = toAdminCompanyIdentification(records[i])
+		}
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(out)
+		})
+	}
+}
+
+// updateCompanyIdentification rotates a Tenant's CompanyIdentification to a newly generated value,
+// or reassigns it to one supplied by the admin.
+func updateCompanyIdentification(logger log.Logger, repo tenants.Repository, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		var req admin.UpdateCompanyIdentification
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		tenant := readTenant(responder, w, r, repo)
+		if tenant == nil {
+			return
+		}
+		ciReq := tenants.CompanyIdentificationRequest{
+			CompanyIdentification: req.CompanyIdentification,
+			EIN:                   req.EIN,
+		}
+		companyIdentification, err := tenants.AssignCompanyIdentification(cfg.Tenants.CompanyIdentification, ciReq, func(companyID string) error {
+			return repo.UpdateCompanyIdentification(tenant.TenantID, companyID)
+		})
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Log("tenants", fmt.Sprintf("assigned CompanyIdentification=%s to tenant=%s", companyIdentification, tenant.TenantID))
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(admin.CompanyIdentification{
+				CompanyIdentification: companyIdentification,
+				Created:               time.Now(),
+			})
+		})
+	}
+}
+
+func toAdminCompanyIdentification(record tenants.CompanyIdentificationRecord) admin.CompanyIdentification {
+	out := admin.CompanyIdentification{
+		CompanyIdentification: record.CompanyIdentification,
+		Created:               record.Created,
+	}
+	if record.Retired != nil {
+		out.Retired = *record.Retired
+	}
+	return out
+}
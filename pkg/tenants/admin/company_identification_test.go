@@ -0,0 +1,109 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestRoutes__CreateWithEIN(t *testing.T) {
+	repo := &tenants.MockRepository{}
+	cfg := &config.Config{
+		Tenants: config.Tenants{
+			CompanyIdentification: config.CompanyIdentification{Strategy: "ein"},
+		},
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, cfg)
+
+	req := admin.CreateTenant{
+		Name:            "My Company",
+		PrimaryCustomer: base.ID(),
+	}
+	_, resp, err := c.TenantsApi.CreateTenant(context.Background(), "userID", req, nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Fatal("expected error without an EIN")
+	}
+
+	req.EIN = "123456789"
+	_, resp, err = c.TenantsApi.CreateTenant(context.Background(), "userID", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestRoutes__CompanyIdentifications(t *testing.T) {
+	tenantID := base.ID()
+	repo := &tenants.MockRepository{
+		Tenants: []client.Tenant{
+			{TenantID: tenantID, Name: "My Company"},
+		},
+		CompanyIdentification: "MOOV000001",
+		CompanyIdentifications: []tenants.CompanyIdentificationRecord{
+			{CompanyIdentification: "MOOV000001"},
+		},
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, &config.Config{})
+
+	// rotate to a generated value
+	record, resp, err := c.TenantsApi.UpdateCompanyIdentification(context.Background(), tenantID, "userID", admin.UpdateCompanyIdentification{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if record.CompanyIdentification == "MOOV000001" || repo.CompanyIdentification != record.CompanyIdentification {
+		t.Errorf("unexpected CompanyIdentification: %#v", record)
+	}
+
+	// reassign to a supplied value
+	req := admin.UpdateCompanyIdentification{CompanyIdentification: "ACME000001"}
+	record, resp, err = c.TenantsApi.UpdateCompanyIdentification(context.Background(), tenantID, "userID", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if record.CompanyIdentification != "ACME000001" {
+		t.Errorf("unexpected CompanyIdentification: %#v", record)
+	}
+
+	// values currently assigned can't be assigned again
+	req.CompanyIdentification = "ACME000001"
+	_, resp, err = c.TenantsApi.UpdateCompanyIdentification(context.Background(), tenantID, "userID", req, nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Error("expected error")
+	}
+
+	records, resp, err := c.TenantsApi.GetCompanyIdentifications(context.Background(), tenantID, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(records) != 3 || records[0].CompanyIdentification != "ACME000001" || !records[0].Retired.IsZero() {
+		t.Errorf("unexpected records: %#v", records)
+	}
+	if records[2].Retired.IsZero() {
+		t.Errorf("expected retired: %#v", records[2])
+	}
+}
@@ -13,13 +13,14 @@ import (
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/admin"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/tenants"
 	"github.com/moov-io/paygate/x/route"
 
 	"github.com/go-kit/kit/log"
 )
 
-func createTenant(logger log.Logger, repo tenants.Repository) http.HandlerFunc {
+func createTenant(logger log.Logger, repo tenants.Repository, cfg *config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(logger, w, r)
 		if r.Method != http.MethodPost {
@@ -37,16 +38,29 @@ func createTenant(logger log.Logger, repo tenants.Repository) http.HandlerFunc {
 			TenantID:        base.ID(),
 			Name:            req.Name,
 			PrimaryCustomer: req.PrimaryCustomer,
+			ODFI:            req.ODFI,
+			Status:          string(tenants.Active),
 		}
 		if err := validateTenant(tenant); err != nil {
 			responder.Problem(err)
 			return
 		}
-		companyIdentification := tenants.CompanyIdentification("MOOV") // TODO(adam): read from config
-		if err := repo.Create(responder.XUserID, companyIdentification, tenant); err != nil {
+		if _, err := cfg.FindODFI(tenant.ODFI); err != nil {
 			responder.Problem(err)
 			return
 		}
+		ciReq := tenants.CompanyIdentificationRequest{
+			CompanyIdentification: req.CompanyIdentification,
+			EIN:                   req.EIN,
+		}
+		companyIdentification, err := tenants.AssignCompanyIdentification(cfg.Tenants.CompanyIdentification, ciReq, func(companyID string) error {
+			return repo.Create(responder.XUserID, companyID, tenant)
+		})
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		responder.Log("tenants", fmt.Sprintf("created tenant=%s with CompanyIdentification=%s", tenant.TenantID, companyIdentification))
 
 		responder.Respond(func(w http.ResponseWriter) {
 			w.WriteHeader(http.StatusOK)
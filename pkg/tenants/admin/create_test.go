@@ -12,6 +12,7 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/tenants"
 	"github.com/moov-io/paygate/pkg/testclient"
 )
@@ -20,7 +21,7 @@ func TestRoutes__Create(t *testing.T) {
 	repo := &tenants.MockRepository{}
 
 	svc, c := testclient.Admin(t)
-	RegisterRoutes(log.NewNopLogger(), svc, repo)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, &config.Config{})
 
 	req := admin.CreateTenant{
 		Name:            "My Company",
@@ -47,7 +48,7 @@ func TestRoutes__CreateErr(t *testing.T) {
 	}
 
 	svc, c := testclient.Admin(t)
-	RegisterRoutes(log.NewNopLogger(), svc, repo)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, &config.Config{})
 
 	req := admin.CreateTenant{
 		Name:            "My Company",
@@ -75,3 +76,42 @@ func TestRoutes__CreateErr(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+func TestRoutes__CreateODFI(t *testing.T) {
+	repo := &tenants.MockRepository{}
+
+	cfg := &config.Config{
+		ODFIs: []config.ODFI{{Name: "bank-a"}, {Name: "bank-b"}},
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, cfg)
+
+	req := admin.CreateTenant{
+		Name:            "My Company",
+		PrimaryCustomer: base.ID(),
+		ODFI:            "bank-b",
+	}
+
+	userID := base.ID()
+	tenant, resp, err := c.TenantsApi.CreateTenant(context.Background(), userID, req, nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant.ODFI != "bank-b" {
+		t.Errorf("tenant.ODFI=%q", tenant.ODFI)
+	}
+
+	// unknown ODFI
+	req.ODFI = "bank-c"
+	_, resp, err = c.TenantsApi.CreateTenant(context.Background(), userID, req, nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
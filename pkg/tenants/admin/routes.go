@@ -6,12 +6,18 @@ package admin
 
 import (
 	"github.com/moov-io/base/admin"
+	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/tenants"
 
 	"github.com/go-kit/kit/log"
 )
 
 // RegisterRoutes will add HTTP handlers for paygate's admin HTTP server
-func RegisterRoutes(logger log.Logger, svc *admin.Server, repo tenants.Repository) {
-	svc.AddHandler("/tenants", createTenant(logger, repo))
+func RegisterRoutes(logger log.Logger, svc *admin.Server, repo tenants.Repository, cfg *config.Config) {
+	svc.AddHandler("/tenants", createTenant(logger, repo, cfg))
+	svc.AddHandler("/tenants/{tenantId}", tenantHandler(logger, repo))
+	svc.AddHandler("/tenants/{tenantId}/suspend", updateStatus(logger, repo, tenants.Suspended))
+	svc.AddHandler("/tenants/{tenantId}/reactivate", updateStatus(logger, repo, tenants.Active))
+	svc.AddHandler("/tenants/{tenantId}/settings", settingsHandler(logger, repo, cfg))
+	svc.AddHandler("/tenants/{tenantId}/company-identifications", companyIdentificationsHandler(logger, repo, cfg))
 }
@@ -0,0 +1,112 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+func settingsHandler(logger log.Logger, repo tenants.Repository, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			getSettings(logger, repo)(w, r)
+		case http.MethodPut:
+			updateSettings(logger, repo, cfg)(w, r)
+		default:
+			route.NewResponder(logger, w, r).Problem(fmt.Errorf("invalid method %s", r.Method))
+		}
+	}
+}
+
+func getSettings(logger log.Logger, repo tenants.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		settings, err := repo.GetSettings(getTenantID(r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if settings == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(toAdminSettings(*settings))
+		})
+	}
+}
+
+func updateSettings(logger log.Logger, repo tenants.Repository, cfg *config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		var req admin.TenantSettings
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(err)
+			return
+		}
+		settings := fromAdminSettings(req)
+		if err := settings.Validate(); err != nil {
+			responder.Problem(err)
+			return
+		}
+		if _, err := cfg.FindODFI(settings.ODFI); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		tenant := readTenant(responder, w, r, repo)
+		if tenant == nil {
+			return
+		}
+		if err := repo.UpdateSettings(tenant.TenantID, settings); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Log("tenants", fmt.Sprintf("updated settings for tenant=%s", tenant.TenantID))
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(toAdminSettings(settings))
+		})
+	}
+}
+
+func toAdminSettings(settings tenants.Settings) admin.TenantSettings {
+	return admin.TenantSettings{
+		CompanyName:    settings.CompanyName,
+		DefaultSECCode: settings.DefaultSECCode,
+		ODFI:           settings.ODFI,
+		MaxEntryAmount: settings.Limits.MaxEntryAmount,
+		MaxFileAmount:  settings.Limits.MaxFileAmount,
+		MaxFileEntries: int32(settings.Limits.MaxFileEntries),
+	}
+}
+
+func fromAdminSettings(req admin.TenantSettings) tenants.Settings {
+	return tenants.Settings{
+		CompanyName:    req.CompanyName,
+		DefaultSECCode: req.DefaultSECCode,
+		ODFI:           req.ODFI,
+		Limits: config.Limits{
+			MaxEntryAmount: req.MaxEntryAmount,
+			MaxFileAmount:  req.MaxFileAmount,
+			MaxFileEntries: int(req.MaxFileEntries),
+		},
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestRoutes__Settings(t *testing.T) {
+	tenantID := base.ID()
+	repo := &tenants.MockRepository{
+		Tenants: []client.Tenant{
+			{TenantID: tenantID, Name: "My Company"},
+		},
+		Settings: &tenants.Settings{ODFI: "bank-a"},
+	}
+	cfg := &config.Config{
+		ODFIs: []config.ODFI{{Name: "bank-a"}, {Name: "bank-b"}},
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, cfg)
+
+	settings, resp, err := c.TenantsApi.GetTenantSettings(context.Background(), tenantID, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if settings.ODFI != "bank-a" {
+		t.Errorf("unexpected settings: %#v", settings)
+	}
+
+	req := admin.TenantSettings{
+		CompanyName:    "Acme Corp",
+		DefaultSECCode: "CCD",
+		ODFI:           "bank-b",
+		MaxEntryAmount: "USD 100.00",
+		MaxFileEntries: 25,
+	}
+	settings, resp, err = c.TenantsApi.UpdateTenantSettings(context.Background(), tenantID, "userID", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if settings != req {
+		t.Errorf("unexpected settings: %#v", settings)
+	}
+	if repo.Settings == nil || repo.Settings.Limits.MaxFileEntries != 25 || repo.Settings.ODFI != "bank-b" {
+		t.Errorf("unexpected saved settings: %#v", repo.Settings)
+	}
+}
+
+func TestRoutes__SettingsErr(t *testing.T) {
+	tenantID := base.ID()
+	repo := &tenants.MockRepository{
+		Tenants: []client.Tenant{
+			{TenantID: tenantID, Name: "My Company"},
+		},
+	}
+	cfg := &config.Config{
+		ODFIs: []config.ODFI{{Name: "bank-a"}},
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, cfg)
+
+	// invalid settings
+	for _, req := range []admin.TenantSettings{
+		{DefaultSECCode: "WEB"},
+		{ODFI: "bank-c"},
+		{MaxEntryAmount: "invalid"},
+	} {
+		_, resp, err := c.TenantsApi.UpdateTenantSettings(context.Background(), tenantID, "userID", req, nil)
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+		if err == nil {
+			t.Errorf("expected error with %#v", req)
+		}
+	}
+
+	// missing Tenant
+	_, resp, err := c.TenantsApi.GetTenantSettings(context.Background(), tenantID, "userID", nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Error("expected error")
+	}
+
+	repo.Err = errors.New("bad error")
+	_, resp, err = c.TenantsApi.GetTenantSettings(context.Background(), tenantID, "userID", nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Error("expected error")
+	}
+}
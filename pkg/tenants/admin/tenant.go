@@ -0,0 +1,117 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+func getTenantID(r *http.Request) string {
+	return route.ReadPathID("tenantId", r)
+}
+
+// readTenant writes a 404 and returns nil when the Tenant doesn't exist
+func readTenant(responder *route.Responder, w http.ResponseWriter, r *http.Request, repo tenants.Repository) *client.Tenant {
+	tenantID := getTenantID(r)
+	if tenantID == "" {
+		responder.Problem(errors.New("missing tenantId"))
+		return nil
+	}
+	tenant, err := repo.GetTenant(tenantID)
+	if err != nil {
+		responder.Problem(err)
+		return nil
+	}
+	if tenant == nil {
+		http.NotFound(w, r)
+		return nil
+	}
+	return tenant
+}
+
+func tenantHandler(logger log.Logger, repo tenants.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			getTenant(logger, repo)(w, r)
+		case http.MethodDelete:
+			deleteTenant(logger, repo)(w, r)
+		default:
+			route.NewResponder(logger, w, r).Problem(fmt.Errorf("invalid method %s", r.Method))
+		}
+	}
+}
+
+func getTenant(logger log.Logger, repo tenants.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		tenant := readTenant(responder, w, r, repo)
+		if tenant == nil {
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(tenant)
+		})
+	}
+}
+
+func deleteTenant(logger log.Logger, repo tenants.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		tenant := readTenant(responder, w, r, repo)
+		if tenant == nil {
+			return
+		}
+		if err := repo.Delete(tenant.TenantID); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Log("tenants", fmt.Sprintf("deleted tenant=%s", tenant.TenantID))
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+}
+
+// updateStatus suspends or reactivates a Tenant
+func updateStatus(logger log.Logger, repo tenants.Repository, status tenants.Status) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		tenant := readTenant(responder, w, r, repo)
+		if tenant == nil {
+			return
+		}
+		if err := repo.UpdateStatus(tenant.TenantID, status); err != nil {
+			responder.Problem(err)
+			return
+		}
+		tenant.Status = string(status)
+
+		responder.Log("tenants", fmt.Sprintf("tenant=%s is now %s", tenant.TenantID, status))
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(tenant)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestRoutes__GetTenant(t *testing.T) {
+	tenantID := base.ID()
+	repo := &tenants.MockRepository{
+		Tenants: []client.Tenant{
+			{TenantID: tenantID, Name: "My Company", Status: string(tenants.Active)},
+		},
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, &config.Config{})
+
+	tenant, resp, err := c.TenantsApi.GetTenant(context.Background(), tenantID, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if tenant.TenantID != tenantID || tenant.Status != "active" {
+		t.Errorf("unexpected tenant: %#v", tenant)
+	}
+
+	_, resp, err = c.TenantsApi.GetTenant(context.Background(), base.ID(), "userID", nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404: %v", err)
+	}
+}
+
+func TestRoutes__SuspendAndReactivate(t *testing.T) {
+	tenantID := base.ID()
+	repo := &tenants.MockRepository{
+		Tenants: []client.Tenant{
+			{TenantID: tenantID, Name: "My Company", Status: string(tenants.Active)},
+		},
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, &config.Config{})
+
+	tenant, resp, err := c.TenantsApi.SuspendTenant(context.Background(), tenantID, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if tenant.Status != "suspended" || repo.Tenants[0].Status != "suspended" {
+		t.Errorf("unexpected tenant: %#v", tenant)
+	}
+
+	tenant, resp, err = c.TenantsApi.ReactivateTenant(context.Background(), tenantID, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if tenant.Status != "active" || repo.Tenants[0].Status != "active" {
+		t.Errorf("unexpected tenant: %#v", tenant)
+	}
+
+	_, resp, err = c.TenantsApi.SuspendTenant(context.Background(), base.ID(), "userID", nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404: %v", err)
+	}
+}
+
+func TestRoutes__DeleteTenant(t *testing.T) {
+	tenantID := base.ID()
+	repo := &tenants.MockRepository{
+		Tenants: []client.Tenant{
+			{TenantID: tenantID, Name: "My Company"},
+		},
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, &config.Config{})
+
+	resp, err := c.TenantsApi.DeleteTenant(context.Background(), tenantID, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(repo.Tenants) != 0 {
+		t.Errorf("unexpected tenants: %#v", repo.Tenants)
+	}
+
+	resp, err = c.TenantsApi.DeleteTenant(context.Background(), tenantID, "userID", nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404: %v", err)
+	}
+}
@@ -7,8 +7,13 @@ package tenants
 import (
 	"crypto/rand"
 	"encoding/base32"
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
 )
 
 var (
@@ -17,6 +22,17 @@ var (
 
 const (
 	maxLength = 10 // CompanyIdentification is a 10-digit BatchHeader field
+
+	// maxAttempts is how many random values are tried before giving up
+	maxAttempts = 5
+)
+
+var (
+	// ErrCompanyIdentificationExists is returned when a CompanyIdentification is currently assigned to a Tenant
+	ErrCompanyIdentificationExists = errors.New("CompanyIdentification is already assigned")
+
+	companyIdentificationRegex = regexp.MustCompile(`^[A-Z0-9]{1,10}$`)
+	einRegex                   = regexp.MustCompile(`^[0-9]{9}$`)
 )
 
 // CompanyIdentification returns a string formatted for the CompanyIdentification field in an ACH
@@ -36,3 +52,59 @@ func CompanyIdentification(prefix string) string {
 
 	return fmt.Sprintf("%s%s", prefix, strings.ToUpper(encoder.EncodeToString(bs))[:length])
 }
+
+// CompanyIdentificationRecord is a CompanyIdentification assigned to a Tenant. Retired is set once
+// it's replaced by another value.
+type CompanyIdentificationRecord struct {
+	CompanyIdentification string
+	Created               time.Time
+	Retired               *time.Time
+}
+
+// CompanyIdentificationRequest holds the values an admin supplied when creating a Tenant
+// or assigning it a new CompanyIdentification.
+type CompanyIdentificationRequest struct {
+	// CompanyIdentification is used as-is when set, regardless of the configured strategy
+	CompanyIdentification string
+
+	// EIN is the Tenant's Employer Identification Number, required by the "ein" strategy
+	EIN string
+}
+
+// AssignCompanyIdentification picks a CompanyIdentification according to cfg and passes it to save.
+// Random values are retried when save returns ErrCompanyIdentificationExists.
+func AssignCompanyIdentification(cfg config.CompanyIdentification, req CompanyIdentificationRequest, save func(companyID string) error) (string, error) {
+	if req.CompanyIdentification != "" {
+		companyID := strings.ToUpper(strings.TrimSpace(req.CompanyIdentification))
+		if !companyIdentificationRegex.MatchString(companyID) {
+			return "", fmt.Errorf("invalid CompanyIdentification %q", req.CompanyIdentification)
+		}
+		return companyID, save(companyID)
+	}
+
+	switch cfg.AssignmentStrategy() {
+	case config.CompanyIdentificationEIN:
+		ein := strings.Replace(strings.TrimSpace(req.EIN), "-", "", -1)
+		if !einRegex.MatchString(ein) {
+			return "", errors.New("a 9-digit EIN is required")
+		}
+		companyID := "1" + ein
+		return companyID, save(companyID)
+
+	case config.CompanyIdentificationSupplied:
+		return "", errors.New("missing CompanyIdentification")
+	}
+
+	for i := 0; i < maxAttempts; i++ {
+		companyID := CompanyIdentification(cfg.RandomPrefix())
+		if companyID == "" {
+			return "", errors.New("unable to generate CompanyIdentification")
+		}
+		err := save(companyID)
+		if err == ErrCompanyIdentificationExists {
+			continue
+		}
+		return companyID, err
+	}
+	return "", fmt.Errorf("no unique CompanyIdentification found after %d attempts", maxAttempts)
+}
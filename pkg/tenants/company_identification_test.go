@@ -7,6 +7,8 @@ package tenants
 import (
 	"strings"
 	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
 )
 
 func TestCompanyIdentification(t *testing.T) {
@@ -18,3 +20,90 @@ func TestCompanyIdentification(t *testing.T) {
 		t.Errorf("unexpected %q", id)
 	}
 }
+
+func TestAssignCompanyIdentification(t *testing.T) {
+	var saved []string
+	save := func(companyID string) error {
+		saved = append(saved, companyID)
+		return nil
+	}
+
+	// random
+	companyID, err := AssignCompanyIdentification(config.CompanyIdentification{Prefix: "ACME"}, CompanyIdentificationRequest{}, save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(companyID) != 10 || !strings.HasPrefix(companyID, "ACME") {
+		t.Errorf("unexpected %q", companyID)
+	}
+
+	// EIN
+	cfg := config.CompanyIdentification{Strategy: "ein"}
+	companyID, err = AssignCompanyIdentification(cfg, CompanyIdentificationRequest{EIN: "12-3456789"}, save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if companyID != "1123456789" {
+		t.Errorf("unexpected %q", companyID)
+	}
+	if _, err := AssignCompanyIdentification(cfg, CompanyIdentificationRequest{EIN: "1234"}, save); err == nil {
+		t.Error("expected error")
+	}
+
+	// supplied
+	cfg = config.CompanyIdentification{Strategy: "supplied"}
+	if _, err := AssignCompanyIdentification(cfg, CompanyIdentificationRequest{}, save); err == nil {
+		t.Error("expected error")
+	}
+	companyID, err = AssignCompanyIdentification(cfg, CompanyIdentificationRequest{CompanyIdentification: "acme123"}, save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if companyID != "ACME123" {
+		t.Errorf("unexpected %q", companyID)
+	}
+	if _, err := AssignCompanyIdentification(cfg, CompanyIdentificationRequest{CompanyIdentification: "ACME-12345678"}, save); err == nil {
+		t.Error("expected error")
+	}
+
+	if len(saved) != 3 {
+		t.Errorf("saved %v", saved)
+	}
+}
+
+func TestAssignCompanyIdentification__Collisions(t *testing.T) {
+	attempts := 0
+	save := func(companyID string) error {
+		attempts++
+		if attempts < 3 {
+			return ErrCompanyIdentificationExists
+		}
+		return nil
+	}
+	companyID, err := AssignCompanyIdentification(config.CompanyIdentification{}, CompanyIdentificationRequest{}, save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 || !strings.HasPrefix(companyID, "MOOV") {
+		t.Errorf("attempts=%d companyID=%q", attempts, companyID)
+	}
+
+	// give up after several collisions
+	save = func(companyID string) error {
+		return ErrCompanyIdentificationExists
+	}
+	if _, err := AssignCompanyIdentification(config.CompanyIdentification{}, CompanyIdentificationRequest{}, save); err == nil {
+		t.Error("expected error")
+	}
+
+	// supplied values aren't retried
+	attempts = 0
+	save = func(companyID string) error {
+		attempts++
+		return ErrCompanyIdentificationExists
+	}
+	req := CompanyIdentificationRequest{CompanyIdentification: "ACME"}
+	if _, err := AssignCompanyIdentification(config.CompanyIdentification{}, req, save); err != ErrCompanyIdentificationExists || attempts != 1 {
+		t.Errorf("attempts=%d error=%v", attempts, err)
+	}
+}
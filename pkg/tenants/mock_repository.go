@@ -5,12 +5,23 @@
 package tenants
 
 import (
+	"time"
+
 	"github.com/moov-io/paygate/pkg/client"
 )
 
 type MockRepository struct {
 	Tenants               []client.Tenant
 	CompanyIdentification string
+	ODFI                  string
+	Settings              *Settings
+
+	// OrganizationTenants and UserTenants map an Organization or user to their TenantID
+	OrganizationTenants map[string]string
+	UserTenants         map[string]string
+
+	// CompanyIdentifications holds every value assigned, newest first
+	CompanyIdentifications []CompanyIdentificationRecord
 
 	Err error
 }
@@ -33,6 +44,109 @@ func (r *MockRepository) GetCompanyIdentification(tenantID string) (string, erro
 	return r.CompanyIdentification, nil
 }
 
+func (r *MockRepository) GetODFI(tenantID string) (string, error) {
+	if r.Err != nil {
+		return "", r.Err
+	}
+	return r.ODFI, nil
+}
+
+func (r *MockRepository) GetOrganizationTenant(orgID string) (string, error) {
+	if r.Err != nil {
+		return "", r.Err
+	}
+	return r.OrganizationTenants[orgID], nil
+}
+
+func (r *MockRepository) GetUserTenant(userID string) (string, error) {
+	if r.Err != nil {
+		return "", r.Err
+	}
+	return r.UserTenants[userID], nil
+}
+
 func (r *MockRepository) UpdateTenant(tenantID string, req client.UpdateTenant) error {
 	return r.Err
 }
+
+func (r *MockRepository) GetTenant(tenantID string) (*client.Tenant, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Tenants {
+		if r.Tenants[i].TenantID == tenantID {
+			return &r.Tenants[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) UpdateStatus(tenantID string, status Status) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Tenants {
+		if r.Tenants[i].TenantID == tenantID {
+			r.Tenants[i].Status = string(status)
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) Delete(tenantID string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Tenants {
+		if r.Tenants[i].TenantID == tenantID {
+			r.Tenants = append(r.Tenants[:i], r.Tenants[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) GetSettings(tenantID string) (*Settings, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Settings, nil
+}
+
+func (r *MockRepository) UpdateSettings(tenantID string, settings Settings) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Settings = &settings
+	return nil
+}
+
+func (r *MockRepository) UpdateCompanyIdentification(tenantID string, companyIdentification string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.CompanyIdentifications {
+		if r.CompanyIdentifications[i].CompanyIdentification == companyIdentification && r.CompanyIdentifications[i].Retired == nil {
+			return ErrCompanyIdentificationExists
+		}
+	}
+	now := time.Now()
+	for i := range r.CompanyIdentifications {
+		if r.CompanyIdentifications[i].Retired == nil {
+			r.CompanyIdentifications[i].Retired = &now
+		}
+	}
+	r.CompanyIdentification = companyIdentification
+	r.CompanyIdentifications = append([]CompanyIdentificationRecord{{
+		CompanyIdentification: companyIdentification,
+		Created:               now,
+	}}, r.CompanyIdentifications...)
+	return nil
+}
+
+func (r *MockRepository) ListCompanyIdentifications(tenantID string) ([]CompanyIdentificationRecord, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.CompanyIdentifications, nil
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
 )
 
 type Repository interface {
@@ -17,8 +18,30 @@ type Repository interface {
 	List(userID string) ([]client.Tenant, error)
 
 	GetCompanyIdentification(tenantID string) (string, error)
+	GetODFI(tenantID string) (string, error)
+
+	// GetOrganizationTenant returns the Tenant an Organization belongs to or an empty string
+	GetOrganizationTenant(orgID string) (string, error)
+
+	// GetUserTenant returns the Tenant a user created, otherwise the first Tenant they were
+	// made a member of. An empty string is returned when the user has neither.
+	GetUserTenant(userID string) (string, error)
 
 	UpdateTenant(tenantID string, req client.UpdateTenant) error
+
+	// GetTenant returns nil when the Tenant doesn't exist or has been deleted
+	GetTenant(tenantID string) (*client.Tenant, error)
+	UpdateStatus(tenantID string, status Status) error
+	Delete(tenantID string) error
+
+	// GetSettings returns nil when the Tenant doesn't exist or has been deleted
+	GetSettings(tenantID string) (*Settings, error)
+	UpdateSettings(tenantID string, settings Settings) error
+
+	// UpdateCompanyIdentification assigns a new CompanyIdentification to the Tenant and retires
+	// its current value. ErrCompanyIdentificationExists is returned for values currently assigned to a Tenant.
+	UpdateCompanyIdentification(tenantID string, companyIdentification string) error
+	ListCompanyIdentifications(tenantID string) ([]CompanyIdentificationRecord, error)
 }
 
 func NewRepo(db *sql.DB) Repository {
@@ -37,26 +60,62 @@ func (r *sqlRepo) Close() error {
 }
 
 func (r *sqlRepo) Create(userID string, companyIdentification string, tenant client.Tenant) error {
-	query := `insert into tenants (tenant_id, user_id, name, primary_customer, company_identification, created_at) values (?, ?, ?, ?, ?, ?);`
-	stmt, err := r.db.Prepare(query)
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `insert into tenants (tenant_id, user_id, name, primary_customer, company_identification, odfi, created_at) values (?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = stmt.Exec(tenant.TenantID, userID, tenant.Name, tenant.PrimaryCustomer, companyIdentification, tenant.ODFI, now)
+	stmt.Close()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := insertCompanyIdentification(tx, tenant.TenantID, companyIdentification, now); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertCompanyIdentification records a Tenant's CompanyIdentification. The unique index over values
+// which haven't been retired keeps one from being assigned to two Tenants at once.
+func insertCompanyIdentification(tx *sql.Tx, tenantID, companyIdentification string, when time.Time) error {
+	query := `insert into tenant_company_identifications (tenant_id, company_identification, created_at) values (?, ?, ?);`
+	stmt, err := tx.Prepare(query)
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	_, err = stmt.Exec(tenant.TenantID, userID, tenant.Name, tenant.PrimaryCustomer, companyIdentification, time.Now())
-	return err
+	if _, err := stmt.Exec(tenantID, companyIdentification, when); err != nil {
+		if database.UniqueViolation(err) {
+			return ErrCompanyIdentificationExists
+		}
+		return err
+	}
+	return nil
 }
 
 func (r *sqlRepo) List(userID string) ([]client.Tenant, error) {
-	query := `select tenant_id, name, primary_customer from tenants where user_id = ? and deleted_at is null;`
+	query := `select tenant_id, name, primary_customer, odfi, status from tenants
+where (user_id = ? or tenant_id in (select resource_id from memberships where kind = 'tenant' and user_id = ? and deleted_at is null))
+and deleted_at is null;`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(userID)
+	rows, err := stmt.Query(userID, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -64,8 +123,8 @@ func (r *sqlRepo) List(userID string) ([]client.Tenant, error) {
 
 	var out []client.Tenant
 	for rows.Next() {
-		var tenant client.Tenant
-		if err := rows.Scan(&tenant.TenantID, &tenant.Name, &tenant.PrimaryCustomer); err != nil {
+		tenant, err := scanTenant(rows)
+		if err != nil {
 			return nil, fmt.Errorf("list: tenantID=%s error=%v", tenant.TenantID, err)
 		}
 		out = append(out, tenant)
@@ -88,6 +147,59 @@ func (r *sqlRepo) GetCompanyIdentification(tenantID string) (string, error) {
 	return companyIdentification, nil
 }
 
+func (r *sqlRepo) GetODFI(tenantID string) (string, error) {
+	query := `select odfi from tenants where tenant_id = ? and deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	var odfi string
+	if err := stmt.QueryRow(tenantID).Scan(&odfi); err != nil {
+		return "", err
+	}
+	return odfi, nil
+}
+
+func (r *sqlRepo) GetOrganizationTenant(orgID string) (string, error) {
+	query := `select ts.tenant_id from tenants_organizations as ts
+inner join tenants as t on ts.tenant_id = t.tenant_id
+where ts.organization_id = ? and ts.deleted_at is null and t.deleted_at is null limit 1;`
+	return r.queryTenantID(query, orgID)
+}
+
+func (r *sqlRepo) GetUserTenant(userID string) (string, error) {
+	query := `select tenant_id from tenants where user_id = ? and deleted_at is null order by created_at limit 1;`
+	tenantID, err := r.queryTenantID(query, userID)
+	if err != nil || tenantID != "" {
+		return tenantID, err
+	}
+
+	query = `select m.resource_id from memberships as m
+inner join tenants as t on m.resource_id = t.tenant_id
+where m.kind = 'tenant' and m.user_id = ? and m.deleted_at is null and t.deleted_at is null
+order by m.created_at limit 1;`
+	return r.queryTenantID(query, userID)
+}
+
+func (r *sqlRepo) queryTenantID(query string, arg string) (string, error) {
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	var tenantID string
+	if err := stmt.QueryRow(arg).Scan(&tenantID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	return tenantID, nil
+}
+
 func (r *sqlRepo) UpdateTenant(tenantID string, req client.UpdateTenant) error {
 	query := `update tenants set name = ? where tenant_id = ? and deleted_at is null;`
 	stmt, err := r.db.Prepare(query)
@@ -99,3 +211,209 @@ func (r *sqlRepo) UpdateTenant(tenantID string, req client.UpdateTenant) error {
 	_, err = stmt.Exec(req.Name, tenantID)
 	return err
 }
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTenant(row scanner) (client.Tenant, error) {
+	var tenant client.Tenant
+	var status *string
+	if err := row.Scan(&tenant.TenantID, &tenant.Name, &tenant.PrimaryCustomer, &tenant.ODFI, &status); err != nil {
+		return tenant, err
+	}
+	// Tenants created before statuses were added are active
+	tenant.Status = string(Active)
+	if status != nil && *status != "" {
+		tenant.Status = *status
+	}
+	return tenant, nil
+}
+
+func (r *sqlRepo) GetTenant(tenantID string) (*client.Tenant, error) {
+	query := `select tenant_id, name, primary_customer, odfi, status from tenants where tenant_id = ? and deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	tenant, err := scanTenant(stmt.QueryRow(tenantID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+func (r *sqlRepo) UpdateStatus(tenantID string, status Status) error {
+	query := `update tenants set status = ? where tenant_id = ? and deleted_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(status, tenantID)
+	return err
+}
+
+func (r *sqlRepo) Delete(tenantID string) error {
+	query := `update tenants set deleted_at = ? where tenant_id = ? and deleted_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(time.Now(), tenantID)
+	return err
+}
+
+func (r *sqlRepo) GetSettings(tenantID string) (*Settings, error) {
+	query := `select t.odfi, s.company_name, s.default_sec_code, s.max_entry_amount, s.max_file_amount, s.max_file_entries from tenants as t
+left outer join tenant_settings as s on t.tenant_id = s.tenant_id
+where t.tenant_id = ? and t.deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var odfi, companyName, secCode, maxEntry, maxFile *string
+	var maxEntries *int
+	if err := stmt.QueryRow(tenantID).Scan(&odfi, &companyName, &secCode, &maxEntry, &maxFile, &maxEntries); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var settings Settings
+	readString := func(dst *string, src *string) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	readString(&settings.ODFI, odfi)
+	readString(&settings.CompanyName, companyName)
+	readString(&settings.DefaultSECCode, secCode)
+	readString(&settings.Limits.MaxEntryAmount, maxEntry)
+	readString(&settings.Limits.MaxFileAmount, maxFile)
+	if maxEntries != nil {
+		settings.Limits.MaxFileEntries = *maxEntries
+	}
+	return &settings, nil
+}
+
+func (r *sqlRepo) UpdateSettings(tenantID string, settings Settings) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `update tenants set odfi = ? where tenant_id = ? and deleted_at is null;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = stmt.Exec(settings.ODFI, tenantID)
+	stmt.Close()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query = `replace into tenant_settings (tenant_id, company_name, default_sec_code, max_entry_amount, max_file_amount, max_file_entries, updated_at) values (?, ?, ?, ?, ?, ?, ?);`
+	stmt, err = tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = stmt.Exec(
+		tenantID, settings.CompanyName, settings.DefaultSECCode,
+		settings.Limits.MaxEntryAmount, settings.Limits.MaxFileAmount, settings.Limits.MaxFileEntries, time.Now(),
+	)
+	stmt.Close()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) UpdateCompanyIdentification(tenantID string, companyIdentification string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `update tenants set company_identification = ? where tenant_id = ? and deleted_at is null;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	res, err := stmt.Exec(companyIdentification, tenantID)
+	stmt.Close()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return fmt.Errorf("tenant %s not found", tenantID)
+	}
+
+	query = `update tenant_company_identifications set retired_at = ? where tenant_id = ? and retired_at is null;`
+	stmt, err = tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = stmt.Exec(now, tenantID)
+	stmt.Close()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := insertCompanyIdentification(tx, tenantID, companyIdentification, now); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) ListCompanyIdentifications(tenantID string) ([]CompanyIdentificationRecord, error) {
+	query := `select company_identification, created_at, retired_at from tenant_company_identifications where tenant_id = ? order by created_at desc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CompanyIdentificationRecord
+	for rows.Next() {
+		var record CompanyIdentificationRecord
+		var retired *time.Time
+		if err := rows.Scan(&record.CompanyIdentification, &record.Created, &retired); err != nil {
+			return nil, fmt.Errorf("list company identifications: tenantID=%s error=%v", tenantID, err)
+		}
+		if retired != nil && !retired.IsZero() {
+			record.Retired = retired
+		}
+		out = append(out, record)
+	}
+	return out, rows.Err()
+}
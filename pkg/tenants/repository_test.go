@@ -6,10 +6,13 @@ package tenants
 
 import (
 	"testing"
+	"time"
 
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/memberships"
 )
 
 func setupSQLiteDB(t *testing.T) *sqlRepo {
@@ -39,6 +42,7 @@ func writeTenant(t *testing.T, userID string, repo Repository) client.Tenant {
 		TenantID:        base.ID(),
 		Name:            "My Company",
 		PrimaryCustomer: base.ID(),
+		ODFI:            "bank-a",
 	}
 	if err := repo.Create(userID, "companyID", tenant); err != nil {
 		t.Fatal(err)
@@ -81,6 +85,106 @@ func TestRepository__List(t *testing.T) {
 	check(t, setupMySQLeDB(t))
 }
 
+func TestRepository__ListMembers(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		tenant := writeTenant(t, base.ID(), repo)
+
+		memberID := base.ID()
+		if tenants, err := repo.List(memberID); err != nil || len(tenants) != 0 {
+			t.Fatalf("unexpected Tenants=%#v error=%v", tenants, err)
+		}
+
+		err := memberships.NewRepo(repo.db).Save(memberships.Membership{
+			Kind:       memberships.Tenant,
+			ResourceID: tenant.TenantID,
+			UserID:     memberID,
+			Role:       memberships.Viewer,
+			Created:    time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tenants, err := repo.List(memberID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tenants) != 1 || tenants[0].TenantID != tenant.TenantID {
+			t.Errorf("unexpected Tenants %#v", tenants)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__GetUserTenant(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		if tenantID, err := repo.GetUserTenant(userID); err != nil || tenantID != "" {
+			t.Fatalf("unexpected tenantID=%q error=%v", tenantID, err)
+		}
+		tenant := writeTenant(t, userID, repo)
+		if tenantID, err := repo.GetUserTenant(userID); err != nil || tenantID != tenant.TenantID {
+			t.Errorf("unexpected tenantID=%q error=%v", tenantID, err)
+		}
+
+		// members are given the Tenant they belong to
+		memberID := base.ID()
+		err := memberships.NewRepo(repo.db).Save(memberships.Membership{
+			Kind:       memberships.Tenant,
+			ResourceID: tenant.TenantID,
+			UserID:     memberID,
+			Role:       memberships.Admin,
+			Created:    time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tenantID, err := repo.GetUserTenant(memberID); err != nil || tenantID != tenant.TenantID {
+			t.Errorf("unexpected tenantID=%q error=%v", tenantID, err)
+		}
+
+		if err := repo.Delete(tenant.TenantID); err != nil {
+			t.Fatal(err)
+		}
+		if tenantID, err := repo.GetUserTenant(memberID); err != nil || tenantID != "" {
+			t.Errorf("unexpected tenantID=%q error=%v", tenantID, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__GetOrganizationTenant(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		tenant := writeTenant(t, base.ID(), repo)
+
+		orgID := base.ID()
+		if tenantID, err := repo.GetOrganizationTenant(orgID); err != nil || tenantID != "" {
+			t.Fatalf("unexpected tenantID=%q error=%v", tenantID, err)
+		}
+
+		query := `insert into tenants_organizations (tenant_id, organization_id, created_at) values (?, ?, ?);`
+		if _, err := repo.db.Exec(query, tenant.TenantID, orgID, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if tenantID, err := repo.GetOrganizationTenant(orgID); err != nil || tenantID != tenant.TenantID {
+			t.Errorf("unexpected tenantID=%q error=%v", tenantID, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
 func TestRepository__GetCompanyIdentification(t *testing.T) {
 	t.Parallel()
 
@@ -101,6 +205,34 @@ func TestRepository__GetCompanyIdentification(t *testing.T) {
 	check(t, setupMySQLeDB(t))
 }
 
+func TestRepository__GetODFI(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		tenant := writeTenant(t, userID, repo)
+
+		odfi, err := repo.GetODFI(tenant.TenantID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if odfi != "bank-a" {
+			t.Errorf("unexpected odfi=%q", odfi)
+		}
+
+		tenants, err := repo.List(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tenants) != 1 || tenants[0].ODFI != "bank-a" {
+			t.Errorf("unexpected Tenants %#v", tenants)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
 func TestRepository__UpdateTenant(t *testing.T) {
 	t.Parallel()
 
@@ -131,3 +263,217 @@ func TestRepository__UpdateTenant(t *testing.T) {
 	check(t, setupSQLiteDB(t))
 	check(t, setupMySQLeDB(t))
 }
+
+func TestRepository__GetTenant(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		tenant := writeTenant(t, userID, repo)
+
+		found, err := repo.GetTenant(tenant.TenantID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.TenantID != tenant.TenantID {
+			t.Fatalf("unexpected tenant: %#v", found)
+		}
+		if found.Status != string(Active) {
+			t.Errorf("unexpected status: %q", found.Status)
+		}
+
+		// missing Tenant
+		found, err = repo.GetTenant(base.ID())
+		if err != nil || found != nil {
+			t.Errorf("unexpected tenant=%#v error=%v", found, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__UpdateStatus(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		tenant := writeTenant(t, userID, repo)
+
+		if err := repo.UpdateStatus(tenant.TenantID, Suspended); err != nil {
+			t.Fatal(err)
+		}
+		tenants, err := repo.List(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tenants) != 1 || tenants[0].Status != string(Suspended) {
+			t.Errorf("unexpected tenants: %#v", tenants)
+		}
+
+		if err := repo.UpdateStatus(tenant.TenantID, Active); err != nil {
+			t.Fatal(err)
+		}
+		found, err := repo.GetTenant(tenant.TenantID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status != string(Active) {
+			t.Errorf("unexpected status: %q", found.Status)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__Delete(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		tenant := writeTenant(t, userID, repo)
+
+		if err := repo.Delete(tenant.TenantID); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := repo.GetTenant(tenant.TenantID)
+		if err != nil || found != nil {
+			t.Errorf("unexpected tenant=%#v error=%v", found, err)
+		}
+		tenants, err := repo.List(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tenants) != 0 {
+			t.Errorf("unexpected tenants: %#v", tenants)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__Settings(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		tenant := writeTenant(t, userID, repo)
+
+		// Tenants without settings return their ODFI
+		settings, err := repo.GetSettings(tenant.TenantID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if settings == nil || settings.ODFI != "bank-a" || settings.CompanyName != "" {
+			t.Fatalf("unexpected settings: %#v", settings)
+		}
+
+		update := Settings{
+			CompanyName:    "Acme Corp",
+			DefaultSECCode: "CCD",
+			ODFI:           "bank-b",
+			Limits: config.Limits{
+				MaxEntryAmount: "USD 100.00",
+				MaxFileEntries: 10,
+			},
+		}
+		for i := 0; i < 2; i++ {
+			if err := repo.UpdateSettings(tenant.TenantID, update); err != nil {
+				t.Fatal(err)
+			}
+		}
+		settings, err = repo.GetSettings(tenant.TenantID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if settings == nil || *settings != update {
+			t.Errorf("unexpected settings: %#v", settings)
+		}
+		if odfi, err := repo.GetODFI(tenant.TenantID); err != nil || odfi != "bank-b" {
+			t.Errorf("odfi=%q error=%v", odfi, err)
+		}
+
+		// missing Tenant
+		settings, err = repo.GetSettings(base.ID())
+		if err != nil || settings != nil {
+			t.Errorf("unexpected settings=%#v error=%v", settings, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestRepository__CompanyIdentifications(t *testing.T) {
+	t.Parallel()
+
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		tenant := writeTenant(t, userID, repo)
+
+		// CompanyIdentifications are unique
+		other := client.Tenant{TenantID: base.ID(), Name: "Other Company", PrimaryCustomer: base.ID()}
+		if err := repo.Create(userID, "companyID", other); err != ErrCompanyIdentificationExists {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if found, err := repo.GetTenant(other.TenantID); err != nil || found != nil {
+			t.Errorf("unexpected tenant=%#v error=%v", found, err)
+		}
+
+		// rotate
+		if err := repo.UpdateCompanyIdentification(tenant.TenantID, "MOOV123456"); err != nil {
+			t.Fatal(err)
+		}
+		if companyID, err := repo.GetCompanyIdentification(tenant.TenantID); err != nil || companyID != "MOOV123456" {
+			t.Errorf("companyID=%q error=%v", companyID, err)
+		}
+
+		records, err := repo.ListCompanyIdentifications(tenant.TenantID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 {
+			t.Fatalf("unexpected records: %#v", records)
+		}
+		for i := range records {
+			switch records[i].CompanyIdentification {
+			case "companyID":
+				if records[i].Retired == nil {
+					t.Errorf("expected retired: %#v", records[i])
+				}
+			case "MOOV123456":
+				if records[i].Retired != nil {
+					t.Errorf("unexpected retired: %#v", records[i])
+				}
+			default:
+				t.Errorf("unexpected record: %#v", records[i])
+			}
+		}
+
+		// retired values can be assigned again, but current values can't
+		if err := repo.Create(userID, "companyID", other); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.UpdateCompanyIdentification(tenant.TenantID, "companyID"); err != ErrCompanyIdentificationExists {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := repo.UpdateCompanyIdentification(other.TenantID, "OTHER12345"); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.UpdateCompanyIdentification(tenant.TenantID, "companyID"); err != nil {
+			t.Fatal(err)
+		}
+		if companyID, err := repo.GetCompanyIdentification(tenant.TenantID); err != nil || companyID != "companyID" {
+			t.Errorf("companyID=%q error=%v", companyID, err)
+		}
+		if err := repo.UpdateCompanyIdentification(base.ID(), "MOOV654321"); err == nil {
+			t.Error("expected error")
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
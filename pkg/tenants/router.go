@@ -10,6 +10,7 @@ import (
 	"net/http"
 
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/memberships"
 	"github.com/moov-io/paygate/x/route"
 
 	"github.com/go-kit/kit/log"
@@ -21,20 +22,23 @@ type Router struct {
 	Repo   Repository
 
 	GetUserTenants http.HandlerFunc
+	GetUserTenant  http.HandlerFunc
 	UpdateTenant   http.HandlerFunc
 }
 
-func NewRouter(logger log.Logger, repo Repository) *Router {
+func NewRouter(logger log.Logger, repo Repository, access memberships.Checker) *Router {
 	return &Router{
 		Logger:         logger,
 		Repo:           repo,
 		GetUserTenants: GetUserTenants(logger, repo),
-		UpdateTenant:   UpdateTenant(logger, repo),
+		GetUserTenant:  GetUserTenant(logger, repo, access),
+		UpdateTenant:   UpdateTenant(logger, repo, access),
 	}
 }
 
 func (c *Router) RegisterRoutes(r *mux.Router) {
 	r.Methods("GET").Path("/tenants").HandlerFunc(c.GetUserTenants)
+	r.Methods("GET").Path("/tenants/{tenantID}").HandlerFunc(c.GetUserTenant)
 	r.Methods("PUT").Path("/tenants/{tenantID}").HandlerFunc(c.UpdateTenant)
 }
 
@@ -55,7 +59,7 @@ func GetUserTenants(logger log.Logger, repo Repository) http.HandlerFunc {
 	}
 }
 
-func UpdateTenant(logger log.Logger, repo Repository) http.HandlerFunc {
+func GetUserTenant(logger log.Logger, repo Repository, access memberships.Checker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(logger, w, r)
 
@@ -64,6 +68,41 @@ func UpdateTenant(logger log.Logger, repo Repository) http.HandlerFunc {
 			responder.Problem(errors.New("missing tenantID"))
 			return
 		}
+		if err := memberships.Require(access, responder.XUserID, memberships.Tenant, tenantID, memberships.Read); err != nil {
+			memberships.Problem(responder, w, r, err)
+			return
+		}
+
+		tenant, err := repo.GetTenant(tenantID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if tenant == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(tenant)
+		})
+	}
+}
+
+func UpdateTenant(logger log.Logger, repo Repository, access memberships.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		tenantID := route.ReadPathID("tenantID", r)
+		if tenantID == "" {
+			responder.Problem(errors.New("missing tenantID"))
+			return
+		}
+		if err := memberships.Require(access, responder.XUserID, memberships.Tenant, tenantID, memberships.Manage); err != nil {
+			memberships.Problem(responder, w, r, err)
+			return
+		}
 
 		var req client.UpdateTenant
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
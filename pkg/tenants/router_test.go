@@ -6,10 +6,12 @@ package tenants
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/memberships"
 	"github.com/moov-io/paygate/pkg/testclient"
 
 	"github.com/go-kit/kit/log"
@@ -28,7 +30,7 @@ func TestRouter__getUserTenants(t *testing.T) {
 	}
 
 	r := mux.NewRouter()
-	router := NewRouter(log.NewNopLogger(), repo)
+	router := NewRouter(log.NewNopLogger(), repo, &memberships.MockRepository{})
 	router.RegisterRoutes(r)
 
 	client := testclient.New(t, r)
@@ -56,8 +58,15 @@ func TestRouter__UpdateTenant(t *testing.T) {
 		},
 	}
 
+	access := &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			{Kind: memberships.Tenant, ResourceID: tenantID, UserID: "userID", Role: memberships.Owner},
+			{Kind: memberships.Tenant, ResourceID: tenantID, UserID: "finance", Role: memberships.Viewer},
+		},
+	}
+
 	r := mux.NewRouter()
-	router := NewRouter(log.NewNopLogger(), repo)
+	router := NewRouter(log.NewNopLogger(), repo, access)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -70,4 +79,55 @@ func TestRouter__UpdateTenant(t *testing.T) {
 		t.Fatal(err)
 	}
 	resp.Body.Close()
+
+	// Viewers can't update the Tenant
+	resp, err = c.TenantsApi.UpdateTenant(context.TODO(), tenantID, "finance", req, nil)
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403: %v", err)
+	}
+}
+
+func TestRouter__GetUserTenant(t *testing.T) {
+	tenantID := base.ID()
+	repo := &MockRepository{
+		Tenants: []client.Tenant{
+			{
+				TenantID:        tenantID,
+				Name:            "My Company",
+				PrimaryCustomer: base.ID(),
+				Status:          string(Active),
+			},
+		},
+	}
+
+	access := &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			{Kind: memberships.Tenant, ResourceID: tenantID, UserID: "userID", Role: memberships.Viewer},
+		},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(log.NewNopLogger(), repo, access)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	tenant, resp, err := c.TenantsApi.GetTenant(context.TODO(), tenantID, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if tenant.TenantID != tenantID || tenant.Status != string(Active) {
+		t.Errorf("unexpected tenant: %#v", tenant)
+	}
+
+	// Tenants which don't belong to the user aren't found
+	_, resp, err = c.TenantsApi.GetTenant(context.TODO(), base.ID(), "userID", nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404: %v", err)
+	}
 }
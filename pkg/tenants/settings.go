@@ -0,0 +1,73 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tenants
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+type Status string
+
+const (
+	Active    Status = "active"
+	Suspended Status = "suspended"
+)
+
+var (
+	// ErrSuspended is returned when a suspended Tenant attempts to create Transfers
+	ErrSuspended = errors.New("tenant is suspended")
+)
+
+// Settings are per-Tenant overrides of how their Transfers are originated. Empty values fall back
+// to paygate's config or the defaults of each fund flow strategy.
+type Settings struct {
+	// CompanyName is written into the BatchHeader of each batch instead of the source Customer's name
+	CompanyName string
+
+	// DefaultSECCode is the Standard Entry Class code batches are created with, e.g. PPD or CCD
+	DefaultSECCode string
+
+	// ODFI is the name of the configured ODFI Transfers are uploaded to
+	ODFI string
+
+	// Limits apply to the Tenant's Transfers on top of the configured limits
+	Limits config.Limits
+}
+
+func (s Settings) Validate() error {
+	if n := len(s.CompanyName); n > 16 {
+		return fmt.Errorf("CompanyName is %d characters which exceeds 16", n)
+	}
+	switch s.DefaultSECCode {
+	case "", ach.PPD, ach.CCD:
+	default:
+		return fmt.Errorf("unsupported DefaultSECCode %q", s.DefaultSECCode)
+	}
+	if err := s.Limits.Validate(); err != nil {
+		return fmt.Errorf("limits: %v", err)
+	}
+	return nil
+}
+
+// MergeLimits returns the configured limits with each of the Tenant's limits applied over them.
+func (s *Settings) MergeLimits(limits config.Limits) config.Limits {
+	if s == nil {
+		return limits
+	}
+	if s.Limits.MaxEntryAmount != "" {
+		limits.MaxEntryAmount = s.Limits.MaxEntryAmount
+	}
+	if s.Limits.MaxFileAmount != "" {
+		limits.MaxFileAmount = s.Limits.MaxFileAmount
+	}
+	if s.Limits.MaxFileEntries > 0 {
+		limits.MaxFileEntries = s.Limits.MaxFileEntries
+	}
+	return limits
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package tenants
+
+import (
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+)
+
+func TestSettings__Validate(t *testing.T) {
+	settings := Settings{
+		CompanyName:    "Acme Corp",
+		DefaultSECCode: "CCD",
+		Limits: config.Limits{
+			MaxEntryAmount: "USD 100.00",
+		},
+	}
+	if err := settings.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	settings.CompanyName = "A name which is too long"
+	if err := settings.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	settings.CompanyName = ""
+
+	settings.DefaultSECCode = "WEB"
+	if err := settings.Validate(); err == nil {
+		t.Error("expected error")
+	}
+	settings.DefaultSECCode = ""
+
+	settings.Limits.MaxEntryAmount = "invalid"
+	if err := settings.Validate(); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSettings__MergeLimits(t *testing.T) {
+	limits := config.Limits{
+		MaxEntryAmount: "USD 500.00",
+		MaxFileAmount:  "USD 5000.00",
+		MaxFileEntries: 100,
+	}
+
+	var settings *Settings
+	if out := settings.MergeLimits(limits); out != limits {
+		t.Errorf("unexpected limits: %#v", out)
+	}
+
+	settings = &Settings{
+		Limits: config.Limits{
+			MaxEntryAmount: "USD 25.00",
+		},
+	}
+	out := settings.MergeLimits(limits)
+	if out.MaxEntryAmount != "USD 25.00" || out.MaxFileAmount != "USD 5000.00" || out.MaxFileEntries != 100 {
+		t.Errorf("unexpected limits: %#v", out)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/memberships"
+)
+
+// checkAccess requires the user is able to create Transfers for the Transfer's Organization, or its
+// Tenant when the Transfer isn't part of an Organization. Organization roles include the Tenant's role.
+func checkAccess(access memberships.Checker, userID, tenantID string, transfer *client.Transfer) error {
+	if transfer.OrganizationID != "" {
+		err := memberships.Require(access, userID, memberships.Organization, transfer.OrganizationID, memberships.CreateTransfers)
+		if err == memberships.ErrNotMember {
+			return fmt.Errorf("organization %s not found", transfer.OrganizationID)
+		}
+		return err
+	}
+	return memberships.Require(access, userID, memberships.Tenant, tenantID, memberships.CreateTransfers)
+}
+
+// readableBatch requires the user can read each Organization or Tenant the batch's Transfers were
+// created in. Batches without any Transfers only hold errors for their creator.
+func readableBatch(repo Repository, access memberships.Checker, userID string, batch *client.TransferBatch) error {
+	checked := make(map[string]bool)
+	for i := range batch.Results {
+		xfer := batch.Results[i].Transfer
+		if xfer.TransferID == "" {
+			continue
+		}
+		kind, resourceID := memberships.Organization, xfer.OrganizationID
+		if resourceID == "" {
+			tenantID, err := repo.getTransferTenant(xfer.TransferID)
+			if err != nil {
+				return err
+			}
+			kind, resourceID = memberships.Tenant, tenantID
+		}
+		if resourceID == "" || checked[resourceID] {
+			continue
+		}
+		if err := memberships.Require(access, userID, kind, resourceID, memberships.Read); err != nil {
+			return err
+		}
+		checked[resourceID] = true
+	}
+	return nil
+}
+
+// readableTransfer returns a Transfer the user created or one for an Organization or Tenant they
+// can read. memberships.ErrNotMember is returned when none are true.
+func readableTransfer(repo Repository, access memberships.Checker, userID, transferID string) (*client.Transfer, error) {
+	xfer, err := repo.getUserTransfer(transferID, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if xfer != nil {
+		return xfer, nil
+	}
+
+	xfer, err = repo.GetTransfer(transferID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if xfer == nil {
+		return nil, memberships.ErrNotMember
+	}
+	if xfer.OrganizationID != "" {
+		if err := memberships.Require(access, userID, memberships.Organization, xfer.OrganizationID, memberships.Read); err != nil {
+			return nil, err
+		}
+		return xfer, nil
+	}
+	tenantID, err := repo.getTransferTenant(transferID)
+	if err != nil {
+		return nil, err
+	}
+	if tenantID == "" {
+		return nil, memberships.ErrNotMember
+	}
+	if err := memberships.Require(access, userID, memberships.Tenant, tenantID, memberships.Read); err != nil {
+		return nil, err
+	}
+	return xfer, nil
+}
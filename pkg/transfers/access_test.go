@@ -0,0 +1,143 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/memberships"
+)
+
+func TestAccess__checkAccess(t *testing.T) {
+	access := &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			{Kind: memberships.Tenant, ResourceID: "tenantID", UserID: "admin", Role: memberships.Admin},
+			{Kind: memberships.Tenant, ResourceID: "tenantID", UserID: "ops", Role: memberships.Operator},
+			{Kind: memberships.Organization, ResourceID: "orgID", UserID: "ops", Role: memberships.Admin},
+		},
+	}
+	transfer := &client.Transfer{Amount: "USD 12.50"}
+
+	if err := checkAccess(access, "admin", "tenantID", transfer); err != nil {
+		t.Error(err)
+	}
+	if err := checkAccess(access, "ops", "tenantID", transfer); err != memberships.ErrForbidden {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := checkAccess(access, base.ID(), "tenantID", transfer); err != memberships.ErrNotMember {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := checkAccess(access, "admin", base.ID(), transfer); err != memberships.ErrNotMember {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Organization roles are checked for their Transfers
+	transfer.OrganizationID = "orgID"
+	if err := checkAccess(access, "ops", "tenantID", transfer); err != nil {
+		t.Error(err)
+	}
+	if err := checkAccess(access, "admin", "tenantID", transfer); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestAccess__readableBatch(t *testing.T) {
+	repo := &MockRepository{TenantID: "tenantID"}
+	access := &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			{Kind: memberships.Tenant, ResourceID: "tenantID", UserID: "creator", Role: memberships.Viewer},
+			{Kind: memberships.Tenant, ResourceID: "otherTenantID", UserID: "other", Role: memberships.Admin},
+		},
+	}
+	batch := &client.TransferBatch{
+		Results: []client.TransferBatchResult{{Transfer: client.Transfer{TransferID: base.ID()}}},
+	}
+
+	if err := readableBatch(repo, access, "creator", batch); err != nil {
+		t.Error(err)
+	}
+	if err := readableBatch(repo, access, base.ID(), batch); err != memberships.ErrNotMember {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// Members of another Tenant can't read the batch
+	if err := readableBatch(repo, access, "other", batch); err != memberships.ErrNotMember {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Organization Transfers need access to the Organization
+	batch.Results[0].Transfer.OrganizationID = "orgID"
+	if err := readableBatch(repo, access, "creator", batch); err != memberships.ErrNotMember {
+		t.Errorf("unexpected error: %v", err)
+	}
+	access.Memberships = append(access.Memberships, memberships.Membership{
+		Kind: memberships.Organization, ResourceID: "orgID", UserID: "creator", Role: memberships.Viewer,
+	})
+	if err := readableBatch(repo, access, "creator", batch); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccess__readableTransfer(t *testing.T) {
+	repo := setupSQLiteDB(t)
+
+	orgID := base.ID()
+	xfer := &client.Transfer{
+		TransferID:     base.ID(),
+		Amount:         "USD 1.25",
+		Description:    "payroll",
+		Status:         client.PENDING,
+		Created:        time.Now(),
+		OrganizationID: orgID,
+	}
+	if err := repo.writeUserTransfers("creator", "tenantID", xfer); err != nil {
+		t.Fatal(err)
+	}
+	access := &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			{Kind: memberships.Organization, ResourceID: orgID, UserID: "finance", Role: memberships.Viewer},
+		},
+	}
+
+	for _, userID := range []string{"creator", "finance"} {
+		found, err := readableTransfer(repo, access, userID, xfer.TransferID)
+		if err != nil {
+			t.Fatalf("userID=%s: %v", userID, err)
+		}
+		if found.TransferID != xfer.TransferID {
+			t.Errorf("userID=%s unexpected transfer: %#v", userID, found)
+		}
+	}
+
+	if _, err := readableTransfer(repo, access, base.ID(), xfer.TransferID); err != memberships.ErrNotMember {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := readableTransfer(repo, access, "finance", base.ID()); err != memberships.ErrNotMember {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Tenant readers can read Transfers outside of an Organization
+	xfer = &client.Transfer{
+		TransferID:  base.ID(),
+		Amount:      "USD 1.25",
+		Description: "payroll",
+		Status:      client.PENDING,
+		Created:     time.Now(),
+	}
+	if err := repo.writeUserTransfers("creator", "tenantID", xfer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readableTransfer(repo, access, "viewer", xfer.TransferID); err != memberships.ErrNotMember {
+		t.Errorf("unexpected error: %v", err)
+	}
+	access.Memberships = append(access.Memberships, memberships.Membership{
+		Kind: memberships.Tenant, ResourceID: "tenantID", UserID: "viewer", Role: memberships.Viewer,
+	})
+	if found, err := readableTransfer(repo, access, "viewer", xfer.TransferID); err != nil || found.TransferID != xfer.TransferID {
+		t.Errorf("found=%#v error=%v", found, err)
+	}
+}
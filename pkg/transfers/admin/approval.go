@@ -12,6 +12,7 @@ import (
 
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
 	"github.com/moov-io/paygate/x/route"
 
 	"github.com/go-kit/kit/log"
@@ -21,7 +22,7 @@ func getTransferID(r *http.Request) string {
 	return route.ReadPathID("transferID", r)
 }
 
-func updateTransferStatus(logger log.Logger, repo transfers.Repository) http.HandlerFunc {
+func updateTransferStatus(logger log.Logger, repo transfers.Repository, reviews *transfers.Reviews, fundsChecker fundflow.FundsChecker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(logger, w, r)
 
@@ -48,8 +49,21 @@ func updateTransferStatus(logger log.Logger, repo transfers.Repository) http.Han
 			return
 		}
 
-		// Perform the DB update since it's an allowed transition
-		if err := repo.UpdateTransferStatus(transferID, request.Status); err != nil {
+		if existing.Status == client.REVIEWABLE {
+			// Reviewed Transfers are recorded and approved ones are originated
+			if request.Status == client.PENDING {
+				_, err = reviews.Approve(transferID, responder.XUserID)
+			} else {
+				_, err = reviews.Reject(transferID, responder.XUserID)
+			}
+		} else {
+			// Perform the DB update since it's an allowed transition
+			err = repo.UpdateTransferStatus(transferID, request.Status)
+			if err == nil && request.Status == client.CANCELED && fundsChecker != nil {
+				err = fundsChecker.Release(existing)
+			}
+		}
+		if err != nil {
 			responder.Problem(err)
 			return
 		}
@@ -13,8 +13,11 @@ import (
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/admin"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/tenants"
 	"github.com/moov-io/paygate/pkg/testclient"
 	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
 
 	"github.com/go-kit/kit/log"
 )
@@ -41,7 +44,7 @@ func TestAdmin__updateTransferStatus(t *testing.T) {
 	}
 
 	svc, c := testclient.Admin(t)
-	RegisterRoutes(log.NewNopLogger(), svc, repo)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, &tenants.MockRepository{}, nil, nil, nil, config.Limits{}, &pipeline.MockPublisher{})
 
 	req := admin.UpdateTransferStatus{
 		Status: admin.CANCELED,
@@ -55,6 +58,49 @@ func TestAdmin__updateTransferStatus(t *testing.T) {
 
 }
 
+func TestAdmin__updateTransferStatusReviewable(t *testing.T) {
+	repo := &transfers.MockRepository{
+		Transfers: []*client.Transfer{
+			{
+				TransferID:  base.ID(),
+				Amount:      "USD 25000.00",
+				Description: "test transfer",
+				Status:      client.REVIEWABLE,
+				Created:     time.Now(),
+			},
+		},
+		Creator: "creator",
+	}
+	tenantRepo := &tenants.MockRepository{
+		UserTenants: map[string]string{"creator": "tenantID"},
+	}
+	pub := &pipeline.MockPublisher{}
+	reviews := transfers.NewReviews(log.NewNopLogger(), repo, tenantRepo, nil, nil, nil, nil, nil, pub)
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, tenantRepo, reviews, nil, nil, config.Limits{}, pub)
+
+	req := admin.UpdateTransferStatus{
+		Status: admin.PENDING,
+	}
+	transferID := repo.Transfers[0].TransferID
+
+	// creators can't approve their own Transfers
+	resp, _ := c.TransfersApi.UpdateTransferStatus(context.TODO(), transferID, "creator", req, nil)
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+
+	resp, err := c.TransfersApi.UpdateTransferStatus(context.TODO(), transferID, "reviewer", req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if repo.Transfers[0].Status != client.PENDING {
+		t.Errorf("unexpected status: %q", repo.Transfers[0].Status)
+	}
+}
+
 // import (
 // 	"fmt"
 // 	"io/ioutil"
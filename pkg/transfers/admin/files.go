@@ -0,0 +1,86 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+func getTenantID(r *http.Request) string {
+	return route.ReadPathID("tenantId", r)
+}
+
+// uploadTenantFile accepts a NACHA file on behalf of a Tenant. Only batches using the Tenant's
+// CompanyIdentification are accepted and the Tenant's limits are applied over the configured limits.
+func uploadTenantFile(logger log.Logger, tenantRepo tenants.Repository, files *transfers.FileProcessor, limits config.Limits) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		file, err := transfers.ReadFile(r.Body)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		tenantID := getTenantID(r)
+		tenant, err := tenantRepo.GetTenant(tenantID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if tenant == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if tenant.Status == string(tenants.Suspended) {
+			responder.Problem(tenants.ErrSuspended)
+			return
+		}
+		settings, err := tenantRepo.GetSettings(tenantID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		companyID, err := tenantRepo.GetCompanyIdentification(tenantID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		odfi, err := tenantRepo.GetODFI(tenantID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		result, err := files.Process(responder.XUserID, settings.MergeLimits(limits), map[string]transfers.FileOriginator{
+			companyID: {TenantID: tenantID, ODFI: odfi},
+		}, file)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		logger.Log(
+			"transfers", fmt.Sprintf("created %d transfers from fileID=%s for tenant=%s", len(result.Transfers), result.FileID, tenantID),
+			"userID", responder.XUserID, "requestID", responder.XRequestID)
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(result)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/go-kit/kit/log"
+)
+
+var testODFIs = []config.ODFI{
+	{
+		RoutingNumber: "987654320",
+		Gateway: config.Gateway{
+			Origin:      "987654320",
+			Destination: "076401251",
+		},
+	},
+}
+
+func TestAdmin__uploadTenantFile(t *testing.T) {
+	repo := &transfers.MockRepository{}
+	tenantRepo := &tenants.MockRepository{
+		Tenants: []client.Tenant{
+			{TenantID: "tenantID", Status: string(tenants.Active)},
+		},
+		CompanyIdentification: "origid",
+	}
+
+	svc, c := testclient.Admin(t)
+	files := transfers.NewFileProcessor(log.NewNopLogger(), repo, tenantRepo, nil, nil, nil, testODFIs, &pipeline.MockPublisher{})
+	RegisterRoutes(log.NewNopLogger(), svc, repo, tenantRepo, nil, files, nil, config.Limits{}, &pipeline.MockPublisher{})
+
+	bs, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, resp, err := c.TransfersApi.UploadTenantFile(context.TODO(), "tenantID", "userID", string(bs), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if len(result.Transfers) != 1 || len(result.Rejected) != 0 {
+		t.Errorf("unexpected result: %#v", result)
+	}
+
+	// batches from another CompanyIdentification are rejected
+	tenantRepo.CompanyIdentification = "other"
+	result, resp, err = c.TransfersApi.UploadTenantFile(context.TODO(), "tenantID", "userID", string(bs), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if len(result.Transfers) != 0 || len(result.Rejected) != 1 {
+		t.Errorf("unexpected result: %#v", result)
+	}
+
+	// the Tenant's limits apply over the configured limits
+	tenantRepo.CompanyIdentification = "origid"
+	tenantRepo.Settings = &tenants.Settings{
+		Limits: config.Limits{MaxEntryAmount: "USD 1.00"},
+	}
+	result, resp, err = c.TransfersApi.UploadTenantFile(context.TODO(), "tenantID", "userID", string(bs), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if len(result.Transfers) != 0 || len(result.Rejected) != 1 {
+		t.Errorf("unexpected result: %#v", result)
+	}
+}
+
+func TestAdmin__uploadTenantFileSuspended(t *testing.T) {
+	repo := &transfers.MockRepository{}
+	tenantRepo := &tenants.MockRepository{
+		Tenants: []client.Tenant{
+			{TenantID: "tenantID", Status: string(tenants.Suspended)},
+		},
+		CompanyIdentification: "origid",
+	}
+
+	svc, c := testclient.Admin(t)
+	files := transfers.NewFileProcessor(log.NewNopLogger(), repo, tenantRepo, nil, nil, nil, testODFIs, &pipeline.MockPublisher{})
+	RegisterRoutes(log.NewNopLogger(), svc, repo, tenantRepo, nil, files, nil, config.Limits{}, &pipeline.MockPublisher{})
+
+	bs, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, resp, err := c.TransfersApi.UploadTenantFile(context.TODO(), "tenantID", "userID", string(bs), nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	// unknown Tenants aren't found
+	_, resp, err = c.TransfersApi.UploadTenantFile(context.TODO(), "other", "userID", string(bs), nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected response: %v", resp)
+	}
+}
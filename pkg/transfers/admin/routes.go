@@ -6,12 +6,19 @@ package admin
 
 import (
 	"github.com/moov-io/base/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/tenants"
 	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
 
 	"github.com/go-kit/kit/log"
 )
 
 // RegisterRoutes will add HTTP handlers for paygate's admin HTTP server
-func RegisterRoutes(logger log.Logger, svc *admin.Server, repo transfers.Repository) {
-	svc.AddHandler("/transfers/{transferId}/status", updateTransferStatus(logger, repo))
+func RegisterRoutes(logger log.Logger, svc *admin.Server, repo transfers.Repository, tenantRepo tenants.Repository, reviews *transfers.Reviews, files *transfers.FileProcessor, fundsChecker fundflow.FundsChecker, limits config.Limits, pub pipeline.XferPublisher) {
+	svc.AddHandler("/transfers", searchTransfers(logger, repo))
+	svc.AddHandler("/transfers/{transferId}/status", updateTransferStatus(logger, repo, reviews, fundsChecker))
+	svc.AddHandler("/tenants/{tenantId}/transfers/cancel", cancelTenantTransfers(logger, repo, fundsChecker, pub))
+	svc.AddHandler("/tenants/{tenantId}/files", uploadTenantFile(logger, tenantRepo, files, limits))
 }
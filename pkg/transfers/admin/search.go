@@ -0,0 +1,165 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/util"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+func readSearchParams(r *http.Request) transfers.SearchParams {
+	q := r.URL.Query()
+	params := transfers.SearchParams{
+		TraceNumber: strings.TrimSpace(q.Get("traceNumber")),
+		Amount:      strings.TrimSpace(q.Get("amount")),
+		CustomerID:  strings.TrimSpace(q.Get("customerID")),
+		Filename:    strings.TrimSpace(q.Get("filename")),
+		TenantID:    strings.TrimSpace(q.Get("tenantID")),
+		Limit:       100,
+	}
+	if s := strings.TrimSpace(q.Get("status")); s != "" {
+		params.Status = client.TransferStatus(strings.ToLower(s))
+	}
+	if v := q.Get("startDate"); v != "" {
+		params.StartDate = util.FirstParsedTime(v, base.ISO8601Format, util.YYMMDDTimeFormat)
+	}
+	if v := q.Get("endDate"); v != "" {
+		params.EndDate = util.FirstParsedTime(v, base.ISO8601Format, util.YYMMDDTimeFormat)
+	}
+	if limit := route.ReadLimit(r); limit != 0 {
+		params.Limit = limit
+	}
+	if offset := route.ReadOffset(r); offset != 0 {
+		params.Offset = offset
+	}
+	return params
+}
+
+// searchTransfers finds Transfers across every user so operators can investigate incidents.
+func searchTransfers(logger log.Logger, repo transfers.Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		xfers, err := repo.SearchTransfers(readSearchParams(r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(xfers)
+		})
+	}
+}
+
+// cancelTenantTransfers cancels every PENDING Transfer of a Tenant and removes them from
+// the files waiting to be uploaded. Transfers which were already uploaded can't be canceled
+// and are reported instead, along with each Transfer which failed to cancel.
+func cancelTenantTransfers(logger log.Logger, repo transfers.Repository, fundsChecker fundflow.FundsChecker, pub pipeline.XferPublisher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		tenantID := getTenantID(r)
+		xfers, err := repo.SearchTransfers(transfers.SearchParams{
+			TenantID: tenantID,
+			Status:   client.PENDING,
+		})
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		result := canceledTransfers{
+			Canceled: make([]*client.Transfer, 0, len(xfers)),
+			Uploaded: make([]*client.Transfer, 0),
+			Failed:   make([]failedCancel, 0),
+		}
+		for i := range xfers {
+			uploaded, err := cancelTransfer(repo, fundsChecker, pub, xfers[i])
+			switch {
+			case err != nil:
+				responder.Log("transfers", fmt.Sprintf("problem canceling transferID=%s: %v", xfers[i].TransferID, err))
+				result.Failed = append(result.Failed, failedCancel{Transfer: xfers[i], Error: err.Error()})
+			case uploaded:
+				result.Uploaded = append(result.Uploaded, xfers[i])
+			default:
+				result.Canceled = append(result.Canceled, xfers[i])
+			}
+		}
+		logger.Log(
+			"transfers", fmt.Sprintf("canceled %d pending transfers for tenant=%s, %d were already uploaded and %d failed", len(result.Canceled), tenantID, len(result.Uploaded), len(result.Failed)),
+			"userID", responder.XUserID, "requestID", responder.XRequestID)
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(result)
+		})
+	}
+}
+
+// cancelTransfer removes a PENDING Transfer from its merging file before marking it CANCELED so
+// a failed cancel leaves the Transfer to be uploaded as it's saved. True is returned when the
+// Transfer was already uploaded and wasn't canceled.
+func cancelTransfer(repo transfers.Repository, fundsChecker fundflow.FundsChecker, pub pipeline.XferPublisher, xfer *client.Transfer) (bool, error) {
+	delivered, err := repo.TransferDelivered(xfer.TransferID)
+	if err != nil {
+		return false, err
+	}
+	if delivered {
+		return true, nil
+	}
+	if pub != nil {
+		if err := pub.Cancel(pipeline.CanceledTransfer{TransferID: xfer.TransferID}); err != nil {
+			return false, fmt.Errorf("removing from merged file: %v", err)
+		}
+	}
+	canceled, err := repo.CancelPendingTransfer(xfer.TransferID)
+	if err != nil {
+		return false, err
+	}
+	if !canceled {
+		return false, errors.New("transfer is no longer pending")
+	}
+	xfer.Status = client.CANCELED
+	if fundsChecker != nil {
+		if err := fundsChecker.Release(xfer); err != nil {
+			return false, fmt.Errorf("releasing funds: %v", err)
+		}
+	}
+	return false, nil
+}
+
+type canceledTransfers struct {
+	Canceled []*client.Transfer `json:"canceled"`
+	Uploaded []*client.Transfer `json:"uploaded"`
+	Failed   []failedCancel     `json:"failed"`
+}
+
+type failedCancel struct {
+	Transfer *client.Transfer `json:"transfer"`
+	Error    string           `json:"error"`
+}
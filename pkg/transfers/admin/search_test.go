@@ -0,0 +1,158 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/antihax/optional"
+	"github.com/go-kit/kit/log"
+)
+
+func TestAdmin__readSearchParams(t *testing.T) {
+	req := httptest.NewRequest("GET", "/transfers?traceNumber=987654320000001&status=PENDING&startDate=2020-05-29T00:00:00Z&limit=25", nil)
+	params := readSearchParams(req)
+	if params.TraceNumber != "987654320000001" || params.Status != client.PENDING {
+		t.Errorf("unexpected params: %#v", params)
+	}
+	if params.StartDate.IsZero() || !params.EndDate.IsZero() {
+		t.Errorf("startDate=%v endDate=%v", params.StartDate, params.EndDate)
+	}
+	if params.Limit != 25 || params.Offset != 0 {
+		t.Errorf("limit=%d offset=%d", params.Limit, params.Offset)
+	}
+
+	// defaults
+	params = readSearchParams(httptest.NewRequest("GET", "/transfers", nil))
+	if params.Limit != 100 || params.Status != "" {
+		t.Errorf("unexpected params: %#v", params)
+	}
+}
+
+func TestAdmin__searchTransfers(t *testing.T) {
+	repo := &transfers.MockRepository{
+		Transfers: []*client.Transfer{
+			{
+				TransferID: base.ID(),
+				Amount:     "USD 12.44",
+				Status:     client.PENDING,
+				Created:    time.Now(),
+			},
+		},
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, &tenants.MockRepository{}, nil, nil, nil, config.Limits{}, &pipeline.MockPublisher{})
+
+	opts := &admin.SearchTransfersOpts{
+		Amount:    optional.NewString("USD 12.44"),
+		StartDate: optional.NewTime(time.Now().Add(-24 * time.Hour)),
+	}
+	xfers, resp, err := c.TransfersApi.SearchTransfers(context.TODO(), "userID", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(xfers) != 1 || xfers[0].TransferID != repo.Transfers[0].TransferID {
+		t.Errorf("unexpected transfers: %#v", xfers)
+	}
+
+	repo.Err = errors.New("bad error")
+	_, resp, _ = c.TransfersApi.SearchTransfers(context.TODO(), "userID", nil)
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+}
+
+func TestAdmin__cancelTenantTransfers(t *testing.T) {
+	repo := &transfers.MockRepository{
+		Transfers: []*client.Transfer{
+			{
+				TransferID: base.ID(),
+				Amount:     "USD 12.44",
+				Status:     client.PENDING,
+				Created:    time.Now(),
+			},
+			{
+				TransferID: base.ID(),
+				Amount:     "USD 3.10",
+				Status:     client.PENDING,
+				Created:    time.Now(),
+			},
+		},
+	}
+	repo.Delivered = []string{repo.Transfers[1].TransferID}
+	pub := &pipeline.MockPublisher{}
+	fundsChecker := &fundflow.MockFundsChecker{}
+
+	svc, c := testclient.Admin(t)
+	RegisterRoutes(log.NewNopLogger(), svc, repo, &tenants.MockRepository{}, nil, nil, fundsChecker, config.Limits{}, pub)
+
+	result, resp, err := c.TransfersApi.CancelTenantTransfers(context.TODO(), base.ID(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	canceled := result.Canceled
+	if len(canceled) != 1 || canceled[0].Status != admin.CANCELED {
+		t.Errorf("unexpected transfers: %#v", canceled)
+	}
+	if len(result.Uploaded) != 1 || result.Uploaded[0].TransferID != repo.Transfers[1].TransferID || result.Uploaded[0].Status != admin.PENDING {
+		t.Errorf("unexpected uploaded transfers: %#v", result.Uploaded)
+	}
+	if len(fundsChecker.Released) != 1 || fundsChecker.Released[0] != canceled[0].TransferID {
+		t.Errorf("unexpected released funds: %v", fundsChecker.Released)
+	}
+
+	// Transfers which fail to cancel are reported and left for the rest to be canceled
+	pub.Err = errors.New("bad error")
+	result, resp, err = c.TransfersApi.CancelTenantTransfers(context.TODO(), base.ID(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(result.Canceled) != 0 || len(result.Uploaded) != 1 {
+		t.Errorf("unexpected result: %#v", result)
+	}
+	if len(result.Failed) != 1 || result.Failed[0].Transfer.TransferID != repo.Transfers[0].TransferID || result.Failed[0].Error == "" {
+		t.Errorf("unexpected failed transfers: %#v", result.Failed)
+	}
+
+	// Transfers which moved out of PENDING aren't canceled or released
+	pub.Err = nil
+	repo.Transfers[0].Status = client.PROCESSED
+	result, resp, err = c.TransfersApi.CancelTenantTransfers(context.TODO(), base.ID(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(result.Canceled) != 0 || len(result.Failed) != 1 {
+		t.Errorf("unexpected result: %#v", result)
+	}
+	if len(fundsChecker.Released) != 1 {
+		t.Errorf("unexpected released funds: %v", fundsChecker.Released)
+	}
+
+	repo.Err = errors.New("bad error")
+	_, resp, _ = c.TransfersApi.CancelTenantTransfers(context.TODO(), base.ID(), "userID", nil)
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+}
@@ -0,0 +1,462 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/client"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/memberships"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/returnrates"
+	"github.com/moov-io/paygate/pkg/transfers/review"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+var (
+	// maxTransferBatchSize is the most items accepted in one CreateTransferBatch request
+	maxTransferBatchSize = 1000
+
+	// maxTransferBatchDuration is how long a batch is processed before its remaining items fail
+	maxTransferBatchDuration = 10 * time.Minute
+
+	errAtomicBatchFailed = errors.New("atomic batch: another item failed")
+)
+
+func getBatchID(r *http.Request) string {
+	return route.ReadPathID("batchID", r)
+}
+
+func GetUserTransferBatch(logger log.Logger, repo Repository, access memberships.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		batch, err := repo.getTransferBatch(responder.XUserID, getBatchID(r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if batch == nil {
+			http.NotFound(w, r)
+			return
+		}
+		if err := readableBatch(repo, access, responder.XUserID, batch); err != nil {
+			memberships.Problem(responder, w, r, err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(batch)
+		})
+	}
+}
+
+// CreateUserTransferBatch validates each item of a CreateTransferBatch and then creates the Transfers
+// in the background. Callers poll the returned TransferBatch for the result of each item.
+func CreateUserTransferBatch(
+	logger log.Logger,
+	repo Repository,
+	tenantRepo tenants.Repository,
+	access memberships.Checker,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	fundStrategy fundflow.Strategy,
+	fundsChecker fundflow.FundsChecker,
+	returnRates returnrates.Checker,
+	reviewRules review.Checker,
+	pub pipeline.XferPublisher,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		var req client.CreateTransferBatch
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(err)
+			return
+		}
+		if n := len(req.Transfers); n == 0 || n > maxTransferBatchSize {
+			responder.Problem(fmt.Errorf("batch must contain between 1 and %d transfers, found %d", maxTransferBatchSize, n))
+			return
+		}
+
+		batch := &client.TransferBatch{
+			BatchID: base.ID(),
+			Status:  client.PROCESSING,
+			Atomic:  req.Atomic,
+			Results: make([]client.TransferBatchResult, len(req.Transfers)),
+			Created: time.Now(),
+		}
+		invalid := false
+		for i := range req.Transfers {
+			batch.Results[i].Index = int32(i)
+			if err := validateTransferRequest(req.Transfers[i]); err != nil {
+				batch.Results[i].Error = err.Error()
+				invalid = true
+			}
+		}
+		if invalid && req.Atomic {
+			batch.Status = client.REJECTED
+			markAtomicFailure(batch.Results)
+		}
+
+		if err := repo.writeTransferBatch(responder.XUserID, batch); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		if batch.Status == client.REJECTED {
+			if err := repo.saveTransferBatchResults(batch.BatchID, batch.Status, batch.Results); err != nil {
+				responder.Problem(err)
+				return
+			}
+		} else {
+			proc := &batchProcessor{
+				logger:           logger,
+				repo:             repo,
+				tenantRepo:       tenantRepo,
+				access:           access,
+				customersClient:  newCachedCustomers(customersClient),
+				accountDecryptor: newCachedDecryptor(accountDecryptor),
+				fundStrategy:     fundStrategy,
+				fundsChecker:     fundsChecker,
+				returnRates:      returnRates,
+				reviewRules:      reviewRules,
+				pub:              pub,
+			}
+			results := make([]client.TransferBatchResult, len(batch.Results))
+			copy(results, batch.Results)
+			go func() {
+				ctx, cancelFunc := context.WithTimeout(context.Background(), maxTransferBatchDuration)
+				defer cancelFunc()
+				proc.process(ctx, responder.XUserID, batch.BatchID, req, results)
+			}()
+		}
+
+		responder.Log("transfers", fmt.Sprintf("accepted batchID=%s with %d transfers", batch.BatchID, len(req.Transfers)))
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(batch)
+		})
+	}
+}
+
+type batchProcessor struct {
+	logger log.Logger
+
+	repo       Repository
+	tenantRepo tenants.Repository
+	access     memberships.Checker
+
+	customersClient  customers.Client
+	accountDecryptor accounts.Decryptor
+
+	fundStrategy fundflow.Strategy
+	fundsChecker fundflow.FundsChecker
+	returnRates  returnrates.Checker
+	reviewRules  review.Checker
+
+	pub pipeline.XferPublisher
+}
+
+type batchItem struct {
+	tenantID    string
+	transfer    *client.Transfer
+	source      fundflow.Source
+	destination fundflow.Destination
+
+	// reasons are why the Transfer is held for review
+	reasons []string
+}
+
+// process creates a Transfer for each valid item and saves the results. Atomic batches
+// only originate Transfers once every item has passed its checks and been saved, and are
+// rejected with each of their Transfers canceled if any fails to originate.
+//
+// Items left once ctx is done fail, and the results are still saved if processing panics
+// so batches aren't left PROCESSING.
+func (p *batchProcessor) process(ctx context.Context, userID string, batchID string, req client.CreateTransferBatch, results []client.TransferBatchResult) {
+	items := make([]*batchItem, len(results))
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Log("transfers", fmt.Sprintf("ERROR processing batchID=%s: %v", batchID, r))
+			p.abort(userID, batchID, req.Atomic, items, results, fmt.Errorf("batch processing stopped: %v", r))
+		}
+	}()
+
+	failed := false
+	for i := range req.Transfers {
+		if results[i].Error != "" {
+			continue // invalid request
+		}
+		if err := ctx.Err(); err != nil {
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		item, err := p.prepare(userID, req.Transfers[i])
+		if err != nil {
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		items[i] = item
+	}
+
+	status := client.COMPLETED
+	if req.Atomic && failed {
+		status = client.REJECTED
+		markAtomicFailure(results)
+		p.release(items)
+	} else {
+		for i := range items {
+			if items[i] == nil {
+				continue
+			}
+			if err := p.repo.writeUserTransfers(userID, items[i].tenantID, items[i].transfer); err != nil {
+				results[i].Error = err.Error()
+				if req.Atomic {
+					p.rollback(userID, items)
+					status = client.REJECTED
+					markAtomicFailure(results)
+					break
+				}
+				releaseFunds(p.logger, p.fundsChecker, items[i].transfer)
+				items[i] = nil
+				continue
+			}
+			if items[i].transfer.Status == client.REVIEWABLE {
+				if err := p.repo.saveReview(items[i].transfer.TransferID, items[i].reasons); err != nil {
+					p.logger.Log("transfers", fmt.Sprintf("ERROR saving batchID=%s review of transferID=%s: %v", batchID, items[i].transfer.TransferID, err))
+				}
+			}
+			results[i].Transfer = *items[i].transfer
+		}
+	}
+
+	if status == client.COMPLETED {
+		for i := range items {
+			if items[i] == nil {
+				continue
+			}
+			err := ctx.Err()
+			if err == nil {
+				err = originateTransfer(p.repo, p.tenantRepo, p.fundStrategy, p.pub, items[i].tenantID, items[i].transfer, items[i].source, items[i].destination)
+			}
+			if err != nil {
+				p.logger.Log("transfers", fmt.Sprintf("batchID=%s problem originating transferID=%s: %v", batchID, items[i].transfer.TransferID, err))
+				results[i].Error = err.Error()
+				if req.Atomic {
+					p.cancel(items[:i])
+					p.rollback(userID, items)
+					status = client.REJECTED
+					markAtomicFailure(results)
+					break
+				}
+				p.fail(items[i])
+				results[i].Transfer = *items[i].transfer
+			}
+		}
+	}
+
+	if err := p.repo.saveTransferBatchResults(batchID, status, results); err != nil {
+		p.logger.Log("transfers", fmt.Sprintf("ERROR saving batchID=%s results: %v", batchID, err))
+	}
+}
+
+func (p *batchProcessor) prepare(userID string, req client.CreateTransfer) (*batchItem, error) {
+	item := &batchItem{
+		transfer: newTransfer(req),
+	}
+	tenantID, err := transferTenant(p.tenantRepo, userID, item.transfer)
+	if err != nil {
+		return nil, err
+	}
+	item.tenantID = tenantID
+	if err := checkTenant(p.tenantRepo, tenantID, item.transfer); err != nil {
+		return nil, err
+	}
+	if err := checkAccess(p.access, userID, tenantID, item.transfer); err != nil {
+		return nil, err
+	}
+	if p.fundStrategy != nil {
+		item.source, item.destination, err = lookupAccounts(p.customersClient, p.accountDecryptor, req.Source, req.Destination)
+		if err != nil {
+			return nil, err
+		}
+	}
+	reasons, err := checkTransfer(userID, tenantID, item.transfer, item.source, p.fundsChecker, p.returnRates, p.reviewRules)
+	if err != nil {
+		releaseFunds(p.logger, p.fundsChecker, item.transfer)
+		return nil, err
+	}
+	item.reasons = reasons
+	return item, nil
+}
+
+// abort saves the results of a batch whose processing stopped part way. Items without a result
+// fail with err and atomic batches are rolled back.
+func (p *batchProcessor) abort(userID string, batchID string, atomic bool, items []*batchItem, results []client.TransferBatchResult, err error) {
+	status := client.COMPLETED
+	for i := range results {
+		if results[i].Error == "" && results[i].Transfer.TransferID == "" {
+			results[i].Error = err.Error()
+			if items[i] != nil && !atomic {
+				releaseFunds(p.logger, p.fundsChecker, items[i].transfer)
+			}
+		}
+	}
+	if atomic {
+		p.cancel(items)
+		p.rollback(userID, items)
+		status = client.REJECTED
+		markAtomicFailure(results)
+	}
+	if err := p.repo.saveTransferBatchResults(batchID, status, results); err != nil {
+		p.logger.Log("transfers", fmt.Sprintf("ERROR saving batchID=%s results: %v", batchID, err))
+	}
+}
+
+// cancel removes each originated Transfer from the files waiting to be uploaded.
+func (p *batchProcessor) cancel(items []*batchItem) {
+	if p.pub == nil {
+		return
+	}
+	for i := range items {
+		if items[i] == nil || items[i].transfer.Status != client.PENDING {
+			continue
+		}
+		if err := p.pub.Cancel(pipeline.CanceledTransfer{TransferID: items[i].transfer.TransferID}); err != nil {
+			p.logger.Log("transfers", fmt.Sprintf("ERROR canceling transferID=%s: %v", items[i].transfer.TransferID, err))
+		}
+	}
+}
+
+// fail marks a saved Transfer which couldn't be originated as FAILED and releases its funds.
+func (p *batchProcessor) fail(item *batchItem) {
+	if err := p.repo.UpdateTransferStatus(item.transfer.TransferID, client.FAILED); err != nil {
+		p.logger.Log("transfers", fmt.Sprintf("ERROR failing transferID=%s: %v", item.transfer.TransferID, err))
+	}
+	item.transfer.Status = client.FAILED
+	releaseFunds(p.logger, p.fundsChecker, item.transfer)
+}
+
+// rollback deletes each Transfer of an atomic batch and releases their funds.
+func (p *batchProcessor) rollback(userID string, items []*batchItem) {
+	for i := range items {
+		if items[i] == nil {
+			continue
+		}
+		if err := p.repo.deleteUserTransfer(userID, items[i].transfer.TransferID); err != nil {
+			p.logger.Log("transfers", fmt.Sprintf("ERROR rolling back transferID=%s: %v", items[i].transfer.TransferID, err))
+		}
+	}
+	p.release(items)
+}
+
+// release frees the funds held for each item which won't be originated.
+func (p *batchProcessor) release(items []*batchItem) {
+	for i := range items {
+		if items[i] != nil {
+			releaseFunds(p.logger, p.fundsChecker, items[i].transfer)
+		}
+	}
+}
+
+// markAtomicFailure clears each Transfer from results and explains why the item wasn't created.
+func markAtomicFailure(results []client.TransferBatchResult) {
+	for i := range results {
+		results[i].Transfer = client.Transfer{}
+		if results[i].Error == "" {
+			results[i].Error = errAtomicBatchFailed.Error()
+		}
+	}
+}
+
+// cachedCustomers reads each Customer and Account once from the Customers service
+// so batches with many Transfers to the same parties make fewer calls.
+type cachedCustomers struct {
+	customers.Client
+
+	customers map[string]*moovcustomers.Customer
+	accounts  map[string]*moovcustomers.Account
+}
+
+func newCachedCustomers(client customers.Client) customers.Client {
+	if client == nil {
+		return nil
+	}
+	return &cachedCustomers{
+		Client:    client,
+		customers: make(map[string]*moovcustomers.Customer),
+		accounts:  make(map[string]*moovcustomers.Account),
+	}
+}
+
+func (c *cachedCustomers) Lookup(customerID string, requestID string, userID string) (*moovcustomers.Customer, error) {
+	if cust, exists := c.customers[customerID]; exists {
+		return cust, nil
+	}
+	cust, err := c.Client.Lookup(customerID, requestID, userID)
+	if err != nil {
+		return nil, err
+	}
+	c.customers[customerID] = cust
+	return cust, nil
+}
+
+func (c *cachedCustomers) FindAccount(customerID, accountID string) (*moovcustomers.Account, error) {
+	key := customerID + "/" + accountID
+	if acct, exists := c.accounts[key]; exists {
+		return acct, nil
+	}
+	acct, err := c.Client.FindAccount(customerID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	c.accounts[key] = acct
+	return acct, nil
+}
+
+type cachedDecryptor struct {
+	underlying accounts.Decryptor
+	numbers    map[string]string
+}
+
+func newCachedDecryptor(decryptor accounts.Decryptor) accounts.Decryptor {
+	if decryptor == nil {
+		return nil
+	}
+	return &cachedDecryptor{
+		underlying: decryptor,
+		numbers:    make(map[string]string),
+	}
+}
+
+func (d *cachedDecryptor) AccountNumber(customerID, accountID string) (string, error) {
+	key := customerID + "/" + accountID
+	if num, exists := d.numbers[key]; exists {
+		return num, nil
+	}
+	num, err := d.underlying.AccountNumber(customerID, accountID)
+	if err != nil {
+		return "", err
+	}
+	d.numbers[key] = num
+	return num, nil
+}
@@ -0,0 +1,355 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/client"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+type countingCustomers struct {
+	*customers.MockClient
+
+	lookups int
+}
+
+func (c *countingCustomers) Lookup(customerID string, requestID string, userID string) (*moovcustomers.Customer, error) {
+	c.lookups++
+	return c.MockClient.Lookup(customerID, requestID, userID)
+}
+
+func batchRequest(n int) client.CreateTransferBatch {
+	source := client.Source{CustomerID: base.ID(), AccountID: base.ID()}
+	destination := client.Destination{CustomerID: base.ID(), AccountID: base.ID()}
+
+	var req client.CreateTransferBatch
+	for i := 0; i < n; i++ {
+		req.Transfers = append(req.Transfers, client.CreateTransfer{
+			Amount:      "USD 12.44",
+			Source:      source,
+			Destination: destination,
+			Description: "payroll",
+		})
+	}
+	return req
+}
+
+func awaitBatch(t *testing.T, c *client.APIClient, userID, batchID string) client.TransferBatch {
+	t.Helper()
+
+	for i := 0; i < 50; i++ {
+		batch, resp, err := c.TransfersApi.GetTransferBatch(context.TODO(), batchID, userID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if batch.Status != client.PROCESSING {
+			return batch
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("batchID=%s never finished processing", batchID)
+	return client.TransferBatch{}
+}
+
+func TestRouter__createUserTransferBatch(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	customersClient := &countingCustomers{MockClient: mockCustomersClient()}
+
+	r := mux.NewRouter()
+	router := NewRouter(log.NewNopLogger(), repo, tenantRepo, access, customersClient, mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	req := batchRequest(3)
+	req.Transfers[1].Description = "" // invalid
+
+	userID := "userID"
+	batch, resp, err := c.TransfersApi.AddTransferBatch(context.TODO(), userID, req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if batch.BatchID == "" || batch.Status != client.PROCESSING {
+		t.Fatalf("unexpected batch: %#v", batch)
+	}
+
+	batch = awaitBatch(t, c, userID, batch.BatchID)
+	if batch.Status != client.COMPLETED {
+		t.Errorf("unexpected status: %q", batch.Status)
+	}
+	if n := len(batch.Results); n != 3 {
+		t.Fatalf("got %d results: %#v", n, batch.Results)
+	}
+	if batch.Results[0].Transfer.TransferID == "" || batch.Results[2].Transfer.TransferID == "" {
+		t.Errorf("missing transfers: %#v", batch.Results)
+	}
+	if batch.Results[1].Error == "" || batch.Results[1].Transfer.TransferID != "" {
+		t.Errorf("unexpected result: %#v", batch.Results[1])
+	}
+
+	// each customer is looked up once
+	if customersClient.lookups != 2 {
+		t.Errorf("got %d customer lookups", customersClient.lookups)
+	}
+}
+
+func TestRouter__createUserTransferBatchAtomic(t *testing.T) {
+	repo := setupSQLiteDB(t)
+
+	r := mux.NewRouter()
+	router := NewRouter(log.NewNopLogger(), repo, tenantRepo, access, mockCustomersClient(), mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+	userID := "userID"
+
+	// invalid items reject the batch immediately
+	req := batchRequest(2)
+	req.Atomic = true
+	req.Transfers[1].Amount = ""
+
+	batch, resp, err := c.TransfersApi.AddTransferBatch(context.TODO(), userID, req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if batch.Status != client.REJECTED {
+		t.Errorf("unexpected status: %q", batch.Status)
+	}
+	batch = awaitBatch(t, c, userID, batch.BatchID)
+	if batch.Results[0].Error != errAtomicBatchFailed.Error() || batch.Results[1].Error == "" {
+		t.Errorf("unexpected results: %#v", batch.Results)
+	}
+
+	// failed lookups reject the batch after processing
+	failing := &customers.MockClient{}
+	r = mux.NewRouter()
+	NewRouter(log.NewNopLogger(), repo, tenantRepo, access, failing, mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher).RegisterRoutes(r)
+	c = testclient.New(t, r)
+
+	req = batchRequest(2)
+	req.Atomic = true
+	batch, resp, err = c.TransfersApi.AddTransferBatch(context.TODO(), userID, req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	batch = awaitBatch(t, c, userID, batch.BatchID)
+	if batch.Status != client.REJECTED {
+		t.Errorf("unexpected status: %q", batch.Status)
+	}
+	for i := range batch.Results {
+		if batch.Results[i].Transfer.TransferID != "" {
+			t.Errorf("unexpected transfer: %#v", batch.Results[i])
+		}
+	}
+}
+
+func TestRouter__createUserTransferBatchOriginateFailure(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	strategy := &fundflow.MockStrategy{Err: errors.New("bad error")}
+	fundsChecker := &fundflow.MockFundsChecker{}
+
+	r := mux.NewRouter()
+	NewRouter(log.NewNopLogger(), repo, tenantRepo, access, mockCustomersClient(), mockDecryptor, strategy, fundsChecker, nil, nil, nil, config.Limits{}, fakePublisher).RegisterRoutes(r)
+	c := testclient.New(t, r)
+	userID := "userID"
+
+	// Transfers which fail to originate are failed
+	batch, resp, err := c.TransfersApi.AddTransferBatch(context.TODO(), userID, batchRequest(2), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	batch = awaitBatch(t, c, userID, batch.BatchID)
+	if batch.Status != client.COMPLETED {
+		t.Errorf("unexpected status: %q", batch.Status)
+	}
+	for i := range batch.Results {
+		if batch.Results[i].Error == "" || batch.Results[i].Transfer.Status != client.FAILED {
+			t.Errorf("unexpected result: %#v", batch.Results[i])
+		}
+		xfer, err := repo.GetTransfer(batch.Results[i].Transfer.TransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if xfer == nil || xfer.Status != client.FAILED {
+			t.Errorf("unexpected transfer: %#v", xfer)
+		}
+	}
+	if len(fundsChecker.Released) != 2 {
+		t.Errorf("unexpected released funds: %v", fundsChecker.Released)
+	}
+
+	// atomic batches are rejected
+	req := batchRequest(2)
+	req.Atomic = true
+	batch, resp, err = c.TransfersApi.AddTransferBatch(context.TODO(), userID, req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	batch = awaitBatch(t, c, userID, batch.BatchID)
+	if batch.Status != client.REJECTED {
+		t.Errorf("unexpected status: %q", batch.Status)
+	}
+	if !strings.Contains(batch.Results[0].Error, "bad error") || batch.Results[1].Error != errAtomicBatchFailed.Error() {
+		t.Errorf("unexpected results: %#v", batch.Results)
+	}
+	for i := range batch.Results {
+		if batch.Results[i].Transfer.TransferID != "" {
+			t.Errorf("unexpected transfer: %#v", batch.Results[i])
+		}
+	}
+}
+
+type panickingCustomers struct {
+	*customers.MockClient
+}
+
+func (c *panickingCustomers) Lookup(customerID string, requestID string, userID string) (*moovcustomers.Customer, error) {
+	panic("lookup")
+}
+
+func TestBatchProcessor__processStopped(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	userID := "userID"
+
+	process := func(ctx context.Context, proc *batchProcessor, atomic bool) client.TransferBatch {
+		t.Helper()
+
+		batch := &client.TransferBatch{
+			BatchID: base.ID(),
+			Status:  client.PROCESSING,
+			Atomic:  atomic,
+			Results: make([]client.TransferBatchResult, 2),
+			Created: time.Now(),
+		}
+		if err := repo.writeTransferBatch(userID, batch); err != nil {
+			t.Fatal(err)
+		}
+		req := batchRequest(2)
+		req.Atomic = atomic
+		proc.process(ctx, userID, batch.BatchID, req, batch.Results)
+
+		found, err := repo.getTransferBatch(userID, batch.BatchID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *found
+	}
+
+	// panics still save the results
+	proc := &batchProcessor{
+		logger:          log.NewNopLogger(),
+		repo:            repo,
+		tenantRepo:      tenantRepo,
+		access:          access,
+		customersClient: &panickingCustomers{},
+		fundStrategy:    mockStrategy,
+		pub:             fakePublisher,
+	}
+	for _, atomic := range []bool{false, true} {
+		batch := process(context.Background(), proc, atomic)
+		if batch.Status == client.PROCESSING || batch.Results[0].Error == "" {
+			t.Errorf("atomic=%v: unexpected batch: %#v", atomic, batch)
+		}
+	}
+
+	// items left once the context is done fail
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	cancelFunc()
+	proc.customersClient = mockCustomersClient()
+	batch := process(ctx, proc, true)
+	if batch.Status != client.REJECTED {
+		t.Errorf("unexpected status: %q", batch.Status)
+	}
+	if batch.Results[0].Error != context.Canceled.Error() {
+		t.Errorf("unexpected results: %#v", batch.Results)
+	}
+}
+
+func TestRouter__createUserTransferBatchSize(t *testing.T) {
+	r := mux.NewRouter()
+	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, access, mockCustomersClient(), mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	_, resp, err := c.TransfersApi.AddTransferBatch(context.TODO(), base.ID(), client.CreateTransferBatch{}, nil)
+	if err == nil {
+		t.Error("expected error")
+	}
+	resp.Body.Close()
+
+	_, resp, err = c.TransfersApi.AddTransferBatch(context.TODO(), base.ID(), batchRequest(maxTransferBatchSize+1), nil)
+	if err == nil {
+		t.Error("expected error")
+	}
+	resp.Body.Close()
+}
+
+func TestRepository__TransferBatch(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		xfer := writeTransfer(t, userID, repo)
+
+		batch := &client.TransferBatch{
+			BatchID: base.ID(),
+			Status:  client.PROCESSING,
+			Created: time.Now(),
+		}
+		if err := repo.writeTransferBatch(userID, batch); err != nil {
+			t.Fatal(err)
+		}
+		results := []client.TransferBatchResult{
+			{Index: 0, Transfer: *xfer},
+			{Index: 1, Error: "bad"},
+		}
+		if err := repo.saveTransferBatchResults(batch.BatchID, client.COMPLETED, results); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := repo.getTransferBatch(userID, batch.BatchID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status != client.COMPLETED || len(found.Results) != 2 {
+			t.Fatalf("unexpected batch: %#v", found)
+		}
+		if found.Results[0].Transfer.TransferID != xfer.TransferID || found.Results[1].Error != "bad" {
+			t.Errorf("unexpected results: %#v", found.Results)
+		}
+
+		// other users can't read the batch
+		if found, err := repo.getTransferBatch(base.ID(), batch.BatchID); found != nil || err != nil {
+			t.Errorf("batch=%#v error=%v", found, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
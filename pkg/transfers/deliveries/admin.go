@@ -0,0 +1,147 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package deliveries
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/moov-io/base/admin"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+// RegisterAdminRoutes will add HTTP handlers for paygate's admin HTTP server
+func RegisterAdminRoutes(logger log.Logger, svc *admin.Server, repo Repository) {
+	svc.AddHandler("/deliveries", getDeliveries(logger, repo))
+	svc.AddHandler("/deliveries/{deliveryId}", getDelivery(logger, repo))
+	svc.AddHandler("/cutoffs", getRuns(logger, repo))
+	svc.AddHandler("/cutoffs/{runId}", getRun(logger, repo))
+}
+
+func readListParams(r *http.Request) ListParams {
+	params := ListParams{
+		Limit: 100,
+	}
+	if s := strings.TrimSpace(r.URL.Query().Get("status")); s != "" {
+		params.Status = Status(strings.ToLower(s))
+	}
+	if limit := route.ReadLimit(r); limit != 0 {
+		params.Limit = limit
+	}
+	if offset := route.ReadOffset(r); offset != 0 {
+		params.Offset = offset
+	}
+	return params
+}
+
+func getDeliveries(logger log.Logger, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		deliveries, err := repo.ListDeliveries(readListParams(r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(deliveries)
+		})
+	}
+}
+
+func getDelivery(logger log.Logger, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		delivery, err := repo.GetDelivery(route.ReadPathID("deliveryId", r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if delivery == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(delivery)
+		})
+	}
+}
+
+func readRunParams(r *http.Request) RunParams {
+	params := RunParams{
+		ODFI:  strings.TrimSpace(r.URL.Query().Get("odfi")),
+		Limit: 100,
+	}
+	if limit := route.ReadLimit(r); limit != 0 {
+		params.Limit = limit
+	}
+	if offset := route.ReadOffset(r); offset != 0 {
+		params.Offset = offset
+	}
+	return params
+}
+
+func getRuns(logger log.Logger, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		runs, err := repo.ListRuns(readRunParams(r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(runs)
+		})
+	}
+}
+
+func getRun(logger log.Logger, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		run, err := repo.GetRun(route.ReadPathID("runId", r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if run == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(run)
+		})
+	}
+}
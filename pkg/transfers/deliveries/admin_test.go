@@ -0,0 +1,162 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package deliveries
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/antihax/optional"
+	"github.com/go-kit/kit/log"
+)
+
+func TestAdmin__getDeliveries(t *testing.T) {
+	repo := &MockRepository{
+		Deliveries: []*Delivery{
+			{
+				DeliveryID: base.ID(),
+				Filename:   "20200529-987654320-1.ach",
+				Status:     Failed,
+				Attempts: []Attempt{
+					{Attempt: 1, Error: "connection reset", Created: time.Now()},
+				},
+				Created: time.Now(),
+			},
+			{
+				DeliveryID: base.ID(),
+				Filename:   "20200529-987654320-2.ach",
+				Status:     Delivered,
+				Created:    time.Now(),
+			},
+		},
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterAdminRoutes(log.NewNopLogger(), svc, repo)
+
+	deliveries, resp, err := c.DeliveriesApi.GetDeliveries(context.Background(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(deliveries) != 2 {
+		t.Errorf("unexpected deliveries: %#v", deliveries)
+	}
+
+	opts := &admin.GetDeliveriesOpts{
+		Status: optional.NewString("failed"),
+	}
+	deliveries, resp, err = c.DeliveriesApi.GetDeliveries(context.Background(), "userID", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(deliveries) != 1 || len(deliveries[0].Attempts) != 1 || deliveries[0].Attempts[0].Error != "connection reset" {
+		t.Errorf("unexpected deliveries: %#v", deliveries)
+	}
+
+	// single delivery
+	delivery, resp, err := c.DeliveriesApi.GetDelivery(context.Background(), repo.Deliveries[1].DeliveryID, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if delivery.Status != "delivered" {
+		t.Errorf("unexpected delivery: %#v", delivery)
+	}
+
+	_, resp, err = c.DeliveriesApi.GetDelivery(context.Background(), base.ID(), "userID", nil)
+	if err == nil {
+		t.Error("expected error")
+	}
+	if resp == nil || resp.StatusCode != 404 {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+}
+
+func TestAdmin__getDeliveriesErr(t *testing.T) {
+	repo := &MockRepository{Err: errors.New("bad error")}
+
+	svc, c := testclient.Admin(t)
+	RegisterAdminRoutes(log.NewNopLogger(), svc, repo)
+
+	_, resp, err := c.DeliveriesApi.GetDeliveries(context.Background(), "userID", nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestAdmin__getRuns(t *testing.T) {
+	finished := time.Now()
+	repo := &MockRepository{
+		Runs: []*Run{
+			{
+				RunID:    base.ID(),
+				ODFI:     "first",
+				Window:   "16:00",
+				Started:  time.Now(),
+				Finished: &finished,
+			},
+		},
+	}
+	repo.Deliveries = []*Delivery{
+		{
+			DeliveryID: base.ID(),
+			Filename:   "20200529-987654320-1.ach",
+			Status:     Delivered,
+			RunID:      repo.Runs[0].RunID,
+			Created:    time.Now(),
+		},
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterAdminRoutes(log.NewNopLogger(), svc, repo)
+
+	runs, resp, err := c.MergingApi.GetCutoffRuns(context.Background(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(runs) != 1 || runs[0].Window != "16:00" || len(runs[0].Deliveries) != 1 {
+		t.Errorf("unexpected runs: %#v", runs)
+	}
+
+	opts := &admin.GetCutoffRunsOpts{
+		Odfi: optional.NewString("second"),
+	}
+	runs, resp, err = c.MergingApi.GetCutoffRuns(context.Background(), "userID", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(runs) != 0 {
+		t.Errorf("unexpected runs: %#v", runs)
+	}
+
+	run, resp, err := c.MergingApi.GetCutoffRun(context.Background(), repo.Runs[0].RunID, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if run.RunID != repo.Runs[0].RunID || run.Deliveries[0].Filename != "20200529-987654320-1.ach" {
+		t.Errorf("unexpected run: %#v", run)
+	}
+
+	// not found
+	_, resp, _ = c.MergingApi.GetCutoffRun(context.Background(), base.ID(), "userID", nil)
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+}
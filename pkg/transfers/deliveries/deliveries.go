@@ -0,0 +1,76 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package deliveries records each attempt at uploading an outbound file to the ODFI
+// so operators can see which files were delivered and why others failed.
+package deliveries
+
+import (
+	"time"
+)
+
+type Status string
+
+const (
+	// Pending deliveries are still being uploaded or retried
+	Pending Status = "pending"
+
+	// Delivered files were uploaded and verified on the ODFI's server
+	Delivered Status = "delivered"
+
+	// Failed deliveries ran out of attempts
+	Failed Status = "failed"
+
+	// Requeued deliveries failed and their file was merged again for the next cutoff
+	Requeued Status = "requeued"
+)
+
+// Delivery is one outbound file we've uploaded to the ODFI.
+type Delivery struct {
+	DeliveryID string `json:"deliveryID"`
+	Filename   string `json:"filename"`
+
+	// Size is the number of bytes uploaded and SHA256 is the hex encoded checksum of them
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+
+	Status   Status    `json:"status"`
+	Attempts []Attempt `json:"attempts"`
+
+	// TraceNumbers of each entry in the file, which link the Delivery to its Transfers
+	TraceNumbers []string `json:"-"`
+
+	// RunID is the cutoff Run which uploaded the file
+	RunID string `json:"runID,omitempty"`
+
+	Created     time.Time `json:"created"`
+	LastUpdated time.Time `json:"lastUpdated"`
+}
+
+// Attempt is one try at uploading a Delivery's file. Error is empty for successful attempts.
+type Attempt struct {
+	Attempt int       `json:"attempt"`
+	Error   string    `json:"error,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// Run is one cutoff where the files merged for an ODFI were uploaded.
+type Run struct {
+	RunID string `json:"runID"`
+	ODFI  string `json:"odfi"`
+
+	// Window is the cutoff time as 15:04 in the ODFI's timezone
+	Window string `json:"window"`
+
+	// Manual runs were triggered by an operator instead of a cutoff time
+	Manual bool `json:"manual"`
+
+	// Error describes why some files failed to merge or upload, it's empty when every file was uploaded
+	Error string `json:"error,omitempty"`
+
+	Deliveries []*Delivery `json:"deliveries"`
+
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package deliveries
+
+import (
+	"sync"
+	"time"
+)
+
+type MockRepository struct {
+	Deliveries []*Delivery
+	Runs       []*Run
+	Err        error
+
+	mu sync.Mutex
+}
+
+func (r *MockRepository) CreateDelivery(delivery *Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Deliveries = append(r.Deliveries, delivery)
+	return nil
+}
+
+func (r *MockRepository) RecordAttempt(deliveryID string, attempt Attempt, status Status) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Deliveries {
+		if r.Deliveries[i].DeliveryID == deliveryID {
+			r.Deliveries[i].Attempts = append(r.Deliveries[i].Attempts, attempt)
+			r.Deliveries[i].Status = status
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) UpdateStatus(deliveryID string, status Status) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Deliveries {
+		if r.Deliveries[i].DeliveryID == deliveryID {
+			r.Deliveries[i].Status = status
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) GetDelivery(deliveryID string) (*Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Deliveries {
+		if r.Deliveries[i].DeliveryID == deliveryID {
+			return r.Deliveries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) ListDeliveries(params ListParams) ([]*Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	out := []*Delivery{}
+	for i := range r.Deliveries {
+		if params.Status == "" || r.Deliveries[i].Status == params.Status {
+			out = append(out, r.Deliveries[i])
+		}
+	}
+	return out, nil
+}
+
+func (r *MockRepository) CreateRun(run *Run) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Runs = append(r.Runs, run)
+	return nil
+}
+
+func (r *MockRepository) FinishRun(runID string, finished time.Time, problem string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Runs {
+		if r.Runs[i].RunID == runID {
+			r.Runs[i].Finished = &finished
+			r.Runs[i].Error = problem
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) GetRun(runID string) (*Run, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Runs {
+		if r.Runs[i].RunID == runID {
+			return r.runWithDeliveries(r.Runs[i]), nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) ListRuns(params RunParams) ([]*Run, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	out := []*Run{}
+	for i := range r.Runs {
+		if params.ODFI == "" || r.Runs[i].ODFI == params.ODFI {
+			out = append(out, r.runWithDeliveries(r.Runs[i]))
+		}
+	}
+	return out, nil
+}
+
+func (r *MockRepository) runWithDeliveries(run *Run) *Run {
+	out := *run
+	out.Deliveries = nil
+	for i := range r.Deliveries {
+		if r.Deliveries[i].RunID == run.RunID {
+			out.Deliveries = append(out.Deliveries, r.Deliveries[i])
+		}
+	}
+	return &out
+}
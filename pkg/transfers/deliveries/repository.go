@@ -0,0 +1,321 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package deliveries
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type Repository interface {
+	// CreateDelivery saves a new Delivery before its first upload attempt.
+	CreateDelivery(delivery *Delivery) error
+
+	// RecordAttempt saves the result of an upload attempt and updates the Delivery's status.
+	RecordAttempt(deliveryID string, attempt Attempt, status Status) error
+
+	// UpdateStatus changes the status of a Delivery without recording an attempt.
+	UpdateStatus(deliveryID string, status Status) error
+
+	GetDelivery(deliveryID string) (*Delivery, error)
+	ListDeliveries(params ListParams) ([]*Delivery, error)
+
+	// CreateRun saves a cutoff Run as it starts and FinishRun records when and how it ended.
+	CreateRun(run *Run) error
+	FinishRun(runID string, finished time.Time, problem string) error
+
+	GetRun(runID string) (*Run, error)
+	ListRuns(params RunParams) ([]*Run, error)
+}
+
+// ListParams filters the Deliveries returned from ListDeliveries, newest first.
+type ListParams struct {
+	Status Status
+	Limit  int64
+	Offset int64
+}
+
+// RunParams filters the Runs returned from ListRuns, newest first.
+type RunParams struct {
+	ODFI   string
+	Limit  int64
+	Offset int64
+}
+
+func NewRepo(db *sql.DB) Repository {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+func (r *sqlRepo) CreateDelivery(delivery *Delivery) error {
+	if delivery == nil {
+		return nil
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `insert into file_deliveries (delivery_id, filename, size, sha256, status, run_id, created_at, last_updated_at) values (?, ?, ?, ?, ?, ?, ?, ?);`
+	_, err = tx.Exec(query, delivery.DeliveryID, delivery.Filename, delivery.Size, delivery.SHA256, delivery.Status, delivery.RunID, delivery.Created, delivery.Created)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query = `insert into file_delivery_trace_numbers (delivery_id, trace_number) values (?, ?);`
+	for i := range delivery.TraceNumbers {
+		if _, err := tx.Exec(query, delivery.DeliveryID, delivery.TraceNumbers[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("deliveryID=%s trace number: %v", delivery.DeliveryID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) RecordAttempt(deliveryID string, attempt Attempt, status Status) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `insert into file_delivery_attempts (delivery_id, attempt, error, created_at) values (?, ?, ?, ?);`
+	if _, err := tx.Exec(query, deliveryID, attempt.Attempt, attempt.Error, attempt.Created); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("deliveryID=%s attempt: %v", deliveryID, err)
+	}
+
+	query = `update file_deliveries set status = ?, last_updated_at = ? where delivery_id = ?;`
+	if _, err := tx.Exec(query, status, time.Now(), deliveryID); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("deliveryID=%s status: %v", deliveryID, err)
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) UpdateStatus(deliveryID string, status Status) error {
+	query := `update file_deliveries set status = ?, last_updated_at = ? where delivery_id = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(status, time.Now(), deliveryID)
+	return err
+}
+
+func (r *sqlRepo) GetDelivery(deliveryID string) (*Delivery, error) {
+	query := `select delivery_id, filename, size, sha256, status, run_id, created_at, last_updated_at from file_deliveries where delivery_id = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var d Delivery
+	err = stmt.QueryRow(deliveryID).Scan(&d.DeliveryID, &d.Filename, &d.Size, &d.SHA256, &d.Status, &d.RunID, &d.Created, &d.LastUpdated)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	d.Attempts, err = r.getAttempts(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *sqlRepo) getAttempts(deliveryID string) ([]Attempt, error) {
+	query := `select attempt, error, created_at from file_delivery_attempts where delivery_id = ? order by attempt asc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []Attempt{}
+	for rows.Next() {
+		var a Attempt
+		if err := rows.Scan(&a.Attempt, &a.Error, &a.Created); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+func (r *sqlRepo) ListDeliveries(params ListParams) ([]*Delivery, error) {
+	var statusQuery string
+	var args []interface{}
+	if params.Status != "" {
+		statusQuery = "where status = ?"
+		args = append(args, params.Status)
+	}
+	args = append(args, params.Limit, params.Offset)
+
+	query := fmt.Sprintf(`select delivery_id from file_deliveries %s order by created_at desc limit ? offset ?;`, statusQuery)
+	return r.queryDeliveries(query, args...)
+}
+
+func (r *sqlRepo) queryDeliveries(query string, args ...interface{}) ([]*Delivery, error) {
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveryIDs []string
+	for rows.Next() {
+		var deliveryID string
+		if err := rows.Scan(&deliveryID); err != nil {
+			return nil, err
+		}
+		deliveryIDs = append(deliveryIDs, deliveryID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := []*Delivery{}
+	for i := range deliveryIDs {
+		d, err := r.GetDelivery(deliveryIDs[i])
+		if err != nil {
+			return nil, fmt.Errorf("deliveryID=%s: %v", deliveryIDs[i], err)
+		}
+		if d != nil {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) CreateRun(run *Run) error {
+	if run == nil {
+		return nil
+	}
+	query := `insert into cutoff_runs (run_id, odfi, cutoff_window, manual, error, started_at) values (?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(run.RunID, run.ODFI, run.Window, run.Manual, run.Error, run.Started)
+	return err
+}
+
+func (r *sqlRepo) FinishRun(runID string, finished time.Time, problem string) error {
+	query := `update cutoff_runs set error = ?, finished_at = ? where run_id = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(problem, finished, runID)
+	return err
+}
+
+func (r *sqlRepo) GetRun(runID string) (*Run, error) {
+	query := `select run_id, odfi, cutoff_window, manual, error, started_at, finished_at from cutoff_runs where run_id = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var run Run
+	var finished *time.Time
+	err = stmt.QueryRow(runID).Scan(&run.RunID, &run.ODFI, &run.Window, &run.Manual, &run.Error, &run.Started, &finished)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if finished != nil && !finished.IsZero() {
+		run.Finished = finished
+	}
+
+	query = `select delivery_id from file_deliveries where run_id = ? order by created_at asc;`
+	run.Deliveries, err = r.queryDeliveries(query, runID)
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *sqlRepo) ListRuns(params RunParams) ([]*Run, error) {
+	var odfiQuery string
+	var args []interface{}
+	if params.ODFI != "" {
+		odfiQuery = "where odfi = ?"
+		args = append(args, params.ODFI)
+	}
+	args = append(args, params.Limit, params.Offset)
+
+	query := fmt.Sprintf(`select run_id from cutoff_runs %s order by started_at desc limit ? offset ?;`, odfiQuery)
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runIDs []string
+	for rows.Next() {
+		var runID string
+		if err := rows.Scan(&runID); err != nil {
+			return nil, err
+		}
+		runIDs = append(runIDs, runID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := []*Run{}
+	for i := range runIDs {
+		run, err := r.GetRun(runIDs[i])
+		if err != nil {
+			return nil, fmt.Errorf("runID=%s: %v", runIDs[i], err)
+		}
+		if run != nil {
+			out = append(out, run)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package deliveries
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+func TestRepository__Deliveries(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		delivery := &Delivery{
+			DeliveryID: base.ID(),
+			Filename:   "20200529-987654320-1.ach",
+			Size:       1894,
+			SHA256:     "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			Status:     Pending,
+			Created:    time.Now(),
+
+			TraceNumbers: []string{"987654320000001", "987654320000002"},
+		}
+		if err := repo.CreateDelivery(delivery); err != nil {
+			t.Fatal(err)
+		}
+
+		attempts := []Attempt{
+			{Attempt: 1, Error: "connection reset", Created: time.Now()},
+			{Attempt: 2, Created: time.Now()},
+		}
+		if err := repo.RecordAttempt(delivery.DeliveryID, attempts[0], Pending); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.RecordAttempt(delivery.DeliveryID, attempts[1], Delivered); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := repo.GetDelivery(delivery.DeliveryID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.Filename != delivery.Filename || found.Size != delivery.Size || found.SHA256 != delivery.SHA256 {
+			t.Fatalf("unexpected delivery: %#v", found)
+		}
+		if found.Status != Delivered {
+			t.Errorf("unexpected status: %q", found.Status)
+		}
+		if len(found.Attempts) != 2 || found.Attempts[0].Error != "connection reset" || found.Attempts[1].Error != "" {
+			t.Errorf("unexpected attempts: %#v", found.Attempts)
+		}
+
+		// list
+		deliveries, err := repo.ListDeliveries(ListParams{Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) != 1 || deliveries[0].DeliveryID != delivery.DeliveryID {
+			t.Errorf("unexpected deliveries: %#v", deliveries)
+		}
+		deliveries, err = repo.ListDeliveries(ListParams{Status: Failed, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deliveries) != 0 {
+			t.Errorf("unexpected deliveries: %#v", deliveries)
+		}
+
+		n := 0
+		query := `select count(*) from file_delivery_trace_numbers where delivery_id = ?`
+		if err := repo.db.QueryRow(query, delivery.DeliveryID).Scan(&n); err != nil || n != 2 {
+			t.Errorf("got %d trace numbers: %v", n, err)
+		}
+
+		if err := repo.UpdateStatus(delivery.DeliveryID, Requeued); err != nil {
+			t.Fatal(err)
+		}
+		if found, _ := repo.GetDelivery(delivery.DeliveryID); found.Status != Requeued || len(found.Attempts) != 2 {
+			t.Errorf("unexpected delivery: %#v", found)
+		}
+
+		// not found
+		found, err = repo.GetDelivery(base.ID())
+		if err != nil || found != nil {
+			t.Errorf("delivery=%#v error=%v", found, err)
+		}
+	}
+
+	// SQLite tests
+	sqliteDB := database.CreateTestSqliteDB(t)
+	defer sqliteDB.Close()
+	check(t, &sqlRepo{db: sqliteDB.DB})
+
+	// MySQL tests
+	mysqlDB := database.CreateTestMySQLDB(t)
+	defer mysqlDB.Close()
+	check(t, &sqlRepo{db: mysqlDB.DB})
+}
+
+func TestRepository__Runs(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		run := &Run{
+			RunID:   base.ID(),
+			ODFI:    "first",
+			Window:  "16:00",
+			Started: time.Now(),
+		}
+		if err := repo.CreateRun(run); err != nil {
+			t.Fatal(err)
+		}
+		delivery := &Delivery{
+			DeliveryID: base.ID(),
+			Filename:   "20200529-987654320-1.ach",
+			Status:     Delivered,
+			RunID:      run.RunID,
+			Created:    time.Now(),
+		}
+		if err := repo.CreateDelivery(delivery); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := repo.GetRun(run.RunID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.Window != "16:00" || found.Manual || found.Finished != nil {
+			t.Fatalf("unexpected run: %#v", found)
+		}
+		if len(found.Deliveries) != 1 || found.Deliveries[0].RunID != run.RunID {
+			t.Errorf("unexpected deliveries: %#v", found.Deliveries)
+		}
+
+		if err := repo.FinishRun(run.RunID, time.Now(), "connection reset"); err != nil {
+			t.Fatal(err)
+		}
+		found, _ = repo.GetRun(run.RunID)
+		if found.Finished == nil || found.Error != "connection reset" {
+			t.Errorf("unexpected run: %#v", found)
+		}
+
+		// list
+		runs, err := repo.ListRuns(RunParams{ODFI: "first", Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) != 1 || runs[0].RunID != run.RunID {
+			t.Errorf("unexpected runs: %#v", runs)
+		}
+		if runs, err := repo.ListRuns(RunParams{ODFI: "second", Limit: 10}); len(runs) != 0 || err != nil {
+			t.Errorf("runs=%#v error=%v", runs, err)
+		}
+
+		// not found
+		if found, err := repo.GetRun(base.ID()); found != nil || err != nil {
+			t.Errorf("run=%#v error=%v", found, err)
+		}
+	}
+
+	// SQLite tests
+	sqliteDB := database.CreateTestSqliteDB(t)
+	defer sqliteDB.Close()
+	check(t, &sqlRepo{db: sqliteDB.DB})
+
+	// MySQL tests
+	mysqlDB := database.CreateTestMySQLDB(t)
+	defer mysqlDB.Close()
+	check(t, &sqlRepo{db: mysqlDB.DB})
+}
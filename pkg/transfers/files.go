@@ -0,0 +1,348 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	moovcustomers "github.com/moov-io/customers/client"
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/memberships"
+	"github.com/moov-io/paygate/pkg/model"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/returnrates"
+	"github.com/moov-io/paygate/pkg/transfers/review"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+// CreateUserTransferFile accepts a NACHA file produced by the caller and creates a Transfer for
+// each entry which passes our checks.
+func CreateUserTransferFile(logger log.Logger, tenantRepo tenants.Repository, access memberships.Checker, limits config.Limits, files *FileProcessor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		file, err := ReadFile(r.Body)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		// Collect the CompanyIdentification and ODFI of each active Tenant the caller can create Transfers for
+		ts, err := tenantRepo.List(responder.XUserID)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		originators := make(map[string]FileOriginator)
+		for i := range ts {
+			if ts[i].Status == string(tenants.Suspended) {
+				continue
+			}
+			err := memberships.Require(access, responder.XUserID, memberships.Tenant, ts[i].TenantID, memberships.CreateTransfers)
+			if err == memberships.ErrNotMember || err == memberships.ErrForbidden {
+				continue
+			}
+			if err != nil {
+				responder.Problem(err)
+				return
+			}
+			companyID, err := tenantRepo.GetCompanyIdentification(ts[i].TenantID)
+			if err != nil {
+				responder.Problem(err)
+				return
+			}
+			originators[companyID] = FileOriginator{
+				TenantID: ts[i].TenantID,
+				ODFI:     ts[i].ODFI,
+			}
+		}
+
+		result, err := files.Process(responder.XUserID, limits, originators, file)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Log("transfers", fmt.Sprintf("created %d transfers from fileID=%s", len(result.Transfers), result.FileID))
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(result)
+		})
+	}
+}
+
+// ReadFile parses and validates a NACHA formatted file.
+func ReadFile(r io.Reader) (*ach.File, error) {
+	if r == nil {
+		return nil, errors.New("nil io.Reader")
+	}
+	file, err := ach.NewReader(r).Read()
+	if err != nil {
+		return nil, fmt.Errorf("problem reading ACH file: %v", err)
+	}
+	if err := file.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid ACH file: %v", err)
+	}
+	return &file, nil
+}
+
+// FileOriginator is the Tenant which owns a CompanyIdentification and the ODFI its Transfers are uploaded to.
+type FileOriginator struct {
+	TenantID string
+	ODFI     string
+}
+
+// FileProcessor splits uploaded NACHA files into a Transfer for each entry. Entries go through the
+// same checks as Transfers created from the API before they're published into the pipeline.
+type FileProcessor struct {
+	logger log.Logger
+	repo   Repository
+
+	tenantRepo   tenants.Repository
+	fundsChecker fundflow.FundsChecker
+	returnRates  returnrates.Checker
+	reviewRules  review.Checker
+
+	// odfis are the configured ODFIs whose Gateway each split file is addressed with
+	odfis []config.ODFI
+	pub   pipeline.XferPublisher
+}
+
+func NewFileProcessor(
+	logger log.Logger,
+	repo Repository,
+	tenantRepo tenants.Repository,
+	fundsChecker fundflow.FundsChecker,
+	returnRates returnrates.Checker,
+	reviewRules review.Checker,
+	odfis []config.ODFI,
+	pub pipeline.XferPublisher,
+) *FileProcessor {
+	return &FileProcessor{
+		logger:       logger,
+		repo:         repo,
+		tenantRepo:   tenantRepo,
+		fundsChecker: fundsChecker,
+		returnRates:  returnRates,
+		reviewRules:  reviewRules,
+		odfis:        odfis,
+		pub:          pub,
+	}
+}
+
+// fileEntry is a Transfer split from one entry of an uploaded file.
+type fileEntry struct {
+	transfer *client.Transfer
+	file     *ach.File
+	tenantID string
+	odfi     string
+
+	batchNumber int32
+	traceNumber string
+}
+
+func (e *fileEntry) rejected(reason string) client.RejectedEntry {
+	return client.RejectedEntry{
+		BatchNumber: e.batchNumber,
+		TraceNumber: e.traceNumber,
+		Error:       reason,
+	}
+}
+
+// Process splits an uploaded file into a Transfer for each entry, saves them linked to the file's ID
+// and publishes each into the pipeline. originators maps each CompanyIdentification the caller owns to
+// its Tenant. Batches whose CompanyIdentification isn't in originators are rejected along with entries
+// that exceed the configured limits or fail their checks. Uploaded files aren't kept to originate
+// once approved, so entries which would be held for review are rejected.
+func (p *FileProcessor) Process(userID string, limits config.Limits, originators map[string]FileOriginator, file *ach.File) (*client.UploadedFile, error) {
+	if file == nil {
+		return nil, errors.New("nil ACH file")
+	}
+	maxEntry, err := limits.EntryAmount()
+	if err != nil {
+		return nil, err
+	}
+	maxFile, err := limits.FileAmount()
+	if err != nil {
+		return nil, err
+	}
+	if n := len(achx.Entries(file)); limits.MaxFileEntries > 0 && n > limits.MaxFileEntries {
+		return nil, fmt.Errorf("file has %d entries which exceeds the limit of %d", n, limits.MaxFileEntries)
+	}
+	if maxFile != nil {
+		total := file.Control.TotalDebitEntryDollarAmountInFile + file.Control.TotalCreditEntryDollarAmountInFile
+		if total > maxFile.Int() {
+			return nil, fmt.Errorf("file total of %d cents exceeds the limit of %s", total, maxFile)
+		}
+	}
+
+	result := &client.UploadedFile{
+		FileID:    base.ID(),
+		Transfers: []client.Transfer{},
+		Rejected:  []client.RejectedEntry{},
+	}
+
+	var entries []*fileEntry
+	for _, batch := range file.Batches {
+		bh := batch.GetHeader()
+		originator, owned := originators[bh.CompanyIdentification]
+		owned = owned && bh.CompanyIdentification != ""
+
+		for _, ed := range batch.GetEntries() {
+			entry := &fileEntry{
+				tenantID:    originator.TenantID,
+				odfi:        originator.ODFI,
+				batchNumber: int32(bh.BatchNumber),
+				traceNumber: ed.TraceNumber,
+			}
+			if !owned {
+				result.Rejected = append(result.Rejected, entry.rejected(fmt.Sprintf("CompanyIdentification %s does not belong to an active tenant", bh.CompanyIdentification)))
+				continue
+			}
+			if maxEntry != nil && ed.Amount > maxEntry.Int() {
+				result.Rejected = append(result.Rejected, entry.rejected(fmt.Sprintf("amount exceeds the limit of %s", maxEntry)))
+				continue
+			}
+			if err := p.prepare(userID, result.FileID, bh, ed, entry); err != nil {
+				result.Rejected = append(result.Rejected, entry.rejected(err.Error()))
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	if len(entries) > 0 {
+		transfers := make([]*client.Transfer, len(entries))
+		tenantIDs := make([]string, len(entries))
+		for i := range entries {
+			transfers[i], tenantIDs[i] = entries[i].transfer, entries[i].tenantID
+		}
+		if err := p.repo.writeFileTransfers(userID, result.FileID, transfers, tenantIDs); err != nil {
+			for i := range entries {
+				releaseFunds(p.logger, p.fundsChecker, entries[i].transfer)
+			}
+			return nil, err
+		}
+	}
+	for _, entry := range entries {
+		if err := publishFiles(p.repo, p.pub, entry.odfi, entry.transfer, []*ach.File{entry.file}); err != nil {
+			// Fail the Transfer so it isn't left PENDING without files to upload
+			p.logger.Log("transfers", fmt.Sprintf("ERROR publishing transferID=%s from fileID=%s: %v", entry.transfer.TransferID, result.FileID, err))
+			if err := p.repo.UpdateTransferStatus(entry.transfer.TransferID, client.FAILED); err != nil {
+				p.logger.Log("transfers", fmt.Sprintf("ERROR failing transferID=%s: %v", entry.transfer.TransferID, err))
+			}
+			releaseFunds(p.logger, p.fundsChecker, entry.transfer)
+			result.Rejected = append(result.Rejected, entry.rejected(fmt.Sprintf("publishing: %v", err)))
+			continue
+		}
+		result.Transfers = append(result.Transfers, *entry.transfer)
+	}
+	return result, nil
+}
+
+// prepare splits the entry into its own Transfer and file and runs the entry through our checks.
+func (p *FileProcessor) prepare(userID string, fileID string, bh *ach.BatchHeader, ed *ach.EntryDetail, entry *fileEntry) error {
+	odfi, err := findODFI(p.odfis, entry.odfi)
+	if err != nil {
+		return err
+	}
+	entry.transfer, entry.file, err = splitEntry(fileID, *odfi, bh, ed)
+	if err != nil {
+		return err
+	}
+	if err := checkTenant(p.tenantRepo, entry.tenantID, entry.transfer); err != nil {
+		return err
+	}
+	reasons, err := checkTransfer(userID, entry.tenantID, entry.transfer, entrySource(*odfi, bh.CompanyIdentification, ed), p.fundsChecker, p.returnRates, p.reviewRules)
+	if err != nil {
+		releaseFunds(p.logger, p.fundsChecker, entry.transfer)
+		return err
+	}
+	if entry.transfer.Status != client.PENDING {
+		releaseFunds(p.logger, p.fundsChecker, entry.transfer)
+		return fmt.Errorf("held for review: %s", strings.Join(reasons, ", "))
+	}
+	return nil
+}
+
+// findODFI returns the named ODFI, or the first ODFI when name is empty.
+func findODFI(odfis []config.ODFI, name string) (*config.ODFI, error) {
+	if len(odfis) == 0 {
+		return nil, errors.New("no ODFIs configured")
+	}
+	cfg := &config.Config{ODFIs: odfis}
+	return cfg.FindODFI(name)
+}
+
+// entrySource is the account an entry draws funds from for the funds check. Debits pull funds from
+// the receiver so only credits are checked. Files don't carry the originator's account, so credits
+// draw from the account at the ODFI identified by the batch's CompanyIdentification.
+func entrySource(odfi config.ODFI, companyID string, ed *ach.EntryDetail) fundflow.Source {
+	if ed.CreditOrDebit() != "C" {
+		return fundflow.Source{}
+	}
+	return fundflow.Source{
+		Account: moovcustomers.Account{
+			AccountID:     companyID,
+			RoutingNumber: odfi.RoutingNumber,
+		},
+	}
+}
+
+// splitEntry creates a Transfer and ACH file holding only the given entry so each Transfer can
+// be merged (or canceled) on its own. Files are addressed to the ODFI rather than copying the
+// uploaded file's header.
+func splitEntry(fileID string, odfi config.ODFI, bh *ach.BatchHeader, entry *ach.EntryDetail) (*client.Transfer, *ach.File, error) {
+	amount, err := model.NewAmountFromInt("USD", entry.Amount)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fileID=%s: %v", fileID, err)
+	}
+	xfer := &client.Transfer{
+		TransferID:  base.ID(),
+		Amount:      amount.String(),
+		Description: bh.CompanyEntryDescription,
+		Status:      client.PENDING,
+		Created:     time.Now(),
+	}
+
+	file := ach.NewFile()
+	file.ID = xfer.TransferID
+	file.Header = achx.FileHeader(xfer.TransferID, odfi)
+
+	// Entries are originated from our ODFI with our own trace numbers so they can't collide
+	// with entries uploaded by another customer.
+	header := *bh
+	header.ID = xfer.TransferID
+	header.ODFIIdentification = achx.ABA8(odfi.RoutingNumber)
+	batch, err := ach.NewBatch(&header)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fileID=%s: %v", fileID, err)
+	}
+	ed := *entry
+	ed.TraceNumber = achx.TraceNumber(odfi.RoutingNumber)
+	batch.AddEntry(&ed)
+	if err := batch.Create(); err != nil {
+		return nil, nil, err
+	}
+	file.AddBatch(batch)
+	if err := file.Create(); err != nil {
+		return nil, nil, err
+	}
+	return xfer, file, nil
+}
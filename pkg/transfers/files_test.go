@@ -0,0 +1,353 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/memberships"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/review"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+func readPPDDebit(t *testing.T) *ach.File {
+	t.Helper()
+
+	fd, err := os.Open(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	file, err := ReadFile(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+var fileODFIs = []config.ODFI{
+	{
+		RoutingNumber: "987654320",
+		Gateway: config.Gateway{
+			Origin:          "987654320",
+			OriginName:      "My Bank",
+			Destination:     "076401251",
+			DestinationName: "Federal Reserve",
+		},
+	},
+}
+
+func testFileProcessor(repo Repository, tenantRepo tenants.Repository) *FileProcessor {
+	return NewFileProcessor(log.NewNopLogger(), repo, tenantRepo, nil, nil, nil, fileODFIs, fakePublisher)
+}
+
+func TestFiles__ReadFile(t *testing.T) {
+	if _, err := ReadFile(nil); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := ReadFile(strings.NewReader("invalid")); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestFiles__ProcessFile(t *testing.T) {
+	repo := &MockRepository{}
+	file := readPPDDebit(t)
+
+	result, err := testFileProcessor(repo, nil).Process("userID", config.Limits{}, map[string]FileOriginator{"origid": {}}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.FileID == "" || len(result.Transfers) != 1 || len(result.Rejected) != 0 {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+	if xfer := result.Transfers[0]; xfer.Amount != "USD 105.00" || xfer.Status != client.PENDING {
+		t.Errorf("unexpected transfer: %#v", xfer)
+	}
+
+	// CompanyIdentification belongs to someone else
+	result, err = testFileProcessor(repo, nil).Process("userID", config.Limits{}, map[string]FileOriginator{"other": {}}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Transfers) != 0 || len(result.Rejected) != 1 {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+	if rej := result.Rejected[0]; rej.BatchNumber != 1 || rej.TraceNumber != "076401255655291" {
+		t.Errorf("unexpected rejection: %#v", rej)
+	}
+
+	// entries from suspended Tenants are rejected
+	tenantRepo := &tenants.MockRepository{
+		Tenants: []client.Tenant{
+			{TenantID: "tenantID", Status: string(tenants.Suspended)},
+		},
+	}
+	originators := map[string]FileOriginator{
+		"origid": {TenantID: "tenantID"},
+	}
+	result, err = testFileProcessor(repo, tenantRepo).Process("userID", config.Limits{}, originators, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Transfers) != 0 || len(result.Rejected) != 1 {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+}
+
+func TestFiles__ProcessFileHeader(t *testing.T) {
+	pub := &pipeline.MockPublisher{}
+	files := NewFileProcessor(log.NewNopLogger(), &MockRepository{}, nil, nil, nil, nil, fileODFIs, pub)
+
+	result, err := files.Process("userID", config.Limits{}, map[string]FileOriginator{"origid": {}}, readPPDDebit(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Transfers) != 1 || len(pub.Xfers) != 1 {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+
+	// split files are addressed to the ODFI rather than with the uploaded header
+	fh := pub.Xfers[0].File.Header
+	if fh.ImmediateOrigin != "987654320" || fh.ImmediateOriginName != "My Bank" || fh.ImmediateDestinationName != "Federal Reserve" {
+		t.Errorf("unexpected file header: %#v", fh)
+	}
+	if fh.ID != result.Transfers[0].TransferID {
+		t.Errorf("unexpected file header ID: %s", fh.ID)
+	}
+
+	// entries are originated from our ODFI with our own trace numbers
+	batch := pub.Xfers[0].File.Batches[0]
+	if odfi := batch.GetHeader().ODFIIdentification; odfi != "98765432" {
+		t.Errorf("unexpected ODFIIdentification: %s", odfi)
+	}
+	entry := batch.GetEntries()[0]
+	if entry.TraceNumber == "076401255655291" || !strings.HasPrefix(entry.TraceNumber, "98765432") {
+		t.Errorf("unexpected trace number: %s", entry.TraceNumber)
+	}
+
+	// unknown ODFIs reject their entries
+	result, err = files.Process("userID", config.Limits{}, map[string]FileOriginator{"origid": {ODFI: "other"}}, readPPDDebit(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Transfers) != 0 || len(result.Rejected) != 1 {
+		t.Errorf("unexpected result: %#v", result)
+	}
+}
+
+func TestFiles__ProcessFileChecks(t *testing.T) {
+	file := readPPDDebit(t)
+	originators := map[string]FileOriginator{"origid": {}}
+
+	// entries which would be held for review are rejected
+	fundsChecker := &fundflow.MockFundsChecker{}
+	reviewRules := &review.MockChecker{Reasons: []string{"amount is at or above USD 100.00"}}
+	files := NewFileProcessor(log.NewNopLogger(), &MockRepository{}, nil, fundsChecker, nil, reviewRules, fileODFIs, fakePublisher)
+	result, err := files.Process("userID", config.Limits{}, originators, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Transfers) != 0 || len(result.Rejected) != 1 {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+	if rej := result.Rejected[0]; !strings.Contains(rej.Error, "amount is at or above") {
+		t.Errorf("unexpected rejection: %#v", rej)
+	}
+	if len(fundsChecker.Released) != 1 {
+		t.Errorf("unexpected released funds: %v", fundsChecker.Released)
+	}
+
+	// paused originators
+	files = NewFileProcessor(log.NewNopLogger(), &MockRepository{}, nil, nil, &pausedOriginators{}, nil, fileODFIs, fakePublisher)
+	result, err = files.Process("userID", config.Limits{}, originators, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Transfers) != 0 || len(result.Rejected) != 1 {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+
+	// Transfers which fail to publish are failed and reported
+	fundsChecker = &fundflow.MockFundsChecker{}
+	pub := &pipeline.MockPublisher{Err: errors.New("bad error")}
+	files = NewFileProcessor(log.NewNopLogger(), &MockRepository{}, nil, fundsChecker, nil, nil, fileODFIs, pub)
+	result, err = files.Process("userID", config.Limits{}, originators, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Transfers) != 0 || len(result.Rejected) != 1 {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+	if rej := result.Rejected[0]; rej.TraceNumber != "076401255655291" || !strings.Contains(rej.Error, "bad error") {
+		t.Errorf("unexpected rejection: %#v", rej)
+	}
+	if len(fundsChecker.Released) != 1 {
+		t.Errorf("unexpected released funds: %v", fundsChecker.Released)
+	}
+}
+
+func TestFiles__entrySource(t *testing.T) {
+	ed := ach.NewEntryDetail()
+	ed.TransactionCode = ach.CheckingDebit
+	if src := entrySource(fileODFIs[0], "origid", ed); src.Account.RoutingNumber != "" {
+		t.Errorf("unexpected source: %#v", src)
+	}
+	ed.TransactionCode = ach.CheckingCredit
+	if src := entrySource(fileODFIs[0], "origid", ed); src.Account.AccountID != "origid" || src.Account.RoutingNumber != "987654320" {
+		t.Errorf("unexpected source: %#v", src)
+	}
+}
+
+func TestFiles__ProcessFileLimits(t *testing.T) {
+	repo := &MockRepository{}
+	file := readPPDDebit(t)
+
+	// entry over the limit
+	limits := config.Limits{MaxEntryAmount: "USD 100.00"}
+	result, err := testFileProcessor(repo, nil).Process("userID", limits, map[string]FileOriginator{"origid": {}}, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Transfers) != 0 || len(result.Rejected) != 1 {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+
+	// whole file is rejected
+	limits = config.Limits{MaxFileAmount: "USD 50.00"}
+	if _, err := testFileProcessor(repo, nil).Process("userID", limits, map[string]FileOriginator{"origid": {}}, file); err == nil {
+		t.Error("expected error")
+	}
+	limits = config.Limits{MaxFileEntries: 0}
+	if _, err := testFileProcessor(repo, nil).Process("userID", limits, map[string]FileOriginator{"origid": {}}, file); err != nil {
+		t.Error(err)
+	}
+
+	// addenda records aren't counted as entries
+	file = readPPDDebit(t)
+	entry := file.Batches[0].GetEntries()[0]
+	addenda := ach.NewAddenda05()
+	addenda.PaymentRelatedInformation = "invoice 1234"
+	entry.AddAddenda05(addenda)
+	entry.AddendaRecordIndicator = 1
+	if err := file.Batches[0].Create(); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Create(); err != nil {
+		t.Fatal(err)
+	}
+	if file.Control.EntryAddendaCount != 2 {
+		t.Fatalf("EntryAddendaCount=%d", file.Control.EntryAddendaCount)
+	}
+	limits = config.Limits{MaxFileEntries: 1}
+	if _, err := testFileProcessor(repo, nil).Process("userID", limits, map[string]FileOriginator{"origid": {}}, file); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRouter__createUserTransferFile(t *testing.T) {
+	customersClient := mockCustomersClient()
+	tenantID := base.ID()
+	tenantRepo := &tenants.MockRepository{
+		Tenants: []client.Tenant{
+			{TenantID: tenantID},
+		},
+		CompanyIdentification: "origid",
+	}
+	access := &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			{Kind: memberships.Tenant, ResourceID: tenantID, UserID: "userID", Role: memberships.Admin},
+			{Kind: memberships.Tenant, ResourceID: tenantID, UserID: "finance", Role: memberships.Viewer},
+		},
+	}
+
+	r := mux.NewRouter()
+	router := NewRouter(log.NewNopLogger(), &MockRepository{}, tenantRepo, access, customersClient, mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, fileODFIs, config.Limits{}, fakePublisher)
+	router.RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	bs, err := ioutil.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, resp, err := c.TransfersApi.UploadTransferFile(context.TODO(), "userID", string(bs), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if len(result.Transfers) != 1 || len(result.Rejected) != 0 {
+		t.Errorf("unexpected result: %#v", result)
+	}
+
+	// Viewers can't create Transfers from the Tenant's batches
+	result, resp, err = c.TransfersApi.UploadTransferFile(context.TODO(), "finance", string(bs), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(result.Transfers) != 0 || len(result.Rejected) != 1 {
+		t.Errorf("unexpected result: %#v", result)
+	}
+
+	// invalid file
+	_, resp, err = c.TransfersApi.UploadTransferFile(context.TODO(), "userID", "invalid", nil)
+	if err == nil {
+		t.Error("expected error")
+	}
+	resp.Body.Close()
+}
+
+func TestRepository__writeFileTransfers(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID, fileID := base.ID(), base.ID()
+		xfer := &client.Transfer{
+			TransferID:  base.ID(),
+			Amount:      "USD 105.00",
+			Description: "CHECKPAYMT",
+			Status:      client.PENDING,
+		}
+		if err := repo.writeFileTransfers(userID, fileID, []*client.Transfer{xfer}, []string{"tenantID"}); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := repo.GetTransfer(xfer.TransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.Amount != xfer.Amount {
+			t.Errorf("unexpected transfer: %#v", found)
+		}
+
+		var storedID string
+		if err := repo.db.QueryRow(`select file_id from transfers where transfer_id = ?`, xfer.TransferID).Scan(&storedID); err != nil {
+			t.Fatal(err)
+		}
+		if storedID != fileID {
+			t.Errorf("unexpected fileID: %q", storedID)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
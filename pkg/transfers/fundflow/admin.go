@@ -0,0 +1,97 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	moovadmin "github.com/moov-io/base/admin"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/model"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+// RegisterAdminRoutes will add HTTP handlers for paygate's admin HTTP server. They manage
+// the balances of the SQL ledger used to check funds.
+func RegisterAdminRoutes(logger log.Logger, svc *moovadmin.Server, db *sql.DB) {
+	ledger := &sqlLedger{db: db}
+	svc.AddHandler("/ledger/accounts/{accountId}", getLedgerAccount(logger, ledger))
+	svc.AddHandler("/ledger/accounts/{accountId}/credits", creditLedgerAccount(logger, ledger))
+}
+
+func getAccountID(r *http.Request) string {
+	return route.ReadPathID("accountId", r)
+}
+
+func getLedgerAccount(logger log.Logger, ledger *sqlLedger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+		respondWithBalance(responder, ledger, getAccountID(r))
+	}
+}
+
+// creditLedgerAccount adds funds to an account, such as after they're deposited at the ODFI.
+func creditLedgerAccount(logger log.Logger, ledger *sqlLedger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		accountID := getAccountID(r)
+		if accountID == "" {
+			responder.Problem(errors.New("missing accountID"))
+			return
+		}
+		var req admin.CreditLedgerAccount
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			responder.Problem(err)
+			return
+		}
+		var amount model.Amount
+		if err := amount.FromString(req.Amount); err != nil {
+			responder.Problem(err)
+			return
+		}
+		if err := ledger.credit(accountID, amount); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Log("fundflow", fmt.Sprintf("credited %s to accountID=%s", amount.String(), accountID))
+		respondWithBalance(responder, ledger, accountID)
+	}
+}
+
+func respondWithBalance(responder *route.Responder, ledger *sqlLedger, accountID string) {
+	balance, err := ledger.balance(accountID)
+	if err != nil {
+		responder.Problem(err)
+		return
+	}
+	amount, err := model.NewAmountFromInt("USD", balance)
+	if err != nil {
+		responder.Problem(fmt.Errorf("accountID=%s balance: %v", accountID, err))
+		return
+	}
+	responder.Respond(func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(admin.LedgerAccount{
+			AccountID: accountID,
+			Balance:   amount.String(),
+		})
+	})
+}
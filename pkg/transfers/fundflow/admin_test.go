@@ -0,0 +1,91 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestAdmin__creditLedgerAccount(t *testing.T) {
+	check := func(t *testing.T, db *sql.DB) {
+		svc, c := testclient.Admin(t)
+		RegisterAdminRoutes(log.NewNopLogger(), svc, db)
+
+		source := odfiSource()
+		accountID := source.Account.AccountID
+
+		acct, resp, err := c.LedgerApi.GetLedgerAccount(context.TODO(), accountID, "userID", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if acct.AccountID != accountID || acct.Balance != "USD 0.00" {
+			t.Errorf("unexpected account: %#v", acct)
+		}
+
+		req := admin.CreditLedgerAccount{Amount: "USD 20.00"}
+		acct, resp, err = c.LedgerApi.CreditLedgerAccount(context.TODO(), accountID, "userID", req, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if acct.Balance != "USD 20.00" {
+			t.Errorf("unexpected account: %#v", acct)
+		}
+
+		// credited funds can be held by Transfers
+		cfg := config.Empty()
+		cfg.ODFI = thirdPartyODFI
+		cfg.Fundflow.FundsCheck = &config.FundsCheck{
+			SQL: &config.SQLFundsCheck{},
+		}
+		checker, err := NewFundsChecker(log.NewNopLogger(), cfg, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status, err := checker.Check(thirdPartyTransfer(), source); err != nil || status != client.PENDING {
+			t.Fatalf("status=%q error=%v", status, err)
+		}
+		acct, resp, err = c.LedgerApi.GetLedgerAccount(context.TODO(), accountID, "userID", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if acct.Balance != "USD 7.56" {
+			t.Errorf("unexpected account: %#v", acct)
+		}
+
+		// only positive amounts can be credited
+		for _, amount := range []string{"USD 0.00", "12.00", ""} {
+			req := admin.CreditLedgerAccount{Amount: amount}
+			_, resp, _ = c.LedgerApi.CreditLedgerAccount(context.TODO(), base.ID(), "userID", req, nil)
+			if resp == nil || resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("amount=%q unexpected response: %#v", amount, resp)
+			}
+		}
+	}
+
+	// SQLite tests
+	sqliteDB := database.CreateTestSqliteDB(t)
+	defer sqliteDB.Close()
+	check(t, sqliteDB.DB)
+
+	// MySQL tests
+	mysqlDB := database.CreateTestMySQLDB(t)
+	defer mysqlDB.Close()
+	check(t, mysqlDB.DB)
+}
@@ -56,6 +56,6 @@ func (fp *FirstParty) Originate(companyID string, xfer *client.Transfer, src Sou
 	return []*ach.File{file}, err
 }
 
-func (fp *FirstParty) HandleReturn(returned *ach.File, xfer *client.Transfer) ([]*ach.File, error) {
+func (fp *FirstParty) HandleReturn(companyID string, entry *ach.EntryDetail, xfer *client.Transfer) ([]*ach.File, error) {
 	return nil, nil
 }
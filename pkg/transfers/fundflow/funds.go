@@ -0,0 +1,314 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/model"
+
+	"github.com/go-kit/kit/log"
+)
+
+var (
+	// ErrInsufficientFunds is returned when the source account can't cover a Transfer
+	ErrInsufficientFunds = errors.New("insufficient funds")
+)
+
+// FundsChecker verifies an ODFI account has the available balance to originate a credit
+// prior to files being created for a Transfer.
+//
+// Check returns the status the Transfer should be saved with. ErrInsufficientFunds is
+// returned when the Transfer should be rejected.
+//
+// Release frees the funds held for a Transfer which won't be originated.
+type FundsChecker interface {
+	Check(xfer *client.Transfer, source Source) (client.TransferStatus, error)
+	Release(xfer *client.Transfer) error
+}
+
+// NewFundsChecker returns the FundsChecker chosen in the Config. Every Transfer is allowed
+// when no funds check is configured.
+func NewFundsChecker(logger log.Logger, cfg *config.Config, db *sql.DB) (FundsChecker, error) {
+	if cfg == nil {
+		return nil, errors.New("nil Config")
+	}
+	fc := cfg.Fundflow.FundsCheck
+	if fc == nil {
+		return &AlwaysAllow{}, nil
+	}
+
+	var checker balanceChecker
+	switch {
+	case fc.SQL != nil:
+		if db == nil {
+			return nil, errors.New("sql funds check: nil *sql.DB")
+		}
+		checker = &sqlLedger{db: db}
+
+	case fc.HTTP != nil:
+		checker = &httpBalance{
+			endpoint: fc.HTTP.Endpoint,
+			client: &http.Client{
+				Timeout: fc.HTTP.RequestTimeout(),
+			},
+		}
+
+	default:
+		return &AlwaysAllow{}, nil
+	}
+
+	return &odfiFundsChecker{
+		routingNumbers: odfiRoutingNumbers(cfg),
+		review:         fc.Review(),
+		checker:        checker,
+		logger:         logger,
+	}, nil
+}
+
+// AlwaysAllow is a FundsChecker which never holds or rejects a Transfer.
+type AlwaysAllow struct{}
+
+func (*AlwaysAllow) Check(xfer *client.Transfer, source Source) (client.TransferStatus, error) {
+	return client.PENDING, nil
+}
+
+func (*AlwaysAllow) Release(xfer *client.Transfer) error {
+	return nil
+}
+
+// balanceChecker reports if an account has enough funds for the given amount. When
+// funds are available implementations should reserve them against the Transfer.
+type balanceChecker interface {
+	sufficient(xfer *client.Transfer, source Source, amount model.Amount) (bool, error)
+	release(xfer *client.Transfer) error
+}
+
+// odfiFundsChecker only checks Transfers which credit out of an account held at the ODFI
+// and applies the configured policy when funds are short.
+type odfiFundsChecker struct {
+	routingNumbers []string
+	review         bool
+	checker        balanceChecker
+	logger         log.Logger
+}
+
+func odfiRoutingNumbers(cfg *config.Config) []string {
+	var out []string
+	for _, odfi := range cfg.ListODFIs() {
+		out = append(out, odfi.RoutingNumber)
+	}
+	return out
+}
+
+func (c *odfiFundsChecker) heldAtODFI(source Source) bool {
+	for i := range c.routingNumbers {
+		if source.Account.RoutingNumber == c.routingNumbers[i] {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *odfiFundsChecker) Check(xfer *client.Transfer, source Source) (client.TransferStatus, error) {
+	if xfer == nil {
+		return "", errors.New("nil Transfer")
+	}
+	if !c.heldAtODFI(source) {
+		return client.PENDING, nil // debiting a remote account
+	}
+
+	var amt model.Amount
+	if err := amt.FromString(xfer.Amount); err != nil {
+		return "", fmt.Errorf("unable to parse '%s': %v", xfer.Amount, err)
+	}
+	ok, err := c.checker.sufficient(xfer, source, amt)
+	if err != nil {
+		return "", fmt.Errorf("funds check: transferID=%s: %v", xfer.TransferID, err)
+	}
+	if ok {
+		return client.PENDING, nil
+	}
+
+	c.logger.Log(
+		"fundflow", fmt.Sprintf("insufficient funds for transferID=%s", xfer.TransferID),
+		"accountID", source.Account.AccountID)
+
+	if c.review {
+		return client.REVIEWABLE, nil
+	}
+	return "", ErrInsufficientFunds
+}
+
+func (c *odfiFundsChecker) Release(xfer *client.Transfer) error {
+	if xfer == nil {
+		return errors.New("nil Transfer")
+	}
+	if err := c.checker.release(xfer); err != nil {
+		return fmt.Errorf("releasing funds: transferID=%s: %v", xfer.TransferID, err)
+	}
+	return nil
+}
+
+// sqlLedger reads balances from the ledger_entries table. Balances are the sum of each
+// account's entries. Admins credit accounts with positive entries and a negative entry is
+// written to hold funds for each Transfer.
+type sqlLedger struct {
+	db *sql.DB
+}
+
+func (l *sqlLedger) balance(accountID string) (int, error) {
+	query := `select coalesce(sum(amount), 0) from ledger_entries where account_id = ?;`
+	stmt, err := l.db.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var balance int
+	if err := stmt.QueryRow(accountID).Scan(&balance); err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// credit adds funds to an account which Transfers can then hold.
+func (l *sqlLedger) credit(accountID string, amount model.Amount) error {
+	if amount.Int() <= 0 {
+		return fmt.Errorf("credit amount must be positive: %s", amount.String())
+	}
+	query := `insert into ledger_entries (entry_id, account_id, transfer_id, amount, created_at) values (?, ?, ?, ?, ?);`
+	stmt, err := l.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(base.ID(), accountID, "", amount.Int(), time.Now())
+	return err
+}
+
+// sufficient reads the balance and holds funds in a serializable transaction, so concurrent
+// Transfers from one account can't both spend the same balance. Checking a Transfer again
+// replaces its hold.
+func (l *sqlLedger) sufficient(xfer *client.Transfer, source Source, amount model.Amount) (bool, error) {
+	tx, err := l.db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return false, err
+	}
+
+	query := `delete from ledger_entries where transfer_id = ? and amount < 0;`
+	if _, err := tx.Exec(query, xfer.TransferID); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	query = `select coalesce(sum(amount), 0) from ledger_entries where account_id = ?;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	defer stmt.Close()
+
+	var balance int
+	if err := stmt.QueryRow(source.Account.AccountID).Scan(&balance); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	if balance < amount.Int() {
+		return false, tx.Rollback()
+	}
+
+	query = `insert into ledger_entries (entry_id, account_id, transfer_id, amount, created_at) values (?, ?, ?, ?, ?);`
+	insert, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	defer insert.Close()
+
+	if _, err := insert.Exec(base.ID(), source.Account.AccountID, xfer.TransferID, -1*amount.Int(), time.Now()); err != nil {
+		tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (l *sqlLedger) release(xfer *client.Transfer) error {
+	query := `delete from ledger_entries where transfer_id = ? and amount < 0;`
+	_, err := l.db.Exec(query, xfer.TransferID)
+	return err
+}
+
+// httpBalance asks a core banking system if an account can cover the Transfer.
+type httpBalance struct {
+	endpoint string
+	client   *http.Client
+}
+
+type balanceRequest struct {
+	TransferID    string `json:"transferID"`
+	CustomerID    string `json:"customerID"`
+	AccountID     string `json:"accountID"`
+	RoutingNumber string `json:"routingNumber"`
+	AccountNumber string `json:"accountNumber"`
+	Amount        string `json:"amount"`
+}
+
+type balanceResponse struct {
+	Sufficient bool `json:"sufficient"`
+}
+
+func (h *httpBalance) sufficient(xfer *client.Transfer, source Source, amount model.Amount) (bool, error) {
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(balanceRequest{
+		TransferID:    xfer.TransferID,
+		CustomerID:    source.Customer.CustomerID,
+		AccountID:     source.Account.AccountID,
+		RoutingNumber: source.Account.RoutingNumber,
+		AccountNumber: source.AccountNumber,
+		Amount:        amount.String(),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest("POST", h.endpoint, &buf)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected HTTP status: %s", resp.Status)
+	}
+	var response balanceResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return false, err
+	}
+	return response.Sufficient, nil
+}
+
+// release does nothing as the core banking system manages its own holds.
+func (h *httpBalance) release(xfer *client.Transfer) error {
+	return nil
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/model"
+
+	"github.com/go-kit/kit/log"
+)
+
+func odfiSource() Source {
+	cust, acct := thirdPartyRemote(thirdPartyODFI.RoutingNumber)
+	return Source{Customer: cust, Account: acct, AccountNumber: "12345"}
+}
+
+func TestFundsChecker__AlwaysAllow(t *testing.T) {
+	checker, err := NewFundsChecker(log.NewNopLogger(), config.Empty(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := checker.Check(thirdPartyTransfer(), odfiSource())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != client.PENDING {
+		t.Errorf("unexpected status: %q", status)
+	}
+}
+
+func TestFundsChecker__SQL(t *testing.T) {
+	check := func(t *testing.T, db *sql.DB) {
+		cfg := config.Empty()
+		cfg.ODFI = thirdPartyODFI
+		cfg.Fundflow.FundsCheck = &config.FundsCheck{
+			SQL: &config.SQLFundsCheck{},
+		}
+		checker, err := NewFundsChecker(log.NewNopLogger(), cfg, db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		source := odfiSource()
+		xfer := thirdPartyTransfer() // USD 12.44
+
+		// no balance
+		if _, err := checker.Check(xfer, source); err != ErrInsufficientFunds {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// deposit funds and check again
+		query := `insert into ledger_entries (entry_id, account_id, transfer_id, amount, created_at) values (?, ?, ?, ?, ?);`
+		if _, err := db.Exec(query, base.ID(), source.Account.AccountID, "", 2000, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		status, err := checker.Check(xfer, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != client.PENDING {
+			t.Errorf("unexpected status: %q", status)
+		}
+
+		// funds were held, so a second transfer is short
+		cfg.Fundflow.FundsCheck.Insufficient = "review"
+		checker, _ = NewFundsChecker(log.NewNopLogger(), cfg, db)
+		status, err = checker.Check(thirdPartyTransfer(), source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != client.REVIEWABLE {
+			t.Errorf("unexpected status: %q", status)
+		}
+
+		// checking the Transfer again replaces its hold
+		if status, err := checker.Check(xfer, source); err != nil || status != client.PENDING {
+			t.Errorf("status=%q error=%v", status, err)
+		}
+
+		// releasing the hold frees funds for another Transfer
+		if err := checker.Release(xfer); err != nil {
+			t.Fatal(err)
+		}
+		status, err = checker.Check(thirdPartyTransfer(), source)
+		if err != nil || status != client.PENDING {
+			t.Errorf("status=%q error=%v", status, err)
+		}
+
+		// remote accounts aren't checked
+		status, err = checker.Check(thirdPartyTransfer(), Source{})
+		if err != nil || status != client.PENDING {
+			t.Errorf("status=%q error=%v", status, err)
+		}
+	}
+
+	// SQLite tests
+	sqliteDB := database.CreateTestSqliteDB(t)
+	defer sqliteDB.Close()
+	check(t, sqliteDB.DB)
+
+	// MySQL tests
+	mysqlDB := database.CreateTestMySQLDB(t)
+	defer mysqlDB.Close()
+	check(t, mysqlDB.DB)
+}
+
+func TestFundsChecker__SQLConcurrent(t *testing.T) {
+	check := func(t *testing.T, db *sql.DB) {
+		ledger := &sqlLedger{db: db}
+		source := odfiSource()
+		amount, _ := model.NewAmountFromInt("USD", 1500)
+
+		query := `insert into ledger_entries (entry_id, account_id, transfer_id, amount, created_at) values (?, ?, ?, ?, ?);`
+		if _, err := db.Exec(query, base.ID(), source.Account.AccountID, "", 2000, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		// only one Transfer can hold the balance, others are short or fail
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		held := 0
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok, _ := ledger.sufficient(thirdPartyTransfer(), source, *amount)
+				if ok {
+					mu.Lock()
+					held++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if held != 1 {
+			t.Errorf("%d transfers held funds", held)
+		}
+	}
+
+	// SQLite tests
+	sqliteDB := database.CreateTestSqliteDB(t)
+	defer sqliteDB.Close()
+	check(t, sqliteDB.DB)
+
+	// MySQL tests
+	mysqlDB := database.CreateTestMySQLDB(t)
+	defer mysqlDB.Close()
+	check(t, mysqlDB.DB)
+}
+
+func TestFundsChecker__HTTP(t *testing.T) {
+	var sufficient bool
+	svc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req balanceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Amount != "USD 12.44" || req.AccountNumber != "12345" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(balanceResponse{Sufficient: sufficient})
+	}))
+	defer svc.Close()
+
+	cfg := config.Empty()
+	cfg.ODFI = thirdPartyODFI
+	cfg.Fundflow.FundsCheck = &config.FundsCheck{
+		HTTP: &config.HTTPFundsCheck{
+			Endpoint: svc.URL,
+		},
+	}
+	checker, err := NewFundsChecker(log.NewNopLogger(), cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := checker.Check(thirdPartyTransfer(), odfiSource()); err != ErrInsufficientFunds {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sufficient = true
+	status, err := checker.Check(thirdPartyTransfer(), odfiSource())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != client.PENDING {
+		t.Errorf("unexpected status: %q", status)
+	}
+}
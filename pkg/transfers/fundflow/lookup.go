@@ -0,0 +1,75 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"fmt"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+)
+
+// GetSource reads the Customer and Account from the customers service for a Transfer's source
+// and decrypts the account number.
+func GetSource(client customers.Client, accountDecryptor accounts.Decryptor, src client.Source) (Source, error) {
+	var source Source
+
+	// Set source Customer
+	cust, err := client.Lookup(src.CustomerID, "requestID", "userID")
+	if err != nil {
+		return source, err
+	}
+	if cust == nil {
+		return source, fmt.Errorf("customerID=%s is not found", src.CustomerID)
+	}
+	source.Customer = *cust
+
+	// Get customer Account
+	if acct, err := client.FindAccount(src.CustomerID, src.AccountID); acct == nil || err != nil {
+		return source, fmt.Errorf("accountID=%s not found for customerID=%s error=%v", src.AccountID, src.CustomerID, err)
+	} else {
+		source.Account = *acct
+	}
+
+	if num, err := accountDecryptor.AccountNumber(src.CustomerID, src.AccountID); num == "" || err != nil {
+		return source, fmt.Errorf("unable to decrypt accountID=%s for customerID=%s error=%v", src.AccountID, src.CustomerID, err)
+	} else {
+		source.AccountNumber = num
+	}
+
+	return source, nil
+}
+
+// GetDestination reads the Customer and Account from the customers service for a Transfer's
+// destination and decrypts the account number.
+func GetDestination(client customers.Client, accountDecryptor accounts.Decryptor, dst client.Destination) (Destination, error) {
+	var destination Destination
+
+	// Set destination Customer
+	cust, err := client.Lookup(dst.CustomerID, "requestID", "userID")
+	if err != nil {
+		return destination, err
+	}
+	if cust == nil {
+		return destination, fmt.Errorf("customerID=%s is not found", dst.CustomerID)
+	}
+	destination.Customer = *cust
+
+	// Get customer Account
+	if acct, err := client.FindAccount(dst.CustomerID, dst.AccountID); acct == nil || err != nil {
+		return destination, fmt.Errorf("accountID=%s not found for customerID=%s error=%v", dst.AccountID, dst.CustomerID, err)
+	} else {
+		destination.Account = *acct
+	}
+
+	if num, err := accountDecryptor.AccountNumber(dst.CustomerID, dst.AccountID); num == "" || err != nil {
+		return destination, fmt.Errorf("unable to decrypt accountID=%s for customerID=%s error=%v", dst.AccountID, dst.CustomerID, err)
+	} else {
+		destination.AccountNumber = num
+	}
+
+	return destination, nil
+}
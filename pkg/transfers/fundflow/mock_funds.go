@@ -0,0 +1,35 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type MockFundsChecker struct {
+	Status client.TransferStatus
+	Err    error
+
+	// Released holds the TransferID of each released Transfer
+	Released []string
+}
+
+func (c *MockFundsChecker) Check(xfer *client.Transfer, source Source) (client.TransferStatus, error) {
+	if c.Err != nil {
+		return "", c.Err
+	}
+	if c.Status == "" {
+		return client.PENDING, nil
+	}
+	return c.Status, nil
+}
+
+func (c *MockFundsChecker) Release(xfer *client.Transfer) error {
+	if c.Err != nil {
+		return c.Err
+	}
+	c.Released = append(c.Released, xfer.TransferID)
+	return nil
+}
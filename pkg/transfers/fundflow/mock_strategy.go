@@ -21,7 +21,7 @@ func (s *MockStrategy) Originate(companyID string, xfer *client.Transfer, source
 	return s.Files, nil
 }
 
-func (s *MockStrategy) HandleReturn(returned *ach.File, xfer *client.Transfer) ([]*ach.File, error) {
+func (s *MockStrategy) HandleReturn(companyID string, entry *ach.EntryDetail, xfer *client.Transfer) ([]*ach.File, error) {
 	if s.Err != nil {
 		return nil, s.Err
 	}
@@ -5,19 +5,40 @@
 package fundflow
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/moov-io/ach"
 	customers "github.com/moov-io/customers/client"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	moovcustomers "github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+
+	"github.com/go-kit/kit/log"
 )
 
 type Strategy interface {
 	Originate(companyID string, xfer *client.Transfer, source Source, destination Destination) ([]*ach.File, error)
-	HandleReturn(returned *ach.File, xfer *client.Transfer) ([]*ach.File, error)
+	// HandleReturn is given each returned entry of a Transfer, along with the company
+	// identification of its batch, and returns any files to originate in response.
+	HandleReturn(companyID string, entry *ach.EntryDetail, xfer *client.Transfer) ([]*ach.File, error)
+}
+
+// Holder is implemented by Strategies which hold a leg of each Transfer until the funds for it
+// are collected. Hold returns the file to originate once the files from Originate can no longer
+// be returned and when that is. Transfers which are returned before then never release the file.
+type Holder interface {
+	Hold(companyID string, xfer *client.Transfer, source Source, destination Destination) (*ach.File, time.Time, error)
 }
 
 type Source struct {
 	Customer customers.Customer
 	Account  customers.Account
+
+	// AccountNumber contains the decrypted account number from the customers service
+	AccountNumber string
 }
 
 type Destination struct {
@@ -27,3 +48,62 @@ type Destination struct {
 	// AccountNumber contains the decrypted account number from the customers service
 	AccountNumber string
 }
+
+// New returns the Strategy chosen in the Config. FirstParty is returned when
+// no fundflow is configured. A Routed Strategy is returned when named ODFIs are configured.
+func New(logger log.Logger, cfg *config.Config, customersClient moovcustomers.Client, accountDecryptor accounts.Decryptor) (Strategy, error) {
+	if cfg == nil {
+		return nil, errors.New("nil Config")
+	}
+	if len(cfg.ODFIs) == 0 {
+		return newStrategy(logger, cfg, cfg.ODFI, customersClient, accountDecryptor), nil
+	}
+	routed := make(Routed)
+	for i, odfi := range cfg.ODFIs {
+		routed[odfi.Name] = newStrategy(logger, cfg, odfi, customersClient, accountDecryptor)
+		if i == 0 {
+			routed[""] = routed[odfi.Name]
+		}
+	}
+	return routed, nil
+}
+
+func newStrategy(logger log.Logger, cfg *config.Config, odfi config.ODFI, customersClient moovcustomers.Client, accountDecryptor accounts.Decryptor) Strategy {
+	if cfg.Fundflow.ThirdParty != nil {
+		return NewThirdParty(logger, odfi, *cfg.Fundflow.ThirdParty, customersClient, accountDecryptor)
+	}
+	return NewFirstPerson(logger, odfi)
+}
+
+// Routed holds a Strategy for each named ODFI so files are originated with the details of the
+// ODFI they're uploaded to. The empty name is the first ODFI, which is used directly as a Strategy.
+type Routed map[string]Strategy
+
+func (r Routed) Originate(companyID string, xfer *client.Transfer, source Source, destination Destination) ([]*ach.File, error) {
+	strategy, err := ForODFI(r, "")
+	if err != nil {
+		return nil, err
+	}
+	return strategy.Originate(companyID, xfer, source, destination)
+}
+
+func (r Routed) HandleReturn(companyID string, entry *ach.EntryDetail, xfer *client.Transfer) ([]*ach.File, error) {
+	strategy, err := ForODFI(r, "")
+	if err != nil {
+		return nil, err
+	}
+	return strategy.HandleReturn(companyID, entry, xfer)
+}
+
+// ForODFI returns the Strategy for the named ODFI out of a Routed Strategy. Other
+// Strategies only originate for one ODFI and are returned as-is.
+func ForODFI(strategy Strategy, name string) (Strategy, error) {
+	routed, ok := strategy.(Routed)
+	if !ok {
+		return strategy, nil
+	}
+	if s, exists := routed[name]; exists && s != nil {
+		return s, nil
+	}
+	return nil, fmt.Errorf("no fundflow strategy for ODFI %q", name)
+}
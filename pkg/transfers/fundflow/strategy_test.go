@@ -0,0 +1,68 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestStrategy__New(t *testing.T) {
+	cfg := config.Empty()
+	cfg.ODFI = thirdPartyODFI
+
+	strategy, err := New(log.NewNopLogger(), cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := strategy.(*FirstParty); !ok {
+		t.Fatalf("unexpected %T", strategy)
+	}
+	if s, err := ForODFI(strategy, "bank-a"); err != nil || s != strategy {
+		t.Errorf("strategy=%T error=%v", s, err)
+	}
+}
+
+func TestStrategy__Routed(t *testing.T) {
+	bankA, bankB := thirdPartyODFI, thirdPartyODFI
+	bankA.Name = "bank-a"
+	bankB.Name, bankB.RoutingNumber = "bank-b", "121042882"
+
+	cfg := config.Empty()
+	cfg.ODFIs = []config.ODFI{bankA, bankB}
+
+	strategy, err := New(log.NewNopLogger(), cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	routed, ok := strategy.(Routed)
+	if !ok || len(routed) != 3 {
+		t.Fatalf("unexpected %#v", strategy)
+	}
+
+	s, err := ForODFI(strategy, "bank-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp, ok := s.(*FirstParty); !ok || fp.cfg.RoutingNumber != "121042882" {
+		t.Errorf("unexpected strategy: %#v", s)
+	}
+
+	// Transfers without an ODFI use the first
+	s, err = ForODFI(strategy, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp, ok := s.(*FirstParty); !ok || fp.cfg.Name != "bank-a" {
+		t.Errorf("unexpected strategy: %#v", s)
+	}
+
+	if _, err := ForODFI(strategy, "bank-c"); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	customers "github.com/moov-io/customers/client"
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	moovcustomers "github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+
+	"github.com/go-kit/kit/log"
+)
+
+// ThirdParty returns a Strategy for pass-through fund flows where neither the source or
+// destination account is held at the ODFI.
+//
+// The source account is debited into a settlement account at the ODFI when the transfer is
+// originated. Crediting the destination out of the settlement account is held until the
+// configured number of banking days after the debit settles, and is never originated if the
+// debit is returned first.
+//
+// A returned credit is refunded from the settlement account back to the source.
+type ThirdParty struct {
+	cfg        config.ODFI
+	settlement config.SettlementAccount
+	holdDays   int
+	logger     log.Logger
+
+	customersClient  moovcustomers.Client
+	accountDecryptor accounts.Decryptor
+}
+
+func NewThirdParty(logger log.Logger, odfi config.ODFI, cfg config.ThirdPartyFundflow, customersClient moovcustomers.Client, accountDecryptor accounts.Decryptor) Strategy {
+	return &ThirdParty{
+		cfg:              odfi,
+		settlement:       cfg.Settlement,
+		holdDays:         cfg.HoldDays,
+		logger:           logger,
+		customersClient:  customersClient,
+		accountDecryptor: accountDecryptor,
+	}
+}
+
+func (tp *ThirdParty) Originate(companyID string, xfer *client.Transfer, src Source, dst Destination) ([]*ach.File, error) {
+	source := achx.Remote{
+		Customer:      src.Customer,
+		Account:       src.Account,
+		AccountNumber: src.AccountNumber,
+	}
+
+	// Debit the source into our settlement account
+	debit, err := achx.ConstrctSettlementFile(base.ID(), tp.cfg, tp.settlement, companyID, companyName(dst.Customer), xfer, source, true, base.Now().AddBankingDay(1).Time)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create debit file: transferID=%s: %v", xfer.TransferID, err)
+	}
+	return []*ach.File{debit}, nil
+}
+
+// Hold returns the file crediting the destination out of our settlement account. It's released
+// once the source's debit has settled and been held for the configured number of banking days.
+func (tp *ThirdParty) Hold(companyID string, xfer *client.Transfer, src Source, dst Destination) (*ach.File, time.Time, error) {
+	destination := achx.Remote{
+		Customer:      dst.Customer,
+		Account:       dst.Account,
+		AccountNumber: dst.AccountNumber,
+	}
+	releaseAt := base.Now().AddBankingDay(1 + tp.holdDays).Time
+
+	credit, err := achx.ConstrctSettlementFile(base.ID(), tp.cfg, tp.settlement, companyID, companyName(src.Customer), xfer, destination, false, releaseAt)
+	if err != nil {
+		return nil, releaseAt, fmt.Errorf("failed to create credit file: transferID=%s: %v", xfer.TransferID, err)
+	}
+	return credit, releaseAt, nil
+}
+
+func (tp *ThirdParty) HandleReturn(companyID string, entry *ach.EntryDetail, xfer *client.Transfer) ([]*ach.File, error) {
+	if entry == nil || xfer == nil || entry.DFIAccountNumber == tp.settlement.AccountNumber {
+		return nil, nil // skip our offsetting entry
+	}
+	switch entry.CreditOrDebit() {
+	case "C":
+		// The destination's credit was returned so refund the source from settlement.
+		file, err := tp.refundSource(companyID, xfer)
+		if err != nil {
+			return nil, err
+		}
+		return []*ach.File{file}, nil
+
+	case "D":
+		// The source's debit was returned so the funds were never collected. The destination's
+		// credit is still held and is dropped when the return is recorded.
+		tp.logger.Log(
+			"fundflow", fmt.Sprintf("source debit returned for transferID=%s, destination credit won't be released", xfer.TransferID),
+			"traceNumber", entry.TraceNumber)
+	}
+	return nil, nil
+}
+
+func (tp *ThirdParty) refundSource(companyID string, xfer *client.Transfer) (*ach.File, error) {
+	src, err := GetSource(tp.customersClient, tp.accountDecryptor, xfer.Source)
+	if err != nil {
+		return nil, fmt.Errorf("refund: transferID=%s: %v", xfer.TransferID, err)
+	}
+	source := achx.Remote{
+		Customer:      src.Customer,
+		Account:       src.Account,
+		AccountNumber: src.AccountNumber,
+	}
+	file, err := achx.ConstrctSettlementFile(base.ID(), tp.cfg, tp.settlement, companyID, tp.settlement.Name, xfer, source, false, base.Now().AddBankingDay(1).Time)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refund file: transferID=%s: %v", xfer.TransferID, err)
+	}
+	return file, nil
+}
+
+func companyName(cust customers.Customer) string {
+	if cust.NickName != "" {
+		return cust.NickName
+	}
+	return fmt.Sprintf("%s %s", cust.FirstName, cust.LastName)
+}
@@ -0,0 +1,223 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package fundflow
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	customers "github.com/moov-io/customers/client"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	moovcustomers "github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+
+	"github.com/go-kit/kit/log"
+)
+
+var (
+	thirdPartyODFI = config.ODFI{
+		RoutingNumber: "987654320",
+		Gateway: config.Gateway{
+			Origin:      "987654320",
+			Destination: "987654320",
+		},
+	}
+	thirdPartyConfig = config.ThirdPartyFundflow{
+		Settlement: config.SettlementAccount{
+			Name:          "Settlement",
+			RoutingNumber: "987654320",
+			AccountNumber: "55555",
+			AccountType:   "checking",
+		},
+		HoldDays: 2,
+	}
+)
+
+func thirdPartyTransfer() *client.Transfer {
+	return &client.Transfer{
+		TransferID: base.ID(),
+		Amount:     "USD 12.44",
+		Source: client.Source{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Destination: client.Destination{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Description: "marketplace",
+		Status:      client.PENDING,
+	}
+}
+
+func thirdPartyRemote(routingNumber string) (customers.Customer, customers.Account) {
+	cust := customers.Customer{
+		CustomerID: base.ID(),
+		FirstName:  "Jane",
+		LastName:   "Doe",
+	}
+	acct := customers.Account{
+		AccountID:     base.ID(),
+		RoutingNumber: routingNumber,
+		Type:          customers.CHECKING,
+	}
+	return cust, acct
+}
+
+func TestThirdParty__Originate(t *testing.T) {
+	strategy := NewThirdParty(log.NewNopLogger(), thirdPartyODFI, thirdPartyConfig, nil, nil)
+
+	srcCust, srcAcct := thirdPartyRemote("231380104")
+	dstCust, dstAcct := thirdPartyRemote("273976369")
+
+	source := Source{Customer: srcCust, Account: srcAcct, AccountNumber: "12345"}
+	destination := Destination{Customer: dstCust, Account: dstAcct, AccountNumber: "67890"}
+
+	xfer := thirdPartyTransfer()
+	files, err := strategy.Originate("MOOV123456", xfer, source, destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(files); n != 1 {
+		t.Fatalf("got %d files", n)
+	}
+
+	// debit leg
+	entries := files[0].Batches[0].GetEntries()
+	if n := len(entries); n != 2 {
+		t.Fatalf("got %d entries", n)
+	}
+	if entries[0].TransactionCode != ach.CheckingDebit || entries[0].DFIAccountNumber != "12345" {
+		t.Errorf("unexpected debit entry: %#v", entries[0])
+	}
+	if entries[1].TransactionCode != ach.CheckingCredit || entries[1].DFIAccountNumber != "55555" {
+		t.Errorf("unexpected settlement entry: %#v", entries[1])
+	}
+
+	// credit leg is held
+	holder, ok := strategy.(Holder)
+	if !ok {
+		t.Fatalf("%T isn't a Holder", strategy)
+	}
+	credit, releaseAt, err := holder.Hold("MOOV123456", xfer, source, destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credit.ID == files[0].ID {
+		t.Errorf("expected unique file IDs: %s", credit.ID)
+	}
+	entries = credit.Batches[0].GetEntries()
+	if entries[0].TransactionCode != ach.CheckingCredit || entries[0].DFIAccountNumber != "67890" {
+		t.Errorf("unexpected credit entry: %#v", entries[0])
+	}
+	if entries[1].TransactionCode != ach.CheckingDebit || entries[1].DFIAccountNumber != "55555" {
+		t.Errorf("unexpected settlement entry: %#v", entries[1])
+	}
+
+	// the credit is released after the debit settles and is held
+	debitDate := files[0].Batches[0].GetHeader().EffectiveEntryDate
+	if date := releaseAt.Format("060102"); date <= debitDate {
+		t.Errorf("debit=%s release=%s", debitDate, date)
+	}
+}
+
+// returnEntry is how the RDFI sends back entry with the return code
+func returnEntry(entry *ach.EntryDetail, code string) *ach.EntryDetail {
+	ret := ach.NewEntryDetail()
+	ret.TransactionCode = entry.TransactionCode - 1 // e.g. 27 (debit) is returned as 26
+	ret.RDFIIdentification = entry.RDFIIdentification
+	ret.CheckDigit = entry.CheckDigit
+	ret.DFIAccountNumber = entry.DFIAccountNumber
+	ret.Amount = entry.Amount
+	ret.IndividualName = entry.IndividualName
+	ret.TraceNumber = "091000017611242"
+	ret.Category = ach.CategoryReturn
+
+	ret.Addenda99 = ach.NewAddenda99()
+	ret.Addenda99.ReturnCode = code
+	ret.Addenda99.OriginalTrace = entry.TraceNumber
+	ret.Addenda99.OriginalDFI = entry.RDFIIdentification
+	ret.Addenda99.TraceNumber = ret.TraceNumber
+	ret.AddendaRecordIndicator = 1
+	return ret
+}
+
+func TestThirdParty__HandleReturn(t *testing.T) {
+	srcCust, srcAcct := thirdPartyRemote("231380104")
+	dstCust, dstAcct := thirdPartyRemote("273976369")
+
+	customersClient := &moovcustomers.MockClient{
+		Customer: &srcCust,
+		Account:  &srcAcct,
+	}
+	decryptor := &accounts.MockDecryptor{Number: "12345"}
+	strategy := NewThirdParty(log.NewNopLogger(), thirdPartyODFI, thirdPartyConfig, customersClient, decryptor)
+
+	xfer := thirdPartyTransfer()
+	xfer.Created = time.Now()
+	source := Source{Customer: srcCust, Account: srcAcct, AccountNumber: "12345"}
+	destination := Destination{Customer: dstCust, Account: dstAcct, AccountNumber: "67890"}
+
+	files, err := strategy.Originate("MOOV123456", xfer, source, destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	debit := files[0].Batches[0].GetEntries()
+	held, _, err := strategy.(Holder).Hold("MOOV123456", xfer, source, destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credit := held.Batches[0].GetEntries()
+
+	// returned debit originates nothing as the credit was never released
+	entry := returnEntry(debit[0], "R01")
+	if entry.CreditOrDebit() != "D" {
+		t.Fatalf("unexpected return: %v", entry.TransactionCode)
+	}
+	if files, err := strategy.HandleReturn("MOOV123456", entry, xfer); err != nil || len(files) != 0 {
+		t.Errorf("files=%#v error=%v", files, err)
+	}
+
+	// returned credit is refunded to the source
+	refunds, err := strategy.HandleReturn("MOOV123456", returnEntry(credit[0], "R03"), xfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(refunds); n != 1 {
+		t.Fatalf("got %d files", n)
+	}
+	entries := refunds[0].Batches[0].GetEntries()
+	if entries[0].TransactionCode != ach.CheckingCredit || entries[0].DFIAccountNumber != "12345" {
+		t.Errorf("unexpected refund entry: %#v", entries[0])
+	}
+
+	// our settlement entries are skipped
+	if files, err := strategy.HandleReturn("MOOV123456", returnEntry(credit[1], "R03"), xfer); err != nil || len(files) != 0 {
+		t.Errorf("files=%#v error=%v", files, err)
+	}
+}
+
+func TestFundflow__New(t *testing.T) {
+	cfg := config.Empty()
+	strategy, err := New(log.NewNopLogger(), cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := strategy.(*FirstParty); !ok {
+		t.Errorf("unexpected %T", strategy)
+	}
+
+	cfg.Fundflow.ThirdParty = &thirdPartyConfig
+	strategy, err = New(log.NewNopLogger(), cfg, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := strategy.(*ThirdParty); !ok {
+		t.Errorf("unexpected %T", strategy)
+	}
+}
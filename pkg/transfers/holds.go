@@ -0,0 +1,99 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/go-kit/kit/log"
+)
+
+// holdReleaseInterval is how often held files are checked for release
+var holdReleaseInterval = 10 * time.Minute
+
+// HoldReleaser publishes the files our fundflow strategy held once their hold has passed.
+// Files of Transfers which were returned, canceled or failed before then are dropped.
+type HoldReleaser struct {
+	logger log.Logger
+	repo   Repository
+	pub    pipeline.XferPublisher
+}
+
+func NewHoldReleaser(logger log.Logger, repo Repository, pub pipeline.XferPublisher) *HoldReleaser {
+	return &HoldReleaser{
+		logger: logger,
+		repo:   repo,
+		pub:    pub,
+	}
+}
+
+// Start releases held files on each interval until ctx is canceled.
+func (hr *HoldReleaser) Start(ctx context.Context) {
+	ticker := time.NewTicker(holdReleaseInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			if err := hr.Release(time.Now()); err != nil {
+				hr.logger.Log("holds", fmt.Sprintf("ERROR releasing held files: %v", err))
+			}
+		}
+	}
+}
+
+// Release publishes each held file which can be released at when. Files which fail to be
+// released are logged and tried again on the next interval.
+func (hr *HoldReleaser) Release(when time.Time) error {
+	held, err := hr.repo.getHeldFiles(when)
+	if err != nil {
+		return err
+	}
+	for i := range held {
+		if err := hr.release(held[i]); err != nil {
+			hr.logger.Log("holds", fmt.Sprintf("ERROR releasing held file for transferID=%s: %v", held[i].TransferID, err))
+		}
+	}
+	return nil
+}
+
+func (hr *HoldReleaser) release(held *heldFile) error {
+	xfer, err := hr.repo.GetTransfer(held.TransferID)
+	if err != nil {
+		return err
+	}
+	if xfer == nil || xfer.ReturnCode.Code != "" || (xfer.Status != client.PENDING && xfer.Status != client.PROCESSED) {
+		hr.logger.Log("holds", fmt.Sprintf("dropping held file for transferID=%s", held.TransferID))
+		return hr.repo.cancelHold(held.TransferID)
+	}
+
+	// Claim the file first so it's never published twice
+	released, err := hr.repo.releaseHold(held.TransferID)
+	if err != nil || !released {
+		return err
+	}
+	effective := base.Now().AddBankingDay(1).Format("060102") // Date to be posted, YYMMDD
+	for _, batch := range held.File.Batches {
+		batch.GetHeader().EffectiveEntryDate = effective
+	}
+	if err := publishFiles(hr.repo, hr.pub, held.ODFI, xfer, []*ach.File{held.File}); err != nil {
+		if err := hr.repo.restoreHold(held.TransferID); err != nil {
+			hr.logger.Log("holds", fmt.Sprintf("ERROR restoring hold for transferID=%s: %v", held.TransferID, err))
+		}
+		return fmt.Errorf("publishing held file: %v", err)
+	}
+	hr.logger.Log("holds", fmt.Sprintf("released held file for transferID=%s", held.TransferID))
+	return nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestRepository__holds(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		xfer := writeTransfer(t, base.ID(), repo)
+		if err := repo.holdFile(xfer.TransferID, "bank-a", file, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+
+		// not released until the hold passes
+		held, err := repo.getHeldFiles(time.Now())
+		if err != nil || len(held) != 0 {
+			t.Fatalf("held=%#v error=%v", held, err)
+		}
+		held, err = repo.getHeldFiles(time.Now().Add(2 * time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(held) != 1 || held[0].TransferID != xfer.TransferID || held[0].ODFI != "bank-a" {
+			t.Fatalf("unexpected held files: %#v", held)
+		}
+		if held[0].File == nil || len(held[0].File.Batches) != len(file.Batches) {
+			t.Errorf("unexpected held file: %#v", held[0].File)
+		}
+
+		// files are only released once
+		if released, err := repo.releaseHold(xfer.TransferID); err != nil || !released {
+			t.Fatalf("released=%v error=%v", released, err)
+		}
+		if released, err := repo.releaseHold(xfer.TransferID); err != nil || released {
+			t.Fatalf("released=%v error=%v", released, err)
+		}
+		if err := repo.cancelHold(xfer.TransferID); err != nil {
+			t.Fatal(err)
+		}
+		if held, err := repo.getHeldFiles(time.Now().Add(2 * time.Hour)); err != nil || len(held) != 0 {
+			t.Fatalf("held=%#v error=%v", held, err)
+		}
+
+		// restored files can be released again, but canceled files can't
+		if err := repo.restoreHold(xfer.TransferID); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.cancelHold(xfer.TransferID); err != nil {
+			t.Fatal(err)
+		}
+		if released, err := repo.releaseHold(xfer.TransferID); err != nil || released {
+			t.Fatalf("released=%v error=%v", released, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestHoldReleaser(t *testing.T) {
+	file, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xfer := &client.Transfer{TransferID: base.ID(), Status: client.PROCESSED}
+	repo := &MockRepository{
+		Transfers: []*client.Transfer{xfer},
+		Held: []*heldFile{
+			{TransferID: xfer.TransferID, ODFI: "bank-a", File: file, ReleaseAt: time.Now()},
+		},
+	}
+	pub := &pipeline.MockPublisher{}
+
+	releaser := NewHoldReleaser(log.NewNopLogger(), repo, pub)
+	if err := releaser.Release(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Released) != 1 || len(pub.Xfers) != 1 {
+		t.Fatalf("released=%v xfers=%#v", repo.Released, pub.Xfers)
+	}
+	if pub.Xfers[0].ODFI != "bank-a" {
+		t.Errorf("unexpected xfer: %#v", pub.Xfers[0])
+	}
+	effective := base.Now().AddBankingDay(1).Format("060102")
+	if date := file.Batches[0].GetHeader().EffectiveEntryDate; date != effective {
+		t.Errorf("effective date %s, expected %s", date, effective)
+	}
+
+	// returned Transfers have their file dropped
+	pub = &pipeline.MockPublisher{}
+	repo.Released = nil
+	xfer.ReturnCode = client.ReturnCode{Code: "R01"}
+	if err := NewHoldReleaser(log.NewNopLogger(), repo, pub).Release(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Canceled) != 1 || len(repo.Released) != 0 || len(pub.Xfers) != 0 {
+		t.Errorf("canceled=%v released=%v xfers=%#v", repo.Canceled, repo.Released, pub.Xfers)
+	}
+
+	// canceled Transfers also have their file dropped
+	repo.Canceled = nil
+	xfer.ReturnCode = client.ReturnCode{}
+	xfer.Status = client.CANCELED
+	if err := NewHoldReleaser(log.NewNopLogger(), repo, pub).Release(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Canceled) != 1 || len(pub.Xfers) != 0 {
+		t.Errorf("canceled=%v xfers=%#v", repo.Canceled, pub.Xfers)
+	}
+
+	// errors listing held files are returned
+	repo.Err = errors.New("bad error")
+	if err := releaser.Release(time.Now()); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/moov-io/base/admin"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+// RegisterAdminRoutes will add HTTP handlers for paygate's admin HTTP server. Archived files are
+// re-processed by the Processor of the ODFI they were received from, keyed by the ODFI's name.
+func RegisterAdminRoutes(logger log.Logger, svc *admin.Server, repo Repository, processors map[string]*Processor) {
+	svc.AddHandler("/inbound/files", getFiles(logger, repo))
+	svc.AddHandler("/inbound/files/{sha256}", getFile(logger, repo))
+	svc.AddHandler("/inbound/files/{sha256}/reprocess", reprocessFile(logger, repo, processors))
+}
+
+func readListParams(r *http.Request) ListParams {
+	params := ListParams{
+		ODFI:  strings.TrimSpace(r.URL.Query().Get("odfi")),
+		Limit: 100,
+	}
+	if k := strings.TrimSpace(r.URL.Query().Get("kind")); k != "" {
+		params.Kind = Kind(strings.ToLower(k))
+	}
+	if limit := route.ReadLimit(r); limit != 0 {
+		params.Limit = limit
+	}
+	if offset := route.ReadOffset(r); offset != 0 {
+		params.Offset = offset
+	}
+	return params
+}
+
+func getFiles(logger log.Logger, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		files, err := repo.ListFiles(readListParams(r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(files)
+		})
+	}
+}
+
+func getFile(logger log.Logger, repo Repository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		file, err := repo.GetFile(route.ReadPathID("sha256", r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if file == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(file)
+		})
+	}
+}
+
+func reprocessFile(logger log.Logger, repo Repository, processors map[string]*Processor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		file, err := repo.GetFile(route.ReadPathID("sha256", r))
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if file == nil {
+			http.NotFound(w, r)
+			return
+		}
+		processor, exists := processors[file.ODFI]
+		if !exists {
+			responder.Problem(fmt.Errorf("no inbound processor for ODFI %q", file.ODFI))
+			return
+		}
+		if err := processor.Reprocess(file); err != nil {
+			responder.Problem(err)
+			return
+		}
+
+		responder.Log("inbound", fmt.Sprintf("re-processed sha256=%s", file.SHA256))
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(file)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/upload"
+
+	"github.com/antihax/optional"
+	"github.com/go-kit/kit/log"
+)
+
+func TestAdmin__files(t *testing.T) {
+	processor, agent, repo, handler := setupProcessor(t, &config.Storage{
+		Local: &config.Local{Directory: storageDir(t)},
+	})
+	agent.ReturnFiles = []upload.File{mockFile("prenote-ppd-debit.ach", readPrenote(t))}
+	if err := processor.Download(); err != nil {
+		t.Fatal(err)
+	}
+	checksum := repo.Files[0].SHA256
+
+	svc, c := testclient.Admin(t)
+	RegisterAdminRoutes(log.NewNopLogger(), svc, repo, map[string]*Processor{
+		"bank-a": processor,
+	})
+
+	files, resp, err := c.InboundApi.GetInboundFiles(context.Background(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(files) != 1 || files[0].Sha256 != checksum || files[0].Kind != "return" {
+		t.Errorf("unexpected files: %#v", files)
+	}
+
+	opts := &admin.GetInboundFilesOpts{
+		Kind: optional.NewString("inbound"),
+	}
+	files, resp, err = c.InboundApi.GetInboundFiles(context.Background(), "userID", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(files) != 0 {
+		t.Errorf("unexpected files: %#v", files)
+	}
+
+	// single file
+	file, resp, err := c.InboundApi.GetInboundFile(context.Background(), checksum, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if file.Odfi != "bank-a" || file.Processed.IsZero() {
+		t.Errorf("unexpected file: %#v", file)
+	}
+
+	// re-process
+	file, resp, err = c.InboundApi.ReprocessInboundFile(context.Background(), checksum, "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if file.Sha256 != checksum || len(handler.files) != 2 {
+		t.Errorf("file=%#v handled=%#v", file, handler.files)
+	}
+
+	// not found
+	missing := "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7"
+	_, resp, err = c.InboundApi.ReprocessInboundFile(context.Background(), missing, "userID", nil)
+	if err == nil {
+		t.Error("expected error")
+	}
+	if resp == nil || resp.StatusCode != 404 {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+
+	// no processor for the file's ODFI
+	repo.Files[0].ODFI = "bank-b"
+	_, resp, err = c.InboundApi.ReprocessInboundFile(context.Background(), checksum, "userID", nil)
+	if err == nil {
+		t.Error("expected error")
+	}
+	if resp == nil || resp.StatusCode != 400 {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+}
+
+func TestAdmin__filesErr(t *testing.T) {
+	repo := &MockRepository{Err: errors.New("bad error")}
+
+	svc, c := testclient.Admin(t)
+	RegisterAdminRoutes(log.NewNopLogger(), svc, repo, nil)
+
+	_, resp, err := c.InboundApi.GetInboundFiles(context.Background(), "userID", nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Error("expected error")
+	}
+}
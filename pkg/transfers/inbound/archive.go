@@ -0,0 +1,118 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+type Kind string
+
+const (
+	// InboundFile is read from the ODFI's inbound directory (corrections, prenotes and incoming transfers)
+	InboundFile Kind = "inbound"
+	// ReturnFile is read from the ODFI's return directory
+	ReturnFile Kind = "return"
+)
+
+// ArchivedFile records when and from where a downloaded file was received. Files are identified
+// by the SHA-256 of their contents as downloaded, so the same file is only processed once.
+type ArchivedFile struct {
+	SHA256   string `json:"sha256"`
+	ODFI     string `json:"odfi"`
+	Kind     Kind   `json:"kind"`
+	Filename string `json:"filename"`
+	// RemotePath is where the file was read from on the ODFI's server
+	RemotePath string `json:"remotePath"`
+	Size       int64  `json:"size"`
+
+	Received time.Time `json:"received"`
+	// Processed is nil until the file has been processed successfully
+	Processed *time.Time `json:"processed,omitempty"`
+}
+
+// Archive stores the contents of downloaded files in a local directory by their SHA-256 checksum.
+type Archive struct {
+	dir string
+}
+
+func NewArchive(dir string) (*Archive, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("missing archive directory")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("problem creating archive directory: %v", err)
+	}
+	return &Archive{dir: dir}, nil
+}
+
+var (
+	checksumRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+)
+
+func (a *Archive) path(checksum string) (string, error) {
+	if !checksumRegex.MatchString(checksum) {
+		return "", fmt.Errorf("invalid sha256 %q", checksum)
+	}
+	return filepath.Join(a.dir, checksum), nil
+}
+
+// Save streams r into the archive unless it's already there and returns the hex encoded SHA-256
+// and size of what was read.
+func (a *Archive) Save(r io.Reader) (string, int64, error) {
+	// Write into a temporary file first so a partial write is never mistaken for the archived file
+	fd, err := ioutil.TempFile(a.dir, ".inbound-")
+	if err != nil {
+		return "", 0, fmt.Errorf("archive: %v", err)
+	}
+	h := sha256.New()
+	n, err := io.Copy(fd, io.TeeReader(r, h))
+	if err != nil {
+		fd.Close()
+		os.Remove(fd.Name())
+		return "", 0, fmt.Errorf("archive: writing: %v", err)
+	}
+	if err := fd.Close(); err != nil {
+		os.Remove(fd.Name())
+		return "", 0, fmt.Errorf("archive: closing: %v", err)
+	}
+
+	checksum := hex.EncodeToString(h.Sum(nil))
+	path, err := a.path(checksum)
+	if err != nil {
+		os.Remove(fd.Name())
+		return "", 0, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		os.Remove(fd.Name())
+		return checksum, n, nil
+	}
+	if err := os.Rename(fd.Name(), path); err != nil {
+		os.Remove(fd.Name())
+		return "", 0, fmt.Errorf("archive: %v", err)
+	}
+	return checksum, n, nil
+}
+
+// Open returns the contents of an archived file, which callers must close.
+func (a *Archive) Open(checksum string) (io.ReadCloser, error) {
+	path, err := a.path(checksum)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("archive: %v", err)
+	}
+	return fd, nil
+}
@@ -0,0 +1,32 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/upload"
+)
+
+// ReadFile decrypts and verifies the ODFI's signature of an inbound or returned file
+// before parsing it. Files are read as-is when gpg is nil.
+func ReadFile(gpg *upload.GPG, f upload.File) (*ach.File, error) {
+	if f.Contents == nil {
+		return nil, errors.New("nil File contents")
+	}
+	f, err := gpg.Decrypt(f)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	file, err := ach.NewReader(f.Contents).Read()
+	if err != nil {
+		return nil, fmt.Errorf("problem reading %s: %v", f.Filename, err)
+	}
+	return &file, nil
+}
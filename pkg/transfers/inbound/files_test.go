@@ -0,0 +1,86 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/upload"
+	"github.com/moov-io/paygate/x/gpgx"
+)
+
+func TestFiles__ReadFile(t *testing.T) {
+	fd, err := os.Open(filepath.Join("testdata", "prenote-ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := ReadFile(nil, upload.File{Filename: "prenote-ppd-debit.ach", Contents: fd})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Batches) != 1 {
+		t.Errorf("unexpected batches: %#v", file.Batches)
+	}
+
+	if _, err := ReadFile(nil, upload.File{}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestFiles__ReadFileGPG(t *testing.T) {
+	dir := filepath.Join("..", "..", "..", "testdata", "gpg")
+	gpg, err := upload.NewGPG(&config.GPG{
+		ODFIPublicKey: filepath.Join(dir, "odfi.pub"),
+		SigningKey: &config.GPGPrivateKey{
+			Path:     filepath.Join(dir, "paygate.priv"),
+			Password: "password",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := ioutil.ReadFile(filepath.Join("testdata", "prenote-ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	odfiKeys, err := gpgx.ReadPrivateKeyFile(filepath.Join(dir, "odfi.priv"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paygateKeys, err := gpgx.ReadArmoredKeyFile(filepath.Join(dir, "paygate.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := gpgx.Encrypt(bs, paygateKeys, odfiKeys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := ReadFile(gpg, upload.File{
+		Filename: "prenote-ppd-debit.ach.gpg",
+		Contents: ioutil.NopCloser(bytes.NewReader(encrypted)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Batches) != 1 {
+		t.Errorf("unexpected batches: %#v", file.Batches)
+	}
+
+	// plaintext files are rejected
+	_, err = ReadFile(gpg, upload.File{
+		Filename: "prenote-ppd-debit.ach",
+		Contents: ioutil.NopCloser(bytes.NewReader(bs)),
+	})
+	if err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"sync"
+	"time"
+)
+
+type MockRepository struct {
+	Files []*ArchivedFile
+	Err   error
+
+	mu sync.Mutex
+}
+
+func (r *MockRepository) SaveFile(file *ArchivedFile) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Files {
+		if r.Files[i].SHA256 == file.SHA256 {
+			return nil
+		}
+	}
+	r.Files = append(r.Files, file)
+	return nil
+}
+
+func (r *MockRepository) GetFile(sha256 string) (*ArchivedFile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Files {
+		if r.Files[i].SHA256 == sha256 {
+			return r.Files[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockRepository) ListFiles(params ListParams) ([]*ArchivedFile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	out := []*ArchivedFile{}
+	for i := range r.Files {
+		if params.ODFI != "" && r.Files[i].ODFI != params.ODFI {
+			continue
+		}
+		if params.Kind != "" && r.Files[i].Kind != params.Kind {
+			continue
+		}
+		out = append(out, r.Files[i])
+	}
+	return out, nil
+}
+
+func (r *MockRepository) MarkProcessed(sha256 string, when time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Files {
+		if r.Files[i].SHA256 == sha256 {
+			r.Files[i].Processed = &when
+		}
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/upload"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Handler is given each inbound or return file after it's been decrypted and parsed.
+// Handlers are called again for a file when an operator re-processes it.
+type Handler interface {
+	Handle(file *ArchivedFile, contents *ach.File) error
+}
+
+// Processor periodically downloads inbound and return files from an ODFI. Each file is
+// archived by its SHA-256 checksum and skipped if it has already been processed, which
+// covers files left on the ODFI's server by KeepRemoteFiles or a failed delete.
+type Processor struct {
+	cfg    config.ODFI
+	logger log.Logger
+
+	agent upload.Agent
+	gpg   *upload.GPG
+
+	archive *Archive
+	repo    Repository
+
+	handlers []Handler
+
+	// mu keeps a file from being processed twice when it's downloaded again or
+	// re-processed while another copy is being handled
+	mu sync.Mutex
+}
+
+func NewProcessor(logger log.Logger, cfg config.ODFI, agent upload.Agent, gpg *upload.GPG, repo Repository) (*Processor, error) {
+	archive, err := NewArchive(cfg.Storage.ArchiveDirectory())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Name != "" {
+		logger = log.With(logger, "odfi", cfg.Name)
+	}
+	return &Processor{
+		cfg:     cfg,
+		logger:  logger,
+		agent:   agent,
+		gpg:     gpg,
+		archive: archive,
+		repo:    repo,
+	}, nil
+}
+
+// AddHandler registers h to be called with each file. Handlers must be safe to call from
+// multiple goroutines as agents can download several files at once.
+func (p *Processor) AddHandler(h Handler) {
+	p.handlers = append(p.handlers, h)
+}
+
+func (p *Processor) Start(ctx context.Context) {
+	if err := p.Download(); err != nil {
+		p.logger.Log("inbound", fmt.Sprintf("ERROR downloading files: %v", err))
+	}
+
+	ticker := time.NewTicker(p.cfg.Inbound.DownloadInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.logger.Log("inbound", "shutting down inbound file processing")
+			return
+
+		case <-ticker.C:
+			if err := p.Download(); err != nil {
+				p.logger.Log("inbound", fmt.Sprintf("ERROR downloading files: %v", err))
+			}
+		}
+	}
+}
+
+// Download reads each inbound and return file from the ODFI and processes the ones we haven't seen before.
+func (p *Processor) Download() error {
+	dirs := []struct {
+		kind Kind
+		list func() ([]upload.FileInfo, error)
+	}{
+		{kind: InboundFile, list: p.agent.ListInboundFiles},
+		{kind: ReturnFile, list: p.agent.ListReturnFiles},
+	}
+
+	var el base.ErrorList
+	for _, dir := range dirs {
+		files, err := dir.list()
+		if err != nil {
+			el.Add(fmt.Errorf("listing %s files: %v", dir.kind, err))
+			continue
+		}
+		paths := make(map[string]string)
+		for i := range files {
+			paths[files[i].Filename] = files[i].Path
+		}
+		kind := dir.kind
+		err = upload.WithEachFile(p.agent, files, p.cfg.Storage.KeepRemoteFiles, func(f upload.File) error {
+			return p.receive(kind, paths[f.Filename], f)
+		})
+		if err != nil {
+			el.Add(err)
+		}
+	}
+	return el.Err()
+}
+
+func (p *Processor) receive(kind Kind, remotePath string, f upload.File) error {
+	checksum, size, err := p.archive.Save(f.Contents)
+	if err != nil {
+		return fmt.Errorf("reading %s: %v", f.Filename, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	file, err := p.repo.GetFile(checksum)
+	if err != nil {
+		return fmt.Errorf("looking up sha256=%s: %v", checksum, err)
+	}
+	if file != nil && file.Processed != nil {
+		p.logger.Log("inbound", fmt.Sprintf("skipping %s which was already processed as sha256=%s", remotePath, checksum))
+		return nil
+	}
+	if file == nil {
+		file = &ArchivedFile{
+			SHA256:     checksum,
+			ODFI:       p.cfg.Name,
+			Kind:       kind,
+			Filename:   f.Filename,
+			RemotePath: remotePath,
+			Size:       size,
+			Received:   time.Now(),
+		}
+		if err := p.repo.SaveFile(file); err != nil {
+			return fmt.Errorf("saving sha256=%s: %v", checksum, err)
+		}
+	}
+	return p.process(file)
+}
+
+// Reprocess reads an archived file and hands it to each Handler again, even if it's been processed before.
+func (p *Processor) Reprocess(file *ArchivedFile) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.logger.Log("inbound", fmt.Sprintf("re-processing %s sha256=%s", file.Filename, file.SHA256))
+	return p.process(file)
+}
+
+func (p *Processor) process(file *ArchivedFile) error {
+	// Files are left on the ODFI's server and unprocessed until something can act on them
+	if len(p.handlers) == 0 {
+		return fmt.Errorf("no handlers for %s sha256=%s", file.Filename, file.SHA256)
+	}
+
+	fd, err := p.archive.Open(file.SHA256)
+	if err != nil {
+		return err
+	}
+	contents, err := ReadFile(p.gpg, upload.File{
+		Filename: file.Filename,
+		Contents: fd,
+	})
+	if err != nil {
+		return err
+	}
+	for i := range p.handlers {
+		if err := p.handlers[i].Handle(file, contents); err != nil {
+			return fmt.Errorf("handling %s sha256=%s: %v", file.Filename, file.SHA256, err)
+		}
+	}
+
+	when := time.Now()
+	if err := p.repo.MarkProcessed(file.SHA256, when); err != nil {
+		return fmt.Errorf("marking sha256=%s processed: %v", file.SHA256, err)
+	}
+	file.Processed = &when
+
+	p.logger.Log("inbound", fmt.Sprintf("processed %s file %s with %d batches sha256=%s", file.Kind, file.Filename, len(contents.Batches), file.SHA256))
+	return nil
+}
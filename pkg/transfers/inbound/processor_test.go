@@ -0,0 +1,248 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/upload"
+
+	"github.com/go-kit/kit/log"
+)
+
+type mockHandler struct {
+	files []*ArchivedFile
+	err   error
+}
+
+func (h *mockHandler) Handle(file *ArchivedFile, contents *ach.File) error {
+	h.files = append(h.files, file)
+	return h.err
+}
+
+func storageDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "inbound")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func setupProcessor(t *testing.T, storage *config.Storage) (*Processor, *upload.MockAgent, *MockRepository, *mockHandler) {
+	t.Helper()
+
+	cfg := config.ODFI{
+		Name:    "bank-a",
+		Storage: storage,
+	}
+	agent, repo, handler := &upload.MockAgent{}, &MockRepository{}, &mockHandler{}
+	processor, err := NewProcessor(log.NewNopLogger(), cfg, agent, nil, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor.AddHandler(handler)
+	return processor, agent, repo, handler
+}
+
+func readPrenote(t *testing.T) []byte {
+	t.Helper()
+
+	bs, err := ioutil.ReadFile(filepath.Join("testdata", "prenote-ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bs
+}
+
+func mockFile(filename string, bs []byte) upload.File {
+	return upload.File{
+		Filename: filename,
+		Contents: ioutil.NopCloser(bytes.NewReader(bs)),
+	}
+}
+
+func TestProcessor__Download(t *testing.T) {
+	storage := &config.Storage{
+		KeepRemoteFiles: true,
+		Local:           &config.Local{Directory: storageDir(t)},
+	}
+	processor, agent, repo, handler := setupProcessor(t, storage)
+
+	bs := readPrenote(t)
+	agent.ReturnFiles = []upload.File{mockFile("prenote-ppd-debit.ach", bs)}
+	if err := processor.Download(); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Files) != 1 || len(handler.files) != 1 {
+		t.Fatalf("files=%#v handled=%#v", repo.Files, handler.files)
+	}
+	file := repo.Files[0]
+	if file.ODFI != "bank-a" || file.Kind != ReturnFile || file.RemotePath != filepath.Join("return", "prenote-ppd-debit.ach") || file.Processed == nil {
+		t.Errorf("unexpected file: %#v", file)
+	}
+	if file.Size != int64(len(bs)) {
+		t.Errorf("unexpected size: %d", file.Size)
+	}
+
+	// archived by checksum
+	archived, err := ioutil.ReadFile(filepath.Join(storage.ArchiveDirectory(), file.SHA256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(archived, bs) {
+		t.Error("archived file differs")
+	}
+
+	// The same file left on the ODFI's server (or renamed) is skipped
+	agent.ReturnFiles = []upload.File{mockFile("prenote-ppd-debit.ach", bs)}
+	agent.InboundFiles = []upload.File{mockFile("renamed.ach", bs)}
+	if err := processor.Download(); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.Files) != 1 || len(handler.files) != 1 {
+		t.Errorf("files=%#v handled=%#v", repo.Files, handler.files)
+	}
+}
+
+func TestProcessor__DownloadRetry(t *testing.T) {
+	processor, agent, repo, handler := setupProcessor(t, &config.Storage{
+		Local: &config.Local{Directory: storageDir(t)},
+	})
+
+	// Files which fail processing are left on the ODFI's server and tried again
+	handler.err = errors.New("bad error")
+	agent.InboundFiles = []upload.File{mockFile("prenote-ppd-debit.ach", readPrenote(t))}
+	if err := processor.Download(); err == nil {
+		t.Error("expected error")
+	}
+	if agent.DeletedFile != "" {
+		t.Errorf("unexpected delete: %s", agent.DeletedFile)
+	}
+	if len(repo.Files) != 1 || repo.Files[0].Processed != nil {
+		t.Fatalf("unexpected files: %#v", repo.Files)
+	}
+
+	handler.err = nil
+	agent.InboundFiles = []upload.File{mockFile("prenote-ppd-debit.ach", readPrenote(t))}
+	if err := processor.Download(); err != nil {
+		t.Fatal(err)
+	}
+	if len(handler.files) != 2 || repo.Files[0].Processed == nil {
+		t.Errorf("files=%#v handled=%#v", repo.Files, handler.files)
+	}
+	if agent.DeletedFile != filepath.Join("inbound", "prenote-ppd-debit.ach") {
+		t.Errorf("unexpected delete: %s", agent.DeletedFile)
+	}
+}
+
+func TestProcessor__noHandlers(t *testing.T) {
+	agent := &upload.MockAgent{}
+	repo := &MockRepository{}
+	processor, err := NewProcessor(log.NewNopLogger(), config.ODFI{
+		Storage: &config.Storage{
+			Local: &config.Local{Directory: storageDir(t)},
+		},
+	}, agent, nil, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files nothing acts on are kept on the ODFI's server and left unprocessed
+	agent.ReturnFiles = []upload.File{mockFile("prenote-ppd-debit.ach", readPrenote(t))}
+	if err := processor.Download(); err == nil {
+		t.Error("expected error")
+	}
+	if agent.DeletedFile != "" {
+		t.Errorf("unexpected delete: %s", agent.DeletedFile)
+	}
+	if len(repo.Files) != 1 || repo.Files[0].Processed != nil {
+		t.Errorf("unexpected files: %#v", repo.Files)
+	}
+}
+
+func TestProcessor__Reprocess(t *testing.T) {
+	processor, agent, repo, handler := setupProcessor(t, &config.Storage{
+		Local: &config.Local{Directory: storageDir(t)},
+	})
+
+	agent.InboundFiles = []upload.File{mockFile("prenote-ppd-debit.ach", readPrenote(t))}
+	if err := processor.Download(); err != nil {
+		t.Fatal(err)
+	}
+	if err := processor.Reprocess(repo.Files[0]); err != nil {
+		t.Fatal(err)
+	}
+	if len(handler.files) != 2 {
+		t.Errorf("handled=%#v", handler.files)
+	}
+
+	// missing from the archive
+	missing := *repo.Files[0]
+	missing.SHA256 = "486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7"
+	if err := processor.Reprocess(&missing); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestProcessor__missingStorage(t *testing.T) {
+	cfg := config.ODFI{}
+	if _, err := NewProcessor(log.NewNopLogger(), cfg, &upload.MockAgent{}, nil, &MockRepository{}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestArchive(t *testing.T) {
+	archive, err := NewArchive(storageDir(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum, size, err := archive.Save(strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checksum != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" || size != 5 {
+		t.Errorf("unexpected checksum=%s size=%d", checksum, size)
+	}
+	if _, _, err := archive.Save(strings.NewReader("hello")); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := archive.Open(checksum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	bs, err := ioutil.ReadAll(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "hello" {
+		t.Errorf("unexpected contents: %q", string(bs))
+	}
+
+	// only archived files are left in the directory
+	infos, err := ioutil.ReadDir(archive.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 {
+		t.Errorf("unexpected files: %d", len(infos))
+	}
+
+	if _, err := archive.Open("../../etc/passwd"); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Repository interface {
+	// SaveFile records a newly received file. Files already saved are left unchanged.
+	SaveFile(file *ArchivedFile) error
+	GetFile(sha256 string) (*ArchivedFile, error)
+	ListFiles(params ListParams) ([]*ArchivedFile, error)
+
+	// MarkProcessed records when a file was successfully processed.
+	MarkProcessed(sha256 string, when time.Time) error
+}
+
+// ListParams filters the files returned from ListFiles, newest first.
+type ListParams struct {
+	ODFI   string
+	Kind   Kind
+	Limit  int64
+	Offset int64
+}
+
+func NewRepo(db *sql.DB) Repository {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+func (r *sqlRepo) SaveFile(file *ArchivedFile) error {
+	if file == nil {
+		return nil
+	}
+	existing, err := r.GetFile(file.SHA256)
+	if err != nil || existing != nil {
+		return err
+	}
+
+	query := `insert into inbound_files (sha256, odfi, kind, filename, remote_path, size, received_at) values (?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(file.SHA256, file.ODFI, file.Kind, file.Filename, file.RemotePath, file.Size, file.Received)
+	return err
+}
+
+func (r *sqlRepo) GetFile(sha256 string) (*ArchivedFile, error) {
+	query := `select sha256, odfi, kind, filename, remote_path, size, received_at, processed_at from inbound_files where sha256 = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	file, err := scanFile(stmt.QueryRow(sha256))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return file, err
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanFile(row scanner) (*ArchivedFile, error) {
+	var f ArchivedFile
+	var processed *time.Time
+	if err := row.Scan(&f.SHA256, &f.ODFI, &f.Kind, &f.Filename, &f.RemotePath, &f.Size, &f.Received, &processed); err != nil {
+		return nil, err
+	}
+	if processed != nil && !processed.IsZero() {
+		f.Processed = processed
+	}
+	return &f, nil
+}
+
+func (r *sqlRepo) ListFiles(params ListParams) ([]*ArchivedFile, error) {
+	var conditions []string
+	var args []interface{}
+	if params.ODFI != "" {
+		conditions = append(conditions, "odfi = ?")
+		args = append(args, params.ODFI)
+	}
+	if params.Kind != "" {
+		conditions = append(conditions, "kind = ?")
+		args = append(args, params.Kind)
+	}
+	var where string
+	if len(conditions) > 0 {
+		where = "where " + strings.Join(conditions, " and ")
+	}
+	query := fmt.Sprintf(`select sha256, odfi, kind, filename, remote_path, size, received_at, processed_at from inbound_files %s order by received_at desc limit ? offset ?;`, where)
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	args = append(args, params.Limit, params.Offset)
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []*ArchivedFile{}
+	for rows.Next() {
+		file, err := scanFile(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, file)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) MarkProcessed(sha256 string, when time.Time) error {
+	query := `update inbound_files set processed_at = ? where sha256 = ?;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(when, sha256)
+	return err
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package inbound
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+func TestRepository__Files(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		file := &ArchivedFile{
+			SHA256:     "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			ODFI:       "bank-a",
+			Kind:       ReturnFile,
+			Filename:   "20200601-987654320-1.ach",
+			RemotePath: "returned/20200601-987654320-1.ach",
+			Size:       1894,
+			Received:   time.Now(),
+		}
+		if err := repo.SaveFile(file); err != nil {
+			t.Fatal(err)
+		}
+		// saving the same file again is ignored
+		if err := repo.SaveFile(file); err != nil {
+			t.Fatal(err)
+		}
+
+		found, err := repo.GetFile(file.SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found == nil || found.ODFI != file.ODFI || found.Kind != ReturnFile || found.RemotePath != file.RemotePath || found.Size != file.Size {
+			t.Fatalf("unexpected file: %#v", found)
+		}
+		if found.Processed != nil {
+			t.Errorf("unexpected processed: %v", found.Processed)
+		}
+
+		if err := repo.MarkProcessed(file.SHA256, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		found, err = repo.GetFile(file.SHA256)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Processed == nil {
+			t.Error("expected processed time")
+		}
+
+		// list
+		files, err := repo.ListFiles(ListParams{ODFI: "bank-a", Kind: ReturnFile, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || files[0].SHA256 != file.SHA256 {
+			t.Errorf("unexpected files: %#v", files)
+		}
+		files, err = repo.ListFiles(ListParams{Kind: InboundFile, Limit: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 0 {
+			t.Errorf("unexpected files: %#v", files)
+		}
+
+		// not found
+		found, err = repo.GetFile("486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7")
+		if err != nil || found != nil {
+			t.Errorf("file=%#v error=%v", found, err)
+		}
+	}
+
+	// SQLite tests
+	sqliteDB := database.CreateTestSqliteDB(t)
+	defer sqliteDB.Close()
+	check(t, &sqlRepo{db: sqliteDB.DB})
+
+	// MySQL tests
+	mysqlDB := database.CreateTestMySQLDB(t)
+	defer mysqlDB.Close()
+	check(t, &sqlRepo{db: mysqlDB.DB})
+}
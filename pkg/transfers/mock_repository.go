@@ -5,12 +5,27 @@
 package transfers
 
 import (
+	"time"
+
+	"github.com/moov-io/ach"
 	"github.com/moov-io/paygate/pkg/client"
 )
 
 type MockRepository struct {
 	Transfers []*client.Transfer
+	Batch     *client.TransferBatch
+	Creator   string
+	TenantID  string
 	Err       error
+
+	// Delivered holds the transferIDs TransferDelivered returns true for
+	Delivered []string
+
+	// Held is returned from getHeldFiles, and each held file is recorded
+	Held []*heldFile
+	// Released and Canceled record the transferIDs of released and canceled holds
+	Released []string
+	Canceled []string
 }
 
 func (r *MockRepository) getUserTransfers(userID string, params transferFilterParams) ([]*client.Transfer, error) {
@@ -20,6 +35,18 @@ func (r *MockRepository) getUserTransfers(userID string, params transferFilterPa
 	return r.Transfers, nil
 }
 
+func (r *MockRepository) getUserTransfer(transferID string, userID string) (*client.Transfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	for i := range r.Transfers {
+		if r.Transfers[i].TransferID == transferID {
+			return r.Transfers[i], nil
+		}
+	}
+	return nil, nil
+}
+
 func (r *MockRepository) GetTransfer(id string) (*client.Transfer, error) {
 	if r.Err != nil {
 		return nil, r.Err
@@ -30,11 +57,41 @@ func (r *MockRepository) GetTransfer(id string) (*client.Transfer, error) {
 	return nil, nil
 }
 
+func (r *MockRepository) getTransferCreator(transferID string) (string, error) {
+	if r.Err != nil {
+		return "", r.Err
+	}
+	return r.Creator, nil
+}
+
+func (r *MockRepository) getTransferTenant(transferID string) (string, error) {
+	if r.Err != nil {
+		return "", r.Err
+	}
+	return r.TenantID, nil
+}
+
 func (r *MockRepository) UpdateTransferStatus(transferID string, status client.TransferStatus) error {
 	return r.Err
 }
 
-func (r *MockRepository) writeUserTransfers(userID string, transfer *client.Transfer) error {
+func (r *MockRepository) CancelPendingTransfer(transferID string) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	for i := range r.Transfers {
+		if r.Transfers[i].TransferID == transferID {
+			return r.Transfers[i].Status == client.PENDING, nil
+		}
+	}
+	return true, nil
+}
+
+func (r *MockRepository) writeUserTransfers(userID string, tenantID string, transfer *client.Transfer) error {
+	return r.Err
+}
+
+func (r *MockRepository) writeFileTransfers(userID string, fileID string, transfers []*client.Transfer, tenantIDs []string) error {
 	return r.Err
 }
 
@@ -45,3 +102,112 @@ func (r *MockRepository) deleteUserTransfer(userID string, transferID string) er
 func (r *MockRepository) SetReturnCode(transferID string, returnCode string) error {
 	return r.Err
 }
+
+func (r *MockRepository) TransferDelivered(transferID string) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	for i := range r.Delivered {
+		if r.Delivered[i] == transferID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MockRepository) holdFile(transferID string, odfi string, file *ach.File, releaseAt time.Time) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Held = append(r.Held, &heldFile{
+		TransferID: transferID,
+		ODFI:       odfi,
+		File:       file,
+		ReleaseAt:  releaseAt,
+	})
+	return nil
+}
+
+func (r *MockRepository) getHeldFiles(when time.Time) ([]*heldFile, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Held, nil
+}
+
+func (r *MockRepository) releaseHold(transferID string) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	r.Released = append(r.Released, transferID)
+	return true, nil
+}
+
+func (r *MockRepository) restoreHold(transferID string) error {
+	return r.Err
+}
+
+func (r *MockRepository) cancelHold(transferID string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	r.Canceled = append(r.Canceled, transferID)
+	return nil
+}
+
+func (r *MockRepository) SearchTransfers(params SearchParams) ([]*client.Transfer, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Transfers, nil
+}
+
+func (r *MockRepository) saveTraceNumbers(transferID string, entries []*ach.EntryDetail) error {
+	return r.Err
+}
+
+func (r *MockRepository) saveReview(transferID string, reasons []string) error {
+	return r.Err
+}
+
+func (r *MockRepository) reviewTransfer(transferID string, reviewerID string, status client.TransferStatus) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Transfers {
+		if r.Transfers[i].TransferID == transferID {
+			if r.Transfers[i].Status != client.REVIEWABLE {
+				return errNotReviewable
+			}
+			r.Transfers[i].Status = status
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) reopenReview(transferID string) error {
+	if r.Err != nil {
+		return r.Err
+	}
+	for i := range r.Transfers {
+		if r.Transfers[i].TransferID == transferID && r.Transfers[i].Status == client.PENDING {
+			r.Transfers[i].Status = client.REVIEWABLE
+		}
+	}
+	return nil
+}
+
+func (r *MockRepository) getTransferBatch(userID string, batchID string) (*client.TransferBatch, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.Batch, nil
+}
+
+func (r *MockRepository) writeTransferBatch(userID string, batch *client.TransferBatch) error {
+	return r.Err
+}
+
+func (r *MockRepository) saveTransferBatchResults(batchID string, status client.TransferBatchStatus, results []client.TransferBatchResult) error {
+	return r.Err
+}
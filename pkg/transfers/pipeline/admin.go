@@ -0,0 +1,182 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/moov-io/base/admin"
+	"github.com/moov-io/paygate/pkg/transfers/deliveries"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+// RegisterAdminRoutes will add HTTP handlers for paygate's admin HTTP server. Each XferAggregator
+// is keyed by the name of the ODFI it uploads files to.
+func RegisterAdminRoutes(logger log.Logger, svc *admin.Server, aggregators map[string]*XferAggregator) {
+	svc.AddHandler("/deliveries/failed/requeue", requeueFailedDeliveries(logger, aggregators))
+	svc.AddHandler("/merging", getMergingQueues(logger, aggregators))
+	svc.AddHandler("/merging/cutoff", triggerCutoff(logger, aggregators))
+	svc.AddHandler("/merging/dry-run", triggerDryRun(logger, aggregators))
+}
+
+// selectAggregators returns the XferAggregator of the "odfi" query param, or every one sorted
+// by name when it's missing. False is returned for unknown ODFIs.
+func selectAggregators(r *http.Request, aggregators map[string]*XferAggregator) ([]*XferAggregator, bool) {
+	if odfi := strings.TrimSpace(r.URL.Query().Get("odfi")); odfi != "" {
+		xfagg, exists := aggregators[odfi]
+		if !exists {
+			return nil, false
+		}
+		return []*XferAggregator{xfagg}, true
+	}
+
+	var names []string
+	for name := range aggregators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]*XferAggregator, 0, len(names))
+	for i := range names {
+		out = append(out, aggregators[names[i]])
+	}
+	return out, true
+}
+
+func getMergingQueues(logger log.Logger, aggregators map[string]*XferAggregator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		aggs, found := selectAggregators(r, aggregators)
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		queues := make([]*MergingQueue, 0, len(aggs))
+		for i := range aggs {
+			queue, err := aggs[i].Queue()
+			if err != nil {
+				responder.Problem(fmt.Errorf("reading %s merging queue: %v", aggs[i].config().Name, err))
+				return
+			}
+			queues = append(queues, queue)
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(queues)
+		})
+	}
+}
+
+// triggerCutoff merges and uploads files now instead of waiting for the next cutoff time.
+func triggerCutoff(logger log.Logger, aggregators map[string]*XferAggregator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		aggs, found := selectAggregators(r, aggregators)
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		runs := make([]*deliveries.Run, 0, len(aggs))
+		for i := range aggs {
+			run, err := aggs[i].Cutoff(r.Context())
+			if err != nil {
+				responder.Problem(fmt.Errorf("running %s cutoff: %v", aggs[i].config().Name, err))
+				return
+			}
+			if run != nil {
+				runs = append(runs, run)
+			}
+		}
+		logger.Log(
+			"pipeline", fmt.Sprintf("ran %d manual cutoffs", len(runs)),
+			"userID", responder.XUserID, "requestID", responder.XRequestID)
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(runs)
+		})
+	}
+}
+
+// requeueFailedDeliveries merges each file which failed to upload again so it's
+// uploaded at the next cutoff.
+func requeueFailedDeliveries(logger log.Logger, aggregators map[string]*XferAggregator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		requeued := make([]*deliveries.Delivery, 0)
+		for name, xfagg := range aggregators {
+			dels, err := xfagg.RequeueFailed()
+			requeued = append(requeued, dels...)
+			if err != nil {
+				responder.Problem(fmt.Errorf("requeueing %s deliveries: %v", name, err))
+				return
+			}
+		}
+		logger.Log(
+			"pipeline", fmt.Sprintf("requeued %d failed deliveries", len(requeued)),
+			"userID", responder.XUserID, "requestID", responder.XRequestID)
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(requeued)
+		})
+	}
+}
+
+// triggerDryRun writes the files the next cutoff would upload to each ODFI's review directory.
+func triggerDryRun(logger log.Logger, aggregators map[string]*XferAggregator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodPost {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		aggs, found := selectAggregators(r, aggregators)
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		runs := make([]*DryRun, 0, len(aggs))
+		for i := range aggs {
+			run, err := aggs[i].DryRun()
+			if err != nil {
+				responder.Problem(fmt.Errorf("%s dry run: %v", aggs[i].config().Name, err))
+				return
+			}
+			runs = append(runs, run)
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(runs)
+		})
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers/deliveries"
+	"github.com/moov-io/paygate/pkg/upload"
+	"github.com/moov-io/paygate/x/schedule"
+
+	"github.com/antihax/optional"
+	"github.com/go-kit/kit/log"
+)
+
+func testMerging(t *testing.T) (XferMerging, string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "paygate-merging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	cfg := config.Pipeline{
+		Merging: &config.Merging{Directory: dir},
+	}
+	merger, err := NewMerging(log.NewNopLogger(), cfg, "odfi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return merger, dir
+}
+
+func TestAdmin__requeueFailedDeliveries(t *testing.T) {
+	merger, dir := testMerging(t)
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivery := &deliveries.Delivery{
+		DeliveryID: base.ID(),
+		Filename:   "20200529-987654320-1.ach",
+		Status:     deliveries.Failed,
+		Created:    time.Now(),
+	}
+	if err := merger.SaveFailed(delivery.DeliveryID, file); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &deliveries.MockRepository{Deliveries: []*deliveries.Delivery{delivery}}
+	xfagg := NewAggregator(log.NewNopLogger(), config.ODFI{}, nil, nil, repo, merger)
+
+	svc, c := testclient.Admin(t)
+	RegisterAdminRoutes(log.NewNopLogger(), svc, map[string]*XferAggregator{"odfi": xfagg})
+
+	requeued, resp, err := c.DeliveriesApi.RequeueFailedDeliveries(context.TODO(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(requeued) != 1 || requeued[0].DeliveryID != delivery.DeliveryID || requeued[0].Status != string(deliveries.Requeued) {
+		t.Fatalf("unexpected deliveries: %#v", requeued)
+	}
+
+	// the file is merged again at the next cutoff
+	if _, err := os.Stat(filepath.Join(dir, "odfi", "mergable", delivery.DeliveryID+".ach")); err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing left to requeue
+	requeued, resp, err = c.DeliveriesApi.RequeueFailedDeliveries(context.TODO(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(requeued) != 0 {
+		t.Errorf("unexpected deliveries: %#v", requeued)
+	}
+}
+
+func TestAdmin__mergingAndCutoffs(t *testing.T) {
+	merger, _ := testMerging(t)
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xfer := Xfer{
+		Transfer: &client.Transfer{TransferID: base.ID(), Amount: "USD 105.00"},
+		File:     file,
+	}
+	if err := merger.HandleXfer(xfer); err != nil {
+		t.Fatal(err)
+	}
+
+	agent := &upload.MockAgent{}
+	repo := &deliveries.MockRepository{}
+	xfagg := NewAggregator(log.NewNopLogger(), config.ODFI{Name: "odfi"}, agent, nil, repo, merger)
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	t.Cleanup(cancelFunc)
+	go xfagg.Start(ctx, &schedule.CutoffTimes{C: make(chan time.Time)})
+
+	svc, c := testclient.Admin(t)
+	RegisterAdminRoutes(log.NewNopLogger(), svc, map[string]*XferAggregator{"odfi": xfagg})
+
+	// preview what the next cutoff would upload
+	queues, resp, err := c.MergingApi.GetMergingQueues(context.TODO(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(queues) != 1 || queues[0].Odfi != "odfi" {
+		t.Fatalf("unexpected queues: %#v", queues)
+	}
+	if len(queues[0].Transfers) != 1 || queues[0].Transfers[0].TransferID != xfer.Transfer.TransferID {
+		t.Errorf("unexpected transfers: %#v", queues[0].Transfers)
+	}
+	if len(queues[0].Files) != 1 {
+		t.Fatalf("unexpected files: %#v", queues[0].Files)
+	}
+	if f := queues[0].Files[0]; f.Batches != 1 || f.Entries != 1 || f.TotalDebit != "USD 105.00" || f.TotalCredit != "USD 0.00" {
+		t.Errorf("unexpected file: %#v", f)
+	}
+
+	// unknown ODFI
+	opts := &admin.GetMergingQueuesOpts{Odfi: optional.NewString("other")}
+	_, resp, _ = c.MergingApi.GetMergingQueues(context.TODO(), "userID", opts)
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+
+	// manual cutoff
+	runs, resp, err := c.MergingApi.TriggerCutoff(context.TODO(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(runs) != 1 || !runs[0].Manual || runs[0].Odfi != "odfi" || runs[0].Error != "" {
+		t.Fatalf("unexpected runs: %#v", runs)
+	}
+	if len(runs[0].Deliveries) != 1 || runs[0].Deliveries[0].Status != string(deliveries.Delivered) {
+		t.Errorf("unexpected deliveries: %#v", runs[0].Deliveries)
+	}
+	if agent.UploadedFile == nil {
+		t.Error("expected uploaded file")
+	}
+
+	// nothing is left to merge
+	queues, resp, err = c.MergingApi.GetMergingQueues(context.TODO(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(queues) != 1 || len(queues[0].Transfers) != 0 || len(queues[0].Files) != 0 {
+		t.Errorf("unexpected queues: %#v", queues)
+	}
+}
+
+func TestAdmin__triggerDryRun(t *testing.T) {
+	merger, dir := testMerging(t)
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xfer := Xfer{
+		Transfer: &client.Transfer{TransferID: base.ID(), Amount: "USD 105.00"},
+		File:     file,
+	}
+	if err := merger.HandleXfer(xfer); err != nil {
+		t.Fatal(err)
+	}
+
+	agent := &upload.MockAgent{}
+	cfg := config.ODFI{
+		Name: "odfi",
+		DryRun: &config.DryRun{
+			Enabled:   true,
+			Directory: filepath.Join(dir, "review"),
+		},
+	}
+	xfagg := NewAggregator(log.NewNopLogger(), cfg, agent, nil, &deliveries.MockRepository{}, merger)
+
+	svc, c := testclient.Admin(t)
+	RegisterAdminRoutes(log.NewNopLogger(), svc, map[string]*XferAggregator{"odfi": xfagg})
+
+	runs, resp, err := c.MergingApi.TriggerDryRun(context.TODO(), "userID", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(runs) != 1 || len(runs[0].Files) != 1 {
+		t.Fatalf("unexpected dry runs: %#v", runs)
+	}
+	f := runs[0].Files[0]
+	if !strings.HasSuffix(f.Filename, "-076401251-1.ach") || f.Entries != 1 {
+		t.Errorf("unexpected file: %#v", f)
+	}
+	written, err := ach.ReadFile(filepath.Join(runs[0].Directory, f.Filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written.Batches) != 1 {
+		t.Errorf("unexpected batches: %#v", written.Batches)
+	}
+
+	// nothing was uploaded and the Transfer is still pending
+	if agent.UploadedFile != nil {
+		t.Errorf("unexpected upload: %#v", agent.UploadedFile)
+	}
+	if xfers, err := merger.ListPending(); len(xfers) != 1 || err != nil {
+		t.Errorf("pending=%#v error=%v", xfers, err)
+	}
+
+	// cutoffs aren't triggered in dry run mode
+	_, resp, _ = c.MergingApi.TriggerCutoff(context.TODO(), "userID", nil)
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+}
@@ -7,18 +7,28 @@ package pipeline
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/achx"
+	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/model"
+	"github.com/moov-io/paygate/pkg/transfers/deliveries"
 	"github.com/moov-io/paygate/pkg/upload"
 	"github.com/moov-io/paygate/x/schedule"
 
 	"github.com/go-kit/kit/log"
-	"gocloud.dev/pubsub"
 )
 
 // XferAggregator ...
@@ -26,69 +36,173 @@ import (
 // this has a for loop which is triggered on cutoff warning
 //  e.g. 10mins before 30mins before cutoff (10 mins is Moov's window, 30mins is ODFI)
 // consume as many transfers as possible, then upload.
+//
+// Each ODFI has its own XferAggregator which uploads the files merged for it. An
+// XferConsumer hands each Xfer to the merger of its ODFI.
 type XferAggregator struct {
 	cfg    config.ODFI
+	cfgMu  sync.RWMutex // protects cfg, which is replaced on config reloads
 	logger log.Logger
 
 	agent upload.Agent
+	gpg   *upload.GPG
+
+	deliveries deliveries.Repository
+
+	merger XferMerging
+
+	// manual receives cutoffs triggered by an operator so they run on the same loop as
+	// scheduled cutoffs and never overlap them
+	manual chan chan *deliveries.Run
 
-	merger       XferMerging
-	subscription *pubsub.Subscription
+	// cutoffs receives replacement CutoffTimes when the config is reloaded
+	cutoffs chan *schedule.CutoffTimes
 }
 
-func NewAggregator(logger log.Logger, cfg config.ODFI, agent upload.Agent, merger XferMerging, sub *pubsub.Subscription) *XferAggregator {
+func NewAggregator(logger log.Logger, cfg config.ODFI, agent upload.Agent, gpg *upload.GPG, deliveryRepo deliveries.Repository, merger XferMerging) *XferAggregator {
+	if cfg.Name != "" {
+		logger = log.With(logger, "odfi", cfg.Name)
+	}
 	return &XferAggregator{
-		cfg:          cfg,
-		logger:       logger,
-		agent:        agent,
-		merger:       merger,
-		subscription: sub,
+		cfg:        cfg,
+		logger:     logger,
+		agent:      agent,
+		gpg:        gpg,
+		deliveries: deliveryRepo,
+		merger:     merger,
+		manual:     make(chan chan *deliveries.Run),
+		cutoffs:    make(chan *schedule.CutoffTimes, 1),
 	}
 }
 
-// receive each message of *pubsub.Subscription, detect message type
-//   - if Xfer, write into ./mergable/
-//   - if Xfer, write/rename as ./mergable/foo.ach.deleted ?
-//   - on cutoff merge files
+func (xfagg *XferAggregator) config() config.ODFI {
+	xfagg.cfgMu.RLock()
+	defer xfagg.cfgMu.RUnlock()
+	return xfagg.cfg
+}
+
+// Reload applies a changed ODFI config, such as its filename template or dry run mode, to
+// files merged from now on. The previous CutoffTimes are stopped and replaced by cutoffs
+// once any run in progress finishes.
+func (xfagg *XferAggregator) Reload(cfg config.ODFI, cutoffs *schedule.CutoffTimes) {
+	xfagg.cfgMu.Lock()
+	xfagg.cfg = cfg
+	xfagg.cfgMu.Unlock()
+
+	if cutoffs == nil {
+		return
+	}
+	for {
+		select {
+		case xfagg.cutoffs <- cutoffs:
+			return
+		case pending := <-xfagg.cutoffs:
+			// an earlier reload hasn't been picked up yet, it's replaced by this one
+			pending.Stop()
+		}
+	}
+}
 
+// on each cutoff merge files and upload them to the ODFI
 func (xfagg *XferAggregator) Start(ctx context.Context, cutoffs *schedule.CutoffTimes) {
-	// TODO(adam): when <-ctx.Done() fires shutdown xfagg.consumer and upload any files we have
+	// TODO(adam): when <-ctx.Done() fires upload any files we have
 	for {
 		select {
 		case tt := <-cutoffs.C:
-			xfagg.withEachFile(tt)
-
-		case msg := <-xfagg.await():
-			if err := handleMessage(xfagg.merger, msg); err != nil {
-				xfagg.logger.Log("aggregate", fmt.Sprintf("ERROR handling message: %v", err))
+			if xfagg.config().DryRun.Active() {
+				xfagg.dryRunCutoff(tt)
+			} else {
+				xfagg.withEachFile(ctx, tt, false)
 			}
 
+		case done := <-xfagg.manual:
+			done <- xfagg.withEachFile(ctx, time.Now(), true)
+
+		case next := <-xfagg.cutoffs:
+			cutoffs.Stop()
+			cutoffs = next
+
 		case <-ctx.Done():
 			xfagg.logger.Log("aggregate", "shutting down xfer aggregation")
 			cutoffs.Stop()
+			return
 		}
 	}
 }
 
-func (xfagg *XferAggregator) withEachFile(when time.Time) {
+// Cutoff merges and uploads files now rather than waiting for the next cutoff time.
+// The Run is returned with each Delivery it made.
+func (xfagg *XferAggregator) Cutoff(ctx context.Context) (*deliveries.Run, error) {
+	if xfagg.config().DryRun.Active() {
+		return nil, errDryRunMode
+	}
+	done := make(chan *deliveries.Run, 1)
+	select {
+	case xfagg.manual <- done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case run := <-done:
+		return xfagg.deliveries.GetRun(run.RunID)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (xfagg *XferAggregator) withEachFile(ctx context.Context, when time.Time, manual bool) *deliveries.Run {
 	window := when.Format("15:04")
 	xfagg.logger.Log("aggregate", fmt.Sprintf("starting %s cutoff window processing", window))
 
-	if err := xfagg.merger.WithEachMerged(xfagg.uploadFile); err != nil {
+	run := &deliveries.Run{
+		RunID:   base.ID(),
+		ODFI:    xfagg.config().Name,
+		Window:  window,
+		Manual:  manual,
+		Started: time.Now(),
+	}
+	if err := xfagg.deliveries.CreateRun(run); err != nil {
+		xfagg.logger.Log("aggregate", fmt.Sprintf("ERROR saving runID=%s: %v", run.RunID, err))
+	}
+
+	err := xfagg.merger.WithEachMerged(func(f *ach.File) error {
+		return xfagg.uploadFile(ctx, run.RunID, f)
+	})
+	if err != nil {
 		xfagg.logger.Log("aggregate", fmt.Sprintf("ERROR inside WithEachMerged: %v", err))
+		run.Error = err.Error()
+	}
+
+	finished := time.Now()
+	run.Finished = &finished
+	if err := xfagg.deliveries.FinishRun(run.RunID, finished, run.Error); err != nil {
+		xfagg.logger.Log("aggregate", fmt.Sprintf("ERROR finishing runID=%s: %v", run.RunID, err))
 	}
 
 	xfagg.logger.Log("aggregate", fmt.Sprintf("ended %s cutoff window processing", window))
+	return run
 }
 
-func (xfagg *XferAggregator) uploadFile(f *ach.File) error {
+// renderFilename returns the name a merged file is uploaded to the ODFI as.
+func (xfagg *XferAggregator) renderFilename(f *ach.File, n string, gpg bool) (string, error) {
+	cfg := xfagg.config()
 	data := upload.FilenameData{
 		RoutingNumber: f.Header.ImmediateDestination,
-		N:             "1", // TODO(adam): upload.ACHFilenameSeq(..) we need to increment sequence number
+		N:             n,
+		GPG:           gpg,
 	}
-	filename, err := upload.RenderACHFilename(xfagg.cfg.FilenameTemplate(), data)
+	filename, err := upload.RenderACHFilename(cfg.FilenameTemplate(), data)
+	if err != nil {
+		return "", fmt.Errorf("problem rendering filename template: %v", err)
+	}
+	return filename, nil
+}
+
+func (xfagg *XferAggregator) uploadFile(ctx context.Context, runID string, f *ach.File) error {
+	// TODO(adam): upload.ACHFilenameSeq(..) we need to increment sequence number
+	filename, err := xfagg.renderFilename(f, "1", xfagg.gpg.Enabled())
 	if err != nil {
-		return fmt.Errorf("problem rendering filename template: %v", err)
+		return err
 	}
 
 	var buf bytes.Buffer
@@ -96,45 +210,248 @@ func (xfagg *XferAggregator) uploadFile(f *ach.File) error {
 		return fmt.Errorf("unable to buffer ACH file: %v", err)
 	}
 
-	return xfagg.agent.UploadFile(upload.File{
+	// Encrypt and sign the file if our ODFI requires it
+	file, err := xfagg.gpg.Encrypt(upload.File{
 		Filename: filename,
 		Contents: ioutil.NopCloser(&buf),
 	})
+	if err != nil {
+		return err
+	}
+	bs, err := ioutil.ReadAll(file.Contents)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %v", file.Filename, err)
+	}
+
+	delivery := newDelivery(file.Filename, bs, achx.TraceNumbers(f))
+	delivery.RunID = runID
+	if err := xfagg.deliver(ctx, delivery, bs); err != nil {
+		if delivery.Status == deliveries.Failed {
+			// Keep the file around so operators can requeue it
+			if err := xfagg.merger.SaveFailed(delivery.DeliveryID, f); err != nil {
+				xfagg.logger.Log("aggregate", fmt.Sprintf("ERROR saving failed deliveryID=%s: %v", delivery.DeliveryID, err))
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func newDelivery(filename string, bs []byte, traceNumbers []string) *deliveries.Delivery {
+	checksum := sha256.Sum256(bs)
+	return &deliveries.Delivery{
+		DeliveryID:   base.ID(),
+		Filename:     filename,
+		Size:         int64(len(bs)),
+		SHA256:       hex.EncodeToString(checksum[:]),
+		Status:       deliveries.Pending,
+		Created:      time.Now(),
+		TraceNumbers: traceNumbers,
+	}
+}
+
+// deliver uploads a file to the ODFI, retrying with backoff according to our policy, and
+// records each attempt so operators can see the file's delivery status. Deliveries which are
+// still retrying when ctx is done are marked as failed so they can be requeued.
+func (xfagg *XferAggregator) deliver(ctx context.Context, delivery *deliveries.Delivery, bs []byte) error {
+	filename := delivery.Filename
+	if err := xfagg.deliveries.CreateDelivery(delivery); err != nil {
+		return fmt.Errorf("problem saving delivery of %s: %v", filename, err)
+	}
+
+	retry := xfagg.config().UploadRetry
+	maxAttempts := retry.MaxAttempts()
+	for attempt := 1; ; attempt++ {
+		err := xfagg.agent.UploadFile(upload.File{
+			Filename: filename,
+			Contents: ioutil.NopCloser(bytes.NewReader(bs)),
+		})
+
+		status, result := deliveries.Delivered, deliveries.Attempt{
+			Attempt: attempt,
+			Created: time.Now(),
+		}
+		if err != nil {
+			result.Error = err.Error()
+			status = deliveries.Pending
+			if attempt >= maxAttempts {
+				status = deliveries.Failed
+			}
+		}
+		delivery.Status = status
+		if err := xfagg.deliveries.RecordAttempt(delivery.DeliveryID, result, status); err != nil {
+			xfagg.logger.Log("aggregate", fmt.Sprintf("ERROR recording deliveryID=%s attempt %d: %v", delivery.DeliveryID, attempt, err))
+		}
+
+		switch status {
+		case deliveries.Delivered:
+			xfagg.logger.Log("aggregate", fmt.Sprintf("delivered %s (deliveryID=%s) on attempt %d", filename, delivery.DeliveryID, attempt))
+			return nil
+		case deliveries.Failed:
+			return fmt.Errorf("giving up on %s (deliveryID=%s) after %d attempts: %v", filename, delivery.DeliveryID, attempt, err)
+		}
+
+		delay := retry.Delay(attempt)
+		xfagg.logger.Log("aggregate", fmt.Sprintf("problem uploading %s (deliveryID=%s), retrying in %v: %v", filename, delivery.DeliveryID, delay, err))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			delivery.Status = deliveries.Failed
+			if err := xfagg.deliveries.UpdateStatus(delivery.DeliveryID, deliveries.Failed); err != nil {
+				xfagg.logger.Log("aggregate", fmt.Sprintf("ERROR failing deliveryID=%s: %v", delivery.DeliveryID, err))
+			}
+			return fmt.Errorf("stopped retrying %s (deliveryID=%s) after %d attempts: %v", filename, delivery.DeliveryID, attempt, ctx.Err())
+		}
+	}
 }
 
-func (xfagg *XferAggregator) await() chan *pubsub.Message {
-	out := make(chan *pubsub.Message, 1)
-	go func() {
-		msg, err := xfagg.subscription.Receive(context.Background())
+// RequeueFailed merges each file which failed to upload again so it's uploaded at the next
+// cutoff. The requeued Deliveries are returned.
+func (xfagg *XferAggregator) RequeueFailed() ([]*deliveries.Delivery, error) {
+	deliveryIDs, err := xfagg.merger.RequeueFailed()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*deliveries.Delivery
+	for i := range deliveryIDs {
+		if err := xfagg.deliveries.UpdateStatus(deliveryIDs[i], deliveries.Requeued); err != nil {
+			return out, fmt.Errorf("deliveryID=%s: %v", deliveryIDs[i], err)
+		}
+		delivery, err := xfagg.deliveries.GetDelivery(deliveryIDs[i])
 		if err != nil {
-			xfagg.logger.Log("aggregate", fmt.Sprintf("ERROR receiving message: %v", err))
+			return out, fmt.Errorf("deliveryID=%s: %v", deliveryIDs[i], err)
+		}
+		if delivery != nil {
+			out = append(out, delivery)
 		}
-		// TODO(adam): we need to wire through a cancel func
-		out <- msg
-	}()
-	return out
+	}
+	return out, nil
 }
 
-// handleMessage attempts to parse a pubsub.Message into a strongly typed message
-// which an XferMerging instance can handle.
-func handleMessage(merger XferMerging, msg *pubsub.Message) error {
-	fmt.Printf("(preview) msg.body=%s\n", string(msg.Body)[:50])
+// MergingQueue is what the next cutoff would upload to an ODFI.
+type MergingQueue struct {
+	ODFI      string             `json:"odfi"`
+	Transfers []*client.Transfer `json:"transfers"`
+	Files     []MergedFile       `json:"files"`
+}
+
+// MergedFile summarizes a file merged for the next cutoff.
+type MergedFile struct {
+	// Filename is set when the file was written by a dry run
+	Filename string `json:"filename,omitempty"`
+
+	ImmediateDestination string `json:"immediateDestination"`
+	ImmediateOrigin      string `json:"immediateOrigin"`
+
+	Batches int `json:"batches"`
+	Entries int `json:"entries"`
 
-	var xfer Xfer
-	if err := json.NewDecoder(bytes.NewReader(msg.Body)).Decode(&xfer); err != nil {
-		msg.Nack()
-		transferID := msg.Metadata["transferID"]
-		return fmt.Errorf("problem decoding for transferID=%s: %v", transferID, err)
+	TotalDebit  string `json:"totalDebit"`
+	TotalCredit string `json:"totalCredit"`
+}
+
+func summarizeFile(file *ach.File) MergedFile {
+	debit, _ := model.NewAmountFromInt("USD", file.Control.TotalDebitEntryDollarAmountInFile)
+	credit, _ := model.NewAmountFromInt("USD", file.Control.TotalCreditEntryDollarAmountInFile)
+	entries := 0
+	for i := range file.Batches {
+		entries += len(file.Batches[i].GetEntries())
 	}
-	fmt.Printf("parsed Xfer=%v\n", xfer)
-	if err := merger.HandleXfer(xfer); err != nil {
-		msg.Nack()
-		return fmt.Errorf("HandleXfer problem with transferID=%s: %v", xfer.Transfer.TransferID, err)
+	return MergedFile{
+		ImmediateDestination: file.Header.ImmediateDestination,
+		ImmediateOrigin:      file.Header.ImmediateOrigin,
+		Batches:              len(file.Batches),
+		Entries:              entries,
+		TotalDebit:           debit.String(),
+		TotalCredit:          credit.String(),
 	}
+}
 
-	msg.Ack()
+// Queue returns the Transfers waiting for the next cutoff and a preview of the files
+// they would be merged into.
+func (xfagg *XferAggregator) Queue() (*MergingQueue, error) {
+	xfers, err := xfagg.merger.ListPending()
+	if err != nil {
+		return nil, err
+	}
+	files, err := xfagg.merger.PreviewMerged()
+	if err != nil {
+		return nil, err
+	}
 
-	// TODO(adam): handle Cancel / HandleCancel
+	queue := &MergingQueue{
+		ODFI:      xfagg.config().Name,
+		Transfers: xfers,
+		Files:     make([]MergedFile, 0, len(files)),
+	}
+	for i := range files {
+		queue.Files = append(queue.Files, summarizeFile(files[i]))
+	}
+	return queue, nil
+}
 
-	return nil
+var errDryRunMode = errors.New("ODFI is in dry run mode, files are only uploaded once dry_run is disabled")
+
+// DryRun is a set of merged files written for review instead of being uploaded.
+type DryRun struct {
+	ODFI      string       `json:"odfi"`
+	Directory string       `json:"directory"`
+	Files     []MergedFile `json:"files"`
+	Created   time.Time    `json:"created"`
+}
+
+// DryRun merges the pending files the same way a cutoff does and writes them under the
+// ODFI's review directory without uploading them. Pending Transfers are left in place
+// for the next cutoff.
+func (xfagg *XferAggregator) DryRun() (*DryRun, error) {
+	files, err := xfagg.merger.PreviewMerged()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := xfagg.config()
+	now := time.Now()
+	dir := filepath.Join(cfg.DryRun.ReviewDirectory(cfg.Name), now.Format("20060102-150405"))
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, err
+	}
+
+	run := &DryRun{
+		ODFI:      cfg.Name,
+		Directory: dir,
+		Files:     make([]MergedFile, 0, len(files)),
+		Created:   now,
+	}
+	for i := range files {
+		// Files are written unencrypted so they can be reviewed
+		filename, err := xfagg.renderFilename(files[i], strconv.Itoa(i+1), false)
+		if err != nil {
+			return run, err
+		}
+		var buf bytes.Buffer
+		if err := ach.NewWriter(&buf).Write(files[i]); err != nil {
+			return run, fmt.Errorf("unable to buffer ACH file: %v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, filename), buf.Bytes(), 0644); err != nil {
+			return run, err
+		}
+
+		summary := summarizeFile(files[i])
+		summary.Filename = filename
+		run.Files = append(run.Files, summary)
+	}
+	xfagg.logger.Log("aggregate", fmt.Sprintf("dry run wrote %d files to %s", len(run.Files), dir))
+
+	return run, nil
+}
+
+func (xfagg *XferAggregator) dryRunCutoff(when time.Time) {
+	window := when.Format("15:04")
+	xfagg.logger.Log("aggregate", fmt.Sprintf("starting %s cutoff window dry run", window))
+
+	if _, err := xfagg.DryRun(); err != nil {
+		xfagg.logger.Log("aggregate", fmt.Sprintf("ERROR during dry run: %v", err))
+	}
 }
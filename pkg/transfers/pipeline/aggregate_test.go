@@ -0,0 +1,127 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/deliveries"
+	"github.com/moov-io/paygate/pkg/upload"
+
+	"github.com/go-kit/kit/log"
+)
+
+// flakyAgent fails the first few uploads before handing them to MockAgent
+type flakyAgent struct {
+	*upload.MockAgent
+
+	failures int
+	attempts int
+}
+
+func (a *flakyAgent) UploadFile(f upload.File) error {
+	a.attempts++
+	if a.attempts <= a.failures {
+		f.Close()
+		return errors.New("connection reset")
+	}
+	return a.MockAgent.UploadFile(f)
+}
+
+func testAggregator(agent upload.Agent, repo deliveries.Repository, attempts int) *XferAggregator {
+	cfg := config.ODFI{
+		UploadRetry: &config.UploadRetry{
+			Attempts: attempts,
+			Backoff:  time.Millisecond,
+		},
+	}
+	return NewAggregator(log.NewNopLogger(), cfg, agent, nil, repo, nil)
+}
+
+func TestAggregator__deliver(t *testing.T) {
+	agent := &flakyAgent{MockAgent: &upload.MockAgent{}, failures: 2}
+	repo := &deliveries.MockRepository{}
+
+	xfagg := testAggregator(agent, repo, 3)
+	if err := xfagg.deliver(context.Background(), newDelivery("20200529-987654320-1.ach", []byte("ach file"), nil), []byte("ach file")); err != nil {
+		t.Fatal(err)
+	}
+
+	if agent.UploadedFile == nil || agent.UploadedFile.Filename != "20200529-987654320-1.ach" {
+		t.Fatalf("unexpected upload: %#v", agent.UploadedFile)
+	}
+	bs, _ := ioutil.ReadAll(agent.UploadedFile.Contents)
+	if string(bs) != "ach file" {
+		t.Errorf("uploaded %q", string(bs))
+	}
+
+	if len(repo.Deliveries) != 1 {
+		t.Fatalf("unexpected deliveries: %#v", repo.Deliveries)
+	}
+	d := repo.Deliveries[0]
+	if d.Status != deliveries.Delivered || d.Size != 8 || d.SHA256 == "" {
+		t.Errorf("unexpected delivery: %#v", d)
+	}
+	if n := len(d.Attempts); n != 3 {
+		t.Fatalf("got %d attempts", n)
+	}
+	if d.Attempts[0].Error != "connection reset" || d.Attempts[2].Error != "" {
+		t.Errorf("unexpected attempts: %#v", d.Attempts)
+	}
+}
+
+func TestAggregator__deliverFailed(t *testing.T) {
+	agent := &flakyAgent{MockAgent: &upload.MockAgent{}, failures: 5}
+	repo := &deliveries.MockRepository{}
+
+	xfagg := testAggregator(agent, repo, 2)
+	if err := xfagg.deliver(context.Background(), newDelivery("20200529-987654320-1.ach", []byte("ach file"), nil), []byte("ach file")); err == nil {
+		t.Fatal("expected error")
+	}
+	if agent.attempts != 2 {
+		t.Errorf("made %d attempts", agent.attempts)
+	}
+	if d := repo.Deliveries[0]; d.Status != deliveries.Failed || len(d.Attempts) != 2 {
+		t.Errorf("unexpected delivery: %#v", d)
+	}
+
+	// problems saving the delivery stop the upload
+	repo = &deliveries.MockRepository{Err: errors.New("bad error")}
+	agent = &flakyAgent{MockAgent: &upload.MockAgent{}}
+	if err := testAggregator(agent, repo, 1).deliver(context.Background(), newDelivery("a.ach", []byte("ach file"), nil), []byte("ach file")); err == nil {
+		t.Error("expected error")
+	}
+	if agent.attempts != 0 {
+		t.Errorf("made %d attempts", agent.attempts)
+	}
+}
+
+func TestAggregator__deliverCanceled(t *testing.T) {
+	agent := &flakyAgent{MockAgent: &upload.MockAgent{}, failures: 5}
+	repo := &deliveries.MockRepository{}
+
+	xfagg := testAggregator(agent, repo, 5)
+	cfg := xfagg.config()
+	cfg.UploadRetry.Backoff = time.Hour
+	xfagg.Reload(cfg, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := xfagg.deliver(ctx, newDelivery("a.ach", []byte("ach file"), nil), []byte("ach file")); err == nil {
+		t.Fatal("expected error")
+	}
+	if agent.attempts != 1 {
+		t.Errorf("made %d attempts", agent.attempts)
+	}
+	if d := repo.Deliveries[0]; d.Status != deliveries.Failed || len(d.Attempts) != 1 {
+		t.Errorf("unexpected delivery: %#v", d)
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-kit/kit/log"
+	"gocloud.dev/pubsub"
+)
+
+// XferConsumer receives each message of the pipeline's subscription and hands the Xfer
+// to the XferMerging of the ODFI it's routed to.
+//
+// receive each message of *pubsub.Subscription, detect message type
+//   - if Xfer, write into ./mergable/
+//   - if CanceledTransfer, rename as ./mergable/foo.ach.canceled
+type XferConsumer struct {
+	logger       log.Logger
+	subscription *pubsub.Subscription
+
+	// mergers holds the XferMerging of each ODFI by name, the first ODFI added
+	// is stored under the empty name as well.
+	mergers map[string]XferMerging
+}
+
+func NewConsumer(logger log.Logger, sub *pubsub.Subscription) *XferConsumer {
+	return &XferConsumer{
+		logger:       logger,
+		subscription: sub,
+		mergers:      make(map[string]XferMerging),
+	}
+}
+
+// AddODFI routes Xfers for the named ODFI into merger. Xfers without an ODFI
+// are routed to the first ODFI added.
+func (c *XferConsumer) AddODFI(name string, merger XferMerging) {
+	if _, exists := c.mergers[""]; !exists {
+		c.mergers[""] = merger
+	}
+	c.mergers[name] = merger
+}
+
+func (c *XferConsumer) Start(ctx context.Context) {
+	for {
+		select {
+		case msg := <-c.await():
+			if msg == nil {
+				continue
+			}
+			if err := c.handleMessage(msg); err != nil {
+				c.logger.Log("consumer", fmt.Sprintf("ERROR handling message: %v", err))
+			}
+
+		case <-ctx.Done():
+			c.logger.Log("consumer", "shutting down xfer consumer")
+			return
+		}
+	}
+}
+
+func (c *XferConsumer) await() chan *pubsub.Message {
+	out := make(chan *pubsub.Message, 1)
+	go func() {
+		msg, err := c.subscription.Receive(context.Background())
+		if err != nil {
+			c.logger.Log("consumer", fmt.Sprintf("ERROR receiving message: %v", err))
+		}
+		// TODO(adam): we need to wire through a cancel func
+		out <- msg
+	}()
+	return out
+}
+
+// handleMessage attempts to parse a pubsub.Message into a strongly typed message
+// which the XferMerging of its ODFI can handle.
+func (c *XferConsumer) handleMessage(msg *pubsub.Message) error {
+	if msg.Metadata["type"] == messageTypeCancel {
+		return c.handleCancel(msg)
+	}
+
+	var xfer Xfer
+	if err := json.NewDecoder(bytes.NewReader(msg.Body)).Decode(&xfer); err != nil {
+		msg.Nack()
+		transferID := msg.Metadata["transferID"]
+		return fmt.Errorf("problem decoding for transferID=%s: %v", transferID, err)
+	}
+	merger, exists := c.mergers[xfer.ODFI]
+	if !exists {
+		// Redelivering won't find the ODFI either, so drop the Xfer and let our caller log it
+		msg.Ack()
+		return fmt.Errorf("transferID=%s has unknown ODFI %q", xfer.Transfer.TransferID, xfer.ODFI)
+	}
+	if err := merger.HandleXfer(xfer); err != nil {
+		msg.Nack()
+		return fmt.Errorf("HandleXfer problem with transferID=%s: %v", xfer.Transfer.TransferID, err)
+	}
+
+	msg.Ack()
+
+	return nil
+}
+
+// handleCancel removes the Transfer from every ODFI's files as cancellations don't know
+// which ODFI the Transfer was routed to.
+func (c *XferConsumer) handleCancel(msg *pubsub.Message) error {
+	var cancel CanceledTransfer
+	if err := json.NewDecoder(bytes.NewReader(msg.Body)).Decode(&cancel); err != nil {
+		msg.Nack()
+		return fmt.Errorf("problem decoding cancel for transferID=%s: %v", msg.Metadata["transferID"], err)
+	}
+
+	canceled := false
+	seen := make(map[XferMerging]bool)
+	for _, merger := range c.mergers {
+		if seen[merger] {
+			continue
+		}
+		seen[merger] = true
+
+		err := merger.HandleCancel(cancel)
+		if err == ErrNotMergable {
+			continue
+		}
+		if err != nil {
+			msg.Nack()
+			return fmt.Errorf("HandleCancel problem with transferID=%s: %v", cancel.TransferID, err)
+		}
+		canceled = true
+	}
+	msg.Ack()
+
+	if !canceled {
+		return fmt.Errorf("canceled transferID=%s was not waiting to be merged and may have been uploaded", cancel.TransferID)
+	}
+	return nil
+}
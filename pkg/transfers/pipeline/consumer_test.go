@@ -0,0 +1,164 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/stream"
+
+	"github.com/go-kit/kit/log"
+)
+
+type recordingMerging struct {
+	xfers    []Xfer
+	canceled []string
+}
+
+func (m *recordingMerging) HandleXfer(xfer Xfer) error {
+	m.xfers = append(m.xfers, xfer)
+	return nil
+}
+
+func (m *recordingMerging) HandleCancel(msg CanceledTransfer) error {
+	for i := range m.xfers {
+		if m.xfers[i].Transfer.TransferID == msg.TransferID {
+			m.canceled = append(m.canceled, msg.TransferID)
+			return nil
+		}
+	}
+	return ErrNotMergable
+}
+
+func (m *recordingMerging) WithEachMerged(func(*ach.File) error) error {
+	return nil
+}
+
+func (m *recordingMerging) SaveFailed(deliveryID string, file *ach.File) error {
+	return nil
+}
+
+func (m *recordingMerging) RequeueFailed() ([]string, error) {
+	return nil, nil
+}
+
+func (m *recordingMerging) ListPending() ([]*client.Transfer, error) {
+	return nil, nil
+}
+
+func (m *recordingMerging) PreviewMerged() ([]*ach.File, error) {
+	return nil, nil
+}
+
+func TestConsumer__handleMessage(t *testing.T) {
+	pub := testingPublisher(t)
+	sub, err := stream.Subscription(context.Background(), fmt.Sprintf("mem://%s", t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sub.Shutdown(context.Background()) })
+
+	bankA, bankB := &recordingMerging{}, &recordingMerging{}
+	consumer := NewConsumer(log.NewNopLogger(), sub)
+	consumer.AddODFI("bank-a", bankA)
+	consumer.AddODFI("bank-b", bankB)
+
+	for _, odfi := range []string{"bank-b", "", "bank-c"} {
+		xfer := Xfer{
+			Transfer: &client.Transfer{TransferID: base.ID()},
+			ODFI:     odfi,
+		}
+		if err := pub.Upload(xfer); err != nil {
+			t.Fatal(err)
+		}
+
+		msg, err := sub.Receive(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Metadata["odfi"] != odfi {
+			t.Errorf("unexpected metadata: %#v", msg.Metadata)
+		}
+		err = consumer.handleMessage(msg)
+		if odfi == "bank-c" {
+			if err == nil {
+				t.Error("expected error for unknown ODFI")
+			}
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Xfers for an unknown ODFI are acked so they aren't redelivered
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if msg, err := sub.Receive(ctx); err == nil {
+		t.Errorf("unexpected redelivery: %#v", msg.Metadata)
+	}
+
+	// Xfers without an ODFI go to the first one added
+	if len(bankA.xfers) != 1 || bankA.xfers[0].ODFI != "" {
+		t.Errorf("bank-a: unexpected xfers %#v", bankA.xfers)
+	}
+	if len(bankB.xfers) != 1 || bankB.xfers[0].ODFI != "bank-b" {
+		t.Errorf("bank-b: unexpected xfers %#v", bankB.xfers)
+	}
+}
+
+func TestConsumer__handleCancel(t *testing.T) {
+	pub := testingPublisher(t)
+	sub, err := stream.Subscription(context.Background(), fmt.Sprintf("mem://%s", t.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sub.Shutdown(context.Background()) })
+
+	bankA, bankB := &recordingMerging{}, &recordingMerging{}
+	consumer := NewConsumer(log.NewNopLogger(), sub)
+	consumer.AddODFI("bank-a", bankA)
+	consumer.AddODFI("bank-b", bankB)
+
+	xfer := Xfer{
+		Transfer: &client.Transfer{TransferID: base.ID()},
+		ODFI:     "bank-b",
+	}
+	bankB.xfers = append(bankB.xfers, xfer)
+
+	// cancels are sent to each ODFI as they don't know where the transfer was routed
+	if err := pub.Cancel(CanceledTransfer{TransferID: xfer.Transfer.TransferID}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := sub.Receive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := consumer.handleMessage(msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(bankA.canceled) != 0 {
+		t.Errorf("bank-a: unexpected cancels %#v", bankA.canceled)
+	}
+	if len(bankB.canceled) != 1 || bankB.canceled[0] != xfer.Transfer.TransferID {
+		t.Errorf("bank-b: unexpected cancels %#v", bankB.canceled)
+	}
+
+	// transfers which aren't waiting to be merged are reported
+	if err := pub.Cancel(CanceledTransfer{TransferID: base.ID()}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err = sub.Receive(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := consumer.handleMessage(msg); err == nil {
+		t.Error("expected error")
+	}
+}
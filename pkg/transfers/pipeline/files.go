@@ -10,12 +10,12 @@ import (
 	"github.com/moov-io/paygate/pkg/client"
 )
 
-// PublishFiles attempts to upload all files to the Pipeline and returns
-// all errors as a base.ErrorList.
+// PublishFiles attempts to upload all files to the Pipeline for the named ODFI
+// and returns all errors as a base.ErrorList.
 //
 // All files are attempted to be published as downstream processors
 // are expected to de-duplicate files.
-func PublishFiles(pub XferPublisher, xfer *client.Transfer, files []*ach.File) error {
+func PublishFiles(pub XferPublisher, odfi string, xfer *client.Transfer, files []*ach.File) error {
 	if pub == nil {
 		return nil
 	}
@@ -25,6 +25,7 @@ func PublishFiles(pub XferPublisher, xfer *client.Transfer, files []*ach.File) e
 		xf := Xfer{
 			File:     files[i],
 			Transfer: xfer,
+			ODFI:     odfi,
 		}
 		if err := pub.Upload(xf); err != nil {
 			el.Add(err)
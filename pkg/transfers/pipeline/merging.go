@@ -7,10 +7,13 @@ package pipeline
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/moov-io/ach"
@@ -21,6 +24,11 @@ import (
 	"github.com/go-kit/kit/log"
 )
 
+var (
+	// ErrNotMergable is returned when canceling a Transfer which isn't waiting to be merged
+	ErrNotMergable = errors.New("transfer is not waiting to be merged")
+)
+
 // XferMerging represents logic for accepting ACH files to be merged together.
 //
 // The idea is to take Xfers and store them on a filesystem (or other durable storage)
@@ -30,17 +38,35 @@ import (
 // each merged file for an upload.
 type XferMerging interface {
 	HandleXfer(xfer Xfer) error
-	HandleCancel(xfer Xfer) error
+
+	// HandleCancel removes a Transfer's files so they aren't merged. ErrNotMergable is returned
+	// when the Transfer isn't waiting to be merged, which means it was already uploaded.
+	HandleCancel(msg CanceledTransfer) error
 
 	// Reset() error
 	WithEachMerged(func(*ach.File) error) error
+
+	// SaveFailed keeps a merged file which couldn't be uploaded so it can be requeued.
+	SaveFailed(deliveryID string, file *ach.File) error
+
+	// RequeueFailed moves each saved file back to be merged at the next cutoff and
+	// returns the deliveryID of each.
+	RequeueFailed() ([]string, error)
+	// ListPending returns each Transfer waiting to be merged at the next cutoff.
+	ListPending() ([]*client.Transfer, error)
+
+	// PreviewMerged returns the files the next cutoff would produce without uploading them.
+	PreviewMerged() ([]*ach.File, error)
 }
 
-func NewMerging(logger log.Logger, cfg config.Pipeline) (XferMerging, error) {
-	dir := filepath.Join("storage", "mergable") // default directory
+// NewMerging returns an XferMerging for the named ODFI. Each named ODFI merges files in its own
+// directory, while an unnamed ODFI uses the top-level directory.
+func NewMerging(logger log.Logger, cfg config.Pipeline, odfi string) (XferMerging, error) {
+	dir := "storage" // default directory
 	if cfg.Merging != nil {
-		dir = filepath.Join(cfg.Merging.Directory, "mergable")
+		dir = cfg.Merging.Directory
 	}
+	dir = filepath.Join(dir, odfi, "mergable")
 
 	if err := os.MkdirAll(dir, 0777); err != nil {
 		return nil, err
@@ -55,11 +81,17 @@ func NewMerging(logger log.Logger, cfg config.Pipeline) (XferMerging, error) {
 type filesystemMerging struct {
 	logger  log.Logger
 	baseDir string
+
+	// mu guards baseDir as Xfers are written while cutoffs isolate the directory
+	mu sync.Mutex
 }
 
 func (m *filesystemMerging) HandleXfer(xfer Xfer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	err1 := m.writeTransfer(xfer.Transfer)
-	err2 := m.writeACHFile(xfer.Transfer.TransferID, xfer.File)
+	err2 := m.writeACHFile(achFilename(xfer), xfer.File)
 
 	if err1 != nil || err2 != nil {
 		return fmt.Errorf("problem writing transfer: %v\n problem writing ACH file: %v", err1, err2)
@@ -68,6 +100,25 @@ func (m *filesystemMerging) HandleXfer(xfer Xfer) error {
 	return nil
 }
 
+// fileID returns the identifier used to store an Xfer's ACH file. Strategies which
+// originate several files for one Transfer give each file its own ID.
+func fileID(xfer Xfer) string {
+	if xfer.File != nil && xfer.File.ID != "" {
+		return xfer.File.ID
+	}
+	return xfer.Transfer.TransferID
+}
+
+// achFilename returns the name an Xfer's ACH file is stored under. Files are prefixed with their
+// TransferID so each file of a Transfer can be found when it's canceled.
+func achFilename(xfer Xfer) string {
+	id := fileID(xfer)
+	if id == xfer.Transfer.TransferID {
+		return fmt.Sprintf("%s.ach", id)
+	}
+	return fmt.Sprintf("%s-%s.ach", xfer.Transfer.TransferID, id)
+}
+
 func (m *filesystemMerging) writeTransfer(transfer *client.Transfer) error {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(transfer); err != nil {
@@ -82,13 +133,13 @@ func (m *filesystemMerging) writeTransfer(transfer *client.Transfer) error {
 	return nil
 }
 
-func (m *filesystemMerging) writeACHFile(transferID string, file *ach.File) error {
+func (m *filesystemMerging) writeACHFile(filename string, file *ach.File) error {
 	var buf bytes.Buffer
 	if err := ach.NewWriter(&buf).Write(file); err != nil {
 		return err
 	}
 
-	path := filepath.Join(m.baseDir, fmt.Sprintf("%s.ach", transferID))
+	path := filepath.Join(m.baseDir, filename)
 	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
 		return err
 	}
@@ -96,11 +147,40 @@ func (m *filesystemMerging) writeACHFile(transferID string, file *ach.File) erro
 	return nil
 }
 
-func (m *filesystemMerging) HandleCancel(xfer Xfer) error {
+// HandleCancel renames each file of the Transfer with a .canceled suffix so they're skipped when
+// merging but kept around to investigate later.
+func (m *filesystemMerging) HandleCancel(msg CanceledTransfer) error {
+	if msg.TransferID == "" {
+		return errors.New("missing TransferID")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var matches []string
+	for _, pattern := range []string{"%s.json", "%s.ach", "%s-*.ach"} {
+		path := filepath.Join(m.baseDir, fmt.Sprintf(pattern, msg.TransferID))
+		found, err := filepath.Glob(path)
+		if err != nil {
+			return fmt.Errorf("problem with %s glob: %v", path, err)
+		}
+		matches = append(matches, found...)
+	}
+	if len(matches) == 0 {
+		return ErrNotMergable
+	}
+	for i := range matches {
+		if err := os.Rename(matches[i], matches[i]+".canceled"); err != nil {
+			return fmt.Errorf("problem canceling transferID=%s: %v", msg.TransferID, err)
+		}
+	}
 	return nil
 }
 
 func (m *filesystemMerging) isolateMergableDir() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	// rename m.baseDir so we're the only accessor for it, then recreate m.baseDir
 	parent, _ := filepath.Split(m.baseDir)
 	newdir := filepath.Join(parent, time.Now().Format("20060102-150405"))
@@ -116,15 +196,48 @@ func (m *filesystemMerging) WithEachMerged(f func(*ach.File) error) error {
 	if err != nil {
 		return fmt.Errorf("problem isolating newdir=%s error=%v", dir, err)
 	}
-	path := filepath.Join(dir, "*.ach") // TODO(adam): exclude matches with '*.canceled' files
+	files, matches, el := mergeDir(dir)
+	if matches < 0 {
+		return el
+	}
+
+	if matches > 0 {
+		m.logger.Log("merging", fmt.Sprintf("merged %d transfers into %d files", matches, len(files)))
+	}
+	if len(files) == 0 {
+		// delete the new directory as there's nothing to merge
+		if err := os.RemoveAll(dir); err != nil {
+			el.Add(err)
+		}
+	}
+
+	for i := range files {
+		// TODO(adam): write each merged file here?
+		if err := f(files[i]); err != nil {
+			el.Add(fmt.Errorf("problem from callback: %v", err))
+		}
+	}
+
+	if !el.Empty() {
+		return el
+	}
+
+	return nil
+}
+
+// mergeDir reads each ACH file in dir and merges them. The number of files read is returned,
+// or -1 when dir couldn't be searched.
+func mergeDir(dir string) ([]*ach.File, int, base.ErrorList) {
+	var el base.ErrorList
 
+	path := filepath.Join(dir, "*.ach") // canceled files end in .canceled and aren't matched
 	matches, err := filepath.Glob(path)
 	if err != nil {
-		return fmt.Errorf("problem with %s glob: %v", path, err)
+		el.Add(fmt.Errorf("problem with %s glob: %v", path, err))
+		return nil, -1, el
 	}
 
 	var files []*ach.File
-	var el base.ErrorList
 	for i := range matches {
 		file, err := ach.ReadFile(matches[i])
 		if err != nil {
@@ -139,27 +252,84 @@ func (m *filesystemMerging) WithEachMerged(f func(*ach.File) error) error {
 	if err != nil {
 		el.Add(fmt.Errorf("unable to merge files: %v", err))
 	}
+	return files, len(matches), el
+}
 
-	if len(matches) > 0 {
-		m.logger.Log("merging", fmt.Sprintf("merged %d transfers into %d files", len(matches), len(files)))
-	}
-	if len(files) == 0 {
-		// delete the new directory as there's nothing to merge
-		if err := os.RemoveAll(dir); err != nil {
-			el.Add(err)
-		}
+func (m *filesystemMerging) ListPending() ([]*client.Transfer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := filepath.Join(m.baseDir, "*.json")
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("problem with %s glob: %v", path, err)
 	}
 
-	for i := range files {
-		// TODO(adam): write each merged file here?
-		if err := f(files[i]); err != nil {
-			el.Add(fmt.Errorf("problem from callback: %v", err))
+	out := make([]*client.Transfer, 0, len(matches))
+	for i := range matches {
+		bs, err := ioutil.ReadFile(matches[i])
+		if err != nil {
+			return nil, fmt.Errorf("problem reading %s: %v", matches[i], err)
+		}
+		var transfer client.Transfer
+		if err := json.Unmarshal(bs, &transfer); err != nil {
+			return nil, fmt.Errorf("problem reading %s: %v", matches[i], err)
 		}
+		out = append(out, &transfer)
 	}
+	return out, nil
+}
+
+func (m *filesystemMerging) PreviewMerged() ([]*ach.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
+	files, _, el := mergeDir(m.baseDir)
 	if !el.Empty() {
-		return el
+		return files, el
 	}
+	return files, nil
+}
 
-	return nil
+// failedDir returns the directory holding merged files which couldn't be uploaded.
+func (m *filesystemMerging) failedDir() string {
+	parent, _ := filepath.Split(m.baseDir)
+	return filepath.Join(parent, "failed")
+}
+
+func (m *filesystemMerging) SaveFailed(deliveryID string, file *ach.File) error {
+	dir := m.failedDir()
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := ach.NewWriter(&buf).Write(file); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%s.ach", deliveryID)), buf.Bytes(), 0644)
+}
+
+func (m *filesystemMerging) RequeueFailed() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	path := filepath.Join(m.failedDir(), "*.ach")
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, fmt.Errorf("problem with %s glob: %v", path, err)
+	}
+
+	var deliveryIDs []string
+	for i := range matches {
+		_, name := filepath.Split(matches[i])
+		if err := os.Rename(matches[i], filepath.Join(m.baseDir, name)); err != nil {
+			return deliveryIDs, fmt.Errorf("problem requeueing %s: %v", name, err)
+		}
+		deliveryIDs = append(deliveryIDs, strings.TrimSuffix(name, ".ach"))
+	}
+	if len(deliveryIDs) > 0 {
+		m.logger.Log("merging", fmt.Sprintf("requeued %d failed files", len(deliveryIDs)))
+	}
+	return deliveryIDs, nil
 }
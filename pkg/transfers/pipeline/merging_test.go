@@ -0,0 +1,75 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+func TestMerging__HandleCancel(t *testing.T) {
+	merger, dir := testMerging(t)
+
+	file, err := ach.ReadFile(filepath.Join("..", "..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept := Xfer{Transfer: &client.Transfer{TransferID: base.ID()}, File: file}
+	canceled := Xfer{Transfer: &client.Transfer{TransferID: base.ID()}, File: file}
+	for _, xfer := range []Xfer{kept, canceled} {
+		if err := merger.HandleXfer(xfer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := merger.HandleCancel(CanceledTransfer{TransferID: canceled.Transfer.TransferID}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "odfi", "mergable", canceled.Transfer.TransferID)
+	for _, suffix := range []string{".json.canceled", ".ach.canceled"} {
+		if _, err := os.Stat(path + suffix); err != nil {
+			t.Error(err)
+		}
+	}
+
+	pending, err := merger.ListPending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].TransferID != kept.Transfer.TransferID {
+		t.Errorf("unexpected pending transfers: %#v", pending)
+	}
+	files, err := merger.PreviewMerged()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || len(files[0].Batches) != 1 || len(files[0].Batches[0].GetEntries()) != 1 {
+		t.Errorf("unexpected merged files: %#v", files)
+	}
+
+	// canceling again, or canceling an unknown transfer, finds nothing to merge
+	if err := merger.HandleCancel(CanceledTransfer{TransferID: canceled.Transfer.TransferID}); err != ErrNotMergable {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := merger.HandleCancel(CanceledTransfer{}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestMerging__achFilename(t *testing.T) {
+	xfer := Xfer{Transfer: &client.Transfer{TransferID: "transferID"}}
+	if name := achFilename(xfer); name != "transferID.ach" {
+		t.Errorf("got %s", name)
+	}
+	xfer.File = &ach.File{ID: "fileID"}
+	if name := achFilename(xfer); name != "transferID-fileID.ach" {
+		t.Errorf("got %s", name)
+	}
+}
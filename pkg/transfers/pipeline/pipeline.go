@@ -12,8 +12,13 @@ import (
 type Xfer struct {
 	Transfer *client.Transfer `json:"transfer"`
 	File     *ach.File        `json:"file"`
+
+	// ODFI is the name of the ODFI this Xfer is uploaded to. Xfers without one
+	// are uploaded to the first configured ODFI.
+	ODFI string `json:"odfi,omitempty"`
 }
 
+// CanceledTransfer removes a Transfer's files from those waiting to be merged.
 type CanceledTransfer struct {
 	TransferID string
 }
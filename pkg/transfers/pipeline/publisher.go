@@ -30,9 +30,18 @@ func NewPublisher(cfg config.Pipeline) (XferPublisher, error) {
 	return nil, errors.New("unknown Pipeline config")
 }
 
+const (
+	// messageTypeCancel is set under the "type" metadata key of CanceledTransfer messages.
+	// Messages without a type hold an Xfer.
+	messageTypeCancel = "cancel"
+)
+
 func createMetadata(xf Xfer) map[string]string {
 	out := make(map[string]string)
 	out["transferID"] = xf.Transfer.TransferID
+	if xf.ODFI != "" {
+		out["odfi"] = xf.ODFI
+	}
 	return out
 }
 
@@ -9,11 +9,16 @@ import (
 )
 
 type MockPublisher struct {
-	Err error
+	Xfers []Xfer
+	Err   error
 }
 
 func (p *MockPublisher) Upload(xfer Xfer) error {
-	return p.Err
+	if p.Err != nil {
+		return p.Err
+	}
+	p.Xfers = append(p.Xfers, xfer)
+	return nil
 }
 
 func (p *MockPublisher) Cancel(msg CanceledTransfer) error {
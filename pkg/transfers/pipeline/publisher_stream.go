@@ -5,7 +5,9 @@
 package pipeline
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -34,7 +36,17 @@ func (pub *streamPublisher) Upload(xfer Xfer) error {
 }
 
 func (pub *streamPublisher) Cancel(msg CanceledTransfer) error {
-	return nil // TODO(adam): impl
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+		return fmt.Errorf("transferID=%s json encode: %v", msg.TransferID, err)
+	}
+	return pub.topic.Send(context.TODO(), &pubsub.Message{
+		Body: buf.Bytes(),
+		Metadata: map[string]string{
+			"transferID": msg.TransferID,
+			"type":       messageTypeCancel,
+		},
+	})
 }
 
 func (pub *streamPublisher) Shutdown(ctx context.Context) {
@@ -0,0 +1,151 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/config/reload"
+	"github.com/moov-io/paygate/pkg/upload"
+	"github.com/moov-io/paygate/x/schedule"
+
+	"github.com/go-kit/kit/log"
+)
+
+// ReloadODFIs returns a reload.Func which applies changes to each ODFI's cutoff times,
+// connection (credentials, allowed IPs and paths), filename template, upload retries and
+// dry run mode. Aggregators and agents are keyed by ODFI name.
+func ReloadODFIs(logger log.Logger, aggregators map[string]*XferAggregator, agents map[string]*upload.SwapAgent) reload.Func {
+	return func(prev, next *config.Config) (reload.Changes, error) {
+		changes := reload.Changes{}
+
+		previous := make(map[string]config.ODFI)
+		for _, odfi := range prev.ListODFIs() {
+			previous[odfi.Name] = odfi
+		}
+
+		var el base.ErrorList
+		for _, odfi := range next.ListODFIs() {
+			before, exists := previous[odfi.Name]
+			delete(previous, odfi.Name)
+
+			xfagg := aggregators[odfi.Name]
+			if !exists || xfagg == nil {
+				changes.Restart = append(changes.Restart, odfiField(odfi.Name, "added"))
+				continue
+			}
+			if err := reloadODFI(logger, xfagg, agents[odfi.Name], before, odfi, &changes); err != nil {
+				el.Add(fmt.Errorf("%s: %v", odfiField(odfi.Name, ""), err))
+			}
+		}
+
+		var removed []string
+		for name := range previous {
+			removed = append(removed, odfiField(name, "removed"))
+		}
+		sort.Strings(removed)
+		changes.Restart = append(changes.Restart, removed...)
+
+		if !el.Empty() {
+			return changes, el
+		}
+		return changes, nil
+	}
+}
+
+// odfiField names part of an ODFI's config like it's written in the config file.
+func odfiField(name, field string) string {
+	path := "odfi"
+	if name != "" {
+		path = fmt.Sprintf("odfis.%s", name)
+	}
+	if field != "" {
+		path = fmt.Sprintf("%s.%s", path, field)
+	}
+	return path
+}
+
+// reloadODFI applies every change of an ODFI or none of them if the new cutoff times
+// or connection can't be created.
+func reloadODFI(logger log.Logger, xfagg *XferAggregator, swap *upload.SwapAgent, before, after config.ODFI, changes *reload.Changes) error {
+	var applied []string
+	fieldChanged := func(field string, b, a interface{}) bool {
+		if reflect.DeepEqual(b, a) {
+			return false
+		}
+		applied = append(applied, odfiField(after.Name, field))
+		return true
+	}
+
+	var cutoffs *schedule.CutoffTimes
+	if fieldChanged("cutoffs", before.Cutoffs, after.Cutoffs) {
+		var err error
+		cutoffs, err = schedule.ForCutoffTimes(after.Cutoffs.Timezone, after.Cutoffs.Windows)
+		if err != nil {
+			return fmt.Errorf("cutoff times: %v", err)
+		}
+	}
+
+	var agent upload.Agent
+	if fieldChanged("connection", connection(before), connection(after)) {
+		if swap == nil {
+			cutoffs.Stop()
+			return fmt.Errorf("no connection to replace")
+		}
+		var err error
+		agent, err = upload.New(logger, after)
+		if err != nil {
+			cutoffs.Stop()
+			return fmt.Errorf("connection: %v", err)
+		}
+	}
+
+	fieldChanged("outbound_filename_template", before.OutboundFilenameTemplate, after.OutboundFilenameTemplate)
+	fieldChanged("upload_retry", before.UploadRetry, after.UploadRetry)
+	fieldChanged("dry_run", before.DryRun, after.DryRun)
+
+	xfagg.Reload(after, cutoffs)
+	if agent != nil {
+		if err := swap.Swap(agent); err != nil {
+			logger.Log("reload", fmt.Sprintf("problem closing previous %s connection: %v", odfiField(after.Name, ""), err))
+		}
+	}
+	changes.Applied = append(changes.Applied, applied...)
+
+	restart := []struct {
+		field string
+		b, a  interface{}
+	}{
+		{"routing_number", before.RoutingNumber, after.RoutingNumber},
+		{"gateway", before.Gateway, after.Gateway},
+		{"gpg", before.GPG, after.GPG},
+		{"inbound", before.Inbound, after.Inbound},
+		{"storage", before.Storage, after.Storage},
+	}
+	for i := range restart {
+		if !reflect.DeepEqual(restart[i].b, restart[i].a) {
+			changes.Restart = append(changes.Restart, odfiField(after.Name, restart[i].field))
+		}
+	}
+	return nil
+}
+
+// connection returns the parts of an ODFI's config used to create its upload.Agent
+func connection(cfg config.ODFI) config.ODFI {
+	return config.ODFI{
+		InboundPath:  cfg.InboundPath,
+		OutboundPath: cfg.OutboundPath,
+		ReturnPath:   cfg.ReturnPath,
+		AllowedIPs:   cfg.AllowedIPs,
+		FTP:          cfg.FTP,
+		SFTP:         cfg.SFTP,
+		Local:        cfg.Local,
+		Bucket:       cfg.Bucket,
+	}
+}
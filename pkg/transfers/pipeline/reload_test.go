@@ -0,0 +1,118 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/transfers/deliveries"
+	"github.com/moov-io/paygate/pkg/upload"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestReloadODFIs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "paygate-reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	before := config.ODFI{
+		Name:          "bank-a",
+		RoutingNumber: "987654320",
+		Cutoffs: config.Cutoffs{
+			Timezone: "America/New_York",
+			Windows:  []string{"16:20"},
+		},
+		OutboundPath: "outbound/",
+		FTP: &config.FTP{
+			Hostname: "ftp.bank-a.com:21",
+		},
+	}
+	after := before
+	after.Cutoffs = config.Cutoffs{
+		Timezone: "America/New_York",
+		Windows:  []string{"16:20", "17:00"},
+	}
+	after.FTP = nil
+	after.Local = &config.LocalAgent{Directory: dir}
+	after.OutboundFilenameTemplate = "{{ .RoutingNumber }}-{{ .N }}.ach"
+	after.Gateway = config.Gateway{Origin: "987654320"}
+
+	xfagg := NewAggregator(log.NewNopLogger(), before, &upload.MockAgent{}, nil, &deliveries.MockRepository{}, nil)
+	agent := upload.NewSwapAgent(&upload.MockAgent{})
+
+	fn := ReloadODFIs(log.NewNopLogger(), map[string]*XferAggregator{"bank-a": xfagg}, map[string]*upload.SwapAgent{"bank-a": agent})
+	changes, err := fn(&config.Config{ODFIs: []config.ODFI{before}}, &config.Config{
+		ODFIs: []config.ODFI{after, {Name: "bank-b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := []string{"odfis.bank-a.cutoffs", "odfis.bank-a.connection", "odfis.bank-a.outbound_filename_template"}
+	if !reflect.DeepEqual(changes.Applied, applied) {
+		t.Errorf("unexpected applied: %v", changes.Applied)
+	}
+	restart := []string{"odfis.bank-a.gateway", "odfis.bank-b.added"}
+	if !reflect.DeepEqual(changes.Restart, restart) {
+		t.Errorf("unexpected restart: %v", changes.Restart)
+	}
+
+	if tmpl := xfagg.config().OutboundFilenameTemplate; tmpl != after.OutboundFilenameTemplate {
+		t.Errorf("unexpected template: %q", tmpl)
+	}
+	select {
+	case cutoffs := <-xfagg.cutoffs:
+		cutoffs.Stop()
+	default:
+		t.Error("expected replacement cutoff times")
+	}
+	if path := agent.OutboundPath(); path != "outbound/" {
+		t.Errorf("unexpected outbound path: %q", path)
+	}
+	if err := agent.Ping(); err != nil {
+		t.Errorf("expected local agent: %v", err)
+	}
+}
+
+func TestReloadODFIs__connectionErr(t *testing.T) {
+	before := config.ODFI{
+		Cutoffs: config.Cutoffs{
+			Windows: []string{"16:20"},
+		},
+		Local: &config.LocalAgent{Directory: "/"},
+	}
+	after := before
+	after.Cutoffs = config.Cutoffs{Windows: []string{"17:00"}}
+	after.Local = nil // no agent type
+
+	xfagg := NewAggregator(log.NewNopLogger(), before, &upload.MockAgent{}, nil, &deliveries.MockRepository{}, nil)
+	fn := ReloadODFIs(log.NewNopLogger(), map[string]*XferAggregator{"": xfagg}, map[string]*upload.SwapAgent{
+		"": upload.NewSwapAgent(&upload.MockAgent{}),
+	})
+	changes, err := fn(&config.Config{ODFI: before}, &config.Config{ODFI: after})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if len(changes.Applied) != 0 {
+		t.Errorf("unexpected applied: %v", changes.Applied)
+	}
+
+	// nothing is applied when the connection can't be created
+	if windows := xfagg.config().Cutoffs.Windows; windows[0] != "16:20" {
+		t.Errorf("unexpected windows: %v", windows)
+	}
+	select {
+	case <-xfagg.cutoffs:
+		t.Error("unexpected cutoff times")
+	default:
+	}
+}
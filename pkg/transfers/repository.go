@@ -6,21 +6,96 @@ package transfers
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/moov-io/ach"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/deliveries"
 )
 
 type Repository interface {
+	// getUserTransfers returns Transfers created by the user, or by anyone when userID is empty
 	getUserTransfers(userID string, params transferFilterParams) ([]*client.Transfer, error)
+	getUserTransfer(transferID string, userID string) (*client.Transfer, error)
 	GetTransfer(id string) (*client.Transfer, error)
+	getTransferCreator(transferID string) (string, error)
+	// getTransferTenant returns the Tenant a Transfer was created in, which is empty for
+	// Transfers saved before their Tenant was recorded
+	getTransferTenant(transferID string) (string, error)
 	UpdateTransferStatus(transferID string, status client.TransferStatus) error
-	writeUserTransfers(userID string, transfer *client.Transfer) error
+	// CancelPendingTransfer moves a PENDING Transfer into CANCELED. False is returned when
+	// the Transfer was no longer PENDING.
+	CancelPendingTransfer(transferID string) (bool, error)
+	writeUserTransfers(userID string, tenantID string, transfer *client.Transfer) error
+	writeFileTransfers(userID string, fileID string, transfers []*client.Transfer, tenantIDs []string) error
 	deleteUserTransfer(userID string, transferID string) error
 
 	SetReturnCode(transferID string, returnCode string) error
+
+	// SearchTransfers returns Transfers from every user which match the params, newest first
+	SearchTransfers(params SearchParams) ([]*client.Transfer, error)
+	// saveTraceNumbers records the trace number of each entry originated for a Transfer and if it's a debit
+	saveTraceNumbers(transferID string, entries []*ach.EntryDetail) error
+	// TransferDelivered returns true when an entry of the Transfer was in a file uploaded to and verified on the ODFI
+	TransferDelivered(transferID string) (bool, error)
+
+	// holdFile saves a file of the Transfer to originate once releaseAt has passed
+	holdFile(transferID string, odfi string, file *ach.File, releaseAt time.Time) error
+	// getHeldFiles returns each held file which can be released at when
+	getHeldFiles(when time.Time) ([]*heldFile, error)
+	// releaseHold marks the Transfer's held file as released. False is returned when the file
+	// was already released or canceled.
+	releaseHold(transferID string) (bool, error)
+	// restoreHold holds a file again after it failed to be released
+	restoreHold(transferID string) error
+	// cancelHold drops the Transfer's held file unless it was already released
+	cancelHold(transferID string) error
+
+	// saveReview records why a Transfer was held in the REVIEWABLE status
+	saveReview(transferID string, reasons []string) error
+	// reviewTransfer moves a REVIEWABLE Transfer into status and records who decided.
+	// errNotReviewable is returned when the Transfer isn't REVIEWABLE.
+	reviewTransfer(transferID string, reviewerID string, status client.TransferStatus) error
+	// reopenReview moves an approved Transfer back into REVIEWABLE and clears its decision
+	reopenReview(transferID string) error
+
+	getTransferBatch(userID string, batchID string) (*client.TransferBatch, error)
+	writeTransferBatch(userID string, batch *client.TransferBatch) error
+	saveTransferBatchResults(batchID string, status client.TransferBatchStatus, results []client.TransferBatchResult) error
+}
+
+// SearchParams filters Transfers across every user. Empty fields are not filtered on.
+type SearchParams struct {
+	// TraceNumber matches Transfers with an entry originated under the trace number
+	TraceNumber string
+	Amount      string
+	// CustomerID matches either the source or destination Customer
+	CustomerID string
+	Status     client.TransferStatus
+	// Filename matches Transfers with an entry in a file delivered to the ODFI
+	Filename string
+	// TenantID matches Transfers originated under the Tenant. Transfers saved before their
+	// Tenant was recorded match when created for one of the Tenant's Organizations, or without
+	// an Organization by the Tenant's owner.
+	TenantID string
+
+	StartDate time.Time
+	EndDate   time.Time
+
+	// Limit is ignored when zero so every matching Transfer is returned
+	Limit  int64
+	Offset int64
+}
+
+// heldFile is a file of a Transfer which is originated once ReleaseAt has passed
+type heldFile struct {
+	TransferID string
+	ODFI       string
+	File       *ach.File
+	ReleaseAt  time.Time
 }
 
 func NewRepo(db *sql.DB) *sqlRepo {
@@ -39,23 +114,37 @@ func (r *sqlRepo) Close() error {
 }
 
 func (r *sqlRepo) getUserTransfers(userID string, params transferFilterParams) ([]*client.Transfer, error) {
-	var statusQuery string
+	var conditions []string
+	args := []interface{}{params.StartDate, params.EndDate}
+	if userID != "" {
+		conditions = append(conditions, "and user_id = ?")
+		args = append(args, userID)
+	}
 	if string(params.Status) != "" {
-		statusQuery = "and status = ?"
+		conditions = append(conditions, "and status = ?")
+		args = append(args, params.Status)
+	}
+	if len(params.OrganizationIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("and organization_id in (?%s)", strings.Repeat(", ?", len(params.OrganizationIDs)-1)))
+		for i := range params.OrganizationIDs {
+			args = append(args, params.OrganizationIDs[i])
+		}
 	}
+	if params.TenantID != "" {
+		conditions = append(conditions, "and tenant_id = ?")
+		args = append(args, params.TenantID)
+	}
+	args = append(args, params.Limit, params.Offset)
+
 	query := fmt.Sprintf(`select transfer_id from transfers
-where user_id = ? and created_at >= ? and created_at <= ? and deleted_at is null %s
-order by created_at desc limit ? offset ?;`, statusQuery)
+where created_at >= ? and created_at <= ? and deleted_at is null %s
+order by created_at desc limit ? offset ?;`, strings.Join(conditions, " "))
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	args := []interface{}{userID, params.StartDate, params.EndDate, params.Limit, params.Offset}
-	if statusQuery != "" {
-		args = append(args, params.Status)
-	}
 	rows, err := stmt.Query(args...)
 	if err != nil {
 		return nil, err
@@ -78,16 +167,231 @@ order by created_at desc limit ? offset ?;`, statusQuery)
 
 	var transfers []*client.Transfer
 	for i := range transferIDs {
-		t, err := r.getUserTransfer(transferIDs[i], userID)
-		if err == nil && t.TransferID != "" {
+		var t *client.Transfer
+		if userID == "" {
+			t, err = r.GetTransfer(transferIDs[i])
+		} else {
+			t, err = r.getUserTransfer(transferIDs[i], userID)
+		}
+		if err == nil && t != nil && t.TransferID != "" {
 			transfers = append(transfers, t)
 		}
 	}
 	return transfers, rows.Err()
 }
 
+func (r *sqlRepo) SearchTransfers(params SearchParams) ([]*client.Transfer, error) {
+	var conditions []string
+	var args []interface{}
+	if !params.StartDate.IsZero() {
+		conditions = append(conditions, "and created_at >= ?")
+		args = append(args, params.StartDate)
+	}
+	if !params.EndDate.IsZero() {
+		conditions = append(conditions, "and created_at <= ?")
+		args = append(args, params.EndDate)
+	}
+	if params.TraceNumber != "" {
+		conditions = append(conditions, "and transfer_id in (select transfer_id from transfer_trace_numbers where trace_number = ?)")
+		args = append(args, params.TraceNumber)
+	}
+	if params.Amount != "" {
+		conditions = append(conditions, "and amount = ?")
+		args = append(args, params.Amount)
+	}
+	if params.CustomerID != "" {
+		conditions = append(conditions, "and (source_customer_id = ? or destination_customer_id = ?)")
+		args = append(args, params.CustomerID, params.CustomerID)
+	}
+	if string(params.Status) != "" {
+		conditions = append(conditions, "and status = ?")
+		args = append(args, params.Status)
+	}
+	if params.Filename != "" {
+		conditions = append(conditions, `and transfer_id in (select tt.transfer_id from transfer_trace_numbers tt
+inner join file_delivery_trace_numbers dt on tt.trace_number = dt.trace_number
+inner join file_deliveries d on dt.delivery_id = d.delivery_id where d.filename = ?)`)
+		args = append(args, params.Filename)
+	}
+	if params.TenantID != "" {
+		conditions = append(conditions, `and (tenant_id = ? or ((tenant_id is null or tenant_id = '')
+and (organization_id in (select organization_id from tenants_organizations where tenant_id = ? and deleted_at is null)
+or ((organization_id is null or organization_id = '') and user_id in (select user_id from tenants where tenant_id = ? and deleted_at is null)))))`)
+		args = append(args, params.TenantID, params.TenantID, params.TenantID)
+	}
+	page := ""
+	if params.Limit > 0 {
+		page = "limit ? offset ?"
+		args = append(args, params.Limit, params.Offset)
+	}
+
+	query := fmt.Sprintf(`select transfer_id from transfers where deleted_at is null %s
+order by created_at desc %s;`, strings.Join(conditions, " "), page)
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transferIDs []string
+	for rows.Next() {
+		var row string
+		if err := rows.Scan(&row); err != nil {
+			return nil, fmt.Errorf("SearchTransfers scan: %v", err)
+		}
+		transferIDs = append(transferIDs, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("SearchTransfers: rows.Err=%v", err)
+	}
+
+	var transfers []*client.Transfer
+	for i := range transferIDs {
+		t, err := r.GetTransfer(transferIDs[i])
+		if err != nil {
+			return nil, fmt.Errorf("SearchTransfers: transferID=%s: %v", transferIDs[i], err)
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, nil
+}
+
+func (r *sqlRepo) saveTraceNumbers(transferID string, entries []*ach.EntryDetail) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `insert into transfer_trace_numbers (transfer_id, trace_number, debit) values (?, ?, ?);`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for i := range entries {
+		if _, err := stmt.Exec(transferID, entries[i].TraceNumber, entries[i].CreditOrDebit() == "D"); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("transferID=%s traceNumber=%s: %v", transferID, entries[i].TraceNumber, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) TransferDelivered(transferID string) (bool, error) {
+	query := `select count(*) from transfer_trace_numbers tt
+inner join file_delivery_trace_numbers dt on tt.trace_number = dt.trace_number
+inner join file_deliveries d on dt.delivery_id = d.delivery_id
+where tt.transfer_id = ? and d.status = ?`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	var n int
+	if err := stmt.QueryRow(transferID, deliveries.Delivered).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *sqlRepo) holdFile(transferID string, odfi string, file *ach.File, releaseAt time.Time) error {
+	bs, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("encoding held file: %v", err)
+	}
+	query := `insert into transfer_holds (transfer_id, odfi, file, release_at, created_at) values (?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(transferID, odfi, string(bs), releaseAt, time.Now())
+	return err
+}
+
+func (r *sqlRepo) getHeldFiles(when time.Time) ([]*heldFile, error) {
+	query := `select transfer_id, odfi, file, release_at from transfer_holds
+where release_at <= ? and released_at is null and canceled_at is null order by release_at asc;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(when)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*heldFile
+	for rows.Next() {
+		var held heldFile
+		var contents string
+		if err := rows.Scan(&held.TransferID, &held.ODFI, &contents, &held.ReleaseAt); err != nil {
+			return nil, err
+		}
+		held.File, err = ach.FileFromJSON([]byte(contents))
+		if err != nil {
+			return nil, fmt.Errorf("reading held file for transferID=%s: %v", held.TransferID, err)
+		}
+		out = append(out, &held)
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) releaseHold(transferID string) (bool, error) {
+	query := `update transfer_holds set released_at = ? where transfer_id = ? and released_at is null and canceled_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(time.Now(), transferID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *sqlRepo) restoreHold(transferID string) error {
+	query := `update transfer_holds set released_at = null where transfer_id = ? and canceled_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(transferID)
+	return err
+}
+
+func (r *sqlRepo) cancelHold(transferID string) error {
+	query := `update transfer_holds set canceled_at = ? where transfer_id = ? and released_at is null and canceled_at is null;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(time.Now(), transferID)
+	return err
+}
+
 func (r *sqlRepo) getUserTransfer(transferID string, userID string) (*client.Transfer, error) {
-	query := `select transfer_id, amount, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, return_code, created_at
+	query := `select transfer_id, amount, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, return_code, created_at, organization_id
 from transfers
 where transfer_id = ? and user_id = ? and deleted_at is null
 limit 1`
@@ -106,16 +410,17 @@ limit 1`
 	)
 	err = row.Scan(
 		&transfer.TransferID,
+		&transfer.Amount, // &amt,
 		&transfer.Source.CustomerID,
 		&transfer.Source.AccountID,
 		&transfer.Destination.CustomerID,
 		&transfer.Destination.AccountID,
-		&transfer.Amount, // &amt,
 		&transfer.Description,
 		&transfer.Status,
 		&transfer.SameDay,
 		&returnCode,
 		&transfer.Created,
+		&transfer.OrganizationID,
 	)
 	if transfer.TransferID == "" || err != nil {
 		return nil, err
@@ -132,18 +437,48 @@ limit 1`
 }
 
 func (r *sqlRepo) GetTransfer(transferID string) (*client.Transfer, error) {
+	userID, err := r.getTransferCreator(transferID)
+	if err != nil {
+		return nil, err
+	}
+	return r.getUserTransfer(transferID, userID)
+}
+
+// getTransferCreator returns the userID which created a Transfer.
+func (r *sqlRepo) getTransferCreator(transferID string) (string, error) {
 	query := `select user_id from transfers where transfer_id = ? and deleted_at is null limit 1`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	defer stmt.Close()
 
 	userID := ""
 	if err := stmt.QueryRow(transferID).Scan(&userID); err != nil {
-		return nil, err
+		return "", err
 	}
-	return r.getUserTransfer(transferID, userID)
+	return userID, nil
+}
+
+func (r *sqlRepo) getTransferTenant(transferID string) (string, error) {
+	query := `select tenant_id from transfers where transfer_id = ? and deleted_at is null limit 1`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return "", err
+	}
+	defer stmt.Close()
+
+	var tenantID *string
+	if err := stmt.QueryRow(transferID).Scan(&tenantID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+	if tenantID == nil {
+		return "", nil
+	}
+	return *tenantID, nil
 }
 
 func (r *sqlRepo) UpdateTransferStatus(transferID string, status client.TransferStatus) error {
@@ -158,8 +493,24 @@ func (r *sqlRepo) UpdateTransferStatus(transferID string, status client.Transfer
 	return err
 }
 
-func (r *sqlRepo) writeUserTransfers(userID string, transfer *client.Transfer) error {
-	query := `insert into transfers (transfer_id, user_id, amount, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+func (r *sqlRepo) CancelPendingTransfer(transferID string) (bool, error) {
+	query := `update transfers set status = ?, last_updated_at = ? where transfer_id = ? and status = ? and deleted_at is null`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	res, err := stmt.Exec(client.CANCELED, time.Now(), transferID, client.PENDING)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (r *sqlRepo) writeUserTransfers(userID string, tenantID string, transfer *client.Transfer) error {
+	query := `insert into transfers (transfer_id, user_id, amount, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, organization_id, tenant_id, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
 	stmt, err := r.db.Prepare(query)
 	if err != nil {
 		return err
@@ -177,11 +528,57 @@ func (r *sqlRepo) writeUserTransfers(userID string, transfer *client.Transfer) e
 		transfer.Description,
 		transfer.Status,
 		transfer.SameDay,
+		transfer.OrganizationID,
+		tenantID,
 		time.Now(),
 	)
 	return err
 }
 
+// writeFileTransfers saves each Transfer split from an uploaded file linked to its fileID.
+// tenantIDs holds the Tenant each Transfer was originated under.
+func (r *sqlRepo) writeFileTransfers(userID string, fileID string, transfers []*client.Transfer, tenantIDs []string) error {
+	if len(transfers) != len(tenantIDs) {
+		return fmt.Errorf("fileID=%s has %d transfers but %d tenants", fileID, len(transfers), len(tenantIDs))
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `insert into transfers (transfer_id, user_id, amount, source_customer_id, source_account_id, destination_customer_id, destination_account_id, description, status, same_day, file_id, organization_id, tenant_id, created_at) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for i, transfer := range transfers {
+		_, err = stmt.Exec(
+			transfer.TransferID,
+			userID,
+			transfer.Amount,
+			transfer.Source.CustomerID,
+			transfer.Source.AccountID,
+			transfer.Destination.CustomerID,
+			transfer.Destination.AccountID,
+			transfer.Description,
+			transfer.Status,
+			transfer.SameDay,
+			fileID,
+			transfer.OrganizationID,
+			tenantIDs[i],
+			time.Now(),
+		)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("fileID=%s transferID=%s: %v", fileID, transfer.TransferID, err)
+		}
+	}
+	return tx.Commit()
+}
+
 func (r *sqlRepo) deleteUserTransfer(userID string, transferID string) error {
 	query := `update transfers set deleted_at = ? where transfer_id = ? and user_id = ? and deleted_at is null`
 	stmt, err := r.db.Prepare(query)
@@ -211,3 +608,193 @@ func (r *sqlRepo) SetReturnCode(transferID string, returnCode string) error {
 	}
 	return err
 }
+
+func (r *sqlRepo) saveReview(transferID string, reasons []string) error {
+	query := `insert into transfer_reviews (transfer_id, reasons, created_at) values (?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	_, err = stmt.Exec(transferID, strings.Join(reasons, "; "), time.Now())
+	return err
+}
+
+func (r *sqlRepo) reviewTransfer(transferID string, reviewerID string, status client.TransferStatus) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// Only one reviewer can move the Transfer out of REVIEWABLE
+	query := `update transfers set status = ?, last_updated_at = ? where transfer_id = ? and status = ? and deleted_at is null`
+	res, err := tx.Exec(query, status, time.Now(), transferID, client.REVIEWABLE)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		tx.Rollback()
+		return errNotReviewable
+	}
+
+	// Transfers held before their reasons were recorded don't have a row yet
+	query = `update transfer_reviews set reviewed_by = ?, decision = ?, reviewed_at = ? where transfer_id = ?`
+	res, err = tx.Exec(query, reviewerID, status, time.Now(), transferID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		query = `insert into transfer_reviews (transfer_id, reasons, reviewed_by, decision, created_at, reviewed_at) values (?, '', ?, ?, ?, ?);`
+		if _, err := tx.Exec(query, transferID, reviewerID, status, time.Now(), time.Now()); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) reopenReview(transferID string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `update transfers set status = ?, last_updated_at = ? where transfer_id = ? and status = ? and deleted_at is null`
+	if _, err := tx.Exec(query, client.REVIEWABLE, time.Now(), transferID, client.PENDING); err != nil {
+		tx.Rollback()
+		return err
+	}
+	query = `update transfer_reviews set reviewed_by = null, decision = null, reviewed_at = null where transfer_id = ?`
+	if _, err := tx.Exec(query, transferID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *sqlRepo) getTransferBatch(userID string, batchID string) (*client.TransferBatch, error) {
+	query := `select batch_id, atomic, status, created_at from transfer_batches where batch_id = ? and user_id = ? limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	batch := &client.TransferBatch{}
+	if err := stmt.QueryRow(batchID, userID).Scan(&batch.BatchID, &batch.Atomic, &batch.Status, &batch.Created); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	query = `select item_index, transfer_id, error from transfer_batch_items where batch_id = ? order by item_index asc;`
+	itemStmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer itemStmt.Close()
+
+	rows, err := itemStmt.Query(batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transferIDs []string
+	for rows.Next() {
+		var result client.TransferBatchResult
+		var transferID, errMsg *string
+		if err := rows.Scan(&result.Index, &transferID, &errMsg); err != nil {
+			return nil, err
+		}
+		if errMsg != nil {
+			result.Error = *errMsg
+		}
+		var id string
+		if transferID != nil {
+			id = *transferID
+		}
+		transferIDs = append(transferIDs, id)
+		batch.Results = append(batch.Results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range transferIDs {
+		if transferIDs[i] == "" {
+			continue
+		}
+		xfer, err := r.getUserTransfer(transferIDs[i], userID)
+		if err != nil {
+			return nil, fmt.Errorf("batchID=%s transferID=%s: %v", batchID, transferIDs[i], err)
+		}
+		if xfer != nil {
+			batch.Results[i].Transfer = *xfer
+		}
+	}
+	if batch.Results == nil {
+		batch.Results = []client.TransferBatchResult{}
+	}
+	return batch, nil
+}
+
+func (r *sqlRepo) writeTransferBatch(userID string, batch *client.TransferBatch) error {
+	query := `insert into transfer_batches (batch_id, user_id, atomic, status, created_at, last_updated_at) values (?, ?, ?, ?, ?, ?);`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	_, err = stmt.Exec(batch.BatchID, userID, batch.Atomic, batch.Status, batch.Created, now)
+	return err
+}
+
+func (r *sqlRepo) saveTransferBatchResults(batchID string, status client.TransferBatchStatus, results []client.TransferBatchResult) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `update transfer_batches set status = ?, last_updated_at = ? where batch_id = ?;`
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	if _, err := stmt.Exec(status, time.Now(), batchID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	query = `insert into transfer_batch_items (batch_id, item_index, transfer_id, error) values (?, ?, ?, ?);`
+	insert, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer insert.Close()
+
+	for i := range results {
+		var transferID, errMsg *string
+		if results[i].Transfer.TransferID != "" {
+			transferID = &results[i].Transfer.TransferID
+		}
+		if results[i].Error != "" {
+			errMsg = &results[i].Error
+		}
+		if _, err := insert.Exec(batchID, results[i].Index, transferID, errMsg); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -9,9 +9,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/client"
 	"github.com/moov-io/paygate/pkg/database"
+	"github.com/moov-io/paygate/pkg/transfers/deliveries"
 )
 
 func TestRepository__getUserTransfers(t *testing.T) {
@@ -29,6 +31,64 @@ func TestRepository__getUserTransfers(t *testing.T) {
 	}
 }
 
+func TestRepository__getUserTransfersByOrganization(t *testing.T) {
+	userID := base.ID()
+	repo := setupSQLiteDB(t)
+	writeTransfer(t, userID, repo)
+
+	xfer := &client.Transfer{
+		TransferID:     base.ID(),
+		Amount:         "USD 1.25",
+		Description:    "payroll",
+		Status:         client.PENDING,
+		Created:        time.Now(),
+		OrganizationID: base.ID(),
+	}
+	if err := repo.writeUserTransfers(userID, "tenantID", xfer); err != nil {
+		t.Fatal(err)
+	}
+
+	params := readTransferFilterParams(&http.Request{})
+	params.Status = client.PENDING
+	params.OrganizationIDs = []string{base.ID(), xfer.OrganizationID}
+	xfers, err := repo.getUserTransfers(userID, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xfers) != 1 || xfers[0].TransferID != xfer.TransferID {
+		t.Fatalf("unexpected transfers: %#v", xfers)
+	}
+	if xfers[0].OrganizationID != xfer.OrganizationID {
+		t.Errorf("unexpected organizationID: %q", xfers[0].OrganizationID)
+	}
+}
+
+func TestRepository__getUserTransfersByTenant(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	xfer := writeTransfer(t, base.ID(), repo)
+	other := &client.Transfer{
+		TransferID:  base.ID(),
+		Amount:      "USD 1.25",
+		Description: "payroll",
+		Status:      client.PENDING,
+		Created:     time.Now(),
+	}
+	if err := repo.writeUserTransfers(base.ID(), "otherTenantID", other); err != nil {
+		t.Fatal(err)
+	}
+
+	// Transfers from every user in the Tenant are returned
+	params := readTransferFilterParams(&http.Request{})
+	params.TenantID = "tenantID"
+	xfers, err := repo.getUserTransfers("", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xfers) != 1 || xfers[0].TransferID != xfer.TransferID {
+		t.Fatalf("unexpected transfers: %#v", xfers)
+	}
+}
+
 func TestRepository__UpdateTransferStatus(t *testing.T) {
 	userID := base.ID()
 	repo := setupSQLiteDB(t)
@@ -55,6 +115,33 @@ func TestRepository__UpdateTransferStatus(t *testing.T) {
 	}
 }
 
+func TestRepository__CancelPendingTransfer(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		xfer := writeTransfer(t, base.ID(), repo)
+		if canceled, err := repo.CancelPendingTransfer(xfer.TransferID); err != nil || !canceled {
+			t.Fatalf("canceled=%v error=%v", canceled, err)
+		}
+		if found, err := repo.GetTransfer(xfer.TransferID); err != nil || found.Status != client.CANCELED {
+			t.Fatalf("transfer=%#v error=%v", found, err)
+		}
+
+		// Transfers which aren't PENDING are left alone
+		xfer = writeTransfer(t, base.ID(), repo)
+		if err := repo.UpdateTransferStatus(xfer.TransferID, client.PROCESSED); err != nil {
+			t.Fatal(err)
+		}
+		if canceled, err := repo.CancelPendingTransfer(xfer.TransferID); err != nil || canceled {
+			t.Fatalf("canceled=%v error=%v", canceled, err)
+		}
+		if found, err := repo.GetTransfer(xfer.TransferID); err != nil || found.Status != client.PROCESSED {
+			t.Fatalf("transfer=%#v error=%v", found, err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
 func TestRepository__writeUserTransfers(t *testing.T) {
 	userID := base.ID()
 	repo := setupSQLiteDB(t)
@@ -86,6 +173,124 @@ func TestRepository__deleteUserTransfer(t *testing.T) {
 	}
 }
 
+func TestRepository__SearchTransfers(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		first := writeTransfer(t, userID, repo)
+		second := writeTransfer(t, base.ID(), repo)
+		if err := repo.UpdateTransferStatus(second.TransferID, client.CANCELED); err != nil {
+			t.Fatal(err)
+		}
+
+		search := func(params SearchParams) []*client.Transfer {
+			t.Helper()
+			xfers, err := repo.SearchTransfers(params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return xfers
+		}
+
+		// no filters
+		if xfers := search(SearchParams{}); len(xfers) != 2 {
+			t.Errorf("unexpected transfers: %#v", xfers)
+		}
+		if xfers := search(SearchParams{Limit: 1}); len(xfers) != 1 {
+			t.Errorf("unexpected transfers: %#v", xfers)
+		}
+
+		if xfers := search(SearchParams{CustomerID: second.Destination.CustomerID}); len(xfers) != 1 || xfers[0].TransferID != second.TransferID {
+			t.Errorf("unexpected transfers: %#v", xfers)
+		}
+		if xfers := search(SearchParams{Status: client.PENDING, Amount: "USD 12.45"}); len(xfers) != 1 || xfers[0].TransferID != first.TransferID {
+			t.Errorf("unexpected transfers: %#v", xfers)
+		}
+		if xfers := search(SearchParams{StartDate: time.Now().Add(time.Hour)}); len(xfers) != 0 {
+			t.Errorf("unexpected transfers: %#v", xfers)
+		}
+
+		// trace numbers and the files they were delivered in
+		if err := repo.saveTraceNumbers(first.TransferID, []*ach.EntryDetail{{TraceNumber: "987654320000001"}}); err != nil {
+			t.Fatal(err)
+		}
+		if xfers := search(SearchParams{TraceNumber: "987654320000001"}); len(xfers) != 1 || xfers[0].TransferID != first.TransferID {
+			t.Errorf("unexpected transfers: %#v", xfers)
+		}
+		delivery := &deliveries.Delivery{
+			DeliveryID:   base.ID(),
+			Filename:     "20200529-987654320-1.ach",
+			Status:       deliveries.Delivered,
+			Created:      time.Now(),
+			TraceNumbers: []string{"987654320000001"},
+		}
+		if err := deliveries.NewRepo(repo.db).CreateDelivery(delivery); err != nil {
+			t.Fatal(err)
+		}
+		if xfers := search(SearchParams{Filename: delivery.Filename}); len(xfers) != 1 || xfers[0].TransferID != first.TransferID {
+			t.Errorf("unexpected transfers: %#v", xfers)
+		}
+		// files which failed to upload don't deliver their Transfers
+		if err := repo.saveTraceNumbers(second.TransferID, []*ach.EntryDetail{{TraceNumber: "987654320000002"}}); err != nil {
+			t.Fatal(err)
+		}
+		failed := &deliveries.Delivery{
+			DeliveryID:   base.ID(),
+			Filename:     "20200529-987654320-2.ach",
+			Status:       deliveries.Failed,
+			Created:      time.Now(),
+			TraceNumbers: []string{"987654320000002"},
+		}
+		if err := deliveries.NewRepo(repo.db).CreateDelivery(failed); err != nil {
+			t.Fatal(err)
+		}
+		for _, xfer := range []*client.Transfer{first, second} {
+			delivered, err := repo.TransferDelivered(xfer.TransferID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if delivered != (xfer == first) {
+				t.Errorf("transferID=%s delivered=%v", xfer.TransferID, delivered)
+			}
+		}
+
+		// Tenants
+		if xfers := search(SearchParams{TenantID: "tenantID"}); len(xfers) != 2 {
+			t.Errorf("unexpected transfers: %#v", xfers)
+		}
+		tenantID := base.ID()
+		query := `insert into tenants (tenant_id, user_id, name, created_at) values (?, ?, ?, ?);`
+		if _, err := repo.db.Exec(query, tenantID, userID, "My Company", time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if xfers := search(SearchParams{TenantID: tenantID}); len(xfers) != 0 {
+			t.Errorf("unexpected transfers: %#v", xfers)
+		}
+
+		// Transfers saved without their Tenant match by Organization, or by the owner without one
+		legacy := &client.Transfer{TransferID: base.ID(), Amount: "USD 1.00", Status: client.PENDING}
+		if err := repo.writeUserTransfers(userID, "", legacy); err != nil {
+			t.Fatal(err)
+		}
+		otherOrg := &client.Transfer{TransferID: base.ID(), Amount: "USD 1.00", Status: client.PENDING, OrganizationID: base.ID()}
+		if err := repo.writeUserTransfers(userID, "", otherOrg); err != nil {
+			t.Fatal(err)
+		}
+		if xfers := search(SearchParams{TenantID: tenantID}); len(xfers) != 1 || xfers[0].TransferID != legacy.TransferID {
+			t.Errorf("unexpected transfers: %#v", xfers)
+		}
+		query = `insert into tenants_organizations (tenant_id, organization_id, created_at) values (?, ?, ?);`
+		if _, err := repo.db.Exec(query, tenantID, otherOrg.OrganizationID, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if xfers := search(SearchParams{TenantID: tenantID}); len(xfers) != 2 {
+			t.Errorf("unexpected transfers: %#v", xfers)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
 func setupSQLiteDB(t *testing.T) *sqlRepo {
 	db := database.CreateTestSqliteDB(t)
 	t.Cleanup(func() { db.Close() })
@@ -126,7 +331,7 @@ func writeTransfer(t *testing.T, userID string, repo Repository) *client.Transfe
 		Created:     time.Now(),
 	}
 
-	if err := repo.writeUserTransfers(userID, xfer); err != nil {
+	if err := repo.writeUserTransfers(userID, "tenantID", xfer); err != nil {
 		t.Fatal(err)
 	}
 
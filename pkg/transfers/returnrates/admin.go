@@ -0,0 +1,51 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package returnrates
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/moov-io/base/admin"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+// RegisterAdminRoutes will add HTTP handlers for paygate's admin HTTP server
+func RegisterAdminRoutes(logger log.Logger, svc *admin.Server, monitor *Monitor) {
+	svc.AddHandler("/return-rates", getReturnRates(logger, monitor))
+}
+
+func getReturnRates(logger log.Logger, monitor *Monitor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+		if r.Method != http.MethodGet {
+			responder.Problem(fmt.Errorf("invalid method %s", r.Method))
+			return
+		}
+
+		rates := monitor.Rates()
+		if typ := strings.TrimSpace(r.URL.Query().Get("originatorType")); typ != "" {
+			var filtered []Rate
+			for i := range rates {
+				if strings.EqualFold(string(rates[i].OriginatorType), typ) {
+					filtered = append(filtered, rates[i])
+				}
+			}
+			rates = filtered
+		}
+		if rates == nil {
+			rates = []Rate{}
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(rates)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package returnrates
+
+import (
+	"time"
+)
+
+type MockRepository struct {
+	Rates             []Rate
+	TenantOriginators []Originator
+	Err               error
+}
+
+func (r *MockRepository) Calculate(since time.Time) ([]Rate, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	out := make([]Rate, len(r.Rates))
+	for i := range r.Rates {
+		out[i] = r.Rates[i].computed()
+	}
+	return out, nil
+}
+
+func (r *MockRepository) Originators(tenantID string) ([]Originator, error) {
+	if r.Err != nil {
+		return nil, r.Err
+	}
+	return r.TenantOriginators, nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package returnrates
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// ErrOriginatorPaused is returned for Transfers created by a paused Originator
+	ErrOriginatorPaused = errors.New("originator is paused for exceeding return rate thresholds")
+
+	returnRates = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "originator_return_rate",
+		Help: "Percentage of Transfers returned per Originator over the rolling window",
+	}, []string{"originator_type", "originator_id", "category"})
+
+	pausedOriginators = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "paused_originators",
+		Help: "Count of Originators paused for exceeding return rate thresholds",
+	}, nil)
+)
+
+// Checker decides if new Transfers can be originated for a Tenant and the Transfer's Organization.
+//
+// Check returns the status the Transfer should be saved with. ErrOriginatorPaused is
+// returned when the Transfer should be rejected.
+type Checker interface {
+	Check(tenantID string, organizationID string) (client.TransferStatus, error)
+}
+
+// Monitor periodically calculates return rates and keeps the latest set of
+// Originators which are paused.
+type Monitor struct {
+	cfg    *config.ReturnRates
+	logger log.Logger
+	repo   Repository
+
+	mu     sync.RWMutex
+	rates  []Rate
+	paused map[Originator]bool
+}
+
+func NewMonitor(logger log.Logger, cfg *config.ReturnRates, repo Repository) *Monitor {
+	return &Monitor{
+		cfg:    cfg,
+		logger: logger,
+		repo:   repo,
+		paused: make(map[Originator]bool),
+	}
+}
+
+// Start calculates return rates immediately and then on each interval until ctx is canceled.
+func (m *Monitor) Start(ctx context.Context) {
+	if err := m.Calculate(); err != nil {
+		m.logger.Log("returnrates", fmt.Sprintf("ERROR calculating return rates: %v", err))
+	}
+
+	ticker := time.NewTicker(m.cfg.CalculationInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			if err := m.Calculate(); err != nil {
+				m.logger.Log("returnrates", fmt.Sprintf("ERROR calculating return rates: %v", err))
+			}
+		}
+	}
+}
+
+// Calculate reads the latest return rates and pauses each Originator over a threshold.
+func (m *Monitor) Calculate() error {
+	rates, err := m.repo.Calculate(time.Now().Add(-1 * m.cfg.Window()))
+	if err != nil {
+		return err
+	}
+
+	th := m.cfg.Thresholds
+	paused := make(map[Originator]bool)
+	for i := range rates {
+		r := rates[i]
+		returnRates.With("originator_type", string(r.OriginatorType), "originator_id", r.OriginatorID, "category", "unauthorized").Set(r.UnauthorizedRate)
+		returnRates.With("originator_type", string(r.OriginatorType), "originator_id", r.OriginatorID, "category", "administrative").Set(r.AdministrativeRate)
+		returnRates.With("originator_type", string(r.OriginatorType), "originator_id", r.OriginatorID, "category", "overall").Set(r.OverallRate)
+
+		if r.UnauthorizedRate > th.UnauthorizedLimit() || r.AdministrativeRate > th.AdministrativeLimit() || r.OverallRate > th.OverallLimit() {
+			rates[i].Paused = true
+			paused[r.originator()] = true
+
+			m.logger.Log(
+				"returnrates", fmt.Sprintf("pausing %s=%s", r.OriginatorType, r.OriginatorID),
+				"unauthorized", r.UnauthorizedRate, "administrative", r.AdministrativeRate, "overall", r.OverallRate)
+		}
+	}
+	pausedOriginators.Set(float64(len(paused)))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rates = rates
+	m.paused = paused
+
+	return nil
+}
+
+// Rates returns the most recently calculated return rates.
+func (m *Monitor) Rates() []Rate {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]Rate, len(m.rates))
+	copy(out, m.rates)
+	return out
+}
+
+func (m *Monitor) Check(tenantID string, organizationID string) (client.TransferStatus, error) {
+	m.mu.RLock()
+	anyPaused := len(m.paused) > 0
+	m.mu.RUnlock()
+	if !anyPaused {
+		return client.PENDING, nil
+	}
+
+	originators, err := m.repo.Originators(tenantID)
+	if err != nil {
+		return "", fmt.Errorf("return rates: %v", err)
+	}
+	if organizationID != "" {
+		originators = append(originators, Originator{Organization, organizationID})
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := range originators {
+		if m.paused[originators[i]] {
+			if m.cfg.Review() {
+				return client.REVIEWABLE, nil
+			}
+			return "", ErrOriginatorPaused
+		}
+	}
+	return client.PENDING, nil
+}
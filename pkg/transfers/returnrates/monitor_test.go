@@ -0,0 +1,127 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package returnrates
+
+import (
+	"context"
+	"testing"
+
+	"github.com/antihax/optional"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/admin"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/testclient"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestMonitor(t *testing.T) {
+	tenant := Originator{Tenant, base.ID()}
+	organizationID := base.ID()
+	repo := &MockRepository{
+		Rates: []Rate{
+			{
+				OriginatorType: tenant.Type,
+				OriginatorID:   tenant.ID,
+				Transfers:      100,
+				Returns:        16,
+			},
+			{
+				OriginatorType: Organization,
+				OriginatorID:   organizationID,
+				Transfers:      100,
+				Returns:        20,
+			},
+			{
+				OriginatorType:      CompanyIdentification,
+				OriginatorID:        "MOOV123456",
+				Transfers:           1000,
+				UnauthorizedReturns: 1,
+				Returns:             10,
+			},
+		},
+		TenantOriginators: []Originator{tenant},
+	}
+	cfg := &config.ReturnRates{}
+	monitor := NewMonitor(log.NewNopLogger(), cfg, repo)
+
+	// nothing is paused before rates are calculated
+	if status, err := monitor.Check(tenant.ID, ""); err != nil || status != client.PENDING {
+		t.Fatalf("status=%q error=%v", status, err)
+	}
+
+	if err := monitor.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+	rates := monitor.Rates()
+	if !rates[0].Paused || !rates[1].Paused || rates[2].Paused {
+		t.Errorf("unexpected rates: %#v", rates)
+	}
+
+	if _, err := monitor.Check(tenant.ID, ""); err != ErrOriginatorPaused {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.Paused = "review"
+	if status, err := monitor.Check(tenant.ID, ""); err != nil || status != client.REVIEWABLE {
+		t.Errorf("status=%q error=%v", status, err)
+	}
+
+	// another tenant's originator isn't paused
+	repo.TenantOriginators = []Originator{{CompanyIdentification, "MOOV123456"}}
+	if status, err := monitor.Check(base.ID(), ""); err != nil || status != client.PENDING {
+		t.Errorf("status=%q error=%v", status, err)
+	}
+
+	// only Transfers for the paused Organization are held
+	if status, err := monitor.Check(base.ID(), organizationID); err != nil || status != client.REVIEWABLE {
+		t.Errorf("status=%q error=%v", status, err)
+	}
+	if status, err := monitor.Check(base.ID(), base.ID()); err != nil || status != client.PENDING {
+		t.Errorf("status=%q error=%v", status, err)
+	}
+}
+
+func TestMonitor__AdminRoutes(t *testing.T) {
+	repo := &MockRepository{
+		Rates: []Rate{
+			{OriginatorType: Tenant, OriginatorID: base.ID(), Transfers: 100, Returns: 16},
+			{OriginatorType: Organization, OriginatorID: base.ID(), Transfers: 100, Returns: 1},
+		},
+	}
+	monitor := NewMonitor(log.NewNopLogger(), &config.ReturnRates{}, repo)
+	if err := monitor.Calculate(); err != nil {
+		t.Fatal(err)
+	}
+
+	svc, c := testclient.Admin(t)
+	RegisterAdminRoutes(log.NewNopLogger(), svc, monitor)
+
+	rates, resp, err := c.ReturnRatesApi.GetReturnRates(context.Background(), base.ID(), nil)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rates); n != 2 {
+		t.Errorf("got %d rates: %#v", n, rates)
+	}
+
+	opts := &admin.GetReturnRatesOpts{
+		OriginatorType: optional.NewString("tenant"),
+	}
+	rates, resp, err = c.ReturnRatesApi.GetReturnRates(context.Background(), base.ID(), opts)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rates) != 1 || !rates[0].Paused || rates[0].OverallRate != 16.0 {
+		t.Errorf("unexpected rates: %#v", rates)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package returnrates calculates NACHA return rates for each Originator and pauses
+// Originators whose rates exceed the configured thresholds.
+//
+// Rates are calculated over a rolling window (60 days by default) against every
+// Transfer created by the Originator, except the unauthorized rate which is only
+// calculated against Transfers which originated a debit.
+package returnrates
+
+type OriginatorType string
+
+const (
+	Tenant                OriginatorType = "tenant"
+	Organization          OriginatorType = "organization"
+	CompanyIdentification OriginatorType = "companyIdentification"
+)
+
+// Originator is one party return rates are calculated and enforced for.
+type Originator struct {
+	Type OriginatorType
+	ID   string
+}
+
+// Rate holds the return counts and rates (as percentages) for an Originator.
+type Rate struct {
+	OriginatorType OriginatorType `json:"originatorType"`
+	OriginatorID   string         `json:"originatorID"`
+
+	Transfers             int `json:"transfers"`
+	Debits                int `json:"debits"`
+	UnauthorizedReturns   int `json:"unauthorizedReturns"`
+	AdministrativeReturns int `json:"administrativeReturns"`
+	Returns               int `json:"returns"`
+
+	UnauthorizedRate   float64 `json:"unauthorizedRate"`
+	AdministrativeRate float64 `json:"administrativeRate"`
+	OverallRate        float64 `json:"overallRate"`
+
+	Paused bool `json:"paused"`
+}
+
+func (r Rate) originator() Originator {
+	return Originator{Type: r.OriginatorType, ID: r.OriginatorID}
+}
+
+// computed fills in each rate. The unauthorized rate is over debits as only debits can be
+// returned as unauthorized, while the others are over every Transfer.
+func (r Rate) computed() Rate {
+	if r.Transfers <= 0 {
+		return r
+	}
+	if r.Debits > 0 {
+		r.UnauthorizedRate = 100.0 * float64(r.UnauthorizedReturns) / float64(r.Debits)
+	}
+	total := float64(r.Transfers)
+	r.AdministrativeRate = 100.0 * float64(r.AdministrativeReturns) / total
+	r.OverallRate = 100.0 * float64(r.Returns) / total
+	return r
+}
@@ -0,0 +1,131 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package returnrates
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// unauthorizedReturnCodes are counted towards NACHA's unauthorized return rate
+	unauthorizedReturnCodes = []string{"R05", "R07", "R10", "R29", "R51"}
+
+	// administrativeReturnCodes are counted towards NACHA's administrative return rate
+	administrativeReturnCodes = []string{"R02", "R03", "R04"}
+)
+
+type Repository interface {
+	// Calculate returns the return rates of each Originator for Transfers created after since.
+	Calculate(since time.Time) ([]Rate, error)
+
+	// Originators returns the Tenant and its current Company Identification as Originators.
+	// Organizations are read from each Transfer instead.
+	Originators(tenantID string) ([]Originator, error)
+}
+
+func NewRepo(db *sql.DB) Repository {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) Close() error {
+	if r == nil || r.db == nil {
+		return nil
+	}
+	return r.db.Close()
+}
+
+func inCodes(codes []string) string {
+	return fmt.Sprintf("('%s')", strings.Join(codes, "','"))
+}
+
+func (r *sqlRepo) Calculate(since time.Time) ([]Rate, error) {
+	var out []Rate
+	for _, typ := range []OriginatorType{Tenant, Organization, CompanyIdentification} {
+		rates, err := r.calculate(typ, since)
+		if err != nil {
+			return nil, fmt.Errorf("%s return rates: %v", typ, err)
+		}
+		out = append(out, rates...)
+	}
+	return out, nil
+}
+
+func (r *sqlRepo) calculate(typ OriginatorType, since time.Time) ([]Rate, error) {
+	// Transfers are counted against the Tenant they were created in and that Tenant's Company
+	// Identification, and against the Organization they were created for.
+	var column, join string
+	switch typ {
+	case Tenant:
+		column = "x.tenant_id"
+	case Organization:
+		column = "x.organization_id"
+	case CompanyIdentification:
+		column = "t.company_identification"
+		join = "inner join tenants t on x.tenant_id = t.tenant_id"
+	default:
+		return nil, fmt.Errorf("unknown OriginatorType %q", typ)
+	}
+
+	// Unauthorized returns are only counted against Transfers which originated a debit
+	query := fmt.Sprintf(`select %s, count(*),
+sum(case when exists (select 1 from transfer_trace_numbers tt where tt.transfer_id = x.transfer_id and tt.debit = 1) then 1 else 0 end),
+sum(case when x.return_code in %s then 1 else 0 end),
+sum(case when x.return_code in %s then 1 else 0 end),
+sum(case when x.return_code is not null and x.return_code <> '' then 1 else 0 end)
+from transfers x %s
+where x.created_at >= ? and x.deleted_at is null and %s is not null and %s <> ''
+group by %s;`, column, inCodes(unauthorizedReturnCodes), inCodes(administrativeReturnCodes), join, column, column, column)
+
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Query(since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Rate
+	for rows.Next() {
+		rate := Rate{OriginatorType: typ}
+		if err := rows.Scan(&rate.OriginatorID, &rate.Transfers, &rate.Debits, &rate.UnauthorizedReturns, &rate.AdministrativeReturns, &rate.Returns); err != nil {
+			return nil, err
+		}
+		out = append(out, rate.computed())
+	}
+	return out, rows.Err()
+}
+
+func (r *sqlRepo) Originators(tenantID string) ([]Originator, error) {
+	query := `select company_identification from tenants where tenant_id = ? and deleted_at is null limit 1;`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var companyID *string
+	if err := stmt.QueryRow(tenantID).Scan(&companyID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	out := []Originator{{Tenant, tenantID}}
+	if companyID != nil && *companyID != "" {
+		out = append(out, Originator{CompanyIdentification, *companyID})
+	}
+	return out, nil
+}
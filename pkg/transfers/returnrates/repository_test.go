@@ -0,0 +1,133 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package returnrates
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+func writeTenant(t *testing.T, db *sql.DB, userID, companyID string) string {
+	t.Helper()
+
+	tenantID := base.ID()
+	query := `insert into tenants (tenant_id, user_id, name, primary_customer, company_identification, created_at) values (?, ?, ?, ?, ?, ?);`
+	if _, err := db.Exec(query, tenantID, userID, "My Company", base.ID(), companyID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	return tenantID
+}
+
+// writeTransfers saves a Transfer for each return code, the first debits of which originate a debit
+func writeTransfers(t *testing.T, db *sql.DB, userID, tenantID, organizationID string, debits int, returnCodes ...string) {
+	t.Helper()
+
+	query := `insert into transfers (transfer_id, user_id, tenant_id, organization_id, amount, status, return_code, created_at) values (?, ?, ?, ?, ?, ?, ?, ?);`
+	for i := range returnCodes {
+		var code *string
+		if returnCodes[i] != "" {
+			code = &returnCodes[i]
+		}
+		transferID := base.ID()
+		if _, err := db.Exec(query, transferID, userID, tenantID, organizationID, "USD 12.44", "processed", code, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		traceQuery := `insert into transfer_trace_numbers (transfer_id, trace_number, debit) values (?, ?, ?);`
+		if _, err := db.Exec(traceQuery, transferID, base.ID(), i < debits); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRepository__Calculate(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		tenantID := writeTenant(t, repo.db, userID, "MOOV123456")
+
+		orgID := base.ID()
+		query := `insert into tenants_organizations (tenant_id, organization_id, created_at) values (?, ?, ?);`
+		if _, err := repo.db.Exec(query, tenantID, orgID, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+
+		// Transfers from the owner and a member of the Tenant are counted
+		writeTransfers(t, repo.db, userID, tenantID, orgID, 5, "", "", "", "", "R05")
+		writeTransfers(t, repo.db, base.ID(), tenantID, orgID, 0, "", "", "R03", "R01", "")
+
+		// the owner's other Tenants don't count Transfers from this one
+		writeTenant(t, repo.db, userID, "MOOV654321")
+
+		// Transfers without a Tenant or Organization aren't counted towards one
+		writeTransfers(t, repo.db, base.ID(), "", "", 0, "R05")
+
+		rates, err := repo.Calculate(time.Now().Add(-1 * time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(rates); n != 3 {
+			t.Fatalf("got %d rates: %#v", n, rates)
+		}
+		for i := range rates {
+			r := rates[i]
+			if r.Transfers != 10 || r.Debits != 5 || r.UnauthorizedReturns != 1 || r.AdministrativeReturns != 1 || r.Returns != 3 {
+				t.Errorf("unexpected counts: %#v", r)
+			}
+			if r.UnauthorizedRate != 20.0 || r.AdministrativeRate != 10.0 || r.OverallRate != 30.0 {
+				t.Errorf("unexpected rates: %#v", r)
+			}
+			switch r.OriginatorType {
+			case Tenant:
+				if r.OriginatorID != tenantID {
+					t.Errorf("unexpected tenant: %#v", r)
+				}
+			case Organization:
+				if r.OriginatorID != orgID {
+					t.Errorf("unexpected organization: %#v", r)
+				}
+			case CompanyIdentification:
+				if r.OriginatorID != "MOOV123456" {
+					t.Errorf("unexpected company identification: %#v", r)
+				}
+			}
+		}
+
+		// transfers outside the window are skipped
+		rates, err = repo.Calculate(time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rates) != 0 {
+			t.Errorf("unexpected rates: %#v", rates)
+		}
+
+		originators, err := repo.Originators(tenantID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(originators); n != 2 {
+			t.Fatalf("got %d originators: %#v", n, originators)
+		}
+		if originators[0] != (Originator{Tenant, tenantID}) || originators[1] != (Originator{CompanyIdentification, "MOOV123456"}) {
+			t.Errorf("unexpected originators: %#v", originators)
+		}
+		if originators, err := repo.Originators(base.ID()); err != nil || len(originators) != 0 {
+			t.Errorf("originators=%#v error=%v", originators, err)
+		}
+	}
+
+	// SQLite tests
+	sqliteDB := database.CreateTestSqliteDB(t)
+	defer sqliteDB.Close()
+	check(t, &sqlRepo{db: sqliteDB.DB})
+
+	// MySQL tests
+	mysqlDB := database.CreateTestMySQLDB(t)
+	defer mysqlDB.Close()
+	check(t, &sqlRepo{db: mysqlDB.DB})
+}
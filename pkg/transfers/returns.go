@@ -0,0 +1,131 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"fmt"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/inbound"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/go-kit/kit/log"
+)
+
+// ReturnHandler reads return and correction (NOC) entries out of files downloaded from the
+// ODFI. Each return code is recorded on the Transfer whose entry was returned, dropping any file
+// held for it, before publishing the files our fundflow strategy originates in response. Corrections are logged for operators
+// to update the Customer's account.
+type ReturnHandler struct {
+	logger   log.Logger
+	repo     Repository
+	strategy fundflow.Strategy
+	pub      pipeline.XferPublisher
+}
+
+func NewReturnHandler(logger log.Logger, repo Repository, strategy fundflow.Strategy, pub pipeline.XferPublisher) *ReturnHandler {
+	return &ReturnHandler{
+		logger:   logger,
+		repo:     repo,
+		strategy: strategy,
+		pub:      pub,
+	}
+}
+
+func (h *ReturnHandler) Handle(file *inbound.ArchivedFile, contents *ach.File) error {
+	for i := range contents.ReturnEntries {
+		companyID := contents.ReturnEntries[i].GetHeader().CompanyIdentification
+		entries := contents.ReturnEntries[i].GetEntries()
+		for j := range entries {
+			if entries[j].Addenda99 == nil {
+				continue
+			}
+			if err := h.handleReturn(file, companyID, entries[j]); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range contents.NotificationOfChange {
+		entries := contents.NotificationOfChange[i].GetEntries()
+		for j := range entries {
+			if entries[j].Addenda98 == nil {
+				continue
+			}
+			if err := h.handleCorrection(file, entries[j]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (h *ReturnHandler) handleReturn(file *inbound.ArchivedFile, companyID string, entry *ach.EntryDetail) error {
+	traceNumber := entry.Addenda99.OriginalTrace
+	xfer, err := h.findTransfer(traceNumber)
+	if err != nil || xfer == nil {
+		return err
+	}
+	code := entry.Addenda99.ReturnCode
+	if xfer.ReturnCode.Code != "" {
+		// Re-processed files and the later return of another leg aren't acted on twice
+		h.logger.Log("returns", fmt.Sprintf("transferID=%s was already returned with %s, skipping %s", xfer.TransferID, xfer.ReturnCode.Code, code), "traceNumber", traceNumber)
+		return nil
+	}
+
+	// Record the return before originating anything in response so reprocessing the file
+	// never originates the same files twice.
+	if err := h.repo.SetReturnCode(xfer.TransferID, code); err != nil {
+		return fmt.Errorf("setting return code for transferID=%s: %v", xfer.TransferID, err)
+	}
+	if err := h.repo.cancelHold(xfer.TransferID); err != nil {
+		return fmt.Errorf("canceling held file for transferID=%s: %v", xfer.TransferID, err)
+	}
+	h.logger.Log("returns", fmt.Sprintf("transferID=%s was returned with %s", xfer.TransferID, code), "traceNumber", traceNumber, "sha256", file.SHA256)
+
+	if h.strategy != nil {
+		strategy, err := fundflow.ForODFI(h.strategy, file.ODFI)
+		if err != nil {
+			return err
+		}
+		files, err := strategy.HandleReturn(companyID, entry, xfer)
+		if err != nil {
+			return fmt.Errorf("handling return of transferID=%s: %v", xfer.TransferID, err)
+		}
+		if len(files) > 0 {
+			if err := publishFiles(h.repo, h.pub, file.ODFI, xfer, files); err != nil {
+				return fmt.Errorf("publishing return files for transferID=%s: %v", xfer.TransferID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (h *ReturnHandler) handleCorrection(file *inbound.ArchivedFile, entry *ach.EntryDetail) error {
+	traceNumber := entry.Addenda98.OriginalTrace
+	xfer, err := h.findTransfer(traceNumber)
+	if err != nil || xfer == nil {
+		return err
+	}
+	h.logger.Log(
+		"returns", fmt.Sprintf("transferID=%s received correction %s", xfer.TransferID, entry.Addenda98.ChangeCode),
+		"correctedData", entry.Addenda98.CorrectedData, "traceNumber", traceNumber, "sha256", file.SHA256)
+	return nil
+}
+
+// findTransfer returns the Transfer originated with traceNumber. Entries we didn't originate
+// are logged and skipped.
+func (h *ReturnHandler) findTransfer(traceNumber string) (*client.Transfer, error) {
+	xfers, err := h.repo.SearchTransfers(SearchParams{TraceNumber: traceNumber, Limit: 1})
+	if err != nil {
+		return nil, fmt.Errorf("finding transfer for traceNumber=%s: %v", traceNumber, err)
+	}
+	if len(xfers) == 0 {
+		h.logger.Log("returns", fmt.Sprintf("no transfer found for traceNumber=%s", traceNumber))
+		return nil, nil
+	}
+	return xfers[0], nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/inbound"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestReturnHandler(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	xfer := writeTransfer(t, base.ID(), repo)
+	if err := repo.saveTraceNumbers(xfer.TransferID, []*ach.EntryDetail{{TraceNumber: "091400600000001"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.holdFile(xfer.TransferID, "", ach.NewFile(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	returned, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "return-WEB.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrected, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "cor-c01.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strategy := &fundflow.MockStrategy{Files: []*ach.File{corrected}}
+	pub := &pipeline.MockPublisher{}
+	handler := NewReturnHandler(log.NewNopLogger(), repo, strategy, pub)
+	file := &inbound.ArchivedFile{Kind: inbound.ReturnFile}
+	for _, contents := range []*ach.File{returned, corrected} {
+		if err := handler.Handle(file, contents); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	xfer, err = repo.GetTransfer(xfer.TransferID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xfer.ReturnCode.Code != "R01" {
+		t.Errorf("unexpected return code: %#v", xfer.ReturnCode)
+	}
+	if len(pub.Xfers) != 1 {
+		t.Errorf("unexpected xfers: %#v", pub.Xfers)
+	}
+	// the file held for the Transfer is dropped
+	if held, err := repo.getHeldFiles(time.Now()); err != nil || len(held) != 0 {
+		t.Errorf("held=%#v error=%v", held, err)
+	}
+
+	// re-processed files don't originate anything again
+	if err := handler.Handle(file, returned); err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.Xfers) != 1 {
+		t.Errorf("unexpected xfers: %#v", pub.Xfers)
+	}
+}
+
+func TestReturnHandler__publishErr(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	xfer := writeTransfer(t, base.ID(), repo)
+	if err := repo.saveTraceNumbers(xfer.TransferID, []*ach.EntryDetail{{TraceNumber: "091400600000001"}}); err != nil {
+		t.Fatal(err)
+	}
+	returned, err := ach.ReadFile(filepath.Join("..", "..", "testdata", "return-WEB.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strategy := &fundflow.MockStrategy{Files: []*ach.File{returned}}
+	pub := &pipeline.MockPublisher{Err: errors.New("bad error")}
+	handler := NewReturnHandler(log.NewNopLogger(), repo, strategy, pub)
+	if err := handler.Handle(&inbound.ArchivedFile{}, returned); err == nil {
+		t.Error("expected error")
+	}
+
+	// the return code is recorded first so reprocessing the file doesn't publish anything
+	xfer, err = repo.GetTransfer(xfer.TransferID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xfer.ReturnCode.Code != "R01" {
+		t.Errorf("unexpected return code: %#v", xfer.ReturnCode)
+	}
+	pub.Err = nil
+	if err := handler.Handle(&inbound.ArchivedFile{}, returned); err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.Xfers) != 0 {
+		t.Errorf("unexpected xfers: %#v", pub.Xfers)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/memberships"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/returnrates"
+	"github.com/moov-io/paygate/x/route"
+
+	"github.com/go-kit/kit/log"
+)
+
+var (
+	// ErrSelfReview is returned when the creator of a Transfer tries to approve or reject it
+	ErrSelfReview = errors.New("transfers must be reviewed by someone other than their creator")
+
+	errNotReviewable = errors.New("transfer is not reviewable")
+)
+
+// Reviews approves and rejects Transfers held in the REVIEWABLE status. Approved Transfers
+// are originated and published like any other Transfer.
+type Reviews struct {
+	logger log.Logger
+	repo   Repository
+
+	tenantRepo       tenants.Repository
+	customersClient  customers.Client
+	accountDecryptor accounts.Decryptor
+	fundStrategy     fundflow.Strategy
+	fundsChecker     fundflow.FundsChecker
+	returnRates      returnrates.Checker
+	pub              pipeline.XferPublisher
+}
+
+func NewReviews(
+	logger log.Logger,
+	repo Repository,
+	tenantRepo tenants.Repository,
+	customersClient customers.Client,
+	accountDecryptor accounts.Decryptor,
+	fundStrategy fundflow.Strategy,
+	fundsChecker fundflow.FundsChecker,
+	returnRates returnrates.Checker,
+	pub pipeline.XferPublisher,
+) *Reviews {
+	return &Reviews{
+		logger:           logger,
+		repo:             repo,
+		tenantRepo:       tenantRepo,
+		customersClient:  customersClient,
+		accountDecryptor: accountDecryptor,
+		fundStrategy:     fundStrategy,
+		fundsChecker:     fundsChecker,
+		returnRates:      returnRates,
+		pub:              pub,
+	}
+}
+
+// reviewable returns the Transfer and its creator if it's waiting on review by someone other than its creator.
+func (rv *Reviews) reviewable(transferID string, reviewerID string) (*client.Transfer, string, error) {
+	xfer, err := rv.repo.GetTransfer(transferID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, "", err
+	}
+	if xfer == nil {
+		return nil, "", errors.New("transfer not found")
+	}
+	if xfer.Status != client.REVIEWABLE {
+		return nil, "", errNotReviewable
+	}
+	creator, err := rv.repo.getTransferCreator(transferID)
+	if err != nil {
+		return nil, "", err
+	}
+	if creator == reviewerID {
+		return nil, "", ErrSelfReview
+	}
+	return xfer, creator, nil
+}
+
+// Approve moves a REVIEWABLE Transfer into PENDING and originates it. The Tenant, return rate and funds
+// checks are run again as they could have changed while the Transfer waited on review. Originators held
+// for their return rates can be approved as the review is how they're released.
+func (rv *Reviews) Approve(transferID string, reviewerID string) (*client.Transfer, error) {
+	xfer, creator, err := rv.reviewable(transferID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lookup accounts before approving so a missing account leaves the Transfer for review
+	var source fundflow.Source
+	var destination fundflow.Destination
+	if rv.fundStrategy != nil {
+		source, destination, err = lookupAccounts(rv.customersClient, rv.accountDecryptor, xfer.Source, xfer.Destination)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Approved Transfers are originated for the Tenant they were created in
+	tenantID, err := savedTransferTenant(rv.repo, rv.tenantRepo, creator, xfer)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTenant(rv.tenantRepo, tenantID, xfer); err != nil {
+		return nil, err
+	}
+	if rv.returnRates != nil {
+		if _, err := rv.returnRates.Check(tenantID, xfer.OrganizationID); err != nil {
+			return nil, err
+		}
+	}
+	if rv.fundsChecker != nil {
+		status, err := rv.fundsChecker.Check(xfer, source)
+		if err != nil {
+			return nil, err
+		}
+		if status != client.PENDING {
+			return nil, fundflow.ErrInsufficientFunds
+		}
+	}
+
+	if err := rv.repo.reviewTransfer(transferID, reviewerID, client.PENDING); err != nil {
+		rv.releaseUnapproved(xfer)
+		return nil, err
+	}
+	xfer.Status = client.PENDING
+
+	if err := originateTransfer(rv.repo, rv.tenantRepo, rv.fundStrategy, rv.pub, tenantID, xfer, source, destination); err != nil {
+		// Leave the Transfer for review again since nothing was sent
+		releaseFunds(rv.logger, rv.fundsChecker, xfer)
+		if rerr := rv.repo.reopenReview(transferID); rerr != nil {
+			rv.logger.Log("transfers", fmt.Sprintf("ERROR reopening review of transferID=%s: %v", transferID, rerr))
+		}
+		return nil, fmt.Errorf("originating approved transfer: %v", err)
+	}
+	rv.logger.Log(
+		"transfers", fmt.Sprintf("approved transferID=%s", transferID),
+		"reviewer", reviewerID)
+
+	return xfer, nil
+}
+
+// releaseUnapproved frees the funds held while approving a Transfer which another reviewer decided
+// on first. Holds are kept by Transfer, so they're left for Transfers which were approved.
+func (rv *Reviews) releaseUnapproved(xfer *client.Transfer) {
+	current, err := rv.repo.GetTransfer(xfer.TransferID)
+	if err != nil {
+		rv.logger.Log("transfers", fmt.Sprintf("ERROR reading transferID=%s: %v", xfer.TransferID, err))
+		return
+	}
+	if current != nil && current.Status == client.PENDING {
+		return
+	}
+	releaseFunds(rv.logger, rv.fundsChecker, xfer)
+}
+
+// Reject cancels a REVIEWABLE Transfer and releases any funds held for it.
+func (rv *Reviews) Reject(transferID string, reviewerID string) (*client.Transfer, error) {
+	xfer, _, err := rv.reviewable(transferID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := rv.repo.reviewTransfer(transferID, reviewerID, client.CANCELED); err != nil {
+		return nil, err
+	}
+	xfer.Status = client.CANCELED
+	releaseFunds(rv.logger, rv.fundsChecker, xfer)
+
+	rv.logger.Log(
+		"transfers", fmt.Sprintf("rejected transferID=%s", transferID),
+		"reviewer", reviewerID)
+
+	return xfer, nil
+}
+
+// checkReviewer requires the user can approve Transfers for the Transfer's Organization, or
+// the Tenant the Transfer was created in when it isn't part of an Organization.
+func checkReviewer(repo Repository, tenantRepo tenants.Repository, access memberships.Checker, userID, transferID string) error {
+	xfer, err := repo.GetTransfer(transferID)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if xfer == nil {
+		return memberships.ErrNotMember
+	}
+	if xfer.OrganizationID != "" {
+		return memberships.Require(access, userID, memberships.Organization, xfer.OrganizationID, memberships.ApproveTransfers)
+	}
+	creator, err := repo.getTransferCreator(transferID)
+	if err != nil {
+		return err
+	}
+	tenantID, err := savedTransferTenant(repo, tenantRepo, creator, xfer)
+	if err != nil {
+		return err
+	}
+	return memberships.Require(access, userID, memberships.Tenant, tenantID, memberships.ApproveTransfers)
+}
+
+func reviewUserTransfer(logger log.Logger, repo Repository, tenantRepo tenants.Repository, access memberships.Checker, decide func(transferID, reviewerID string) (*client.Transfer, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responder := route.NewResponder(logger, w, r)
+
+		transferID := getTransferID(r)
+		if err := checkReviewer(repo, tenantRepo, access, responder.XUserID, transferID); err != nil {
+			memberships.Problem(responder, w, r, err)
+			return
+		}
+
+		xfer, err := decide(transferID, responder.XUserID)
+		if err != nil {
+			if err == ErrSelfReview {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+			responder.Problem(err)
+			return
+		}
+
+		responder.Respond(func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusOK)
+			json.NewEncoder(w).Encode(xfer)
+		})
+	}
+}
+
+func ApproveUserTransfer(logger log.Logger, repo Repository, access memberships.Checker, reviews *Reviews) http.HandlerFunc {
+	return reviewUserTransfer(logger, repo, reviews.tenantRepo, access, reviews.Approve)
+}
+
+func RejectUserTransfer(logger log.Logger, repo Repository, access memberships.Checker, reviews *Reviews) http.HandlerFunc {
+	return reviewUserTransfer(logger, repo, reviews.tenantRepo, access, reviews.Reject)
+}
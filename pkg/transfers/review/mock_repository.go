@@ -0,0 +1,21 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package review
+
+import (
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type MockRepository struct {
+	Sent bool
+	Err  error
+}
+
+func (r *MockRepository) SentTo(userID string, destination client.Destination) (bool, error) {
+	if r.Err != nil {
+		return false, r.Err
+	}
+	return r.Sent, nil
+}
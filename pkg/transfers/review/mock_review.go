@@ -0,0 +1,22 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package review
+
+import (
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+// MockChecker holds every Transfer for the Reasons given.
+type MockChecker struct {
+	Reasons []string
+	Err     error
+}
+
+func (c *MockChecker) Check(userID string, transfer *client.Transfer) ([]string, error) {
+	if c.Err != nil {
+		return nil, c.Err
+	}
+	return c.Reasons, nil
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package review
+
+import (
+	"database/sql"
+
+	"github.com/moov-io/paygate/pkg/client"
+)
+
+type Repository interface {
+	// SentTo returns true if the user has a Transfer to the destination account which wasn't
+	// canceled, failed or still waiting on review.
+	SentTo(userID string, destination client.Destination) (bool, error)
+}
+
+func NewRepo(db *sql.DB) Repository {
+	return &sqlRepo{db: db}
+}
+
+type sqlRepo struct {
+	db *sql.DB
+}
+
+func (r *sqlRepo) SentTo(userID string, destination client.Destination) (bool, error) {
+	query := `select count(*) from transfers
+where user_id = ? and destination_customer_id = ? and destination_account_id = ?
+and status in (?, ?) and deleted_at is null`
+	stmt, err := r.db.Prepare(query)
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+
+	n := 0
+	err = stmt.QueryRow(userID, destination.CustomerID, destination.AccountID, client.PENDING, client.PROCESSED).Scan(&n)
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package review
+
+import (
+	"testing"
+	"time"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/database"
+)
+
+func TestRepository__SentTo(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		userID := base.ID()
+		destination := client.Destination{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		}
+		if sent, err := repo.SentTo(userID, destination); sent || err != nil {
+			t.Fatalf("sent=%v error=%v", sent, err)
+		}
+
+		query := `insert into transfers (transfer_id, user_id, amount, destination_customer_id, destination_account_id, status, created_at) values (?, ?, ?, ?, ?, ?, ?);`
+		write := func(status client.TransferStatus) {
+			t.Helper()
+			if _, err := repo.db.Exec(query, base.ID(), userID, "USD 12.44", destination.CustomerID, destination.AccountID, status, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Transfers held for review or canceled aren't counted
+		write(client.REVIEWABLE)
+		write(client.CANCELED)
+		if sent, err := repo.SentTo(userID, destination); sent || err != nil {
+			t.Fatalf("sent=%v error=%v", sent, err)
+		}
+
+		write(client.PROCESSED)
+		if sent, err := repo.SentTo(userID, destination); !sent || err != nil {
+			t.Fatalf("sent=%v error=%v", sent, err)
+		}
+
+		// other users haven't sent to the destination
+		if sent, err := repo.SentTo(base.ID(), destination); sent || err != nil {
+			t.Fatalf("sent=%v error=%v", sent, err)
+		}
+	}
+
+	// SQLite tests
+	sqliteDB := database.CreateTestSqliteDB(t)
+	defer sqliteDB.Close()
+	check(t, &sqlRepo{db: sqliteDB.DB})
+
+	// MySQL tests
+	mysqlDB := database.CreateTestMySQLDB(t)
+	defer mysqlDB.Close()
+	check(t, &sqlRepo{db: mysqlDB.DB})
+}
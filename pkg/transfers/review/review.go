@@ -0,0 +1,103 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package review
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+	"github.com/moov-io/paygate/pkg/model"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Checker decides if a Transfer must be approved by someone other than its creator
+// before it's originated.
+//
+// Check returns the reason for each rule the Transfer matched, or nothing when it can
+// be originated right away.
+type Checker interface {
+	Check(userID string, transfer *client.Transfer) ([]string, error)
+}
+
+// NewChecker returns a Checker for the configured rules. Nil is returned when no rules
+// are configured.
+func NewChecker(logger log.Logger, cfg *config.Review, repo Repository, customersClient customers.Client) (Checker, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	threshold, err := cfg.Threshold()
+	if err != nil {
+		return nil, fmt.Errorf("review: amount_threshold: %v", err)
+	}
+	if cfg.OFACMatch > 0.0 && customersClient == nil {
+		return nil, errors.New("review: ofac_match requires a customers client")
+	}
+	return &rules{
+		threshold:       threshold,
+		newDestination:  cfg.NewDestination,
+		ofacMatch:       cfg.OFACMatch,
+		repo:            repo,
+		customersClient: customersClient,
+		logger:          logger,
+	}, nil
+}
+
+type rules struct {
+	threshold      *model.Amount
+	newDestination bool
+	ofacMatch      float32
+
+	repo            Repository
+	customersClient customers.Client
+	logger          log.Logger
+}
+
+func (r *rules) Check(userID string, transfer *client.Transfer) ([]string, error) {
+	if transfer == nil {
+		return nil, errors.New("nil Transfer")
+	}
+	var reasons []string
+
+	if r.threshold != nil {
+		var amt model.Amount
+		if err := amt.FromString(transfer.Amount); err != nil {
+			return nil, fmt.Errorf("unable to parse '%s': %v", transfer.Amount, err)
+		}
+		if amt.Int() >= r.threshold.Int() {
+			reasons = append(reasons, fmt.Sprintf("amount is at or above %s", r.threshold))
+		}
+	}
+
+	if r.newDestination {
+		sent, err := r.repo.SentTo(userID, transfer.Destination)
+		if err != nil {
+			return nil, fmt.Errorf("new destination: %v", err)
+		}
+		if !sent {
+			reasons = append(reasons, "new destination")
+		}
+	}
+
+	if r.ofacMatch > 0.0 {
+		search, err := r.customersClient.LatestOFACSearch(transfer.Destination.CustomerID, "requestID", userID)
+		if err != nil {
+			return nil, fmt.Errorf("ofac search: %v", err)
+		}
+		if search != nil && search.Match >= r.ofacMatch {
+			reasons = append(reasons, fmt.Sprintf("destination OFAC match of %.2f", search.Match))
+		}
+	}
+
+	if len(reasons) > 0 {
+		r.logger.Log(
+			"review", fmt.Sprintf("holding transferID=%s for review", transfer.TransferID),
+			"userID", userID)
+	}
+	return reasons, nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package review
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/customers"
+
+	"github.com/go-kit/kit/log"
+)
+
+func TestReview__NewChecker(t *testing.T) {
+	checker, err := NewChecker(log.NewNopLogger(), nil, &MockRepository{}, nil)
+	if checker != nil || err != nil {
+		t.Errorf("checker=%#v error=%v", checker, err)
+	}
+
+	cfg := &config.Review{AmountThreshold: "10000"}
+	if _, err := NewChecker(log.NewNopLogger(), cfg, &MockRepository{}, nil); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg = &config.Review{OFACMatch: 0.95}
+	if _, err := NewChecker(log.NewNopLogger(), cfg, &MockRepository{}, nil); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestReview__Check(t *testing.T) {
+	cfg := &config.Review{
+		AmountThreshold: "USD 10000.00",
+		NewDestination:  true,
+		OFACMatch:       0.95,
+	}
+	repo := &MockRepository{Sent: true}
+	customersClient := &customers.MockClient{
+		Result: &customers.OfacSearch{EntityId: base.ID(), Match: 0.72},
+	}
+	checker, err := NewChecker(log.NewNopLogger(), cfg, repo, customersClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount:     "USD 9999.99",
+		Destination: client.Destination{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+	}
+	if reasons, err := checker.Check("userID", xfer); len(reasons) != 0 || err != nil {
+		t.Fatalf("reasons=%v error=%v", reasons, err)
+	}
+
+	// match every rule
+	xfer.Amount = "USD 10000.00"
+	repo.Sent = false
+	customersClient.Result.Match = 0.98
+	reasons, err := checker.Check("userID", xfer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reasons) != 3 {
+		t.Fatalf("unexpected reasons: %v", reasons)
+	}
+	if reasons[1] != "new destination" {
+		t.Errorf("unexpected reason: %q", reasons[1])
+	}
+
+	// errors
+	repo.Err = errors.New("bad error")
+	if _, err := checker.Check("userID", xfer); err == nil {
+		t.Error("expected error")
+	}
+	repo.Err = nil
+	customersClient.Err = errors.New("bad error")
+	if _, err := checker.Check("userID", xfer); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := checker.Check("userID", nil); err == nil {
+		t.Error("expected error")
+	}
+}
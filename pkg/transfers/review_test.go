@@ -0,0 +1,337 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/memberships"
+	"github.com/moov-io/paygate/pkg/tenants"
+	"github.com/moov-io/paygate/pkg/testclient"
+	"github.com/moov-io/paygate/pkg/transfers/fundflow"
+	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/returnrates"
+	"github.com/moov-io/paygate/pkg/transfers/review"
+
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+)
+
+func writeReviewableTransfer(t *testing.T, userID string, repo Repository) *client.Transfer {
+	t.Helper()
+
+	xfer := &client.Transfer{
+		TransferID: base.ID(),
+		Amount:     "USD 25000.00",
+		Source: client.Source{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Destination: client.Destination{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Description: "payroll",
+		Status:      client.REVIEWABLE,
+		Created:     time.Now(),
+	}
+	if err := repo.writeUserTransfers(userID, "tenantID", xfer); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.saveReview(xfer.TransferID, []string{"new destination"}); err != nil {
+		t.Fatal(err)
+	}
+	return xfer
+}
+
+func TestRepository__reviewTransfer(t *testing.T) {
+	check := func(t *testing.T, repo *sqlRepo) {
+		xfer := writeReviewableTransfer(t, "creator", repo)
+		if creator, err := repo.getTransferCreator(xfer.TransferID); creator != "creator" || err != nil {
+			t.Fatalf("creator=%q error=%v", creator, err)
+		}
+
+		if err := repo.reviewTransfer(xfer.TransferID, "reviewer", client.PENDING); err != nil {
+			t.Fatal(err)
+		}
+		found, err := repo.GetTransfer(xfer.TransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if found.Status != client.PENDING {
+			t.Errorf("unexpected status: %q", found.Status)
+		}
+
+		reviewer, decision := "", ""
+		query := `select reviewed_by, decision from transfer_reviews where transfer_id = ?`
+		if err := repo.db.QueryRow(query, xfer.TransferID).Scan(&reviewer, &decision); err != nil {
+			t.Fatal(err)
+		}
+		if reviewer != "reviewer" || decision != string(client.PENDING) {
+			t.Errorf("reviewer=%q decision=%q", reviewer, decision)
+		}
+
+		// Transfers can only be reviewed once
+		if err := repo.reviewTransfer(xfer.TransferID, "reviewer", client.CANCELED); err != errNotReviewable {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		// reopened Transfers are reviewed again
+		if err := repo.reopenReview(xfer.TransferID); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.reviewTransfer(xfer.TransferID, "reviewer", client.CANCELED); err != nil {
+			t.Fatal(err)
+		}
+
+		// Transfers held without a recorded reason
+		xfer = writeTransfer(t, "creator", repo)
+		if err := repo.UpdateTransferStatus(xfer.TransferID, client.REVIEWABLE); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.reviewTransfer(xfer.TransferID, "reviewer", client.CANCELED); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check(t, setupSQLiteDB(t))
+	check(t, setupMySQLeDB(t))
+}
+
+func TestTransfers__checkTransferReview(t *testing.T) {
+	xfer := &client.Transfer{Amount: "USD 12.44", Status: client.PENDING}
+
+	checker := &fundflow.MockFundsChecker{Status: client.REVIEWABLE}
+	rules := &review.MockChecker{Reasons: []string{"new destination"}}
+	reasons, err := checkTransfer("userID", "tenantID", xfer, fundflow.Source{}, checker, nil, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xfer.Status != client.REVIEWABLE || len(reasons) != 2 {
+		t.Errorf("status=%q reasons=%v", xfer.Status, reasons)
+	}
+
+	// no rules matched
+	xfer.Status = client.PENDING
+	rules.Reasons = nil
+	if reasons, err := checkTransfer("userID", "tenantID", xfer, fundflow.Source{}, nil, nil, rules); len(reasons) != 0 || err != nil {
+		t.Errorf("reasons=%v error=%v", reasons, err)
+	}
+	if xfer.Status != client.PENDING {
+		t.Errorf("unexpected status: %q", xfer.Status)
+	}
+}
+
+func TestRouter__reviewUserTransfer(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	access := &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			{Kind: memberships.Tenant, ResourceID: "tenantID", UserID: "creator", Role: memberships.Owner},
+			{Kind: memberships.Tenant, ResourceID: "tenantID", UserID: "ops", Role: memberships.Operator},
+			{Kind: memberships.Tenant, ResourceID: "tenantID", UserID: "viewer", Role: memberships.Viewer},
+		},
+	}
+	strategy := &fundflow.MockStrategy{Files: []*ach.File{ach.NewFile()}}
+	pub := &pipeline.MockPublisher{}
+
+	// reviewers are checked against the Tenant the Transfer was created in rather than the
+	// creator's other Tenants
+	r := mux.NewRouter()
+	tenantRepo := &tenants.MockRepository{
+		UserTenants: map[string]string{"creator": "otherTenantID"},
+	}
+	fundsChecker := &fundflow.MockFundsChecker{}
+	NewRouter(log.NewNopLogger(), repo, tenantRepo, access, mockCustomersClient(), mockDecryptor, strategy, fundsChecker, nil, nil, nil, config.Limits{}, pub).RegisterRoutes(r)
+	c := testclient.New(t, r)
+
+	xfer := writeReviewableTransfer(t, "creator", repo)
+
+	// creators can't approve their own Transfers
+	_, resp, _ := c.TransfersApi.ApproveTransfer(context.TODO(), xfer.TransferID, "creator", nil)
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+
+	// viewers can't approve Transfers
+	_, resp, _ = c.TransfersApi.ApproveTransfer(context.TODO(), xfer.TransferID, "viewer", nil)
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+
+	// non-members don't find the Transfer
+	_, resp, _ = c.TransfersApi.ApproveTransfer(context.TODO(), xfer.TransferID, base.ID(), nil)
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+
+	approved, resp, err := c.TransfersApi.ApproveTransfer(context.TODO(), xfer.TransferID, "ops", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if approved.Status != client.PENDING {
+		t.Errorf("unexpected status: %q", approved.Status)
+	}
+	if len(pub.Xfers) != 1 {
+		t.Errorf("unexpected Xfers: %#v", pub.Xfers)
+	}
+
+	// approved Transfers can't be rejected
+	_, resp, _ = c.TransfersApi.RejectTransfer(context.TODO(), xfer.TransferID, "ops", nil)
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unexpected response: %#v", resp)
+	}
+
+	xfer = writeReviewableTransfer(t, "creator", repo)
+	rejected, resp, err := c.TransfersApi.RejectTransfer(context.TODO(), xfer.TransferID, "ops", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if rejected.Status != client.CANCELED {
+		t.Errorf("unexpected status: %q", rejected.Status)
+	}
+	if len(pub.Xfers) != 1 {
+		t.Errorf("unexpected Xfers: %#v", pub.Xfers)
+	}
+	if len(fundsChecker.Released) != 1 || fundsChecker.Released[0] != xfer.TransferID {
+		t.Errorf("unexpected released funds: %v", fundsChecker.Released)
+	}
+}
+
+type pausedOriginators struct {
+	err error
+}
+
+func (c *pausedOriginators) Check(tenantID string, organizationID string) (client.TransferStatus, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	return client.REVIEWABLE, nil
+}
+
+func TestReviews__Approve(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	// Transfers are approved under the Tenant they were created in rather than the creator's other Tenants
+	tenantRepo := &tenants.MockRepository{
+		Tenants:     []client.Tenant{{TenantID: "tenantID", Status: string(tenants.Active)}},
+		UserTenants: map[string]string{"creator": "otherTenantID"},
+	}
+	strategy := &fundflow.MockStrategy{Files: []*ach.File{ach.NewFile()}}
+	fundsChecker := &fundflow.MockFundsChecker{}
+	returnRates := &pausedOriginators{}
+	pub := &pipeline.MockPublisher{}
+	reviews := NewReviews(log.NewNopLogger(), repo, tenantRepo, mockCustomersClient(), mockDecryptor, strategy, fundsChecker, returnRates, pub)
+
+	xfer := writeReviewableTransfer(t, "creator", repo)
+	status := func() client.TransferStatus {
+		found, err := repo.GetTransfer(xfer.TransferID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return found.Status
+	}
+
+	// failing to originate leaves the Transfer for review and releases its funds
+	strategy.Err = errors.New("bad error")
+	if _, err := reviews.Approve(xfer.TransferID, "ops"); err == nil {
+		t.Error("expected error")
+	}
+	if s := status(); s != client.REVIEWABLE {
+		t.Errorf("unexpected status: %q", s)
+	}
+	if len(fundsChecker.Released) != 1 || len(pub.Xfers) != 0 {
+		t.Errorf("released=%v xfers=%#v", fundsChecker.Released, pub.Xfers)
+	}
+	strategy.Err = nil
+
+	// the source account is still short
+	fundsChecker.Status = client.REVIEWABLE
+	if _, err := reviews.Approve(xfer.TransferID, "ops"); err != fundflow.ErrInsufficientFunds {
+		t.Errorf("unexpected error: %v", err)
+	}
+	fundsChecker.Status = ""
+
+	// the creator was paused after the Transfer was held
+	returnRates.err = returnrates.ErrOriginatorPaused
+	if _, err := reviews.Approve(xfer.TransferID, "ops"); err != returnrates.ErrOriginatorPaused {
+		t.Errorf("unexpected error: %v", err)
+	}
+	returnRates.err = nil
+
+	// the Tenant was suspended
+	tenantRepo.Tenants[0].Status = string(tenants.Suspended)
+	if _, err := reviews.Approve(xfer.TransferID, "ops"); err != tenants.ErrSuspended {
+		t.Errorf("unexpected error: %v", err)
+	}
+	tenantRepo.Tenants[0].Status = string(tenants.Active)
+
+	if s := status(); s != client.REVIEWABLE {
+		t.Errorf("unexpected status: %q", s)
+	}
+
+	// originators held for their return rates are released by the review
+	approved, err := reviews.Approve(xfer.TransferID, "ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved.Status != client.PENDING || status() != client.PENDING {
+		t.Errorf("unexpected status: %q", approved.Status)
+	}
+	if len(pub.Xfers) != 1 {
+		t.Errorf("unexpected Xfers: %#v", pub.Xfers)
+	}
+}
+
+// racingRepository decides on each Transfer as another reviewer right before it's reviewed
+type racingRepository struct {
+	*sqlRepo
+	decision client.TransferStatus
+}
+
+func (r *racingRepository) reviewTransfer(transferID string, reviewerID string, status client.TransferStatus) error {
+	if err := r.sqlRepo.reviewTransfer(transferID, "other", r.decision); err != nil {
+		return err
+	}
+	return r.sqlRepo.reviewTransfer(transferID, reviewerID, status)
+}
+
+func TestReviews__ApproveRace(t *testing.T) {
+	repo := &racingRepository{sqlRepo: setupSQLiteDB(t), decision: client.CANCELED}
+	tenantRepo := &tenants.MockRepository{
+		Tenants: []client.Tenant{{TenantID: "tenantID", Status: string(tenants.Active)}},
+	}
+	strategy := &fundflow.MockStrategy{Files: []*ach.File{ach.NewFile()}}
+	fundsChecker := &fundflow.MockFundsChecker{}
+	reviews := NewReviews(log.NewNopLogger(), repo, tenantRepo, mockCustomersClient(), mockDecryptor, strategy, fundsChecker, nil, &pipeline.MockPublisher{})
+
+	// funds held while losing to a rejection are released
+	xfer := writeReviewableTransfer(t, "creator", repo)
+	if _, err := reviews.Approve(xfer.TransferID, "ops"); err != errNotReviewable {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(fundsChecker.Released) != 1 || fundsChecker.Released[0] != xfer.TransferID {
+		t.Errorf("unexpected released funds: %v", fundsChecker.Released)
+	}
+
+	// funds held while losing to another approval are kept for it
+	fundsChecker.Released = nil
+	repo.decision = client.PENDING
+	xfer = writeReviewableTransfer(t, "creator", repo)
+	if _, err := reviews.Approve(xfer.TransferID, "ops"); err != errNotReviewable {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(fundsChecker.Released) != 0 {
+		t.Errorf("unexpected released funds: %v", fundsChecker.Released)
+	}
+}
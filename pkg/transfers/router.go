@@ -5,6 +5,7 @@
 package transfers
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,14 +13,20 @@ import (
 	"strings"
 	"time"
 
+	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/achx"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/customers"
 	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/memberships"
 	"github.com/moov-io/paygate/pkg/model"
 	"github.com/moov-io/paygate/pkg/tenants"
 	"github.com/moov-io/paygate/pkg/transfers/fundflow"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline"
+	"github.com/moov-io/paygate/pkg/transfers/returnrates"
+	"github.com/moov-io/paygate/pkg/transfers/review"
 	"github.com/moov-io/paygate/pkg/util"
 	"github.com/moov-io/paygate/x/route"
 
@@ -32,38 +39,71 @@ type Router struct {
 	Repo   Repository
 
 	Publisher pipeline.XferPublisher
+	Reviews   *Reviews
+	Files     *FileProcessor
 
 	GetUserTransfers   http.HandlerFunc
 	CreateUserTransfer http.HandlerFunc
 	GetUserTransfer    http.HandlerFunc
 	DeleteUserTransfer http.HandlerFunc
+
+	ApproveUserTransfer http.HandlerFunc
+	RejectUserTransfer  http.HandlerFunc
+
+	CreateUserTransferBatch http.HandlerFunc
+	GetUserTransferBatch    http.HandlerFunc
+
+	CreateUserTransferFile http.HandlerFunc
 }
 
 func NewRouter(
 	logger log.Logger,
 	repo Repository,
 	tenantRepo tenants.Repository,
+	access memberships.Checker,
 	customersClient customers.Client,
 	accountDecryptor accounts.Decryptor,
 	fundStrategy fundflow.Strategy,
+	fundsChecker fundflow.FundsChecker,
+	returnRates returnrates.Checker,
+	reviewRules review.Checker,
+	odfis []config.ODFI,
+	limits config.Limits,
 	pub pipeline.XferPublisher,
 ) *Router {
+	reviews := NewReviews(logger, repo, tenantRepo, customersClient, accountDecryptor, fundStrategy, fundsChecker, returnRates, pub)
+	files := NewFileProcessor(logger, repo, tenantRepo, fundsChecker, returnRates, reviewRules, odfis, pub)
 	return &Router{
 		Logger:             logger,
 		Repo:               repo,
 		Publisher:          pub,
-		GetUserTransfers:   GetUserTransfers(logger, repo),
-		CreateUserTransfer: CreateUserTransfer(logger, repo, tenantRepo, customersClient, accountDecryptor, fundStrategy, pub),
-		GetUserTransfer:    GetUserTransfer(logger, repo),
-		DeleteUserTransfer: DeleteUserTransfer(logger, repo, pub),
+		Reviews:            reviews,
+		Files:              files,
+		GetUserTransfers:   GetUserTransfers(logger, repo, access),
+		CreateUserTransfer: CreateUserTransfer(logger, repo, tenantRepo, access, customersClient, accountDecryptor, fundStrategy, fundsChecker, returnRates, reviewRules, pub),
+		GetUserTransfer:    GetUserTransfer(logger, repo, access),
+		DeleteUserTransfer: DeleteUserTransfer(logger, repo, access, fundsChecker, pub),
+
+		ApproveUserTransfer: ApproveUserTransfer(logger, repo, access, reviews),
+		RejectUserTransfer:  RejectUserTransfer(logger, repo, access, reviews),
+
+		CreateUserTransferBatch: CreateUserTransferBatch(logger, repo, tenantRepo, access, customersClient, accountDecryptor, fundStrategy, fundsChecker, returnRates, reviewRules, pub),
+		GetUserTransferBatch:    GetUserTransferBatch(logger, repo, access),
+
+		CreateUserTransferFile: CreateUserTransferFile(logger, tenantRepo, access, limits, files),
 	}
 }
 
 func (c *Router) RegisterRoutes(r *mux.Router) {
 	r.Methods("GET").Path("/transfers").HandlerFunc(c.GetUserTransfers)
 	r.Methods("POST").Path("/transfers").HandlerFunc(c.CreateUserTransfer)
+	r.Methods("POST").Path("/transfers/batch").HandlerFunc(c.CreateUserTransferBatch)
+	r.Methods("GET").Path("/transfers/batch/{batchID}").HandlerFunc(c.GetUserTransferBatch)
+	r.Methods("POST").Path("/transfers/files").HandlerFunc(c.CreateUserTransferFile)
 	r.Methods("GET").Path("/transfers/{transferID}").HandlerFunc(c.GetUserTransfer)
 	r.Methods("DELETE").Path("/transfers/{transferID}").HandlerFunc(c.DeleteUserTransfer)
+	r.Methods("POST").Path("/transfers/{transferID}/approve").HandlerFunc(c.ApproveUserTransfer)
+	r.Methods("POST").Path("/transfers/{transferID}/reject").HandlerFunc(c.RejectUserTransfer)
 }
 
 func getTransferID(r *http.Request) string {
@@ -71,11 +111,13 @@ func getTransferID(r *http.Request) string {
 }
 
 type transferFilterParams struct {
-	Status    client.TransferStatus
-	StartDate time.Time
-	EndDate   time.Time
-	Limit     int64
-	Offset    int64
+	Status          client.TransferStatus
+	OrganizationIDs []string
+	TenantID        string
+	StartDate       time.Time
+	EndDate         time.Time
+	Limit           int64
+	Offset          int64
 }
 
 func readTransferFilterParams(r *http.Request) transferFilterParams {
@@ -100,6 +142,12 @@ func readTransferFilterParams(r *http.Request) transferFilterParams {
 		if s := strings.TrimSpace(q.Get("status")); s != "" {
 			params.Status = client.TransferStatus(s)
 		}
+		for _, id := range strings.Split(q.Get("organizationIDs"), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				params.OrganizationIDs = append(params.OrganizationIDs, id)
+			}
+		}
+		params.TenantID = strings.TrimSpace(q.Get("tenantID"))
 	}
 	if limit := route.ReadLimit(r); limit != 0 {
 		params.Limit = limit
@@ -110,12 +158,31 @@ func readTransferFilterParams(r *http.Request) transferFilterParams {
 	return params
 }
 
-func GetUserTransfers(logger log.Logger, repo Repository) http.HandlerFunc {
+func GetUserTransfers(logger log.Logger, repo Repository, access memberships.Checker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(logger, w, r)
 
 		params := readTransferFilterParams(r)
-		xfers, err := repo.getUserTransfers(responder.XUserID, params)
+
+		// Members who can read every Organization requested see Transfers created by anyone in them
+		userID := responder.XUserID
+		for i := range params.OrganizationIDs {
+			if err := memberships.Require(access, responder.XUserID, memberships.Organization, params.OrganizationIDs[i], memberships.Read); err != nil {
+				memberships.Problem(responder, w, r, err)
+				return
+			}
+			userID = ""
+		}
+		// Members who can read the Tenant see Transfers created by anyone in it
+		if params.TenantID != "" {
+			if err := memberships.Require(access, responder.XUserID, memberships.Tenant, params.TenantID, memberships.Read); err != nil {
+				memberships.Problem(responder, w, r, err)
+				return
+			}
+			userID = ""
+		}
+
+		xfers, err := repo.getUserTransfers(userID, params)
 		if err != nil {
 			responder.Problem(err)
 			return
@@ -132,9 +199,13 @@ func CreateUserTransfer(
 	logger log.Logger,
 	repo Repository,
 	tenantRepo tenants.Repository,
+	access memberships.Checker,
 	customersClient customers.Client,
 	accountDecryptor accounts.Decryptor,
 	fundStrategy fundflow.Strategy,
+	fundsChecker fundflow.FundsChecker,
+	returnRates returnrates.Checker,
+	reviewRules review.Checker,
 	pub pipeline.XferPublisher,
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -150,60 +221,62 @@ func CreateUserTransfer(
 			return
 		}
 
-		transfer := &client.Transfer{
-			TransferID:  base.ID(),
-			Amount:      req.Amount,
-			Source:      req.Source,
-			Destination: req.Destination,
-			Description: req.Description,
-			Status:      client.PENDING,
-			SameDay:     req.SameDay,
-			Created:     time.Now(),
-		}
-
-		// TODO(adam): validate both Customer and get get their Accounts
-		// get/decrypt destination account number
-		//
-		// TODO(adam): future: limits checks
+		transfer := newTransfer(req)
 
-		// Save our Transfer to the database
-		if err := repo.writeUserTransfers(responder.XUserID, transfer); err != nil {
+		tenantID, err := transferTenant(tenantRepo, responder.XUserID, transfer)
+		if err != nil {
+			responder.Problem(err)
+			return
+		}
+		if err := checkTenant(tenantRepo, tenantID, transfer); err != nil {
 			responder.Problem(err)
 			return
 		}
+		if err := checkAccess(access, responder.XUserID, tenantID, transfer); err != nil {
+			memberships.Problem(responder, w, r, err)
+			return
+		}
 
-		// According to our strategy create (originate) ACH files to be published somewhere
+		var source fundflow.Source
+		var destination fundflow.Destination
 		if fundStrategy != nil {
-			companyID, err := tenantRepo.GetCompanyIdentification("tenantID") // TODO(adam): need to get from auth
-			if err != nil {
-				responder.Problem(err)
-				return
-			}
-			source, err := getFundflowSource(customersClient, req.Source)
+			source, destination, err = lookupAccounts(customersClient, accountDecryptor, req.Source, req.Destination)
 			if err != nil {
-				fmt.Printf("error getting source: %v\n", err)
+				fmt.Printf("error getting accounts: %v\n", err)
 				responder.Problem(err)
 				return
 			}
-			destination, err := getFundflowDestination(customersClient, accountDecryptor, req.Destination)
-			if err != nil {
-				fmt.Printf("error getting destination: %v\n", err)
-				responder.Problem(err)
-				return
-			}
-			files, err := fundStrategy.Originate(companyID, transfer, source, destination)
-			if err != nil {
-				fmt.Printf("error originating ACH files: %v\n", err)
-				responder.Problem(err)
-				return
-			}
-			if err := pipeline.PublishFiles(pub, transfer, files); err != nil {
-				fmt.Printf("error publishing ACH files: %v\n", err)
+		}
+
+		reasons, err := checkTransfer(responder.XUserID, tenantID, transfer, source, fundsChecker, returnRates, reviewRules)
+		if err != nil {
+			releaseFunds(logger, fundsChecker, transfer)
+			responder.Problem(err)
+			return
+		}
+
+		// Save our Transfer to the database
+		if err := repo.writeUserTransfers(responder.XUserID, tenantID, transfer); err != nil {
+			releaseFunds(logger, fundsChecker, transfer)
+			responder.Problem(err)
+			return
+		}
+		if transfer.Status == client.REVIEWABLE {
+			if err := repo.saveReview(transfer.TransferID, reasons); err != nil {
+				releaseFunds(logger, fundsChecker, transfer)
 				responder.Problem(err)
 				return
 			}
 		}
 
+		// According to our strategy create (originate) ACH files to be published somewhere.
+		if err := originateTransfer(repo, tenantRepo, fundStrategy, pub, tenantID, transfer, source, destination); err != nil {
+			fmt.Printf("error originating transfer: %v\n", err)
+			releaseFunds(logger, fundsChecker, transfer)
+			responder.Problem(err)
+			return
+		}
+
 		responder.Respond(func(w http.ResponseWriter) {
 			w.WriteHeader(http.StatusOK)
 			json.NewEncoder(w).Encode(transfer)
@@ -211,6 +284,153 @@ func CreateUserTransfer(
 	}
 }
 
+func newTransfer(req client.CreateTransfer) *client.Transfer {
+	return &client.Transfer{
+		TransferID:  base.ID(),
+		Amount:      req.Amount,
+		Source:      req.Source,
+		Destination: req.Destination,
+		Description: req.Description,
+		Status:      client.PENDING,
+		SameDay:     req.SameDay,
+		Created:     time.Now(),
+
+		OrganizationID: req.OrganizationID,
+	}
+}
+
+// lookupAccounts reads the Customer and Account of each side of a Transfer and decrypts their account numbers.
+func lookupAccounts(customersClient customers.Client, accountDecryptor accounts.Decryptor, src client.Source, dst client.Destination) (fundflow.Source, fundflow.Destination, error) {
+	source, err := fundflow.GetSource(customersClient, accountDecryptor, src)
+	if err != nil {
+		return source, fundflow.Destination{}, fmt.Errorf("source: %v", err)
+	}
+	destination, err := fundflow.GetDestination(customersClient, accountDecryptor, dst)
+	if err != nil {
+		return source, destination, fmt.Errorf("destination: %v", err)
+	}
+	return source, destination, nil
+}
+
+// checkTransfer updates the Transfer's status from our originator, funds and review checks and
+// returns why the Transfer is held for review. An error is returned if the Transfer should be rejected.
+func checkTransfer(userID string, tenantID string, transfer *client.Transfer, source fundflow.Source, fundsChecker fundflow.FundsChecker, returnRates returnrates.Checker, reviewRules review.Checker) ([]string, error) {
+	var reasons []string
+
+	// Originators over their return rate thresholds are paused
+	if returnRates != nil {
+		status, err := returnRates.Check(tenantID, transfer.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		if status == client.REVIEWABLE {
+			reasons = append(reasons, "originator paused for return rates")
+		}
+		transfer.Status = status
+	}
+
+	// Verify the source account can cover this Transfer
+	if fundsChecker != nil && transfer.Status == client.PENDING {
+		status, err := fundsChecker.Check(transfer, source)
+		if err != nil {
+			return nil, err
+		}
+		if status == client.REVIEWABLE {
+			reasons = append(reasons, "insufficient funds")
+		}
+		transfer.Status = status
+	}
+
+	// Hold Transfers matching any of our review rules for approval
+	if reviewRules != nil {
+		matched, err := reviewRules.Check(userID, transfer)
+		if err != nil {
+			return nil, err
+		}
+		if len(matched) > 0 {
+			reasons = append(reasons, matched...)
+			transfer.Status = client.REVIEWABLE
+		}
+	}
+	return reasons, nil
+}
+
+// releaseFunds frees the funds held for a Transfer which won't be originated. Errors are only
+// logged as the caller is already handling why the Transfer stopped.
+func releaseFunds(logger log.Logger, fundsChecker fundflow.FundsChecker, transfer *client.Transfer) {
+	if fundsChecker == nil || transfer == nil {
+		return
+	}
+	if err := fundsChecker.Release(transfer); err != nil {
+		logger.Log("transfers", fmt.Sprintf("ERROR releasing funds for transferID=%s: %v", transfer.TransferID, err))
+	}
+}
+
+// originateTransfer creates ACH files according to our strategy and publishes them to be uploaded
+// with the Tenant's CompanyIdentification, ODFI and settings. Files our strategy holds are saved
+// for a HoldReleaser to publish later.
+// Transfers held for review are originated once they're approved.
+func originateTransfer(repo Repository, tenantRepo tenants.Repository, fundStrategy fundflow.Strategy, pub pipeline.XferPublisher, tenantID string, transfer *client.Transfer, source fundflow.Source, destination fundflow.Destination) error {
+	if fundStrategy == nil || transfer.Status != client.PENDING {
+		return nil
+	}
+	companyID, err := tenantRepo.GetCompanyIdentification(tenantID)
+	if err != nil {
+		return err
+	}
+	odfi, err := tenantRepo.GetODFI(tenantID)
+	if err != nil {
+		return err
+	}
+	strategy, err := fundflow.ForODFI(fundStrategy, odfi)
+	if err != nil {
+		return err
+	}
+	files, err := strategy.Originate(companyID, transfer, source, destination)
+	if err != nil {
+		return fmt.Errorf("originating ACH files: %v", err)
+	}
+	settings, err := tenantRepo.GetSettings(tenantID)
+	if err != nil {
+		return err
+	}
+	if err := applyTenantSettings(settings, files); err != nil {
+		return fmt.Errorf("applying tenant settings: %v", err)
+	}
+	if holder, ok := strategy.(fundflow.Holder); ok {
+		held, releaseAt, err := holder.Hold(companyID, transfer, source, destination)
+		if err != nil {
+			return fmt.Errorf("holding ACH file: %v", err)
+		}
+		if err := applyTenantSettings(settings, []*ach.File{held}); err != nil {
+			return fmt.Errorf("applying tenant settings: %v", err)
+		}
+		if err := repo.holdFile(transfer.TransferID, odfi, held, releaseAt); err != nil {
+			return fmt.Errorf("saving held ACH file: %v", err)
+		}
+	}
+	if err := publishFiles(repo, pub, odfi, transfer, files); err != nil {
+		if err := repo.cancelHold(transfer.TransferID); err != nil {
+			return fmt.Errorf("canceling held ACH file: %v", err)
+		}
+		return fmt.Errorf("publishing ACH files: %v", err)
+	}
+	return nil
+}
+
+// publishFiles records the trace number of each entry in a Transfer's ACH files, so the Transfer
+// can be found from them, and publishes the files to be uploaded.
+func publishFiles(repo Repository, pub pipeline.XferPublisher, odfi string, transfer *client.Transfer, files []*ach.File) error {
+	var entries []*ach.EntryDetail
+	for i := range files {
+		entries = append(entries, achx.Entries(files[i])...)
+	}
+	if err := repo.saveTraceNumbers(transfer.TransferID, entries); err != nil {
+		return fmt.Errorf("saving trace numbers: %v", err)
+	}
+	return pipeline.PublishFiles(pub, odfi, transfer, files)
+}
+
 func validateTransferRequest(req client.CreateTransfer) error {
 	if req.Source.CustomerID == "" || req.Source.AccountID == "" {
 		return errors.New("incomplete source")
@@ -239,65 +459,13 @@ func validateAmount(amount string) error {
 	return nil
 }
 
-func getFundflowSource(client customers.Client, src client.Source) (fundflow.Source, error) {
-	var source fundflow.Source
-
-	// Set source Customer
-	cust, err := client.Lookup(src.CustomerID, "requestID", "userID")
-	if err != nil {
-		return source, err
-	}
-	if cust == nil {
-		return source, fmt.Errorf("customerID=%s is not found", src.CustomerID)
-	}
-	source.Customer = *cust
-
-	// Get customer Account
-	if acct, err := client.FindAccount(src.CustomerID, src.AccountID); acct == nil || err != nil {
-		return source, fmt.Errorf("accountID=%s not found for customerID=%s error=%v", src.AccountID, src.CustomerID, err)
-	} else {
-		source.Account = *acct
-	}
-
-	return source, nil
-}
-
-func getFundflowDestination(client customers.Client, accountDecryptor accounts.Decryptor, dst client.Destination) (fundflow.Destination, error) {
-	var destination fundflow.Destination
-
-	// Set destination Customer
-	cust, err := client.Lookup(dst.CustomerID, "requestID", "userID")
-	if err != nil {
-		return destination, err
-	}
-	if cust == nil {
-		return destination, fmt.Errorf("customerID=%s is not found", dst.CustomerID)
-	}
-	destination.Customer = *cust
-
-	// Get customer Account
-	if acct, err := client.FindAccount(dst.CustomerID, dst.AccountID); acct == nil || err != nil {
-		return destination, fmt.Errorf("accountID=%s not found for customerID=%s error=%v", dst.AccountID, dst.CustomerID, err)
-	} else {
-		destination.Account = *acct
-	}
-
-	if num, err := accountDecryptor.AccountNumber(dst.CustomerID, dst.AccountID); num == "" || err != nil {
-		return destination, fmt.Errorf("unable to decrypt accountID=%s for customerID=%s error=%v", dst.AccountID, dst.CustomerID, err)
-	} else {
-		destination.AccountNumber = num
-	}
-
-	return destination, nil
-}
-
-func GetUserTransfer(logger log.Logger, repo Repository) http.HandlerFunc {
+func GetUserTransfer(logger log.Logger, repo Repository, access memberships.Checker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(logger, w, r)
 
-		xfer, err := repo.GetTransfer(getTransferID(r))
+		xfer, err := readableTransfer(repo, access, responder.XUserID, getTransferID(r))
 		if err != nil {
-			responder.Problem(err)
+			memberships.Problem(responder, w, r, err)
 			return
 		}
 
@@ -308,15 +476,32 @@ func GetUserTransfer(logger log.Logger, repo Repository) http.HandlerFunc {
 	}
 }
 
-func DeleteUserTransfer(logger log.Logger, repo Repository, pub pipeline.XferPublisher) http.HandlerFunc {
+func DeleteUserTransfer(logger log.Logger, repo Repository, access memberships.Checker, fundsChecker fundflow.FundsChecker, pub pipeline.XferPublisher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		responder := route.NewResponder(logger, w, r)
 
+		// Members need to still be able to create Transfers for the Organization to delete theirs
 		transferID := getTransferID(r)
+		xfer, err := repo.getUserTransfer(transferID, responder.XUserID)
+		if err != nil && err != sql.ErrNoRows {
+			responder.Problem(err)
+			return
+		}
+		if xfer != nil && xfer.OrganizationID != "" {
+			err := memberships.Require(access, responder.XUserID, memberships.Organization, xfer.OrganizationID, memberships.CreateTransfers)
+			if err != nil {
+				memberships.Problem(responder, w, r, err)
+				return
+			}
+		}
+
 		if err := repo.deleteUserTransfer(responder.XUserID, transferID); err != nil {
 			responder.Problem(err)
 			return
 		}
+		if xfer != nil {
+			releaseFunds(logger, fundsChecker, xfer)
+		}
 
 		if pub != nil {
 			msg := pipeline.CanceledTransfer{
@@ -6,23 +6,28 @@ package transfers
 
 import (
 	"context"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"testing"
 	"time"
 
+	"github.com/moov-io/ach"
 	"github.com/moov-io/base"
 	moovcustomers "github.com/moov-io/customers/client"
 	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
 	"github.com/moov-io/paygate/pkg/customers"
 	"github.com/moov-io/paygate/pkg/customers/accounts"
+	"github.com/moov-io/paygate/pkg/memberships"
 	"github.com/moov-io/paygate/pkg/tenants"
 	"github.com/moov-io/paygate/pkg/testclient"
 	"github.com/moov-io/paygate/pkg/transfers/fundflow"
 	"github.com/moov-io/paygate/pkg/transfers/pipeline"
 	"github.com/moov-io/paygate/pkg/util"
 
+	"github.com/antihax/optional"
 	"github.com/go-kit/kit/log"
 	"github.com/gorilla/mux"
 )
@@ -48,12 +53,22 @@ var (
 		},
 	}
 
-	tenantRepo = &tenants.MockRepository{}
+	tenantRepo = &tenants.MockRepository{
+		UserTenants: map[string]string{"userID": "tenantID"},
+	}
+
+	access = &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			{Kind: memberships.Tenant, ResourceID: "tenantID", UserID: "userID", Role: memberships.Owner},
+		},
+	}
 
 	fakePublisher = &pipeline.MockPublisher{}
 
 	mockStrategy = &fundflow.MockStrategy{}
 
+	mockFundsChecker = &fundflow.MockFundsChecker{}
+
 	mockDecryptor = &accounts.MockDecryptor{Number: "12345"}
 )
 
@@ -76,7 +91,7 @@ func mockCustomersClient() *customers.MockClient {
 }
 
 func TestTransfers__readTransferFilterParams(t *testing.T) {
-	u, _ := url.Parse("http://localhost:8082/transfers?startDate=2020-04-06&limit=10&status=failed")
+	u, _ := url.Parse("http://localhost:8082/transfers?startDate=2020-04-06&limit=10&status=failed&organizationIDs=org1,%20org2")
 	req := &http.Request{URL: u}
 	params := readTransferFilterParams(req)
 
@@ -89,6 +104,9 @@ func TestTransfers__readTransferFilterParams(t *testing.T) {
 	if params.Status != client.FAILED {
 		t.Errorf("expected status: %q", params.Status)
 	}
+	if len(params.OrganizationIDs) != 2 || params.OrganizationIDs[1] != "org2" {
+		t.Errorf("unexpected organizationIDs: %v", params.OrganizationIDs)
+	}
 	if params.Limit != 10 {
 		t.Errorf("unexpected limit: %d", params.Limit)
 	}
@@ -101,7 +119,7 @@ func TestRouter__getUserTransfers(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, access, customersClient, mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -115,13 +133,39 @@ func TestRouter__getUserTransfers(t *testing.T) {
 	if n := len(xfers); n != 1 {
 		t.Errorf("got %d transfers: %#v", n, xfers)
 	}
+
+	// Organizations the user isn't a member of aren't found
+	_, resp, err = c.TransfersApi.GetTransfers(context.TODO(), "userID", &client.GetTransfersOpts{
+		OrganizationIDs: optional.NewString(base.ID()),
+	})
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404: %v", err)
+	}
+
+	// Tenant members can list the Tenant's Transfers
+	xfers, resp, err = c.TransfersApi.GetTransfers(context.TODO(), "userID", &client.GetTransfersOpts{
+		TenantID: optional.NewString("tenantID"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if n := len(xfers); n != 1 {
+		t.Errorf("got %d transfers: %#v", n, xfers)
+	}
+	_, resp, err = c.TransfersApi.GetTransfers(context.TODO(), "userID", &client.GetTransfersOpts{
+		TenantID: optional.NewString(base.ID()),
+	})
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404: %v", err)
+	}
 }
 
 func TestRouter__createUserTransfer(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, access, customersClient, mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -151,11 +195,210 @@ func TestRouter__createUserTransfer(t *testing.T) {
 	}
 }
 
+func TestRouter__createUserTransferInsufficientFunds(t *testing.T) {
+	customersClient := mockCustomersClient()
+
+	opts := client.CreateTransfer{
+		Amount: "USD 12.44",
+		Source: client.Source{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Destination: client.Destination{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Description: "test transfer",
+	}
+
+	// rejected
+	checker := &fundflow.MockFundsChecker{Err: fundflow.ErrInsufficientFunds}
+	r := mux.NewRouter()
+	NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, access, customersClient, mockDecryptor, mockStrategy, checker, nil, nil, nil, config.Limits{}, fakePublisher).RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "userID", opts, nil)
+	if err == nil {
+		t.Error("expected error")
+	}
+	resp.Body.Close()
+
+	// held for review
+	checker = &fundflow.MockFundsChecker{Status: client.REVIEWABLE}
+	r = mux.NewRouter()
+	NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, access, customersClient, mockDecryptor, mockStrategy, checker, nil, nil, nil, config.Limits{}, fakePublisher).RegisterRoutes(r)
+
+	c = testclient.New(t, r)
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "userID", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if xfer.Status != client.REVIEWABLE {
+		t.Errorf("unexpected status: %q", xfer.Status)
+	}
+}
+
+func TestRouter__createUserTransferReleasesFunds(t *testing.T) {
+	checker := &fundflow.MockFundsChecker{}
+	strategy := &fundflow.MockStrategy{Err: errors.New("bad error")}
+
+	r := mux.NewRouter()
+	NewRouter(log.NewNopLogger(), &MockRepository{}, tenantRepo, access, mockCustomersClient(), mockDecryptor, strategy, checker, nil, nil, nil, config.Limits{}, fakePublisher).RegisterRoutes(r)
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: "USD 12.44",
+		Source: client.Source{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Destination: client.Destination{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Description: "test transfer",
+	}
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "userID", opts, nil)
+	if err == nil {
+		t.Error("expected error")
+	}
+	resp.Body.Close()
+
+	// funds held for a Transfer which wasn't originated are released
+	if len(checker.Released) != 1 {
+		t.Errorf("unexpected released funds: %v", checker.Released)
+	}
+}
+
+func TestRouter__createUserTransferSuspendedTenant(t *testing.T) {
+	repo := setupSQLiteDB(t)
+	tenantRepo := tenants.NewRepo(repo.db)
+	access := memberships.NewRepo(repo.db)
+
+	userID := base.ID()
+	tenant := client.Tenant{
+		TenantID:        base.ID(),
+		Name:            "My Company",
+		PrimaryCustomer: base.ID(),
+		ODFI:            "bank-a",
+	}
+	if err := tenantRepo.Create(userID, "companyID", tenant); err != nil {
+		t.Fatal(err)
+	}
+
+	r := mux.NewRouter()
+	NewRouter(log.NewNopLogger(), repo, tenantRepo, access, mockCustomersClient(), mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher).RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: "USD 12.44",
+		Source: client.Source{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Destination: client.Destination{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Description: "test transfer",
+	}
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), userID, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if err := tenantRepo.UpdateStatus(tenant.TenantID, tenants.Suspended); err != nil {
+		t.Fatal(err)
+	}
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), userID, opts, nil)
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400: %v", err)
+	}
+	resp.Body.Close()
+
+	// the Tenant's entry limit applies once it's active again
+	if err := tenantRepo.UpdateStatus(tenant.TenantID, tenants.Active); err != nil {
+		t.Fatal(err)
+	}
+	err = tenantRepo.UpdateSettings(tenant.TenantID, tenants.Settings{
+		Limits: config.Limits{MaxEntryAmount: "USD 10.00"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), userID, opts, nil)
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestRouter__createUserTransferOrganization(t *testing.T) {
+	customersClient := mockCustomersClient()
+
+	orgID := base.ID()
+	access := &memberships.MockRepository{
+		Memberships: []memberships.Membership{
+			{Kind: memberships.Organization, ResourceID: orgID, UserID: "userID", Role: memberships.Admin},
+			{Kind: memberships.Organization, ResourceID: orgID, UserID: "ops", Role: memberships.Operator},
+		},
+	}
+	tenantRepo := &tenants.MockRepository{
+		OrganizationTenants: map[string]string{orgID: "tenantID"},
+	}
+	r := mux.NewRouter()
+	NewRouter(log.NewNopLogger(), &MockRepository{}, tenantRepo, access, customersClient, mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher).RegisterRoutes(r)
+
+	c := testclient.New(t, r)
+
+	opts := client.CreateTransfer{
+		Amount: "USD 12.44",
+		Source: client.Source{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Destination: client.Destination{
+			CustomerID: base.ID(),
+			AccountID:  base.ID(),
+		},
+		Description:    "test transfer",
+		OrganizationID: base.ID(),
+	}
+
+	// unknown Organization
+	_, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "userID", opts, nil)
+	if err == nil {
+		t.Error("expected error")
+	}
+	resp.Body.Close()
+
+	// Operators can't create Transfers
+	opts.OrganizationID = orgID
+	_, resp, err = c.TransfersApi.AddTransfer(context.TODO(), "ops", opts, nil)
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403: %v", err)
+	}
+
+	xfer, resp, err := c.TransfersApi.AddTransfer(context.TODO(), "userID", opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if xfer.OrganizationID != opts.OrganizationID {
+		t.Errorf("unexpected organizationID: %q", xfer.OrganizationID)
+	}
+}
+
 func TestRouter__createUserTransfersInvalidAmount(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, access, customersClient, mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -178,7 +421,7 @@ func TestRouter__MissingSource(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, access, customersClient, mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -204,7 +447,7 @@ func TestRouter__MissingDestination(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, access, customersClient, mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -240,12 +483,13 @@ func TestRouter__getUserTransfer(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, access, customersClient, mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
 
-	xfer, resp, err := c.TransfersApi.GetTransferByID(context.TODO(), "transferID", "userID", nil)
+	transferID := repoWithTransfer.Transfers[0].TransferID
+	xfer, resp, err := c.TransfersApi.GetTransferByID(context.TODO(), transferID, "userID", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -260,7 +504,7 @@ func TestRouter__deleteUserTransfer(t *testing.T) {
 	customersClient := mockCustomersClient()
 
 	r := mux.NewRouter()
-	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, customersClient, mockDecryptor, mockStrategy, fakePublisher)
+	router := NewRouter(log.NewNopLogger(), repoWithTransfer, tenantRepo, access, customersClient, mockDecryptor, mockStrategy, mockFundsChecker, nil, nil, nil, config.Limits{}, fakePublisher)
 	router.RegisterRoutes(r)
 
 	c := testclient.New(t, r)
@@ -271,3 +515,76 @@ func TestRouter__deleteUserTransfer(t *testing.T) {
 	}
 	resp.Body.Close()
 }
+
+func TestRouter__originateTransferODFI(t *testing.T) {
+	strategy := fundflow.Routed{
+		"":       &fundflow.MockStrategy{Err: errors.New("wrong ODFI")},
+		"bank-a": &fundflow.MockStrategy{Err: errors.New("wrong ODFI")},
+		"bank-b": &fundflow.MockStrategy{Files: []*ach.File{ach.NewFile()}},
+	}
+	repo := &tenants.MockRepository{ODFI: "bank-b"}
+	pub := &pipeline.MockPublisher{}
+
+	xfer := &client.Transfer{TransferID: base.ID(), Status: client.PENDING}
+	if err := originateTransfer(&MockRepository{}, repo, strategy, pub, "tenantID", xfer, fundflow.Source{}, fundflow.Destination{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.Xfers) != 1 || pub.Xfers[0].ODFI != "bank-b" {
+		t.Errorf("unexpected Xfers: %#v", pub.Xfers)
+	}
+
+	// Tenant assigned to an unknown ODFI
+	repo.ODFI = "bank-c"
+	if err := originateTransfer(&MockRepository{}, repo, strategy, pub, "tenantID", xfer, fundflow.Source{}, fundflow.Destination{}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestRouter__originateTransferHold(t *testing.T) {
+	odfi := config.ODFI{
+		RoutingNumber: "987654320",
+		Gateway: config.Gateway{
+			Origin:      "987654320",
+			Destination: "987654320",
+		},
+	}
+	strategy := fundflow.NewThirdParty(log.NewNopLogger(), odfi, config.ThirdPartyFundflow{
+		Settlement: config.SettlementAccount{
+			Name:          "Settlement",
+			RoutingNumber: "987654320",
+			AccountNumber: "55555",
+			AccountType:   "checking",
+		},
+		HoldDays: 2,
+	}, nil, nil)
+
+	source := fundflow.Source{
+		Customer:      moovcustomers.Customer{CustomerID: base.ID(), FirstName: "Jane", LastName: "Doe"},
+		Account:       moovcustomers.Account{AccountID: base.ID(), RoutingNumber: "231380104", Type: moovcustomers.CHECKING},
+		AccountNumber: "12345",
+	}
+	destination := fundflow.Destination{
+		Customer:      moovcustomers.Customer{CustomerID: base.ID(), FirstName: "John", LastName: "Doe"},
+		Account:       moovcustomers.Account{AccountID: base.ID(), RoutingNumber: "273976369", Type: moovcustomers.CHECKING},
+		AccountNumber: "67890",
+	}
+
+	repo := &MockRepository{}
+	pub := &pipeline.MockPublisher{}
+	xfer := &client.Transfer{TransferID: base.ID(), Amount: "USD 12.44", Description: "marketplace", Status: client.PENDING}
+	if err := originateTransfer(repo, &tenants.MockRepository{CompanyIdentification: "MOOV123456"}, strategy, pub, "tenantID", xfer, source, destination); err != nil {
+		t.Fatal(err)
+	}
+
+	// only the debit is published, the credit is held
+	if len(pub.Xfers) != 1 {
+		t.Fatalf("unexpected Xfers: %#v", pub.Xfers)
+	}
+	if len(repo.Held) != 1 || repo.Held[0].TransferID != xfer.TransferID {
+		t.Fatalf("unexpected held files: %#v", repo.Held)
+	}
+	entries := repo.Held[0].File.Batches[0].GetEntries()
+	if entries[0].DFIAccountNumber != "67890" || entries[0].CreditOrDebit() != "C" {
+		t.Errorf("unexpected held entry: %#v", entries[0])
+	}
+}
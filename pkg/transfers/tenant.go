@@ -0,0 +1,127 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/model"
+	"github.com/moov-io/paygate/pkg/tenants"
+)
+
+var (
+	errNoTenant = errors.New("no tenant found to create transfers for")
+)
+
+// transferTenant returns the Tenant a Transfer is created for. Transfers for an Organization belong to
+// the Organization's Tenant and all others to the Tenant of the user creating them.
+func transferTenant(tenantRepo tenants.Repository, userID string, transfer *client.Transfer) (string, error) {
+	if tenantRepo == nil {
+		return "", nil
+	}
+	if transfer.OrganizationID != "" {
+		tenantID, err := tenantRepo.GetOrganizationTenant(transfer.OrganizationID)
+		if err != nil {
+			return "", fmt.Errorf("tenant: %v", err)
+		}
+		if tenantID == "" {
+			return "", fmt.Errorf("organization %s not found", transfer.OrganizationID)
+		}
+		return tenantID, nil
+	}
+	tenantID, err := tenantRepo.GetUserTenant(userID)
+	if err != nil {
+		return "", fmt.Errorf("tenant: %v", err)
+	}
+	if tenantID == "" {
+		return "", errNoTenant
+	}
+	return tenantID, nil
+}
+
+// savedTransferTenant returns the Tenant a Transfer was created in. Transfers saved before their
+// Tenant was recorded fall back to the Tenant of their Organization or creator.
+func savedTransferTenant(repo Repository, tenantRepo tenants.Repository, creator string, transfer *client.Transfer) (string, error) {
+	tenantID, err := repo.getTransferTenant(transfer.TransferID)
+	if err != nil {
+		return "", fmt.Errorf("tenant: %v", err)
+	}
+	if tenantID != "" {
+		return tenantID, nil
+	}
+	return transferTenant(tenantRepo, creator, transfer)
+}
+
+// checkTenant rejects Transfers from suspended Tenants and those over the Tenant's entry limit.
+// Tenants which aren't found are allowed to keep existing behavior.
+func checkTenant(tenantRepo tenants.Repository, tenantID string, transfer *client.Transfer) error {
+	if tenantRepo == nil {
+		return nil
+	}
+	tenant, err := tenantRepo.GetTenant(tenantID)
+	if err != nil {
+		return fmt.Errorf("tenant: %v", err)
+	}
+	if tenant == nil {
+		return nil
+	}
+	if tenant.Status == string(tenants.Suspended) {
+		return tenants.ErrSuspended
+	}
+
+	settings, err := tenantRepo.GetSettings(tenantID)
+	if err != nil || settings == nil {
+		return err
+	}
+	maxEntry, err := settings.Limits.EntryAmount()
+	if err != nil || maxEntry == nil {
+		return err
+	}
+	var amt model.Amount
+	if err := amt.FromString(transfer.Amount); err != nil {
+		return err
+	}
+	if amt.Int() > maxEntry.Int() {
+		return fmt.Errorf("amount exceeds the limit of %s", maxEntry)
+	}
+	return nil
+}
+
+// applyTenantSettings overrides the CompanyName and Standard Entry Class code of each batch
+// with the Tenant's settings.
+func applyTenantSettings(settings *tenants.Settings, files []*ach.File) error {
+	if settings == nil {
+		return nil
+	}
+	for _, file := range files {
+		for i, batch := range file.Batches {
+			bh := batch.GetHeader()
+			if settings.CompanyName != "" {
+				bh.CompanyName = settings.CompanyName
+			}
+			if settings.DefaultSECCode == "" || bh.StandardEntryClassCode == settings.DefaultSECCode {
+				continue
+			}
+
+			// Each SEC code has its own Batcher, so copy the entries into a new batch
+			bh.StandardEntryClassCode = settings.DefaultSECCode
+			b, err := ach.NewBatch(bh)
+			if err != nil {
+				return fmt.Errorf("%s batch: %v", bh.StandardEntryClassCode, err)
+			}
+			for _, entry := range batch.GetEntries() {
+				b.AddEntry(entry)
+			}
+			if err := b.Create(); err != nil {
+				return fmt.Errorf("%s batch: %v", bh.StandardEntryClassCode, err)
+			}
+			file.Batches[i] = b
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package transfers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moov-io/ach"
+	"github.com/moov-io/paygate/pkg/client"
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/pkg/tenants"
+)
+
+func TestTenant__transferTenant(t *testing.T) {
+	repo := &tenants.MockRepository{
+		OrganizationTenants: map[string]string{"orgID": "orgTenant"},
+		UserTenants:         map[string]string{"userID": "userTenant"},
+	}
+
+	if tenantID, err := transferTenant(repo, "userID", &client.Transfer{}); err != nil || tenantID != "userTenant" {
+		t.Errorf("unexpected tenantID=%q error=%v", tenantID, err)
+	}
+	if tenantID, err := transferTenant(repo, "userID", &client.Transfer{OrganizationID: "orgID"}); err != nil || tenantID != "orgTenant" {
+		t.Errorf("unexpected tenantID=%q error=%v", tenantID, err)
+	}
+
+	if _, err := transferTenant(repo, "other", &client.Transfer{}); err != errNoTenant {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := transferTenant(repo, "userID", &client.Transfer{OrganizationID: "other"}); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestTenant__checkTenant(t *testing.T) {
+	transfer := &client.Transfer{Amount: "USD 12.50"}
+
+	// missing Tenants are allowed
+	repo := &tenants.MockRepository{}
+	if err := checkTenant(repo, "tenantID", transfer); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.Tenants = []client.Tenant{
+		{TenantID: "tenantID", Status: string(tenants.Active)},
+	}
+	if err := checkTenant(repo, "tenantID", transfer); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.Settings = &tenants.Settings{
+		Limits: config.Limits{MaxEntryAmount: "USD 10.00"},
+	}
+	if err := checkTenant(repo, "tenantID", transfer); err == nil {
+		t.Error("expected error")
+	}
+	repo.Settings = nil
+
+	repo.Tenants[0].Status = string(tenants.Suspended)
+	if err := checkTenant(repo, "tenantID", transfer); err != tenants.ErrSuspended {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTenant__applyTenantSettings(t *testing.T) {
+	fd, err := os.Open(filepath.Join("..", "..", "testdata", "ppd-debit.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	file, err := ReadFile(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := []*ach.File{file}
+
+	if err := applyTenantSettings(nil, files); err != nil {
+		t.Fatal(err)
+	}
+
+	settings := &tenants.Settings{
+		CompanyName:    "Acme Corp",
+		DefaultSECCode: ach.CCD,
+	}
+	if err := applyTenantSettings(settings, files); err != nil {
+		t.Fatal(err)
+	}
+
+	bh := file.Batches[0].GetHeader()
+	if bh.CompanyName != "Acme Corp" || bh.StandardEntryClassCode != ach.CCD {
+		t.Errorf("unexpected BatchHeader: %#v", bh)
+	}
+	if _, ok := file.Batches[0].(*ach.BatchCCD); !ok {
+		t.Errorf("unexpected batch: %T", file.Batches[0])
+	}
+	if n := len(file.Batches[0].GetEntries()); n != 1 {
+		t.Errorf("got %d entries", n)
+	}
+	if err := file.Validate(); err != nil {
+		t.Error(err)
+	}
+}
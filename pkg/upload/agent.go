@@ -14,8 +14,13 @@ import (
 
 // Agent represents an interface for uploading and retrieving ACH files from a remote service.
 type Agent interface {
-	GetInboundFiles() ([]File, error)
-	GetReturnFiles() ([]File, error)
+	ListInboundFiles() ([]FileInfo, error)
+	ListReturnFiles() ([]FileInfo, error)
+
+	// Open streams the contents of a remote file. Callers must close each File before calling
+	// other methods as some Agents hold their connection until then.
+	Open(path string) (File, error)
+
 	UploadFile(f File) error
 	Delete(path string) error
 
@@ -36,6 +41,12 @@ func New(logger log.Logger, cfg config.ODFI) (Agent, error) {
 	if cfg.SFTP != nil {
 		return newSFTPTransferAgent(logger, cfg)
 	}
+	if cfg.Local != nil {
+		return newLocalTransferAgent(logger, cfg)
+	}
+	if cfg.Bucket != nil {
+		return newBlobTransferAgent(logger, cfg)
+	}
 	return nil, errors.New("upload: unknown Agent type")
 }
 
@@ -46,5 +57,11 @@ func Type(cfg config.ODFI) string {
 	if cfg.SFTP != nil {
 		return "sftp"
 	}
+	if cfg.Local != nil {
+		return "local"
+	}
+	if cfg.Bucket != nil {
+		return "bucket"
+	}
 	return "unknown"
 }
@@ -0,0 +1,252 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"gocloud.dev/blob"
+	_ "gocloud.dev/blob/fileblob"
+	_ "gocloud.dev/blob/gcsblob"
+	_ "gocloud.dev/blob/memblob"
+	_ "gocloud.dev/blob/s3blob"
+	"gocloud.dev/gcerrors"
+)
+
+var (
+	blobAgentUp = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "blob_agent_up",
+		Help: "Status of object storage agent",
+	}, []string{"bucket"})
+)
+
+// BlobTransferAgent is an Agent which exchanges files through an object storage bucket
+// opened with gocloud.dev/blob. Inbound, outbound and return paths are key prefixes.
+type BlobTransferAgent struct {
+	bucket *blob.Bucket
+	cfg    config.ODFI
+	logger log.Logger
+}
+
+func newBlobTransferAgent(logger log.Logger, cfg config.ODFI) (*BlobTransferAgent, error) {
+	if cfg.Bucket == nil {
+		return nil, errors.New("nil Bucket config")
+	}
+	bucket, err := blob.OpenBucket(context.Background(), cfg.Bucket.URL)
+	if err != nil {
+		return nil, fmt.Errorf("bucket: %v", err)
+	}
+	agent := &BlobTransferAgent{
+		bucket: bucket,
+		cfg:    cfg,
+		logger: logger,
+	}
+	return agent, agent.Ping()
+}
+
+func (agent *BlobTransferAgent) Ping() error {
+	if agent == nil || agent.bucket == nil {
+		return errors.New("nil BlobTransferAgent")
+	}
+	_, err := agent.bucket.List(nil).Next(context.Background())
+	if err == io.EOF {
+		err = nil // empty bucket
+	}
+	agent.record(err)
+	return err
+}
+
+func (agent *BlobTransferAgent) record(err error) {
+	if agent == nil || agent.cfg.Bucket == nil {
+		return
+	}
+	if err != nil {
+		blobAgentUp.With("bucket", agent.bucketName()).Set(0)
+	} else {
+		blobAgentUp.With("bucket", agent.bucketName()).Set(1)
+	}
+}
+
+// bucketName returns the bucket URL without any query parameters which could hold credentials
+func (agent *BlobTransferAgent) bucketName() string {
+	if idx := strings.Index(agent.cfg.Bucket.URL, "?"); idx > 0 {
+		return agent.cfg.Bucket.URL[:idx]
+	}
+	return agent.cfg.Bucket.URL
+}
+
+func (agent *BlobTransferAgent) Close() error {
+	if agent == nil || agent.bucket == nil {
+		return nil
+	}
+	return agent.bucket.Close()
+}
+
+func (agent *BlobTransferAgent) InboundPath() string {
+	return agent.cfg.InboundPath
+}
+
+func (agent *BlobTransferAgent) OutboundPath() string {
+	return agent.cfg.OutboundPath
+}
+
+func (agent *BlobTransferAgent) ReturnPath() string {
+	return agent.cfg.ReturnPath
+}
+
+// key returns the object key for name under prefix and rejects keys which would escape
+// our paths (e.g. '../../etc/passwd').
+func key(prefix, name string) (string, error) {
+	k := path.Join(prefix, name)
+	if k == ".." || strings.HasPrefix(k, "../") || strings.HasPrefix(k, "/") {
+		return "", fmt.Errorf("bucket: invalid key %s", k)
+	}
+	return k, nil
+}
+
+// prefix returns the key prefix for listing objects within dir
+func prefix(dir string) string {
+	dir = strings.TrimPrefix(path.Clean("/"+dir), "/")
+	if dir == "" {
+		return ""
+	}
+	return dir + "/"
+}
+
+func (agent *BlobTransferAgent) Delete(path string) error {
+	if path == "" || strings.HasSuffix(path, "/") {
+		return fmt.Errorf("BlobTransferAgent: invalid path %v", path)
+	}
+	k, err := key("", path)
+	if err != nil {
+		return err
+	}
+	if agent.cfg.Bucket.DisableDeletes {
+		agent.logger.Log("bucket", fmt.Sprintf("skipping delete of %s", k))
+		return nil
+	}
+	if err := agent.bucket.Delete(context.Background(), k); err != nil && gcerrors.Code(err) != gcerrors.NotFound {
+		return fmt.Errorf("bucket: delete: %v", err)
+	}
+	return nil // deleted or not found
+}
+
+// UploadFile saves the content of File at the given filename in the OutboundPath prefix.
+// Objects are only visible once fully written.
+//
+// The File's contents will always be closed
+func (agent *BlobTransferAgent) UploadFile(f File) error {
+	defer f.Close()
+
+	// Take the base of f.Filename and our (out of band) OutboundPath to avoid accepting a write like '../../../../etc/passwd'.
+	k, err := key(prefix(agent.cfg.OutboundPath), path.Base(f.Filename))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Objects aren't visible until the writer is closed, so there's no need for a temporary key.
+	w, err := agent.bucket.NewWriter(ctx, k, nil)
+	if err != nil {
+		return fmt.Errorf("bucket: problem creating %s: %v", k, err)
+	}
+	digest := md5.New()
+	r := newVerifyingReader(io.TeeReader(f.Contents, digest))
+	n, err := io.Copy(w, r)
+	if n == 0 || err != nil {
+		cancel() // abort the write
+		w.Close()
+		return fmt.Errorf("bucket: problem copying (n=%d) %s: %v", n, k, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("bucket: problem closing %s: %v", k, err)
+	}
+	if err := agent.verify(k, r, digest.Sum(nil)); err != nil {
+		agent.bucket.Delete(context.Background(), k)
+		return fmt.Errorf("bucket: %v", err)
+	}
+	return nil
+}
+
+// verify compares the stored object's size, and MD5 when the provider reports one, against what we sent.
+func (agent *BlobTransferAgent) verify(k string, sent *verifyingReader, md5sum []byte) error {
+	attrs, err := agent.bucket.Attributes(context.Background(), k)
+	if err != nil {
+		return fmt.Errorf("problem verifying %s: %v", k, err)
+	}
+	if err := sent.verifySize(k, attrs.Size); err != nil {
+		return err
+	}
+	if len(attrs.MD5) > 0 && !bytes.Equal(attrs.MD5, md5sum) {
+		return fmt.Errorf("%s checksum mismatch", k)
+	}
+	return nil
+}
+
+func (agent *BlobTransferAgent) ListInboundFiles() ([]FileInfo, error) {
+	return agent.listFiles(agent.cfg.InboundPath)
+}
+
+func (agent *BlobTransferAgent) ListReturnFiles() ([]FileInfo, error) {
+	return agent.listFiles(agent.cfg.ReturnPath)
+}
+
+func (agent *BlobTransferAgent) listFiles(dir string) ([]FileInfo, error) {
+	ctx := context.Background()
+	iter := agent.bucket.List(&blob.ListOptions{
+		Prefix:    prefix(dir),
+		Delimiter: "/",
+	})
+
+	var files []FileInfo
+	for {
+		obj, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bucket: list %s: %v", dir, err)
+		}
+		if obj.IsDir {
+			continue
+		}
+		files = append(files, FileInfo{
+			Filename: path.Base(obj.Key),
+			Path:     obj.Key,
+			Size:     obj.Size,
+			ModTime:  obj.ModTime,
+		})
+	}
+	return files, nil
+}
+
+func (agent *BlobTransferAgent) Open(p string) (File, error) {
+	k, err := key("", p)
+	if err != nil {
+		return File{}, err
+	}
+	r, err := agent.bucket.NewReader(context.Background(), k, nil)
+	if err != nil {
+		return File{}, fmt.Errorf("bucket: open %s: %v", k, err)
+	}
+	return File{
+		Filename: path.Base(k),
+		Contents: r,
+	}, nil
+}
@@ -0,0 +1,200 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/log"
+)
+
+func createTestBlobAgent(t *testing.T, url string) *BlobTransferAgent {
+	t.Helper()
+
+	cfg := config.ODFI{
+		InboundPath:  "inbound/",
+		OutboundPath: "outbound/",
+		ReturnPath:   "returned/",
+		Bucket: &config.BucketAgent{
+			URL: url,
+		},
+	}
+	agent, err := New(log.NewNopLogger(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { agent.Close() })
+
+	if v := Type(cfg); v != "bucket" {
+		t.Errorf("unexpected type: %s", v)
+	}
+	return agent.(*BlobTransferAgent)
+}
+
+func TestBlobAgent(t *testing.T) {
+	agent := createTestBlobAgent(t, "mem://")
+
+	if err := agent.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if v := agent.InboundPath(); v != "inbound/" {
+		t.Errorf("got %s", v)
+	}
+	if v := agent.OutboundPath(); v != "outbound/" {
+		t.Errorf("got %s", v)
+	}
+	if v := agent.ReturnPath(); v != "returned/" {
+		t.Errorf("got %s", v)
+	}
+
+	cfg := config.ODFI{
+		Bucket: &config.BucketAgent{URL: "other://bucket"},
+	}
+	if _, err := newBlobTransferAgent(log.NewNopLogger(), cfg); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestBlobAgent__bucketName(t *testing.T) {
+	agent := &BlobTransferAgent{
+		cfg: config.ODFI{
+			Bucket: &config.BucketAgent{URL: "s3://my-bucket?region=us-east-1"},
+		},
+	}
+	if name := agent.bucketName(); name != "s3://my-bucket" {
+		t.Errorf("unexpected bucket name: %s", name)
+	}
+}
+
+func TestBlobAgent__uploadFile(t *testing.T) {
+	agent := createTestBlobAgent(t, "mem://")
+
+	content := base.ID()
+	f := File{
+		Filename: "../../" + base.ID(), // only the base is used
+		Contents: ioutil.NopCloser(strings.NewReader(content)),
+	}
+	if err := agent.UploadFile(f); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := agent.bucket.ReadAll(context.Background(), "outbound/"+filepath.Base(f.Filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != content {
+		t.Errorf("got %q", string(bs))
+	}
+
+	// empty files aren't written
+	f = File{
+		Filename: base.ID(),
+		Contents: ioutil.NopCloser(strings.NewReader("")),
+	}
+	if err := agent.UploadFile(f); err == nil {
+		t.Error("expected error")
+	}
+	if exists, _ := agent.bucket.Exists(context.Background(), "outbound/"+f.Filename); exists {
+		t.Error("partial file was written")
+	}
+}
+
+func TestBlobAgent__listFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blob-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// write files as our ODFI would
+	if err := os.MkdirAll(filepath.Join(dir, "returned", "archive"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "returned", "return-WEB.ach"), []byte("return"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "returned", "archive", "old.ach"), []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	agent := createTestBlobAgent(t, "file://"+dir)
+
+	files, err := agent.ListInboundFiles()
+	if err != nil || len(files) != 0 {
+		t.Fatalf("files=%#v error=%v", files, err)
+	}
+
+	files, err = agent.ListReturnFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Filename != "return-WEB.ach" {
+		t.Fatalf("unexpected files: %#v", files)
+	}
+	if files[0].Size != 6 || files[0].ModTime.IsZero() {
+		t.Errorf("unexpected metadata: %#v", files[0])
+	}
+
+	f, err := agent.Open(files[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bs, _ := ioutil.ReadAll(f.Contents); string(bs) != "return" {
+		t.Errorf("got %q", string(bs))
+	}
+	f.Close()
+
+	// delete the file
+	if err := agent.Delete(files[0].Path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "returned", "return-WEB.ach")); !os.IsNotExist(err) {
+		t.Errorf("expected file to be deleted: %v", err)
+	}
+}
+
+func TestBlobAgent__Delete(t *testing.T) {
+	agent := createTestBlobAgent(t, "mem://")
+	ctx := context.Background()
+
+	if err := agent.bucket.WriteAll(ctx, "inbound/ppd-debit.ach", []byte("ach"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// deletes are disabled
+	agent.cfg.Bucket.DisableDeletes = true
+	if err := agent.Delete("inbound/ppd-debit.ach"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := agent.bucket.Exists(ctx, "inbound/ppd-debit.ach"); !exists {
+		t.Error("expected file to be kept")
+	}
+
+	agent.cfg.Bucket.DisableDeletes = false
+	if err := agent.Delete("inbound/ppd-debit.ach"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := agent.bucket.Exists(ctx, "inbound/ppd-debit.ach"); exists {
+		t.Error("expected file to be deleted")
+	}
+	if err := agent.Delete("inbound/ppd-debit.ach"); err != nil {
+		t.Errorf("missing files aren't an error: %v", err)
+	}
+
+	// invalid paths
+	for _, p := range []string{"", "inbound/", "../../etc/passwd", "/etc/passwd"} {
+		if err := agent.Delete(p); err == nil {
+			t.Errorf("expected error for %q", p)
+		}
+	}
+}
@@ -5,7 +5,16 @@
 package upload
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
 	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moov-io/base"
 )
 
 type File struct {
@@ -19,3 +28,134 @@ func (f File) Close() error {
 	}
 	return nil
 }
+
+// FileInfo describes a remote file without reading its contents.
+type FileInfo struct {
+	Filename string
+
+	// Path is the file's location on the remote server as accepted by Agent.Open and Agent.Delete
+	Path string
+
+	Size    int64
+	ModTime time.Time
+}
+
+// WithEachFile opens each file and calls fn with its contents. Each file is closed after fn
+// returns and then deleted from the remote server unless keepRemoteFiles is set or fn returned
+// an error. Every file is attempted and any errors are returned together.
+//
+// Agents which can read several files at once have them opened concurrently, so fn must be
+// safe to call from multiple goroutines.
+func WithEachFile(agent Agent, files []FileInfo, keepRemoteFiles bool, fn func(File) error) error {
+	workers := 1
+	if a, ok := agent.(concurrentAgent); ok && a.Concurrency() > 1 {
+		workers = a.Concurrency()
+	}
+
+	var (
+		el   base.ErrorList
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		sema = make(chan struct{}, workers)
+	)
+	add := func(err error) {
+		mu.Lock()
+		el.Add(err)
+		mu.Unlock()
+	}
+	for i := range files {
+		sema <- struct{}{}
+		wg.Add(1)
+		go func(info FileInfo) {
+			defer func() {
+				<-sema
+				wg.Done()
+			}()
+
+			f, err := agent.Open(info.Path)
+			if err != nil {
+				add(fmt.Errorf("opening %s: %v", info.Path, err))
+				return
+			}
+			err = fn(f)
+			f.Close()
+			if err != nil {
+				add(fmt.Errorf("processing %s: %v", info.Path, err))
+				return
+			}
+			if !keepRemoteFiles {
+				if err := agent.Delete(info.Path); err != nil {
+					add(fmt.Errorf("deleting %s: %v", info.Path, err))
+				}
+			}
+		}(files[i])
+	}
+	wg.Wait()
+	return el.Err()
+}
+
+// concurrentAgent is implemented by Agents which can read several files at once.
+type concurrentAgent interface {
+	Concurrency() int
+}
+
+// lockedReader holds an Agent's lock (or pooled session) while a remote file is streamed,
+// which keeps us to the ODFI's connection limits. The lock is released on Close.
+type lockedReader struct {
+	io.ReadCloser
+
+	once   sync.Once
+	unlock func()
+}
+
+func (r *lockedReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.unlock)
+	return err
+}
+
+// tempFilename is where an upload is written before it's verified and renamed into place. The
+// leading dot keeps it from being picked up by ODFIs which only look for visible files.
+func tempFilename(filename string) string {
+	return "." + filename + ".part"
+}
+
+// verifyingReader counts and hashes the bytes read through it so an upload can be
+// compared against what the remote server stored.
+type verifyingReader struct {
+	r io.Reader
+	n int64
+	h hash.Hash
+}
+
+func newVerifyingReader(r io.Reader) *verifyingReader {
+	return &verifyingReader{r: r, h: sha256.New()}
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	r.h.Write(p[:n])
+	return n, err
+}
+
+// checksum returns the hex encoded SHA-256 of everything read.
+func (r *verifyingReader) checksum() string {
+	return hex.EncodeToString(r.h.Sum(nil))
+}
+
+// verifySize returns an error if the remote server stored a different number of bytes than we sent.
+func (r *verifyingReader) verifySize(filename string, remote int64) error {
+	if r.n != remote {
+		return fmt.Errorf("%s size mismatch: sent %d bytes but found %d", filename, r.n, remote)
+	}
+	return nil
+}
+
+// verifyChecksum returns an error if the SHA-256 of what the remote server stored doesn't match what we sent.
+func (r *verifyingReader) verifyChecksum(filename string, remote string) error {
+	if !strings.EqualFold(r.checksum(), remote) {
+		return fmt.Errorf("%s checksum mismatch: sent %s but found %s", filename, r.checksum(), remote)
+	}
+	return nil
+}
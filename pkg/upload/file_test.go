@@ -5,8 +5,12 @@
 package upload
 
 import (
+	"errors"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -21,3 +25,135 @@ func TestFile__close(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestWithEachFile(t *testing.T) {
+	agent := createTestLocalAgent(t)
+
+	dir := filepath.Join(agent.root, agent.InboundPath())
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.ach", "b.ach", "c.ach"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := agent.ListInboundFiles()
+	if err != nil || len(files) != 3 {
+		t.Fatalf("files=%#v error=%v", files, err)
+	}
+
+	var seen []string
+	err = WithEachFile(agent, files, false, func(f File) error {
+		bs, _ := ioutil.ReadAll(f.Contents)
+		seen = append(seen, string(bs))
+		if f.Filename == "b.ach" {
+			return errors.New("bad file")
+		}
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "bad file") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(seen) != 3 {
+		t.Errorf("read files: %v", seen)
+	}
+
+	// only the file which failed is left
+	files, _ = agent.ListInboundFiles()
+	if len(files) != 1 || files[0].Filename != "b.ach" {
+		t.Errorf("unexpected files: %#v", files)
+	}
+
+	// keep remote files
+	if err := WithEachFile(agent, files, true, func(f File) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ = agent.ListInboundFiles(); len(files) != 1 {
+		t.Errorf("unexpected files: %#v", files)
+	}
+}
+
+// concurrentLocalAgent reports it can read several files at once
+type concurrentLocalAgent struct {
+	*LocalTransferAgent
+}
+
+func (a *concurrentLocalAgent) Concurrency() int {
+	return 3
+}
+
+func TestWithEachFile__concurrent(t *testing.T) {
+	local := createTestLocalAgent(t)
+	agent := &concurrentLocalAgent{LocalTransferAgent: local}
+
+	dir := filepath.Join(local.root, local.InboundPath())
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.ach", "b.ach", "c.ach"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := agent.ListInboundFiles()
+	if err != nil || len(files) != 3 {
+		t.Fatalf("files=%#v error=%v", files, err)
+	}
+
+	// every file is open at once before any are finished
+	var wg sync.WaitGroup
+	wg.Add(len(files))
+	err = WithEachFile(agent, files, false, func(f File) error {
+		wg.Done()
+		wg.Wait()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files, _ = agent.ListInboundFiles(); len(files) != 0 {
+		t.Errorf("unexpected files: %#v", files)
+	}
+}
+
+func TestLockedReader(t *testing.T) {
+	var mu sync.Mutex
+	mu.Lock()
+
+	r := &lockedReader{
+		ReadCloser: ioutil.NopCloser(strings.NewReader("test")),
+		unlock:     mu.Unlock,
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// closing again shouldn't unlock twice
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock() // our lock was released
+	mu.Unlock()
+}
+
+func TestVerifyingReader(t *testing.T) {
+	r := newVerifyingReader(strings.NewReader("hello"))
+	if _, err := ioutil.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.verifySize("test.ach", 5); err != nil {
+		t.Error(err)
+	}
+	if err := r.verifySize("test.ach", 3); err == nil {
+		t.Error("expected error")
+	}
+	if sum := r.checksum(); sum != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("unexpected checksum: %s", sum)
+	}
+	if err := r.verifyChecksum("test.ach", "2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824"); err != nil {
+		t.Error(err)
+	}
+	if err := r.verifyChecksum("test.ach", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"); err == nil {
+		t.Error("expected error")
+	}
+}
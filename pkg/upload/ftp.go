@@ -5,13 +5,15 @@
 package upload
 
 import (
-	"bytes"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -34,6 +36,7 @@ var (
 // FTPTransferAgent is an FTP implementation of a Agent
 type FTPTransferAgent struct {
 	conn   *ftp.ServerConn
+	dialer *net.Dialer
 	cfg    config.ODFI
 	logger log.Logger
 	mu     sync.Mutex // protects all read/write methods
@@ -51,11 +54,13 @@ func newFTPTransferAgent(logger log.Logger, cfg config.ODFI) (*FTPTransferAgent,
 		logger: logger,
 	}
 
-	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), cfg.FTP.Hostname); err != nil {
-		return nil, fmt.Errorf("ftp: %s is not whitelisted: %v", cfg.FTP.Hostname, err)
+	dialer, err := newDialer(logger, cfg.SplitAllowedIPs(), cfg.FTP.Timeout())
+	if err != nil {
+		return nil, fmt.Errorf("ftp: %v", err)
 	}
+	agent.dialer = dialer
 
-	_, err := agent.connection() // initial connection
+	_, err = agent.connection() // initial connection
 
 	return agent, err
 }
@@ -82,6 +87,8 @@ func (agent *FTPTransferAgent) connection() (*ftp.ServerConn, error) {
 
 	// Setup our FTP connection
 	opts := []ftp.DialOption{
+		// Our dialer checks the allowed IPs of the control and data connections
+		ftp.DialWithDialer(*agent.dialer),
 		ftp.DialWithTimeout(agent.cfg.FTP.Timeout()),
 		ftp.DialWithDisabledEPSV(agent.cfg.FTP.DisableEPSV()),
 	}
@@ -203,7 +210,8 @@ func (agent *FTPTransferAgent) Delete(path string) error {
 
 // uploadFile saves the content of File at the given filename in the OutboundPath directory
 //
-// The File's contents will always be closed
+// The contents are written to a temporary file which is renamed once the server reports
+// the size we sent. The File's contents will always be closed
 func (agent *FTPTransferAgent) UploadFile(f File) error {
 	defer f.Close()
 
@@ -232,18 +240,68 @@ func (agent *FTPTransferAgent) UploadFile(f File) error {
 
 	// Write file contents into path
 	// Take the base of f.Filename and our (out of band) OutboundPath to avoid accepting a write like '../../../../etc/passwd'.
-	return conn.Stor(filepath.Base(f.Filename), f.Contents)
+	filename := filepath.Base(f.Filename)
+	tmp := tempFilename(filename)
+
+	r := newVerifyingReader(f.Contents)
+	if err := conn.Stor(tmp, r); err != nil {
+		conn.Delete(tmp)
+		return fmt.Errorf("ftp: problem writing %s: %v", filename, err)
+	}
+	size, err := conn.FileSize(tmp)
+	if err != nil {
+		conn.Delete(tmp)
+		return fmt.Errorf("ftp: problem verifying %s: %v", filename, err)
+	}
+	if err := r.verifySize(filename, size); err != nil {
+		conn.Delete(tmp)
+		return fmt.Errorf("ftp: %v", err)
+	}
+	if sum, err := agent.remoteChecksum(conn, tmp); err != nil {
+		conn.Delete(tmp)
+		return fmt.Errorf("ftp: problem verifying %s: %v", filename, err)
+	} else if sum != "" {
+		if err := r.verifyChecksum(filename, sum); err != nil {
+			conn.Delete(tmp)
+			return fmt.Errorf("ftp: %v", err)
+		}
+	}
+	if err := conn.Rename(tmp, filename); err != nil {
+		conn.Delete(tmp)
+		return fmt.Errorf("ftp: problem renaming %s: %v", filename, err)
+	}
+	return nil
 }
 
-func (agent *FTPTransferAgent) GetInboundFiles() ([]File, error) {
-	return agent.readFiles(agent.cfg.InboundPath)
+// remoteChecksum returns the hex encoded SHA-256 of what the server stored at path. Our FTP client
+// can't send HASH or XSHA256, so the file is read back and hashed. An empty checksum is returned
+// for servers which don't allow reading uploads, which leaves them verified by size alone.
+func (agent *FTPTransferAgent) remoteChecksum(conn *ftp.ServerConn, path string) (string, error) {
+	resp, err := conn.Retr(path)
+	if err != nil {
+		agent.logger.Log("ftp", fmt.Sprintf("verifying %s by size, unable to read it back: %v", path, err))
+		return "", nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, resp); err != nil {
+		resp.Close()
+		return "", err
+	}
+	if err := resp.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
-func (agent *FTPTransferAgent) GetReturnFiles() ([]File, error) {
-	return agent.readFiles(agent.cfg.ReturnPath)
+func (agent *FTPTransferAgent) ListInboundFiles() ([]FileInfo, error) {
+	return agent.listFiles(agent.cfg.InboundPath)
 }
 
-func (agent *FTPTransferAgent) readFiles(path string) ([]File, error) {
+func (agent *FTPTransferAgent) ListReturnFiles() ([]FileInfo, error) {
+	return agent.listFiles(agent.cfg.ReturnPath)
+}
+
+func (agent *FTPTransferAgent) listFiles(dir string) ([]FileInfo, error) {
 	agent.mu.Lock()
 	defer agent.mu.Unlock()
 
@@ -252,51 +310,45 @@ func (agent *FTPTransferAgent) readFiles(path string) ([]File, error) {
 		return nil, err
 	}
 
-	// move into inbound directory and set a trigger to undo
-	wd, err := conn.CurrentDir()
-	if err != nil {
-		return nil, err
-	}
-	defer func(path string) {
-		// Return to our previous directory when initially called
-		if err := conn.ChangeDir(wd); err != nil {
-			agent.logger.Log("ftp", fmt.Sprintf("FTP: problem with readFiles: %v", err))
-		}
-	}(wd)
-	if err := conn.ChangeDir(path); err != nil {
-		return nil, err
-	}
-
-	// Read files in current directory
-	items, err := conn.NameList("")
+	entries, err := conn.List(dir)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("problem listing %s: %v", dir, err)
 	}
-	var files []File
-	for i := range items {
-		resp, err := conn.Retr(items[i])
-		if err != nil {
-			return nil, fmt.Errorf("problem retrieving %s: %v", items[i], err)
+	var files []FileInfo
+	for i := range entries {
+		if entries[i].Type != ftp.EntryTypeFile {
+			continue
 		}
-		r, err := agent.readResponse(resp)
-		if err != nil {
-			return nil, fmt.Errorf("problem reading %s: %v", items[i], err)
-		}
-		files = append(files, File{
-			Filename: items[i],
-			Contents: r,
+		files = append(files, FileInfo{
+			Filename: entries[i].Name,
+			Path:     filepath.Join(dir, entries[i].Name),
+			Size:     int64(entries[i].Size),
+			ModTime:  entries[i].Time,
 		})
 	}
 	return files, nil
 }
 
-func (*FTPTransferAgent) readResponse(resp *ftp.Response) (io.ReadCloser, error) {
-	defer resp.Close()
+// Open streams a remote file over the FTP data connection. Our FTP connection can only transfer one
+// file at a time so the agent stays locked until the returned File is closed.
+func (agent *FTPTransferAgent) Open(path string) (File, error) {
+	agent.mu.Lock()
 
-	var buf bytes.Buffer
-	n, err := io.Copy(&buf, resp)
-	if n == 0 || err != nil {
-		return ioutil.NopCloser(&buf), fmt.Errorf("n=%d error=%v", n, err)
+	conn, err := agent.connection()
+	if err != nil {
+		agent.mu.Unlock()
+		return File{}, err
 	}
-	return ioutil.NopCloser(&buf), nil
+	resp, err := conn.Retr(path)
+	if err != nil {
+		agent.mu.Unlock()
+		return File{}, fmt.Errorf("problem retrieving %s: %v", path, err)
+	}
+	return File{
+		Filename: filepath.Base(path),
+		Contents: &lockedReader{
+			ReadCloser: resp,
+			unlock:     agent.mu.Unlock,
+		},
+	}, nil
 }
@@ -200,12 +200,12 @@ func TestFTP__tlsDialOption(t *testing.T) {
 	}
 }
 
-func TestFTP__getInboundFiles(t *testing.T) {
+func TestFTP__listInboundFiles(t *testing.T) {
 	svc, agent := createTestFTPAgent(t)
 	defer agent.Close()
 	defer svc.Shutdown()
 
-	files, err := agent.GetInboundFiles()
+	files, err := agent.ListInboundFiles()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -213,8 +213,16 @@ func TestFTP__getInboundFiles(t *testing.T) {
 		t.Errorf("got %d files", len(files))
 	}
 	for i := range files {
+		if files[i].Size == 0 {
+			t.Errorf("files[%d] has no size: %#v", i, files[i])
+		}
 		if files[i].Filename == "iat-credit.ach" {
-			bs, _ := ioutil.ReadAll(files[i].Contents)
+			f, err := agent.Open(files[i].Path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bs, _ := ioutil.ReadAll(f.Contents)
+			f.Close()
 			bs = bytes.TrimSpace(bs)
 			if !strings.HasPrefix(string(bs), "101 121042882 2313801041812180000A094101Bank                   My Bank Name                   ") {
 				t.Errorf("got %v", string(bs))
@@ -223,7 +231,7 @@ func TestFTP__getInboundFiles(t *testing.T) {
 	}
 
 	// make sure we perform the same call and get the same result
-	files, err = agent.GetInboundFiles()
+	files, err = agent.ListInboundFiles()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -231,50 +239,50 @@ func TestFTP__getInboundFiles(t *testing.T) {
 		t.Errorf("got %d files", len(files))
 	}
 	for i := range files {
-		if files[0].Filename == "iat-credit.ach" {
-			continue
-		}
-		if files[0].Filename == "cor-c01.ach" {
+		switch files[i].Filename {
+		case "iat-credit.ach", "cor-c01.ach", "prenote-ppd-debit.ach":
 			continue
 		}
-		if files[0].Filename == "prenote-ppd-debit.ach" {
-			continue
-		}
-		t.Errorf("files[%d]=%s", i, files[i])
+		t.Errorf("files[%d]=%#v", i, files[i])
 	}
 }
 
-func TestFTP__getReturnFiles(t *testing.T) {
+func TestFTP__listReturnFiles(t *testing.T) {
 	svc, agent := createTestFTPAgent(t)
 	defer agent.Close()
 	defer svc.Shutdown()
 
-	files, err := agent.GetReturnFiles()
+	files, err := agent.ListReturnFiles()
 	if err != nil {
 		t.Fatal(err)
 	}
 	if len(files) != 1 {
-		t.Errorf("got %d files", len(files))
+		t.Fatalf("got %d files", len(files))
 	}
 	if files[0].Filename != "return-WEB.ach" {
-		t.Errorf("files[0]=%s", files[0])
+		t.Errorf("files[0]=%#v", files[0])
 	}
-	bs, _ := ioutil.ReadAll(files[0].Contents)
+	f, err := agent.Open(files[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, _ := ioutil.ReadAll(f.Contents)
+	f.Close()
 	bs = bytes.TrimSpace(bs)
 	if !strings.HasPrefix(string(bs), "101 091400606 6910001341810170306A094101FIRST BANK & TRUST     ASF APPLICATION SUPERVI        ") {
 		t.Errorf("got %v", string(bs))
 	}
 
 	// make sure we perform the same call and get the same result
-	files, err = agent.GetReturnFiles()
+	files, err = agent.ListReturnFiles()
 	if err != nil {
 		t.Fatal(err)
 	}
 	if len(files) != 1 {
-		t.Errorf("got %d files", len(files))
+		t.Fatalf("got %d files", len(files))
 	}
 	if files[0].Filename != "return-WEB.ach" {
-		t.Errorf("files[0]=%s", files[0])
+		t.Errorf("files[0]=%#v", files[0])
 	}
 }
 
@@ -299,17 +307,17 @@ func TestFTP__uploadFile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// manually read file contents
+	// read the file back
 	agent.conn.ChangeDir(agent.OutboundPath())
-	resp, _ := agent.conn.Retr(f.Filename)
-	if resp == nil {
-		t.Fatal("nil File response")
+	if _, err := agent.conn.FileSize(tempFilename(f.Filename)); err == nil {
+		t.Error("expected temporary file to be renamed")
 	}
-	r, _ := agent.readResponse(resp)
-	if r == nil {
-		t.Fatal("failed to read file")
+	file, err := agent.Open(f.Filename)
+	if err != nil {
+		t.Fatal(err)
 	}
-	bs, _ := ioutil.ReadAll(r)
+	bs, _ := ioutil.ReadAll(file.Contents)
+	file.Close()
 	if !bytes.Equal(bs, []byte(content)) {
 		t.Errorf("got %q", string(bs))
 	}
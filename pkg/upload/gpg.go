@@ -0,0 +1,106 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/gpgx"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// GPG encrypts and signs files before they're uploaded to the ODFI and decrypts and
+// verifies the files we retrieve from them. A nil *GPG leaves files unchanged.
+type GPG struct {
+	odfiKeys    openpgp.EntityList
+	signingKeys openpgp.EntityList
+}
+
+// NewGPG reads the keys from cfg. A nil *GPG is returned when cfg is nil.
+func NewGPG(cfg *config.GPG) (*GPG, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	g := &GPG{}
+
+	var err error
+	g.odfiKeys, err = gpgx.ReadArmoredKeyFile(cfg.ODFIPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("odfi public key: %v", err)
+	}
+	if cfg.SigningKey != nil {
+		g.signingKeys, err = gpgx.ReadPrivateKeyFile(cfg.SigningKey.Path, []byte(cfg.SigningKey.Password))
+		if err != nil {
+			return nil, fmt.Errorf("signing key: %v", err)
+		}
+	}
+	return g, nil
+}
+
+// Enabled returns true if files are encrypted prior to upload
+func (g *GPG) Enabled() bool {
+	return g != nil
+}
+
+// Encrypt returns a File whose Contents are encrypted for the ODFI and signed with our key.
+func (g *GPG) Encrypt(f File) (File, error) {
+	if g == nil {
+		return f, nil
+	}
+	bs, err := readContents(f)
+	if err != nil {
+		return f, err
+	}
+	var signer *openpgp.Entity
+	if len(g.signingKeys) > 0 {
+		signer = g.signingKeys[0]
+	}
+	bs, err = gpgx.Encrypt(bs, g.odfiKeys, signer)
+	if err != nil {
+		return f, fmt.Errorf("encrypting %s: %v", f.Filename, err)
+	}
+	return File{
+		Filename: f.Filename,
+		Contents: ioutil.NopCloser(bytes.NewReader(bs)),
+	}, nil
+}
+
+// Decrypt returns a File whose Contents have been decrypted with our key. The ODFI's
+// signature is required and verified.
+func (g *GPG) Decrypt(f File) (File, error) {
+	if g == nil {
+		return f, nil
+	}
+	if f.Contents == nil {
+		return f, errors.New("nil File contents")
+	}
+	defer f.Close()
+
+	bs, err := gpgx.Decrypt(f.Contents, g.signingKeys, g.odfiKeys)
+	if err != nil {
+		return f, fmt.Errorf("decrypting %s: %v", f.Filename, err)
+	}
+	return File{
+		Filename: strings.TrimSuffix(f.Filename, ".gpg"),
+		Contents: ioutil.NopCloser(bytes.NewReader(bs)),
+	}, nil
+}
+
+func readContents(f File) ([]byte, error) {
+	if f.Contents == nil {
+		return nil, errors.New("nil File contents")
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f.Contents)
+}
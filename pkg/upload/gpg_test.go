@@ -0,0 +1,141 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+	"github.com/moov-io/paygate/x/gpgx"
+)
+
+func gpgConfig() *config.GPG {
+	return &config.GPG{
+		ODFIPublicKey: filepath.Join("..", "..", "testdata", "gpg", "odfi.pub"),
+		SigningKey: &config.GPGPrivateKey{
+			Path:     filepath.Join("..", "..", "testdata", "gpg", "paygate.priv"),
+			Password: "password",
+		},
+	}
+}
+
+func TestGPG__nil(t *testing.T) {
+	g, err := NewGPG(nil)
+	if g != nil || err != nil {
+		t.Fatalf("gpg=%#v error=%v", g, err)
+	}
+	if g.Enabled() {
+		t.Error("expected disabled")
+	}
+
+	f := File{Filename: "20200601-987654320-1.ach", Contents: ioutil.NopCloser(strings.NewReader("data"))}
+	if out, err := g.Encrypt(f); err != nil || out.Filename != f.Filename {
+		t.Errorf("file=%#v error=%v", out, err)
+	}
+	if out, err := g.Decrypt(f); err != nil || out.Filename != f.Filename {
+		t.Errorf("file=%#v error=%v", out, err)
+	}
+}
+
+func TestGPG__Encrypt(t *testing.T) {
+	g, err := NewGPG(gpgConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.Enabled() {
+		t.Error("expected enabled")
+	}
+
+	f, err := g.Encrypt(File{
+		Filename: "20200601-987654320-1.ach.gpg",
+		Contents: ioutil.NopCloser(strings.NewReader("nacha file")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read the file as our ODFI would
+	odfiKeys, err := gpgx.ReadPrivateKeyFile(filepath.Join("..", "..", "testdata", "gpg", "odfi.priv"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paygateKeys, err := gpgx.ReadArmoredKeyFile(filepath.Join("..", "..", "testdata", "gpg", "paygate.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := gpgx.Decrypt(f.Contents, odfiKeys, paygateKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "nacha file" {
+		t.Errorf("unexpected contents: %q", string(bs))
+	}
+}
+
+func TestGPG__Decrypt(t *testing.T) {
+	g, err := NewGPG(gpgConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Encrypt and sign the file as our ODFI would
+	odfiKeys, err := gpgx.ReadPrivateKeyFile(filepath.Join("..", "..", "testdata", "gpg", "odfi.priv"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paygateKeys, err := gpgx.ReadArmoredKeyFile(filepath.Join("..", "..", "testdata", "gpg", "paygate.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := gpgx.Encrypt([]byte("nacha file"), paygateKeys, odfiKeys[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := g.Decrypt(File{
+		Filename: "return-WEB.ach.gpg",
+		Contents: ioutil.NopCloser(bytes.NewReader(bs)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Filename != "return-WEB.ach" {
+		t.Errorf("unexpected filename: %s", f.Filename)
+	}
+	if bs, _ := ioutil.ReadAll(f.Contents); string(bs) != "nacha file" {
+		t.Errorf("unexpected contents: %q", string(bs))
+	}
+
+	// unsigned files are rejected
+	bs, err = gpgx.Encrypt([]byte("nacha file"), paygateKeys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.Decrypt(File{
+		Filename: "return-WEB.ach.gpg",
+		Contents: ioutil.NopCloser(bytes.NewReader(bs)),
+	})
+	if err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestGPG__NewGPGErr(t *testing.T) {
+	cfg := gpgConfig()
+	cfg.SigningKey.Password = "wrong"
+	if _, err := NewGPG(cfg); err == nil {
+		t.Error("expected error")
+	}
+
+	cfg = gpgConfig()
+	cfg.ODFIPublicKey = ""
+	if _, err := NewGPG(cfg); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	localAgentUp = prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+		Name: "local_agent_up",
+		Help: "Status of local filesystem agent",
+	}, []string{"directory"})
+)
+
+// LocalTransferAgent is an Agent which reads and writes files within a directory
+// on the local filesystem, such as a mounted network share.
+type LocalTransferAgent struct {
+	root   string
+	cfg    config.ODFI
+	logger log.Logger
+	mu     sync.Mutex // protects all read/write methods
+}
+
+func newLocalTransferAgent(logger log.Logger, cfg config.ODFI) (*LocalTransferAgent, error) {
+	if cfg.Local == nil {
+		return nil, errors.New("nil Local config")
+	}
+	root, err := filepath.Abs(cfg.Local.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("local: %v", err)
+	}
+	agent := &LocalTransferAgent{
+		root:   root,
+		cfg:    cfg,
+		logger: logger,
+	}
+	return agent, agent.Ping()
+}
+
+func (agent *LocalTransferAgent) Ping() error {
+	if agent == nil {
+		return errors.New("nil LocalTransferAgent")
+	}
+	info, err := os.Stat(agent.root)
+	if err == nil && !info.IsDir() {
+		err = fmt.Errorf("local: %s is not a directory", agent.root)
+	}
+	agent.record(err)
+	return err
+}
+
+func (agent *LocalTransferAgent) record(err error) {
+	if agent == nil {
+		return
+	}
+	if err != nil {
+		localAgentUp.With("directory", agent.root).Set(0)
+	} else {
+		localAgentUp.With("directory", agent.root).Set(1)
+	}
+}
+
+func (agent *LocalTransferAgent) Close() error {
+	return nil
+}
+
+func (agent *LocalTransferAgent) InboundPath() string {
+	return agent.cfg.InboundPath
+}
+
+func (agent *LocalTransferAgent) OutboundPath() string {
+	return agent.cfg.OutboundPath
+}
+
+func (agent *LocalTransferAgent) ReturnPath() string {
+	return agent.cfg.ReturnPath
+}
+
+// path returns the absolute filepath of p within our root directory and rejects
+// paths which would escape it (e.g. '../../etc/passwd').
+func (agent *LocalTransferAgent) path(p string) (string, error) {
+	full := filepath.Join(agent.root, p)
+	rel, err := filepath.Rel(agent.root, full)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("local: %s is outside of %s", p, agent.root)
+	}
+	return full, nil
+}
+
+func (agent *LocalTransferAgent) Delete(path string) error {
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	if path == "" || strings.HasSuffix(path, "/") {
+		return fmt.Errorf("LocalTransferAgent: invalid path %v", path)
+	}
+	full, err := agent.path(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("local: delete: %v", err)
+	}
+	return nil // deleted or not found
+}
+
+// UploadFile saves the content of File at the given filename in the OutboundPath directory.
+// Contents are written to a temporary file which is renamed once complete so readers of the
+// directory never see a partial file.
+//
+// The File's contents will always be closed
+func (agent *LocalTransferAgent) UploadFile(f File) error {
+	defer f.Close()
+
+	agent.mu.Lock()
+	defer agent.mu.Unlock()
+
+	dir, err := agent.path(agent.cfg.OutboundPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return fmt.Errorf("local: problem creating parent dir %s: %v", dir, err)
+	}
+
+	// Take the base of f.Filename and our (out of band) OutboundPath to avoid accepting a write like '../../../../etc/passwd'.
+	filename := filepath.Base(f.Filename)
+
+	fd, err := ioutil.TempFile(dir, "."+filename+".tmp-")
+	if err != nil {
+		return fmt.Errorf("local: problem creating %s: %v", filename, err)
+	}
+	tmp := fd.Name()
+	cleanup := func(err error) error {
+		fd.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	r := newVerifyingReader(f.Contents)
+	n, err := io.Copy(fd, r)
+	if n == 0 || err != nil {
+		return cleanup(fmt.Errorf("local: problem copying (n=%d) %s: %v", n, filename, err))
+	}
+	if err := fd.Chmod(0600); err != nil {
+		return cleanup(fmt.Errorf("local: problem chmod %s: %v", filename, err))
+	}
+	if err := fd.Sync(); err != nil {
+		return cleanup(fmt.Errorf("local: problem syncing %s: %v", filename, err))
+	}
+	if err := fd.Close(); err != nil {
+		return cleanup(fmt.Errorf("local: problem closing %s: %v", filename, err))
+	}
+	if err := verifyLocalFile(tmp, filename, r); err != nil {
+		return cleanup(fmt.Errorf("local: %v", err))
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, filename)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("local: problem renaming %s: %v", filename, err)
+	}
+	return nil
+}
+
+// verifyLocalFile reads back what was written to path and compares it against what we sent.
+func verifyLocalFile(path, filename string, sent *verifyingReader) error {
+	fd, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("problem verifying %s: %v", filename, err)
+	}
+	defer fd.Close()
+
+	written := newVerifyingReader(fd)
+	if _, err := io.Copy(ioutil.Discard, written); err != nil {
+		return fmt.Errorf("problem verifying %s: %v", filename, err)
+	}
+	if err := sent.verifySize(filename, written.n); err != nil {
+		return err
+	}
+	if sent.checksum() != written.checksum() {
+		return fmt.Errorf("%s checksum mismatch", filename)
+	}
+	return nil
+}
+
+func (agent *LocalTransferAgent) ListInboundFiles() ([]FileInfo, error) {
+	return agent.listFiles(agent.cfg.InboundPath)
+}
+
+func (agent *LocalTransferAgent) ListReturnFiles() ([]FileInfo, error) {
+	return agent.listFiles(agent.cfg.ReturnPath)
+}
+
+func (agent *LocalTransferAgent) listFiles(path string) ([]FileInfo, error) {
+	dir, err := agent.path(path)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("local: readdir %s: %v", path, err)
+	}
+
+	var files []FileInfo
+	for i := range infos {
+		// Skip directories and hidden files, which includes partially written uploads
+		if infos[i].IsDir() || strings.HasPrefix(infos[i].Name(), ".") {
+			continue
+		}
+		files = append(files, FileInfo{
+			Filename: infos[i].Name(),
+			Path:     filepath.Join(path, infos[i].Name()),
+			Size:     infos[i].Size(),
+			ModTime:  infos[i].ModTime(),
+		})
+	}
+	return files, nil
+}
+
+func (agent *LocalTransferAgent) Open(path string) (File, error) {
+	full, err := agent.path(path)
+	if err != nil {
+		return File{}, err
+	}
+	fd, err := os.Open(full)
+	if err != nil {
+		return File{}, fmt.Errorf("local: open %s: %v", path, err)
+	}
+	return File{
+		Filename: filepath.Base(path),
+		Contents: fd,
+	}, nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/base"
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/log"
+)
+
+func createTestLocalAgent(t *testing.T) *LocalTransferAgent {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "local-agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	cfg := config.ODFI{
+		InboundPath:  "inbound/",
+		OutboundPath: "outbound/",
+		ReturnPath:   "returned/",
+		Local: &config.LocalAgent{
+			Directory: dir,
+		},
+	}
+	agent, err := New(log.NewNopLogger(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := Type(cfg); v != "local" {
+		t.Errorf("unexpected type: %s", v)
+	}
+	return agent.(*LocalTransferAgent)
+}
+
+func TestLocalAgent(t *testing.T) {
+	agent := createTestLocalAgent(t)
+	defer agent.Close()
+
+	if err := agent.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	if v := agent.InboundPath(); v != "inbound/" {
+		t.Errorf("got %s", v)
+	}
+	if v := agent.OutboundPath(); v != "outbound/" {
+		t.Errorf("got %s", v)
+	}
+	if v := agent.ReturnPath(); v != "returned/" {
+		t.Errorf("got %s", v)
+	}
+
+	// missing directory
+	cfg := config.ODFI{
+		Local: &config.LocalAgent{
+			Directory: filepath.Join(agent.root, "missing"),
+		},
+	}
+	if _, err := newLocalTransferAgent(log.NewNopLogger(), cfg); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestLocalAgent__uploadFile(t *testing.T) {
+	agent := createTestLocalAgent(t)
+
+	content := base.ID()
+	f := File{
+		Filename: "../../" + base.ID(), // only the base is used
+		Contents: ioutil.NopCloser(strings.NewReader(content)),
+	}
+	if err := agent.UploadFile(f); err != nil {
+		t.Fatal(err)
+	}
+
+	// only our file exists, the temporary file was renamed
+	dir := filepath.Join(agent.root, agent.OutboundPath())
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Name() != filepath.Base(f.Filename) {
+		t.Fatalf("unexpected files: %#v", infos)
+	}
+	bs, err := ioutil.ReadFile(filepath.Join(dir, infos[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != content {
+		t.Errorf("got %q", string(bs))
+	}
+
+	// empty files aren't written
+	f = File{
+		Filename: base.ID(),
+		Contents: ioutil.NopCloser(strings.NewReader("")),
+	}
+	if err := agent.UploadFile(f); err == nil {
+		t.Error("expected error")
+	}
+	if infos, _ := ioutil.ReadDir(dir); len(infos) != 1 {
+		t.Errorf("unexpected files: %#v", infos)
+	}
+}
+
+func TestLocalAgent__listFiles(t *testing.T) {
+	agent := createTestLocalAgent(t)
+
+	// no directory yet
+	files, err := agent.ListInboundFiles()
+	if err != nil || len(files) != 0 {
+		t.Fatalf("files=%#v error=%v", files, err)
+	}
+
+	dir := filepath.Join(agent.root, agent.ReturnPath())
+	if err := os.MkdirAll(filepath.Join(dir, "archive"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "return-WEB.ach"), []byte("return"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ".partial.ach.tmp-1"), []byte("partial"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err = agent.ListReturnFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Filename != "return-WEB.ach" {
+		t.Fatalf("unexpected files: %#v", files)
+	}
+	if files[0].Size != 6 || files[0].ModTime.IsZero() {
+		t.Errorf("unexpected metadata: %#v", files[0])
+	}
+
+	f, err := agent.Open(files[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if bs, _ := ioutil.ReadAll(f.Contents); string(bs) != "return" {
+		t.Errorf("got %q", string(bs))
+	}
+
+	// files outside of our directory can't be opened
+	if _, err := agent.Open("../../etc/passwd"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestLocalAgent__Delete(t *testing.T) {
+	agent := createTestLocalAgent(t)
+
+	path := filepath.Join(agent.InboundPath(), "ppd-debit.ach")
+	if err := os.MkdirAll(filepath.Join(agent.root, agent.InboundPath()), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(agent.root, path), []byte("ach"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := agent.Delete(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(agent.root, path)); !os.IsNotExist(err) {
+		t.Errorf("expected file to be deleted: %v", err)
+	}
+	if err := agent.Delete(path); err != nil {
+		t.Errorf("missing files aren't an error: %v", err)
+	}
+
+	// invalid paths
+	for _, p := range []string{"", "inbound/", "../../etc/passwd"} {
+		if err := agent.Delete(p); err == nil {
+			t.Errorf("expected error for %q", p)
+		}
+	}
+}
@@ -6,7 +6,9 @@ package upload
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"sync"
 )
 
@@ -20,18 +22,49 @@ type MockAgent struct {
 	Err error
 }
 
-func (a *MockAgent) GetInboundFiles() ([]File, error) {
+func (a *MockAgent) ListInboundFiles() ([]FileInfo, error) {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
-	return a.InboundFiles, nil
+	return mockFileInfos(a.InboundPath(), a.InboundFiles), nil
 }
 
-func (a *MockAgent) GetReturnFiles() ([]File, error) {
+func (a *MockAgent) ListReturnFiles() ([]FileInfo, error) {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
-	return a.ReturnFiles, nil
+	return mockFileInfos(a.ReturnPath(), a.ReturnFiles), nil
+}
+
+func mockFileInfos(dir string, files []File) []FileInfo {
+	var out []FileInfo
+	for i := range files {
+		out = append(out, FileInfo{
+			Filename: files[i].Filename,
+			Path:     filepath.Join(dir, files[i].Filename),
+		})
+	}
+	return out
+}
+
+func (a *MockAgent) Open(path string) (File, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.Err != nil {
+		return File{}, a.Err
+	}
+	for i := range a.InboundFiles {
+		if filepath.Join(a.InboundPath(), a.InboundFiles[i].Filename) == path {
+			return a.InboundFiles[i], nil
+		}
+	}
+	for i := range a.ReturnFiles {
+		if filepath.Join(a.ReturnPath(), a.ReturnFiles[i].Filename) == path {
+			return a.ReturnFiles[i], nil
+		}
+	}
+	return File{}, fmt.Errorf("%s not found", path)
 }
 
 func (a *MockAgent) UploadFile(f File) error {
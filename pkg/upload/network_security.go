@@ -8,39 +8,84 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/kit/log"
 )
 
-func rejectOutboundIPRange(allowedIPs []string, hostname string) error {
-	// perform an initial check to see if we can resolve the hostname
-	if strings.Contains(hostname, ":") {
-		if host, _, err := net.SplitHostPort(hostname); err != nil {
-			return err
-		} else {
-			hostname = host
+// allowlist holds the IP addresses and CIDR ranges (IPv4 or IPv6) we're allowed to connect to.
+type allowlist []*net.IPNet
+
+func parseAllowlist(allowedIPs []string) (allowlist, error) {
+	var out allowlist
+	for i := range allowedIPs {
+		raw := strings.TrimSpace(allowedIPs[i])
+		if raw == "" {
+			continue
+		}
+		if strings.Contains(raw, "/") {
+			_, ipnet, err := net.ParseCIDR(raw)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, ipnet)
+			continue
+		}
+		ip := net.ParseIP(raw)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", raw)
+		}
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		out = append(out, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return out, nil
+}
+
+// allowed returns true if ip is within any of our ranges. Every IP is allowed when the list is empty.
+func (list allowlist) allowed(ip net.IP) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for i := range list {
+		if list[i].Contains(ip) {
+			return true
 		}
 	}
-	addrs, err := net.LookupIP(hostname)
-	if len(addrs) == 0 || err != nil {
-		return fmt.Errorf("unable to resolve (found %d) %s: %v", len(addrs), hostname, err)
+	return false
+}
+
+// newDialer returns a net.Dialer which checks every IP address it connects to against allowedIPs.
+//
+// The check happens on the address actually dialed, after DNS resolution, so reconnects and
+// hostnames with several records are covered. The dialer tries the next resolved address when
+// one is rejected. Each rejection is logged as a security event.
+func newDialer(logger log.Logger, allowedIPs []string, timeout time.Duration) (*net.Dialer, error) {
+	list, err := parseAllowlist(allowedIPs)
+	if err != nil {
+		return nil, fmt.Errorf("allowed_ips: %v", err)
 	}
-	// skip whitelist check if none were specified, assume it was empty in the config
-	if len(allowedIPs) == 0 {
-		return nil
+	dialer := &net.Dialer{
+		Timeout: timeout,
 	}
-	for i := range allowedIPs {
-		if strings.Contains(allowedIPs[i], "/") {
-			ip, ipnet, err := net.ParseCIDR(allowedIPs[i])
-			if err != nil {
-				return err
-			}
-			if ip.Equal(addrs[0]) || ipnet.Contains(addrs[0]) {
-				return nil // whitelisted
-			}
-		} else {
-			if net.ParseIP(allowedIPs[i]).Equal(addrs[0]) {
-				return nil // whitelisted
-			}
+	if len(list) == 0 {
+		return dialer, nil
+	}
+	dialer.Control = func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip := net.ParseIP(host); ip != nil && list.allowed(ip) {
+			return nil
 		}
+		logger.Log(
+			"security", fmt.Sprintf("rejected outbound connection to %s which is not in allowed_ips", address),
+			"event", "outbound_ip_rejected", "network", network)
+		return fmt.Errorf("%s is not in allowed_ips", host)
 	}
-	return fmt.Errorf("%s is not whitelisted", addrs[0].String())
+	return dialer, nil
 }
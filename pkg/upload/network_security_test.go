@@ -5,61 +5,183 @@
 package upload
 
 import (
+	"bytes"
 	"fmt"
 	"net"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/log"
 )
 
-func TestRejectOutboundIPRange(t *testing.T) {
-	addrs, err := net.LookupIP("moov.io")
+func TestAllowlist(t *testing.T) {
+	cfg := &config.ODFI{AllowedIPs: "10.1.0.1, 10.2.0.0/16,2001:db8::/32,::1"}
+	list, err := parseAllowlist(cfg.SplitAllowedIPs())
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	cfg := &config.ODFI{AllowedIPs: addrs[0].String()}
+	cases := map[string]bool{
+		"10.1.0.1":      true,
+		"10.1.0.2":      false,
+		"10.2.44.1":     true,
+		"2001:db8::1":   true,
+		"2001:db9::1":   false,
+		"::1":           true,
+		"::ffff:a01:1":  true, // IPv4-mapped 10.1.0.1
+		"192.168.1.100": false,
+	}
+	for ip, allowed := range cases {
+		if v := list.allowed(net.ParseIP(ip)); v != allowed {
+			t.Errorf("%s allowed=%v", ip, v)
+		}
+	}
 
-	// exact IP match
-	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), "moov.io"); err != nil {
-		t.Error(err)
+	// empty list allows all
+	list, err = parseAllowlist(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !list.allowed(net.ParseIP("8.8.8.8")) {
+		t.Error("expected IP to be allowed")
 	}
 
-	// multiple whitelisted, but exact IP match
-	cfg.AllowedIPs = fmt.Sprintf("127.0.0.1/24,%s", addrs[0].String())
-	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), "moov.io"); err != nil {
-		t.Error(err)
+	// error cases
+	for _, raw := range []string{"afkjsafkjahfa", "10...../8", "2001:db8::/200"} {
+		if _, err := parseAllowlist([]string{raw}); err == nil {
+			t.Errorf("%s: expected error", raw)
+		}
 	}
+}
 
-	// multiple whitelisted, match range (convert IP to /24)
-	cfg.AllowedIPs = fmt.Sprintf("%s/24", addrs[0].Mask(net.IPv4Mask(0xFF, 0xFF, 0xFF, 0x0)).String())
-	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), "moov.io"); err != nil {
-		t.Error(err)
+func listen(t *testing.T, network, address string) net.Listener {
+	t.Helper()
+
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		t.Skipf("unable to listen on %s: %v", address, err)
 	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return ln
+}
 
-	// no match
-	cfg.AllowedIPs = "8.8.8.0/24"
-	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), "moov.io"); err == nil {
-		t.Error("expected error")
+func TestNewDialer(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.NewLogfmtLogger(&buf)
+
+	v4 := listen(t, "tcp4", "127.0.0.1:0")
+
+	dialer, err := newDialer(logger, []string{"127.0.0.0/8"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
 	}
+	conn, err := dialer.Dial("tcp", v4.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
 
-	// empty whitelist, allow all
-	cfg.AllowedIPs = ""
-	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), "moov.io"); err != nil {
-		t.Errorf("expected no error: %v", err)
+	// rejected
+	dialer, err = newDialer(logger, []string{"10.0.0.0/8", "2001:db8::/32"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn, err := dialer.Dial("tcp", v4.Addr().String()); err == nil {
+		conn.Close()
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(buf.String(), "outbound_ip_rejected") {
+		t.Errorf("expected security event: %s", buf.String())
 	}
 
-	// error cases
-	cfg.AllowedIPs = "afkjsafkjahfa"
-	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), "moov.io"); err == nil {
-		t.Error("expected error")
+	// no allowlist
+	dialer, err = newDialer(logger, nil, time.Second)
+	if err != nil {
+		t.Fatal(err)
 	}
-	cfg.AllowedIPs = "10.0.0.0/8"
-	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), "lsjafkshfaksjfhas"); err == nil {
-		t.Error("expected error")
+	if dialer.Control != nil {
+		t.Error("expected no Control func")
 	}
-	cfg.AllowedIPs = "10...../8"
-	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), "moov.io"); err == nil {
+
+	if _, err := newDialer(logger, []string{"10...../8"}, time.Second); err == nil {
 		t.Error("expected error")
 	}
 }
+
+func TestNewDialer__IPv6(t *testing.T) {
+	v6 := listen(t, "tcp6", "[::1]:0")
+
+	dialer, err := newDialer(log.NewNopLogger(), []string{"::1/128"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := dialer.Dial("tcp", v6.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	dialer, err = newDialer(log.NewNopLogger(), []string{"127.0.0.1"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn, err := dialer.Dial("tcp", v6.Addr().String()); err == nil {
+		conn.Close()
+		t.Fatal("expected error")
+	}
+}
+
+// TestNewDialer__resolvedAddresses dials a hostname whose addresses are each checked
+// against the allowlist, rather than only the first one.
+func TestNewDialer__resolvedAddresses(t *testing.T) {
+	addrs, err := net.LookupIP("localhost")
+	if err != nil {
+		t.Skip(err)
+	}
+	found := false
+	for i := range addrs {
+		found = found || addrs[i].Equal(net.ParseIP("127.0.0.1"))
+	}
+	if !found {
+		t.Skipf("localhost resolves to %v", addrs)
+	}
+	v4 := listen(t, "tcp4", "127.0.0.1:0")
+	_, port, _ := net.SplitHostPort(v4.Addr().String())
+	address := fmt.Sprintf("localhost:%s", port)
+
+	// Only our IPv4 listener is allowed
+	dialer, err := newDialer(log.NewNopLogger(), []string{"127.0.0.1"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := dialer.Dial("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host, _, _ := net.SplitHostPort(conn.RemoteAddr().String()); host != "127.0.0.1" {
+		t.Errorf("connected to %s", conn.RemoteAddr())
+	}
+	conn.Close()
+
+	// None of the addresses are allowed
+	dialer, err = newDialer(log.NewNopLogger(), []string{"10.0.0.0/8"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn, err := dialer.Dial("tcp", address); err == nil {
+		conn.Close()
+		t.Fatal("expected error")
+	}
+}
@@ -5,18 +5,19 @@
 package upload
 
 import (
-	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/moov-io/base"
 	"github.com/moov-io/paygate/pkg/config"
 
 	"github.com/go-kit/kit/log"
@@ -34,49 +35,41 @@ var (
 )
 
 type SFTPTransferAgent struct {
-	conn   *ssh.Client
-	client *sftp.Client
+	pool   *sftpPool
 	cfg    config.ODFI
 	logger log.Logger
-	mu     sync.Mutex // protects all read/write methods
 }
 
 func newSFTPTransferAgent(logger log.Logger, cfg config.ODFI) (*SFTPTransferAgent, error) {
 	agent := &SFTPTransferAgent{cfg: cfg, logger: logger}
 
-	if err := rejectOutboundIPRange(cfg.SplitAllowedIPs(), cfg.SFTP.Hostname); err != nil {
-		return nil, fmt.Errorf("sftp: %s is not whitelisted: %v", cfg.SFTP.Hostname, err)
+	// Each connection is checked against our allowed IPs, but catch a bad list up front
+	if _, err := newDialer(logger, cfg.SplitAllowedIPs(), cfg.SFTP.Timeout()); err != nil {
+		return nil, fmt.Errorf("sftp: %v", err)
 	}
 
-	_, err := agent.connection()
+	agent.pool = newSFTPPool(cfg.SFTP.Sessions(), cfg.SFTP.Keepalive(), agent.connect, agent.record)
+
+	// Open our first session to verify the connection
+	sess, err := agent.pool.get()
+	if err == nil {
+		agent.pool.put(sess, nil)
+	}
 
 	return agent, err
 }
 
-// connection returns an sftp.Client which is connected to the remote server.
-// This function will attempt to establish a new connection if none exists already.
-//
-// connection must be called within a mutex lock.
-func (agent *SFTPTransferAgent) connection() (*sftp.Client, error) {
+// connect opens a new sftp session to the remote server. The server's host key is
+// verified for every connection.
+func (agent *SFTPTransferAgent) connect() (*sftpSession, error) {
 	if agent == nil || agent.cfg.SFTP == nil {
 		return nil, errors.New("nil agent / config")
 	}
 
-	if agent.client != nil {
-		// Verify the connection works and if not drop through and reconnect
-		if _, err := agent.client.Getwd(); err == nil {
-			return agent.client, nil
-		} else {
-			// Our connection is having issues, so retry connecting
-			agent.client.Close()
-		}
-	}
-
 	conn, stdin, stdout, err := sftpConnect(agent.logger, agent.cfg)
 	if err != nil {
 		return nil, fmt.Errorf("upload: %v", err)
 	}
-	agent.conn = conn
 
 	// Setup our SFTP client
 	var opts = []sftp.ClientOption{
@@ -89,15 +82,14 @@ func (agent *SFTPTransferAgent) connection() (*sftp.Client, error) {
 		go conn.Close()
 		return nil, fmt.Errorf("upload: sftp connect: %v", err)
 	}
-	agent.client = client
 
-	return agent.client, nil
+	return &sftpSession{conn: conn, client: client}, nil
 }
 
 var (
 	hostKeyCallbackOnce sync.Once
 	hostKeyCallback     = func(logger log.Logger) {
-		logger.Log("sftp", "WARNING!!! Insecure default of skipping SFTP host key validation. Please set sftp_configs.host_public_key")
+		logger.Log("sftp", "WARNING!!! Insecure default of skipping SFTP host key validation. Please set hostPublicKey or known_hosts_file")
 	}
 )
 
@@ -112,18 +104,18 @@ func sftpConnect(logger log.Logger, cfg config.ODFI) (*ssh.Client, io.WriteClose
 	}
 	conf.SetDefaults()
 
-	if cfg.SFTP.HostPublicKey != "" {
-		pubKey, err := readPubKey(cfg.SFTP.HostPublicKey)
-		if err != nil {
-			return nil, nil, nil, fmt.Errorf("problem parsing ssh public key: %v", err)
-		}
-		conf.HostKeyCallback = ssh.FixedHostKey(pubKey)
-	} else {
-		hostKeyCallbackOnce.Do(func() {
-			hostKeyCallback(logger)
-		})
-		conf.HostKeyCallback = ssh.InsecureIgnoreHostKey() // insecure default
+	callback, err := hostKeyVerifier(logger, cfg.SFTP)
+	if err != nil {
+		return nil, nil, nil, err
 	}
+	conf.HostKeyCallback = callback
+
+	// Our dialer checks the allowed IPs of each connection
+	dialer, err := newDialer(logger, cfg.SplitAllowedIPs(), cfg.SFTP.Timeout())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	switch {
 	case cfg.SFTP.Password != "":
 		conf.Auth = append(conf.Auth, ssh.Password(cfg.SFTP.Password))
@@ -139,10 +131,9 @@ func sftpConnect(logger log.Logger, cfg config.ODFI) (*ssh.Client, io.WriteClose
 
 	// Connect to the remote server
 	var client *ssh.Client
-	var err error
 	for i := 0; i < 3; i++ {
 		if client == nil {
-			client, err = ssh.Dial("tcp", cfg.SFTP.Hostname, conf) // retry connection
+			client, err = sshDial(dialer, cfg.SFTP.Hostname, conf) // retry connection
 			time.Sleep(250 * time.Millisecond)
 		}
 	}
@@ -173,6 +164,73 @@ func sftpConnect(logger log.Logger, cfg config.ODFI) (*ssh.Client, io.WriteClose
 	return client, pw, pr, nil
 }
 
+// hostKeyVerifier returns an ssh.HostKeyCallback which accepts the configured host public key or any
+// key listed for the host in our known_hosts file. The file is read for each connection so keys can be
+// rotated without a restart. Without either keys are accepted unverified, unless strict host key checking
+// is enabled. The presented key's fingerprint is logged so operators can pin it.
+func hostKeyVerifier(logger log.Logger, cfg *config.SFTP) (ssh.HostKeyCallback, error) {
+	var callbacks []ssh.HostKeyCallback
+	if cfg.HostPublicKey != "" {
+		pubKey, err := readPubKey(cfg.HostPublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("problem parsing ssh public key: %v", err)
+		}
+		callbacks = append(callbacks, ssh.FixedHostKey(pubKey))
+	}
+	if cfg.KnownHostsFile != "" {
+		hosts, err := readKnownHosts(cfg.KnownHostsFile)
+		if err != nil {
+			return nil, fmt.Errorf("problem reading known_hosts_file: %v", err)
+		}
+		callbacks = append(callbacks, hosts.HostKeyCallback)
+	}
+
+	if len(callbacks) == 0 {
+		if cfg.StrictHostKeyChecking {
+			return nil, errors.New("sftpConnect: strict host key checking requires hostPublicKey or known_hosts_file")
+		}
+		hostKeyCallbackOnce.Do(func() {
+			hostKeyCallback(logger)
+		})
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			logger.Log("sftp", fmt.Sprintf("accepting unverified host key from %s: %s", hostname, fingerprint(key)))
+			return nil // insecure default
+		}, nil
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		var el base.ErrorList
+		for i := range callbacks {
+			err := callbacks[i](hostname, remote, key)
+			if err == nil {
+				return nil
+			}
+			el.Add(err)
+		}
+		logger.Log("sftp", fmt.Sprintf("ERROR: unable to verify host key from %s: %s", hostname, fingerprint(key)))
+		return fmt.Errorf("host key %s from %s not trusted: %v", fingerprint(key), hostname, el)
+	}, nil
+}
+
+// fingerprint returns the key's type and SHA256 fingerprint as OpenSSH displays them.
+func fingerprint(key ssh.PublicKey) string {
+	return fmt.Sprintf("%s %s", key.Type(), ssh.FingerprintSHA256(key))
+}
+
+// sshDial is ssh.Dial with our own net.Dialer
+func sshDial(dialer *net.Dialer, addr string, conf *ssh.ClientConfig) (*ssh.Client, error) {
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, conf)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
 func readPubKey(raw string) (ssh.PublicKey, error) {
 	readAuthd := func(raw string) (ssh.PublicKey, error) {
 		pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(raw))
@@ -206,16 +264,14 @@ func (agent *SFTPTransferAgent) Ping() error {
 		return errors.New("nil SFTPTransferAgent")
 	}
 
-	agent.mu.Lock()
-	defer agent.mu.Unlock()
-
-	conn, err := agent.connection()
-	agent.record(err)
-	if err != nil {
-		return err
+	if agent.pool == nil {
+		return errors.New("sftp: not connected")
 	}
 
-	_, err = conn.ReadDir(".")
+	err := agent.pool.withClient(func(conn *sftp.Client) error {
+		_, err := conn.ReadDir(".")
+		return err
+	})
 	agent.record(err)
 	if err != nil {
 		return fmt.Errorf("sftp: ping %v", err)
@@ -238,13 +294,7 @@ func (agent *SFTPTransferAgent) Close() error {
 	if agent == nil {
 		return nil
 	}
-	if agent.client != nil {
-		agent.client.Close()
-	}
-	if agent.conn != nil {
-		agent.conn.Close()
-	}
-	return nil
+	return agent.pool.Close()
 }
 
 func (agent *SFTPTransferAgent) InboundPath() string {
@@ -260,24 +310,18 @@ func (agent *SFTPTransferAgent) ReturnPath() string {
 }
 
 func (agent *SFTPTransferAgent) Delete(path string) error {
-	agent.mu.Lock()
-	defer agent.mu.Unlock()
-
-	conn, err := agent.connection()
-	if err != nil {
-		return err
-	}
-
-	info, err := conn.Stat(path)
-	if err != nil {
-		return fmt.Errorf("sftp: delete stat: %v", err)
-	}
-	if info != nil {
-		if err := conn.Remove(path); err != nil {
-			return fmt.Errorf("sftp: delete: %v", err)
+	return agent.pool.withClient(func(conn *sftp.Client) error {
+		info, err := conn.Stat(path)
+		if err != nil {
+			return fmt.Errorf("sftp: delete stat: %v", err)
 		}
-	}
-	return nil // not found
+		if info != nil {
+			if err := conn.Remove(path); err != nil {
+				return fmt.Errorf("sftp: delete: %v", err)
+			}
+		}
+		return nil // not found
+	})
 }
 
 // uploadFile saves the content of File at the given filename in the OutboundPath directory
@@ -286,13 +330,13 @@ func (agent *SFTPTransferAgent) Delete(path string) error {
 func (agent *SFTPTransferAgent) UploadFile(f File) error {
 	defer f.Close()
 
-	agent.mu.Lock()
-	defer agent.mu.Unlock()
+	return agent.pool.withSession(func(sess *sftpSession) error {
+		return agent.uploadFile(sess, f)
+	})
+}
 
-	conn, err := agent.connection()
-	if err != nil {
-		return err
-	}
+func (agent *SFTPTransferAgent) uploadFile(sess *sftpSession, f File) error {
+	conn := sess.client
 
 	// Create OutboundPath if it doesn't exist
 	info, err := conn.Stat(agent.cfg.OutboundPath)
@@ -303,65 +347,140 @@ func (agent *SFTPTransferAgent) UploadFile(f File) error {
 	}
 
 	// Take the base of f.Filename and our (out of band) OutboundPath to avoid accepting a write like '../../../../etc/passwd'.
-	fd, err := conn.Create(filepath.Join(agent.cfg.OutboundPath, filepath.Base(f.Filename)))
+	filename := filepath.Base(f.Filename)
+	path := filepath.Join(agent.cfg.OutboundPath, filename)
+	tmp := filepath.Join(agent.cfg.OutboundPath, tempFilename(filename))
+
+	// Write into a temporary file that's renamed once the server has all of our bytes
+	fd, err := conn.Create(tmp)
 	if err != nil {
 		return fmt.Errorf("sftp: problem creating %s: %v", f.Filename, err)
 	}
-	n, err := io.Copy(fd, f.Contents)
+	cleanup := func(err error) error {
+		fd.Close()
+		conn.Remove(tmp)
+		return err
+	}
+	r := newVerifyingReader(f.Contents)
+	n, err := io.Copy(fd, r)
 	if n == 0 || err != nil {
-		return fmt.Errorf("sftp: problem copying (n=%d) %s: %v", n, f.Filename, err)
+		return cleanup(fmt.Errorf("sftp: problem copying (n=%d) %s: %v", n, f.Filename, err))
+	}
+	if err := fd.Chmod(0600); err != nil {
+		return cleanup(fmt.Errorf("sftp: problem chmod %s: %v", f.Filename, err))
 	}
 	if err := fd.Close(); err != nil {
-		return fmt.Errorf("sftp: problem closing %s: %v", f.Filename, err)
+		return cleanup(fmt.Errorf("sftp: problem closing %s: %v", f.Filename, err))
 	}
-	if err := fd.Chmod(0600); err != nil {
-		return fmt.Errorf("sftp: problem chmod %s: %v", f.Filename, err)
+	info, err = conn.Stat(tmp)
+	if err != nil {
+		return cleanup(fmt.Errorf("sftp: problem verifying %s: %v", f.Filename, err))
+	}
+	if err := r.verifySize(filename, info.Size()); err != nil {
+		return cleanup(fmt.Errorf("sftp: %v", err))
+	}
+	if sum, err := remoteChecksum(sess.conn, tmp); err != nil {
+		// Servers which only allow sftp can't checksum the file, so it's verified by size alone
+		agent.logger.Log("sftp", fmt.Sprintf("verifying %s by size, unable to checksum: %v", filename, err))
+	} else {
+		if err := r.verifyChecksum(filename, sum); err != nil {
+			return cleanup(fmt.Errorf("sftp: %v", err))
+		}
+	}
+	if err := conn.PosixRename(tmp, path); err != nil {
+		// Not every server supports the posix-rename extension, so fallback to a regular rename
+		if err := conn.Rename(tmp, path); err != nil {
+			return cleanup(fmt.Errorf("sftp: problem renaming %s: %v", f.Filename, err))
+		}
 	}
 	return nil
 }
 
-func (agent *SFTPTransferAgent) GetInboundFiles() ([]File, error) {
-	return agent.readFiles(agent.cfg.InboundPath)
+// remoteChecksum runs sha256sum on the server and returns the hex encoded SHA-256 of path.
+func remoteChecksum(conn *ssh.Client, path string) (string, error) {
+	if conn == nil {
+		return "", errors.New("no ssh connection")
+	}
+	sess, err := conn.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer sess.Close()
+
+	out, err := sess.Output("sha256sum " + shellQuote(path))
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("unexpected sha256sum output: %q", string(out))
+	}
+	return fields[0], nil
 }
 
-func (agent *SFTPTransferAgent) GetReturnFiles() ([]File, error) {
-	return agent.readFiles(agent.cfg.ReturnPath)
+// shellQuote wraps s in single quotes so the remote shell reads it as one argument.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
 }
 
-func (agent *SFTPTransferAgent) readFiles(dir string) ([]File, error) {
-	agent.mu.Lock()
-	defer agent.mu.Unlock()
+func (agent *SFTPTransferAgent) ListInboundFiles() ([]FileInfo, error) {
+	return agent.listFiles(agent.cfg.InboundPath)
+}
 
-	conn, err := agent.connection()
-	if err != nil {
-		return nil, err
-	}
+func (agent *SFTPTransferAgent) ListReturnFiles() ([]FileInfo, error) {
+	return agent.listFiles(agent.cfg.ReturnPath)
+}
 
-	infos, err := conn.ReadDir(dir)
+func (agent *SFTPTransferAgent) listFiles(dir string) ([]FileInfo, error) {
+	var infos []os.FileInfo
+	err := agent.pool.withClient(func(conn *sftp.Client) error {
+		var err error
+		infos, err = conn.ReadDir(dir)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("sftp: readdir %s: %v", dir, err)
 	}
 
-	var files []File
+	var files []FileInfo
 	for i := range infos {
-		fd, err := conn.Open(filepath.Join(dir, infos[i].Name()))
-		if err != nil {
-			return nil, fmt.Errorf("sftp: open %s: %v", infos[i].Name(), err)
+		if infos[i].IsDir() {
+			continue
 		}
-		var buf bytes.Buffer
-		if n, err := io.Copy(&buf, fd); n == 0 || err != nil {
-			fd.Close()
-			if err != nil && !strings.Contains(err.Error(), sftp.ErrInternalInconsistency.Error()) {
-				return nil, fmt.Errorf("sftp: read (n=%d) %s: %v", n, infos[i].Name(), err)
-			}
-			return nil, fmt.Errorf("sftp: read (n=%d) on %s", n, infos[i].Name())
-		} else {
-			fd.Close()
-		}
-		files = append(files, File{
+		files = append(files, FileInfo{
 			Filename: infos[i].Name(),
-			Contents: ioutil.NopCloser(&buf),
+			Path:     filepath.Join(dir, infos[i].Name()),
+			Size:     infos[i].Size(),
+			ModTime:  infos[i].ModTime(),
 		})
 	}
 	return files, nil
 }
+
+// Open streams a remote file from the SFTP server. The file holds one of our pooled sessions
+// until it's closed, so up to Concurrency files are read at once.
+func (agent *SFTPTransferAgent) Open(path string) (File, error) {
+	sess, err := agent.pool.get()
+	if err != nil {
+		return File{}, err
+	}
+	fd, err := sess.client.Open(path)
+	if err != nil {
+		agent.pool.put(sess, err)
+		return File{}, fmt.Errorf("sftp: open %s: %v", path, err)
+	}
+	return File{
+		Filename: filepath.Base(path),
+		Contents: &lockedReader{
+			ReadCloser: fd,
+			unlock: func() {
+				agent.pool.put(sess, nil)
+			},
+		},
+	}, nil
+}
+
+// Concurrency returns how many files can be opened at once.
+func (agent *SFTPTransferAgent) Concurrency() int {
+	return agent.pool.size()
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// knownHosts holds each key listed in an OpenSSH known_hosts file.
+//
+// Unlike golang.org/x/crypto/ssh/knownhosts every key listed for a host is accepted, rather than
+// only the first of each key type, so an ODFI's current and next keys can be listed while they rotate.
+type knownHosts struct {
+	entries []knownHost
+}
+
+type knownHost struct {
+	marker   string
+	patterns []string
+	key      ssh.PublicKey
+}
+
+func readKnownHosts(path string) (*knownHosts, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	out := &knownHosts{}
+	for len(bs) > 0 {
+		marker, patterns, key, _, rest, err := ssh.ParseKnownHosts(bs)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		out.entries = append(out.entries, knownHost{
+			marker:   marker,
+			patterns: patterns,
+			key:      key,
+		})
+		bs = rest
+	}
+	return out, nil
+}
+
+// HostKeyCallback accepts key when it's listed for the server's hostname or remote address
+// and hasn't been revoked.
+func (kh *knownHosts) HostKeyCallback(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	addresses := []string{knownhosts.Normalize(hostname)}
+	if remote != nil {
+		addresses = append(addresses, knownhosts.Normalize(remote.String()))
+	}
+
+	trusted := false
+	marshaled := key.Marshal()
+	for _, entry := range kh.entries {
+		if !bytes.Equal(entry.key.Marshal(), marshaled) || !matchesAny(entry.patterns, addresses) {
+			continue
+		}
+		switch entry.marker {
+		case "@revoked":
+			return fmt.Errorf("known_hosts: host key for %s is revoked", hostname)
+		case "":
+			trusted = true
+		}
+	}
+	if trusted {
+		return nil
+	}
+	return fmt.Errorf("known_hosts: no matching host key for %s", hostname)
+}
+
+func matchesAny(patterns []string, addresses []string) bool {
+	for i := range addresses {
+		if matchPatterns(patterns, addresses[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPatterns follows OpenSSH where any negated pattern which matches excludes the address.
+func matchPatterns(patterns []string, address string) bool {
+	matched := false
+	for _, pattern := range patterns {
+		negate := strings.HasPrefix(pattern, "!")
+		if matchPattern(strings.TrimPrefix(pattern, "!"), address) {
+			if negate {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// matchPattern compares a normalized address against a hashed, wildcard or plain host pattern.
+func matchPattern(pattern, address string) bool {
+	if strings.HasPrefix(pattern, "|1|") {
+		parts := strings.Split(pattern, "|")
+		if len(parts) != 4 {
+			return false
+		}
+		salt, err := base64.StdEncoding.DecodeString(parts[2])
+		if err != nil {
+			return false
+		}
+		hash, err := base64.StdEncoding.DecodeString(parts[3])
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(address))
+		return hmac.Equal(mac.Sum(nil), hash)
+	}
+
+	patternHost, patternPort := splitKnownHost(strings.ToLower(pattern))
+	host, port := splitKnownHost(strings.ToLower(address))
+	if patternPort != port {
+		return false
+	}
+	matched, _ := path.Match(patternHost, host)
+	return matched
+}
+
+// splitKnownHost splits the '[host]:port' form used in known_hosts files for non-standard ports.
+func splitKnownHost(address string) (string, string) {
+	if strings.HasPrefix(address, "[") {
+		if idx := strings.Index(address, "]:"); idx > 0 {
+			return address[1:idx], address[idx+2:]
+		}
+	}
+	return address, "22"
+}
@@ -0,0 +1,177 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moov-io/paygate/pkg/config"
+
+	"github.com/go-kit/kit/log"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func generateHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func knownHostsDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "known-hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func writeKnownHosts(t *testing.T, lines ...string) string {
+	t.Helper()
+
+	path := filepath.Join(knownHostsDir(t), "known_hosts")
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+var (
+	knownHostAddr = &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 22}
+)
+
+func TestKnownHosts__rotation(t *testing.T) {
+	current, next, other := generateHostKey(t), generateHostKey(t), generateHostKey(t)
+
+	path := writeKnownHosts(t,
+		"# keys for our ODFI",
+		knownhosts.Line([]string{"sftp.bank.com"}, current),
+		knownhosts.Line([]string{"sftp.bank.com"}, next),
+	)
+	hosts, err := readKnownHosts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// both keys of the same type are accepted
+	for _, key := range []ssh.PublicKey{current, next} {
+		if err := hosts.HostKeyCallback("sftp.bank.com:22", knownHostAddr, key); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := hosts.HostKeyCallback("sftp.bank.com:22", knownHostAddr, other); err == nil {
+		t.Error("expected error")
+	}
+	if err := hosts.HostKeyCallback("sftp.other.com:22", knownHostAddr, current); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestKnownHosts__patterns(t *testing.T) {
+	key := generateHostKey(t)
+
+	cases := []struct {
+		line     string
+		hostname string
+		trusted  bool
+	}{
+		{knownhosts.Line([]string{"sftp.bank.com:2222"}, key), "sftp.bank.com:2222", true},
+		{knownhosts.Line([]string{"sftp.bank.com"}, key), "sftp.bank.com:2222", false},
+		{knownhosts.Line([]string{"*.bank.com"}, key), "sftp.bank.com:22", true},
+		{knownhosts.Line([]string{"*.bank.com", "!test.bank.com"}, key), "test.bank.com:22", false},
+		{knownhosts.HashHostname("[sftp.bank.com]:2222") + " " + string(ssh.MarshalAuthorizedKey(key)), "sftp.bank.com:2222", true},
+		{knownhosts.HashHostname("sftp.bank.com") + " " + string(ssh.MarshalAuthorizedKey(key)), "sftp.other.com:22", false},
+		{knownhosts.Line([]string{"10.1.2.3"}, key), "sftp.bank.com:22", true},
+		{"@revoked " + knownhosts.Line([]string{"sftp.bank.com"}, key), "sftp.bank.com:22", false},
+	}
+	for i := range cases {
+		hosts, err := readKnownHosts(writeKnownHosts(t, cases[i].line))
+		if err != nil {
+			t.Fatalf("#%d: %v", i, err)
+		}
+		err = hosts.HostKeyCallback(cases[i].hostname, knownHostAddr, key)
+		if trusted := err == nil; trusted != cases[i].trusted {
+			t.Errorf("#%d: %q for %s trusted=%v: %v", i, cases[i].line, cases[i].hostname, trusted, err)
+		}
+	}
+}
+
+func TestKnownHosts__invalid(t *testing.T) {
+	if _, err := readKnownHosts(filepath.Join(knownHostsDir(t), "missing")); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := readKnownHosts(writeKnownHosts(t, "sftp.bank.com ssh-ed25519 invalid")); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestSFTP__hostKeyVerifier(t *testing.T) {
+	logger := log.NewNopLogger()
+	key, other := generateHostKey(t), generateHostKey(t)
+
+	// insecure default
+	callback, err := hostKeyVerifier(logger, &config.SFTP{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := callback("sftp.bank.com:22", knownHostAddr, key); err != nil {
+		t.Error(err)
+	}
+
+	// strict mode without any keys
+	if _, err := hostKeyVerifier(logger, &config.SFTP{StrictHostKeyChecking: true}); err == nil {
+		t.Error("expected error")
+	}
+
+	// pinned key
+	cfg := &config.SFTP{
+		HostPublicKey:         string(ssh.MarshalAuthorizedKey(key)),
+		StrictHostKeyChecking: true,
+	}
+	callback, err = hostKeyVerifier(logger, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := callback("sftp.bank.com:22", knownHostAddr, key); err != nil {
+		t.Error(err)
+	}
+	err = callback("sftp.bank.com:22", knownHostAddr, other)
+	if err == nil || !strings.Contains(err.Error(), ssh.FingerprintSHA256(other)) {
+		t.Errorf("expected presented fingerprint: %v", err)
+	}
+
+	// pinned key along with known_hosts
+	cfg.KnownHostsFile = writeKnownHosts(t, knownhosts.Line([]string{"sftp.bank.com"}, other))
+	callback, err = hostKeyVerifier(logger, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []ssh.PublicKey{key, other} {
+		if err := callback("sftp.bank.com:22", knownHostAddr, k); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := callback("sftp.bank.com:22", knownHostAddr, generateHostKey(t)); err == nil {
+		t.Error("expected error")
+	}
+}
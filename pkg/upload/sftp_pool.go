@@ -0,0 +1,224 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// sftpSession is one SFTP client over its own ssh connection.
+type sftpSession struct {
+	conn   *ssh.Client
+	client *sftp.Client
+}
+
+// keepalive sends a request over the ssh connection which servers must reply to,
+// this keeps idle connections open and tells us if they've been dropped.
+func (s *sftpSession) keepalive() error {
+	if s == nil || s.conn == nil {
+		return errors.New("sftp: no ssh connection")
+	}
+	_, _, err := s.conn.SendRequest("keepalive@openssh.com", true, nil)
+	return err
+}
+
+func (s *sftpSession) Close() error {
+	if s == nil {
+		return nil
+	}
+	if s.client != nil {
+		s.client.Close()
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	return nil
+}
+
+// sftpPool holds up to size sessions to an SFTP server. Sessions are dialed as they're
+// needed and idle sessions are checked on each keepalive interval.
+type sftpPool struct {
+	dial   func() (*sftpSession, error)
+	check  func(*sftpSession) error
+	record func(error)
+
+	// slots bounds how many sessions can be in use at once
+	slots chan struct{}
+
+	mu     sync.Mutex
+	idle   []*sftpSession
+	closed bool
+
+	shutdown chan struct{}
+}
+
+func newSFTPPool(size int, keepalive time.Duration, dial func() (*sftpSession, error), record func(error)) *sftpPool {
+	if size <= 0 {
+		size = 1
+	}
+	pool := &sftpPool{
+		dial:     dial,
+		check:    (*sftpSession).keepalive,
+		record:   record,
+		slots:    make(chan struct{}, size),
+		shutdown: make(chan struct{}),
+	}
+	go pool.keepalives(keepalive)
+	return pool
+}
+
+// get returns an idle session, or dials a new one, once fewer than size sessions are in use.
+// Callers must return the session with put.
+func (p *sftpPool) get() (*sftpSession, error) {
+	p.slots <- struct{}{}
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			<-p.slots
+			return nil, errors.New("sftp: pool closed")
+		}
+		if n := len(p.idle); n > 0 {
+			sess := p.idle[n-1]
+			p.idle = p.idle[:n-1]
+			p.mu.Unlock()
+
+			// Verify the connection works and if not drop it and try another
+			if err := p.check(sess); err != nil {
+				sess.Close()
+				continue
+			}
+			return sess, nil
+		}
+		p.mu.Unlock()
+		break
+	}
+
+	sess, err := p.dial()
+	p.record(err)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	return sess, nil
+}
+
+// put returns a session to the pool. When the caller's operation failed the session is
+// kept only if its connection still responds.
+func (p *sftpPool) put(sess *sftpSession, err error) {
+	defer func() { <-p.slots }()
+
+	if err != nil {
+		if err := p.check(sess); err != nil {
+			p.record(err)
+			sess.Close()
+			return
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed || len(p.idle) >= cap(p.slots) {
+		sess.Close()
+		return
+	}
+	p.idle = append(p.idle, sess)
+}
+
+// withClient calls fn with an sftp.Client from the pool.
+func (p *sftpPool) withClient(fn func(*sftp.Client) error) error {
+	return p.withSession(func(sess *sftpSession) error {
+		return fn(sess.client)
+	})
+}
+
+// withSession calls fn with a session from the pool, for callers which also need its ssh connection.
+func (p *sftpPool) withSession(fn func(*sftpSession) error) error {
+	sess, err := p.get()
+	if err != nil {
+		return err
+	}
+	err = fn(sess)
+	p.put(sess, err)
+	return err
+}
+
+// size returns how many sessions can be used at once.
+func (p *sftpPool) size() int {
+	return cap(p.slots)
+}
+
+func (p *sftpPool) keepalives(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.checkIdle()
+
+		case <-p.shutdown:
+			return
+		}
+	}
+}
+
+// checkIdle sends a keepalive over each idle session and drops those which have disconnected.
+func (p *sftpPool) checkIdle() {
+	p.mu.Lock()
+	sessions := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+
+	var alive []*sftpSession
+	for i := range sessions {
+		err := p.check(sessions[i])
+		p.record(err)
+		if err != nil {
+			sessions[i].Close()
+			continue
+		}
+		alive = append(alive, sessions[i])
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := range alive {
+		if p.closed || len(p.idle) >= cap(p.slots) {
+			alive[i].Close()
+			continue
+		}
+		p.idle = append(p.idle, alive[i])
+	}
+}
+
+// Close drops every idle session and stops keepalives. Sessions in use are closed as they're returned.
+func (p *sftpPool) Close() error {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	close(p.shutdown)
+
+	for i := range p.idle {
+		p.idle[i].Close()
+	}
+	p.idle = nil
+	return nil
+}
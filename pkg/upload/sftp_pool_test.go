@@ -0,0 +1,150 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testPool struct {
+	*sftpPool
+
+	mu      sync.Mutex
+	dialed  int
+	broken  map[*sftpSession]bool
+	records []error
+}
+
+func newTestPool(t *testing.T, size int) *testPool {
+	tp := &testPool{broken: make(map[*sftpSession]bool)}
+	tp.sftpPool = newSFTPPool(size, time.Hour, func() (*sftpSession, error) {
+		tp.mu.Lock()
+		defer tp.mu.Unlock()
+		tp.dialed++
+		return &sftpSession{}, nil
+	}, func(err error) {
+		tp.mu.Lock()
+		defer tp.mu.Unlock()
+		tp.records = append(tp.records, err)
+	})
+	tp.check = func(sess *sftpSession) error {
+		tp.mu.Lock()
+		defer tp.mu.Unlock()
+		if tp.broken[sess] {
+			return errors.New("connection lost")
+		}
+		return nil
+	}
+	t.Cleanup(func() { tp.Close() })
+	return tp
+}
+
+func TestSFTPPool__reuse(t *testing.T) {
+	pool := newTestPool(t, 2)
+
+	sess, err := pool.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.put(sess, nil)
+
+	other, err := pool.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess != other {
+		t.Error("expected idle session to be reused")
+	}
+	if pool.dialed != 1 {
+		t.Errorf("dialed %d sessions", pool.dialed)
+	}
+
+	// A dropped connection is replaced
+	pool.broken[other] = true
+	pool.put(other, errors.New("EOF"))
+
+	sess, err = pool.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sess == other {
+		t.Error("expected broken session to be dropped")
+	}
+	if pool.dialed != 2 {
+		t.Errorf("dialed %d sessions", pool.dialed)
+	}
+	pool.put(sess, nil)
+}
+
+func TestSFTPPool__bounded(t *testing.T) {
+	pool := newTestPool(t, 2)
+
+	first, _ := pool.get()
+	second, _ := pool.get()
+
+	got := make(chan *sftpSession)
+	go func() {
+		sess, _ := pool.get()
+		got <- sess
+	}()
+
+	select {
+	case <-got:
+		t.Fatal("expected get to wait for a free session")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	pool.put(first, nil)
+	if sess := <-got; sess != first {
+		t.Error("expected the returned session")
+	}
+	pool.put(second, nil)
+
+	if pool.size() != 2 || pool.dialed != 2 {
+		t.Errorf("size=%d dialed=%d", pool.size(), pool.dialed)
+	}
+}
+
+func TestSFTPPool__checkIdle(t *testing.T) {
+	pool := newTestPool(t, 2)
+
+	first, _ := pool.get()
+	second, _ := pool.get()
+	pool.put(first, nil)
+	pool.put(second, nil)
+
+	pool.broken[first] = true
+	pool.checkIdle()
+
+	if len(pool.idle) != 1 || pool.idle[0] != second {
+		t.Errorf("unexpected idle sessions: %#v", pool.idle)
+	}
+	if n := len(pool.records); n == 0 || pool.records[n-1] != nil {
+		t.Errorf("expected healthy session to be recorded: %v", pool.records)
+	}
+}
+
+func TestSFTPPool__Close(t *testing.T) {
+	pool := newTestPool(t, 1)
+
+	sess, _ := pool.get()
+	pool.put(sess, nil)
+
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(pool.idle) != 0 {
+		t.Errorf("unexpected idle sessions: %#v", pool.idle)
+	}
+	if _, err := pool.get(); err == nil {
+		t.Error("expected error")
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -7,10 +7,15 @@
 package upload
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -24,6 +29,9 @@ import (
 
 	"github.com/go-kit/kit/log"
 	"github.com/ory/dockertest/v3"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 type sftpDeployment struct {
@@ -33,6 +41,17 @@ type sftpDeployment struct {
 	dir string // temporary directory
 }
 
+// stat inspects path on the server with one of the agent's pooled sessions
+func (s *sftpDeployment) stat(path string) (os.FileInfo, error) {
+	var info os.FileInfo
+	err := s.agent.pool.withClient(func(conn *sftp.Client) error {
+		var err error
+		info, err = conn.Stat(path)
+		return err
+	})
+	return info, err
+}
+
 func (s *sftpDeployment) close(t *testing.T) {
 	defer func() {
 		// Always try and cleanup our scratch dir
@@ -54,6 +73,17 @@ func (s *sftpDeployment) close(t *testing.T) {
 // You can verify this container launches with an ssh command like:
 //  $ ssh ssh://demo@127.0.0.1:33138 -s sftp
 func spawnSFTP(t *testing.T) *sftpDeployment {
+	return spawnSFTPWithOptions(t, sftpOptions{password: "password"})
+}
+
+type sftpOptions struct {
+	password string
+
+	// clientKey is authorized for the demo user when set, and used instead of a password
+	clientKey *rsa.PrivateKey
+}
+
+func spawnSFTPWithOptions(t *testing.T, opts sftpOptions) *sftpDeployment {
 	if testing.Short() {
 		t.Skip("-short flag enabled")
 	}
@@ -70,6 +100,33 @@ func spawnSFTP(t *testing.T) *sftpDeployment {
 	// Setup a temp directory for our SFTP instance
 	dir, uid, gid := mkdir(t)
 
+	mounts := []string{
+		fmt.Sprintf("%s:/home/demo/upload", dir),
+	}
+	var privateKey string
+	if opts.clientKey != nil {
+		pub, err := ssh.NewPublicKey(&opts.clientKey.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keysDir, err := ioutil.TempDir("", "sftp-keys")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.RemoveAll(keysDir) })
+
+		path := filepath.Join(keysDir, "id_rsa.pub")
+		if err := ioutil.WriteFile(path, ssh.MarshalAuthorizedKey(pub), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mounts = append(mounts, fmt.Sprintf("%s:/home/demo/.ssh/keys/id_rsa.pub:ro", path))
+
+		privateKey = string(pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(opts.clientKey),
+		}))
+	}
+
 	// Start our Docker image
 	pool, err := dockertest.NewPool("")
 	if err != nil {
@@ -80,11 +137,9 @@ func spawnSFTP(t *testing.T) *sftpDeployment {
 		Tag:        "latest",
 		// set user and group to grant write permissions
 		Cmd: []string{
-			fmt.Sprintf("demo:password:%d:%d:upload", uid, gid),
-		},
-		Mounts: []string{
-			fmt.Sprintf("%s:/home/demo/upload", dir),
+			fmt.Sprintf("demo:%s:%d:%d:upload", opts.password, uid, gid),
 		},
+		Mounts: mounts,
 	})
 	if err != nil {
 		t.Fatal(err)
@@ -94,7 +149,7 @@ func spawnSFTP(t *testing.T) *sftpDeployment {
 	var agent *SFTPTransferAgent
 	for i := 0; i < 10; i++ {
 		if agent == nil {
-			agent, err = newAgent(addr, "demo", "password", "")
+			agent, err = newAgent(addr, "demo", opts.password, privateKey)
 			time.Sleep(250 * time.Millisecond)
 		}
 	}
@@ -192,7 +247,7 @@ func TestSFTP__password(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	files, err := deployment.agent.GetInboundFiles()
+	files, err := deployment.agent.ListInboundFiles()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -209,17 +264,25 @@ func TestSFTP__password(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	files, err = deployment.agent.GetReturnFiles()
+	files, err = deployment.agent.ListReturnFiles()
 	if err != nil {
 		t.Fatal(err)
 	}
 	if len(files) != 1 || files[0].Filename != "return-WEB.ach" {
-		t.Errorf("%d of files: %#v", len(files), files)
+		t.Fatalf("%d of files: %#v", len(files), files)
+	}
+	f, err := deployment.agent.Open(files[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if bs, _ := ioutil.ReadAll(f.Contents); int64(len(bs)) != files[0].Size {
+		t.Errorf("read %d bytes, expected %d", len(bs), files[0].Size)
 	}
 }
 
-// TestSFTP__readFilesEmpty is setup to encounter error cases with readFiles
-func TestSFTP__readFilesEmpty(t *testing.T) {
+// TestSFTP__listFilesEmpty is setup to encounter error cases with listFiles
+func TestSFTP__listFilesEmpty(t *testing.T) {
 	deployment := spawnSFTP(t)
 	defer deployment.close(t)
 
@@ -238,45 +301,116 @@ func TestSFTP__readFilesEmpty(t *testing.T) {
 
 	path := filepath.Join(deployment.agent.OutboundPath(), "upload.ach")
 
-	// Truncate and then copy down
-	if err := deployment.agent.client.Truncate(path, 0); err != nil {
-		t.Fatal(err)
+	// The failed upload shouldn't leave anything behind
+	for _, p := range []string{path, filepath.Join(deployment.agent.OutboundPath(), tempFilename("upload.ach"))} {
+		if _, err := deployment.stat(p); !os.IsNotExist(err) {
+			t.Errorf("expected %s to not exist: %v", p, err)
+		}
 	}
-	info, err := deployment.agent.client.Stat(path)
+
+	// Create an empty file as our ODFI might
+	err = deployment.agent.pool.withClient(func(conn *sftp.Client) error {
+		fd, err := conn.Create(path)
+		if err != nil {
+			return err
+		}
+		return fd.Close()
+	})
 	if err != nil {
 		t.Fatal(err)
 	}
-	if n := info.Size(); n != 0 {
-		t.Errorf("upload.ach is %d bytes", n)
-	}
 
-	// Read the empty file
-	files, err := deployment.agent.readFiles(deployment.agent.OutboundPath())
-	if err == nil || !strings.Contains(err.Error(), "sftp: read (n=0) on upload.ach") {
+	// List the empty file
+	files, err := deployment.agent.listFiles(deployment.agent.OutboundPath())
+	if err != nil {
 		t.Fatal(err)
 	}
-	if len(files) != 0 {
+	if len(files) != 1 || files[0].Size != 0 {
 		t.Errorf("files: %#v", files)
 	}
 
-	// read a non-existent directory
-	files, err = deployment.agent.readFiles("/dev/null")
+	// list a non-existent directory
+	files, err = deployment.agent.listFiles("/dev/null")
 	if err == nil {
 		t.Errorf("expected error -- files: %#v", files)
 	}
 }
 
-// Generate keys (in Go) and mount them into our test container
-//
-// docker run \
-//     -v /host/id_rsa.pub:/home/foo/.ssh/keys/id_rsa.pub:ro \
-//     -v /host/id_other.pub:/home/foo/.ssh/keys/id_other.pub:ro \
-//     -v /host/share:/home/foo/share \
-//     -p 2222:22 -d atmoz/sftp \
-//     foo::1001
+func TestSFTP__ClientPrivateKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-func TestSFTP__ClientPrivateKey(t *testing.T) { // TODO(adam): need to write this test
+	deployment := spawnSFTPWithOptions(t, sftpOptions{clientKey: key})
+	defer deployment.close(t)
 
+	err = deployment.agent.UploadFile(File{
+		Filename: "upload.ach",
+		Contents: ioutil.NopCloser(strings.NewReader("test data")),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Record the server's host key
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hostKey ssh.PublicKey
+	conn, err := ssh.Dial("tcp", deployment.agent.cfg.SFTP.Hostname, &ssh.ClientConfig{
+		User: "demo",
+		Auth: []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	// Connect again in strict mode with the host key in our known_hosts file
+	dir, err := ioutil.TempDir("", "sftp-known-hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{deployment.agent.cfg.SFTP.Hostname}, hostKey)
+	if err := ioutil.WriteFile(path, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	strict := deployment.agent.cfg
+	strict.SFTP = &config.SFTP{
+		Hostname:              deployment.agent.cfg.SFTP.Hostname,
+		Username:              "demo",
+		ClientPrivateKey:      deployment.agent.cfg.SFTP.ClientPrivateKey,
+		KnownHostsFile:        path,
+		StrictHostKeyChecking: true,
+	}
+	agent, err := newSFTPTransferAgent(log.NewNopLogger(), strict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer agent.Close()
+
+	if err := agent.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unknown host key is refused
+	other := generateHostKey(t)
+	line = knownhosts.Line([]string{deployment.agent.cfg.SFTP.Hostname}, other)
+	if err := ioutil.WriteFile(path, []byte(line+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newSFTPTransferAgent(log.NewNopLogger(), strict); err == nil {
+		t.Fatal("expected error")
+	}
 }
 
 func TestSFTP__uploadFile(t *testing.T) {
@@ -296,6 +430,16 @@ func TestSFTP__uploadFile(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	info, err := deployment.stat(filepath.Join("upload", "foo", "upload.ach"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len("test data")) {
+		t.Errorf("upload.ach is %d bytes", info.Size())
+	}
+	if _, err := deployment.stat(filepath.Join("upload", "foo", tempFilename("upload.ach"))); !os.IsNotExist(err) {
+		t.Errorf("expected temporary file to be renamed: %v", err)
+	}
 
 	// fail to create the OutboundPath
 	deployment.agent.cfg.OutboundPath = string(os.PathSeparator) + filepath.Join("home", "bad-path")
@@ -377,6 +521,15 @@ func TestSFTP__sftpConnect(t *testing.T) {
 	}
 }
 
+func TestSFTP__shellQuote(t *testing.T) {
+	if v := shellQuote("outbound/.20200529.ach.part"); v != "'outbound/.20200529.ach.part'" {
+		t.Errorf("unexpected quoting: %s", v)
+	}
+	if v := shellQuote("it's.ach"); v != `'it'\''s.ach'` {
+		t.Errorf("unexpected quoting: %s", v)
+	}
+}
+
 func TestSFTPAgent(t *testing.T) {
 	agent := &SFTPTransferAgent{
 		cfg: config.ODFI{
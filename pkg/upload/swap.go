@@ -0,0 +1,180 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+var errNoAgent = errors.New("upload: no Agent configured")
+
+// SwapAgent is an Agent whose underlying connection can be replaced while PayGate runs,
+// such as after its config file is reloaded with new credentials or allowed IPs.
+//
+// Calls (and opened Files) which started on the previous Agent finish on it before it's closed.
+type SwapAgent struct {
+	mu      sync.RWMutex
+	current *swappedAgent
+}
+
+type swappedAgent struct {
+	agent    Agent
+	inflight sync.WaitGroup
+}
+
+// NewSwapAgent wraps agent, which can be nil if the initial connection failed to be created.
+func NewSwapAgent(agent Agent) *SwapAgent {
+	return &SwapAgent{
+		current: &swappedAgent{agent: agent},
+	}
+}
+
+// Swap replaces the underlying Agent with next and closes the previous Agent once all
+// calls in progress on it have returned.
+func (s *SwapAgent) Swap(next Agent) error {
+	s.mu.Lock()
+	prev := s.current
+	s.current = &swappedAgent{agent: next}
+	s.mu.Unlock()
+
+	prev.inflight.Wait()
+	if prev.agent != nil {
+		return prev.agent.Close()
+	}
+	return nil
+}
+
+// acquire returns the current Agent, which must be released with inflight.Done()
+func (s *SwapAgent) acquire() (*swappedAgent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.current.agent == nil {
+		return nil, errNoAgent
+	}
+	s.current.inflight.Add(1)
+	return s.current, nil
+}
+
+func (s *SwapAgent) agent() Agent {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.agent
+}
+
+func (s *SwapAgent) ListInboundFiles() ([]FileInfo, error) {
+	cur, err := s.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer cur.inflight.Done()
+	return cur.agent.ListInboundFiles()
+}
+
+func (s *SwapAgent) ListReturnFiles() ([]FileInfo, error) {
+	cur, err := s.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer cur.inflight.Done()
+	return cur.agent.ListReturnFiles()
+}
+
+// Open streams a file from the current Agent, which is kept open until the File is closed.
+func (s *SwapAgent) Open(path string) (File, error) {
+	cur, err := s.acquire()
+	if err != nil {
+		return File{}, err
+	}
+	f, err := cur.agent.Open(path)
+	if err != nil || f.Contents == nil {
+		cur.inflight.Done()
+		return f, err
+	}
+	f.Contents = &releasingReader{ReadCloser: f.Contents, release: cur.inflight.Done}
+	return f, nil
+}
+
+// releasingReader marks a SwapAgent call as finished once the File is closed.
+type releasingReader struct {
+	io.ReadCloser
+
+	once    sync.Once
+	release func()
+}
+
+func (r *releasingReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
+
+func (s *SwapAgent) UploadFile(f File) error {
+	cur, err := s.acquire()
+	if err != nil {
+		return err
+	}
+	defer cur.inflight.Done()
+	return cur.agent.UploadFile(f)
+}
+
+func (s *SwapAgent) Delete(path string) error {
+	cur, err := s.acquire()
+	if err != nil {
+		return err
+	}
+	defer cur.inflight.Done()
+	return cur.agent.Delete(path)
+}
+
+func (s *SwapAgent) InboundPath() string {
+	if agent := s.agent(); agent != nil {
+		return agent.InboundPath()
+	}
+	return ""
+}
+
+func (s *SwapAgent) OutboundPath() string {
+	if agent := s.agent(); agent != nil {
+		return agent.OutboundPath()
+	}
+	return ""
+}
+
+func (s *SwapAgent) ReturnPath() string {
+	if agent := s.agent(); agent != nil {
+		return agent.ReturnPath()
+	}
+	return ""
+}
+
+// Concurrency reports how many files the current Agent can read at once.
+func (s *SwapAgent) Concurrency() int {
+	if a, ok := s.agent().(concurrentAgent); ok {
+		return a.Concurrency()
+	}
+	return 1
+}
+
+func (s *SwapAgent) Ping() error {
+	cur, err := s.acquire()
+	if err != nil {
+		return err
+	}
+	defer cur.inflight.Done()
+	return cur.agent.Ping()
+}
+
+func (s *SwapAgent) Close() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.current.agent != nil {
+		return s.current.agent.Close()
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package upload
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type closingAgent struct {
+	*MockAgent
+	closed int32
+}
+
+func (a *closingAgent) Close() error {
+	atomic.AddInt32(&a.closed, 1)
+	return nil
+}
+
+func (a *closingAgent) isClosed() bool {
+	return atomic.LoadInt32(&a.closed) > 0
+}
+
+func TestSwapAgent__missing(t *testing.T) {
+	agent := NewSwapAgent(nil)
+
+	if err := agent.Ping(); err != errNoAgent {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := agent.ListInboundFiles(); err != errNoAgent {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if path := agent.OutboundPath(); path != "" {
+		t.Errorf("unexpected path %q", path)
+	}
+	if n := agent.Concurrency(); n != 1 {
+		t.Errorf("unexpected concurrency %d", n)
+	}
+	if err := agent.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a connection can be made later on
+	if err := agent.Swap(&MockAgent{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := agent.Ping(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSwapAgent(t *testing.T) {
+	first := &closingAgent{
+		MockAgent: &MockAgent{
+			InboundFiles: []File{
+				{
+					Filename: "ppd-debit.ach",
+					Contents: ioutil.NopCloser(strings.NewReader("nacha")),
+				},
+			},
+		},
+	}
+	agent := NewSwapAgent(first)
+
+	f, err := agent.Open("inbound/ppd-debit.ach")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second := &closingAgent{MockAgent: &MockAgent{Err: errors.New("bad credentials")}}
+	swapped := make(chan error)
+	go func() {
+		swapped <- agent.Swap(second)
+	}()
+
+	// the first agent is kept open until the file is closed
+	select {
+	case <-swapped:
+		t.Fatal("expected Swap to wait on the open file")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if first.isClosed() {
+		t.Fatal("first agent closed too early")
+	}
+
+	if bs, _ := ioutil.ReadAll(f.Contents); string(bs) != "nacha" {
+		t.Errorf("unexpected contents: %q", string(bs))
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-swapped; err != nil {
+		t.Fatal(err)
+	}
+	if !first.isClosed() {
+		t.Error("expected first agent to be closed")
+	}
+
+	// calls are made on the new agent
+	if err := agent.Ping(); err == nil || err.Error() != "bad credentials" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if second.isClosed() {
+		t.Error("second agent shouldn't be closed")
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+// Package gpgx wraps golang.org/x/crypto/openpgp for encrypting, signing,
+// decrypting and verifying files with GPG keys read from disk.
+package gpgx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+var (
+	ErrNotSigned = errors.New("gpgx: message is not signed")
+)
+
+// ReadArmoredKeyFile reads the ASCII armored public (or private) keys at path.
+func ReadArmoredKeyFile(path string) (openpgp.EntityList, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	keys, err := openpgp.ReadArmoredKeyRing(fd)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	return keys, nil
+}
+
+// ReadPrivateKeyFile reads the ASCII armored private keys at path and decrypts them
+// with password if they're protected.
+func ReadPrivateKeyFile(path string, password []byte) (openpgp.EntityList, error) {
+	keys, err := ReadArmoredKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if key.PrivateKey == nil {
+			return nil, fmt.Errorf("%s: key %X is not a private key", path, key.PrimaryKey.KeyId)
+		}
+		if key.PrivateKey.Encrypted {
+			if err := key.PrivateKey.Decrypt(password); err != nil {
+				return nil, fmt.Errorf("%s: decrypting key %X: %v", path, key.PrimaryKey.KeyId, err)
+			}
+		}
+		for _, sub := range key.Subkeys {
+			if sub.PrivateKey != nil && sub.PrivateKey.Encrypted {
+				if err := sub.PrivateKey.Decrypt(password); err != nil {
+					return nil, fmt.Errorf("%s: decrypting subkey %X: %v", path, sub.PublicKey.KeyId, err)
+				}
+			}
+		}
+	}
+	return keys, nil
+}
+
+// Encrypt encrypts msg for each recipient and signs it with signer if non-nil.
+func Encrypt(msg []byte, recipients openpgp.EntityList, signer *openpgp.Entity) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("gpgx: no recipients")
+	}
+	var buf bytes.Buffer
+	w, err := openpgp.Encrypt(&buf, recipients, signer, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decrypt reads an encrypted (and optionally signed) message with keys.
+//
+// If verifiers is non-empty the message must be signed by one of them.
+func Decrypt(r io.Reader, keys openpgp.EntityList, verifiers openpgp.EntityList) ([]byte, error) {
+	var keyring openpgp.EntityList
+	keyring = append(keyring, keys...)
+	keyring = append(keyring, verifiers...)
+
+	md, err := openpgp.ReadMessage(r, keyring, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The signature is only checked once the body has been fully read
+	bs, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, err
+	}
+	if len(verifiers) > 0 {
+		if !md.IsSigned {
+			return nil, ErrNotSigned
+		}
+		if md.SignedBy == nil || !signedBy(verifiers, md.SignedByKeyId) {
+			return nil, fmt.Errorf("gpgx: message signed by unknown key %X", md.SignedByKeyId)
+		}
+		if md.SignatureError != nil {
+			return nil, fmt.Errorf("gpgx: invalid signature: %v", md.SignatureError)
+		}
+	}
+	return bs, nil
+}
+
+func signedBy(keys openpgp.EntityList, keyID uint64) bool {
+	return len(keys.KeysById(keyID)) > 0
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 The Moov Authors
+// Use of this source code is governed by an Apache License
+// license that can be found in the LICENSE file.
+
+package gpgx
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	testdata = filepath.Join("..", "..", "testdata", "gpg")
+
+	// paygate.priv is protected with this password
+	password = []byte("password")
+)
+
+func TestGPGX__ReadKeys(t *testing.T) {
+	keys, err := ReadArmoredKeyFile(filepath.Join(testdata, "odfi.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].PrivateKey != nil {
+		t.Errorf("unexpected keys: %#v", keys)
+	}
+
+	if _, err := ReadPrivateKeyFile(filepath.Join(testdata, "odfi.pub"), nil); err == nil {
+		t.Error("expected error")
+	}
+	if _, err := ReadPrivateKeyFile(filepath.Join(testdata, "paygate.priv"), []byte("wrong")); err == nil {
+		t.Error("expected error")
+	}
+	keys, err = ReadPrivateKeyFile(filepath.Join(testdata, "paygate.priv"), password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].PrivateKey.Encrypted {
+		t.Errorf("unexpected keys: %#v", keys)
+	}
+
+	if _, err := ReadArmoredKeyFile(filepath.Join(testdata, "missing.pub")); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestGPGX__EncryptDecrypt(t *testing.T) {
+	odfiPub, err := ReadArmoredKeyFile(filepath.Join(testdata, "odfi.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	odfiPriv, err := ReadPrivateKeyFile(filepath.Join(testdata, "odfi.priv"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paygatePub, err := ReadArmoredKeyFile(filepath.Join(testdata, "paygate.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	paygatePriv, err := ReadPrivateKeyFile(filepath.Join(testdata, "paygate.priv"), password)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("hello, world")
+
+	// encrypted for the ODFI and signed by us
+	encrypted, err := Encrypt(msg, odfiPub, paygatePriv[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(encrypted, msg) {
+		t.Fatal("message wasn't encrypted")
+	}
+	decrypted, err := Decrypt(bytes.NewReader(encrypted), odfiPriv, paygatePub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg, decrypted) {
+		t.Errorf("decrypted=%q", decrypted)
+	}
+
+	// signed by another key
+	if _, err := Decrypt(bytes.NewReader(encrypted), odfiPriv, odfiPub); err == nil {
+		t.Error("expected error")
+	}
+
+	// unsigned messages are rejected when verifying
+	encrypted, err = Encrypt(msg, odfiPub, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(bytes.NewReader(encrypted), odfiPriv, paygatePub); err != ErrNotSigned {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := Decrypt(bytes.NewReader(encrypted), odfiPriv, nil); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := Encrypt(msg, nil, nil); err == nil {
+		t.Error("expected error")
+	}
+}
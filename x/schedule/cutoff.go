@@ -7,6 +7,7 @@ package schedule
 import (
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/moov-io/base"
@@ -20,12 +21,15 @@ type CutoffTimes struct {
 	C chan time.Time
 
 	sched *cron.Cron
+	done  chan struct{}
+	once  sync.Once
 }
 
 func ForCutoffTimes(tz string, timestamps []string) (*CutoffTimes, error) {
 	ct := &CutoffTimes{
 		C:     make(chan time.Time),
 		sched: cron.New(),
+		done:  make(chan struct{}),
 	}
 	if err := ct.registerCutoffs(tz, timestamps); err != nil {
 		return nil, err
@@ -34,22 +38,29 @@ func ForCutoffTimes(tz string, timestamps []string) (*CutoffTimes, error) {
 	return ct, nil
 }
 
+// Stop prevents any further cutoffs from firing. C is not closed so a replacement
+// CutoffTimes can be swapped in by the reader without it seeing a zero time.
 func (ct *CutoffTimes) Stop() {
 	if ct == nil {
 		return
 	}
-	if ct.C != nil {
-		close(ct.C)
-	}
-	if ct.sched != nil {
-		ct.sched.Stop()
-	}
+	ct.once.Do(func() {
+		if ct.sched != nil {
+			ct.sched.Stop()
+		}
+		if ct.done != nil {
+			close(ct.done)
+		}
+	})
 }
 
 func (ct *CutoffTimes) maybeTick() {
 	now := base.Now()
 	if !now.IsWeekend() && now.IsBankingDay() {
-		ct.C <- now.Time.In(time.Local)
+		select {
+		case ct.C <- now.Time.In(time.Local):
+		case <-ct.done:
+		}
 	}
 }
 